@@ -0,0 +1,202 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// parseFlags parses args with fs after expanding its args files, see
+// expandArgFiles. Errors about a flag from an args file cite its file and
+// line. -h and -help print the usage and exit.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	args, origins, err := expandArgFiles(fs, args)
+	if err != nil {
+		return errors.Wrap(err, "expand args files")
+	}
+
+	// The flag set must not exit or print on its own, so that the error
+	// can be returned with the origin of the flag it is about.
+	out := fs.Output()
+	fs.Init(fs.Name(), flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	err = fs.Parse(args)
+	fs.SetOutput(out)
+
+	if err == nil {
+		return nil
+	}
+
+	printUsage(fs)
+
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+
+	if origin := origins[failedArg(fs, args, err)]; origin != "" {
+		return errors.Wrap(err, origin)
+	}
+
+	return err
+}
+
+// failedArg returns the index in args of the flag fs failed to parse with
+// err. The flag set has consumed it, and its value if that was the next
+// argument, unless its syntax was bad.
+func failedArg(fs *flag.FlagSet, args []string, err error) int {
+	i := len(args) - len(fs.Args())
+	if strings.HasPrefix(err.Error(), "bad flag syntax") {
+		return min(i, len(args)-1)
+	}
+
+	i--
+	if i >= 1 && takesValue(fs, args[i-1]) {
+		i--
+	}
+
+	return max(i, 0)
+}
+
+// printUsage prints the usage of fs, as the flag set would on an error.
+func printUsage(fs *flag.FlagSet) {
+	if fs.Usage != nil {
+		fs.Usage()
+		return
+	}
+
+	fmt.Fprintf(fs.Output(), "Usage of %s:\n", fs.Name())
+	fs.PrintDefaults()
+}
+
+// expandArgFiles replaces every "@path" argument among the flags of fs with
+// the flags listed in that file. Each non-empty line holds one flag, either
+// as "-flag", "-flag=value" or "-flag value". Lines starting with "#" are
+// comments. Argument files cannot include other argument files. origins
+// holds the "path:line" every argument came from, or "" for the command
+// line.
+//
+// Flag values are left alone, so "-exclude @#" works, and so is everything
+// from the first positional argument or "--" on, which is for subcommands to
+// parse. A leading "@@" stands for a literal "@".
+func expandArgFiles(fs *flag.FlagSet, args []string) (ret []string, origins []string, err error) {
+	add := func(origin string, args ...string) {
+		for _, arg := range args {
+			ret = append(ret, arg)
+			origins = append(origins, origin)
+		}
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case strings.HasPrefix(arg, "@@"):
+			add("", arg[1:])
+		case strings.HasPrefix(arg, "@") && len(arg) > 1:
+			fileArgs, fileOrigins, err := readArgFile(arg[1:])
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "read args file")
+			}
+
+			ret = append(ret, fileArgs...)
+			origins = append(origins, fileOrigins...)
+		case arg == "--" || arg == "-" || !strings.HasPrefix(arg, "-"):
+			add("", args[i:]...)
+			return ret, origins, nil
+		case takesValue(fs, arg) && i+1 < len(args):
+			add("", arg, args[i+1])
+			i++
+		default:
+			add("", arg)
+		}
+	}
+
+	return ret, origins, nil
+}
+
+// takesValue reports whether arg is a flag of fs whose value is the next
+// argument.
+func takesValue(fs *flag.FlagSet, arg string) bool {
+	name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+	if strings.Contains(name, "=") {
+		return false
+	}
+
+	f := fs.Lookup(name)
+	if f == nil {
+		return false
+	}
+
+	if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		return false
+	}
+
+	return true
+}
+
+// readArgFile returns the arguments listed in the args file at path, and
+// the "path:line" each of them is on.
+func readArgFile(path string) ([]string, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var ret, origins []string
+
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		origin := fmt.Sprintf("%v:%v", path, lineNum)
+
+		if strings.HasPrefix(line, "@") {
+			return nil, nil, fmt.Errorf("%v: args files cannot include other args files", origin)
+		}
+
+		if !strings.HasPrefix(line, "-") {
+			return nil, nil, fmt.Errorf("%v: expected a flag starting with '-', got %q", origin, line)
+		}
+
+		name, value, hasValue := strings.Cut(line, " ")
+		if hasValue && !strings.Contains(name, "=") {
+			ret = append(ret, name, strings.TrimSpace(value))
+			origins = append(origins, origin, origin)
+		} else {
+			ret = append(ret, line)
+			origins = append(origins, origin)
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "scan lines")
+	}
+
+	return ret, origins, nil
+}
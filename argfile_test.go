@@ -0,0 +1,186 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExpandArgFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.args")
+	err := os.WriteFile(path, []byte("# policy\n-length 14\n\n-big\n-exclude=@#\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Uint("length", 0, "")
+	fs.String("exclude", "", "")
+	fs.String("special-chars", "", "")
+	fs.Bool("big", false, "")
+
+	for _, tc := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"@" + path}, []string{"-length", "14", "-big", "-exclude=@#"}},
+		{[]string{"-special-chars", "@!", "-exclude", "@#"}, []string{"-special-chars", "@!", "-exclude", "@#"}},
+		{[]string{"-big", "@" + path}, []string{"-big", "-length", "14", "-big", "-exclude=@#"}},
+		{[]string{"@@literal"}, []string{"@literal"}},
+		{[]string{"--", "@" + path}, []string{"--", "@" + path}},
+		{[]string{"name", "@" + path}, []string{"name", "@" + path}},
+	} {
+		got, origins, err := expandArgFiles(fs, tc.args)
+		if err != nil {
+			t.Errorf("%q: %v", tc.args, err)
+			continue
+		}
+
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%q: got %q, want %q", tc.args, got, tc.want)
+		}
+
+		if len(origins) != len(got) {
+			t.Errorf("%q: got %v origins for %v args", tc.args, len(origins), len(got))
+		}
+	}
+}
+
+func TestExpandArgFilesErrors(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "nested.args")
+	err := os.WriteFile(nested, []byte("-length 14\n@other.args\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+
+	_, _, err = expandArgFiles(fs, []string{"@" + nested})
+	if err == nil || !strings.Contains(err.Error(), nested+":2:") {
+		t.Errorf("nested include: got %v, want an error citing line 2", err)
+	}
+
+	_, _, err = expandArgFiles(fs, []string{"@" + filepath.Join(dir, "missing")})
+	if err == nil || strings.Contains(err.Error(), "open: open") {
+		t.Errorf("missing file: got %v", err)
+	}
+}
+
+func TestExpandArgFilesOrigins(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.args")
+	err := os.WriteFile(path, []byte("# policy\n-length 14\n\n-big\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Uint("length", 0, "")
+	fs.Bool("big", false, "")
+
+	_, origins, err := expandArgFiles(fs, []string{"-length", "3", "@" + path, "-big"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"", "", path + ":2", path + ":2", path + ":4", ""}
+	if !slices.Equal(origins, want) {
+		t.Errorf("got %q, want %q", origins, want)
+	}
+}
+
+func TestParseFlagsCitesArgFile(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, tc := range []struct {
+		file string
+		args []string
+		// want is the line the error must cite, or 0 for none.
+		want int
+	}{
+		{"-length 14\n-upper x\n", nil, 2},
+		{"-length 14\n-upper=x\n", nil, 2},
+		{"# unknown\n-nope\n", nil, 2},
+		{"-length 14\n\n-big\n---length 3\n", nil, 4},
+		{"-length x\n-upper 3\n", []string{"-big"}, 1},
+		{"-length 14\n-upper\n", nil, 2},
+		{"-length 14\n", []string{"-upper", "x"}, 0},
+		{"-length 14\n", []string{"-nope"}, 0},
+	} {
+		path := filepath.Join(dir, "policy.args")
+		err := os.WriteFile(path, []byte(tc.file), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fs := flag.NewFlagSet("test", flag.ExitOnError)
+		fs.SetOutput(io.Discard)
+		fs.Uint("length", 0, "")
+		fs.Uint("upper", 0, "")
+		fs.Bool("big", false, "")
+
+		args := append(tc.args[:len(tc.args):len(tc.args)], "@"+path)
+		if tc.want == 0 {
+			args = append([]string{"@" + path}, tc.args...)
+		}
+
+		err = parseFlags(fs, args)
+		if err == nil {
+			t.Errorf("%q %q: expected an error", tc.file, args)
+			continue
+		}
+
+		cited := strings.Contains(err.Error(), path+":")
+		if tc.want == 0 && cited {
+			t.Errorf("%q %q: got %v, want no args file cited", tc.file, args, err)
+		}
+
+		if tc.want != 0 && !strings.HasPrefix(err.Error(), fmt.Sprintf("%v:%v: ", path, tc.want)) {
+			t.Errorf("%q %q: got %v, want an error citing line %v", tc.file, args, err, tc.want)
+		}
+	}
+}
+
+func TestArgFileErrorExitCode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.args")
+	err := os.WriteFile(path, []byte("-length 14\n-upper x\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, stderr, err := runCpass(t, "", "@"+path)
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 {
+		t.Errorf("got %v, want exit code 2", err)
+	}
+
+	if !strings.Contains(stderr, "Error: parse flags: "+path+":2: invalid value \"x\" for flag -upper") {
+		t.Errorf("the error doesn't cite the args file:\n%s", stderr)
+	}
+}
@@ -0,0 +1,192 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//go:embed data/bigfont.txt
+var bigFontData string
+
+const (
+	bigFontHeight = 6
+	// bigPixel is printed for every ink pixel. It is two columns wide to
+	// make the pixels roughly square in a terminal.
+	bigPixel       = "██"
+	bigBlank       = "  "
+	bigCapitalMark = "^^"
+	bigGlyphGap    = "  "
+)
+
+type bigGlyph struct {
+	rows  [bigFontHeight]string
+	width int
+}
+
+var loadBigFont = sync.OnceValues(func() (map[byte]bigGlyph, error) {
+	return parseBigFont(bigFontData)
+})
+
+func parseBigFont(data string) (map[byte]bigGlyph, error) {
+	font := make(map[byte]bigGlyph)
+
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if len(line) != 3 || !strings.HasPrefix(line, ": ") {
+			return nil, fmt.Errorf("line %v: expected a glyph header, got %q", i+1, line)
+		}
+
+		c := line[2]
+		if _, ok := font[c]; ok {
+			return nil, fmt.Errorf("line %v: duplicate glyph for %q", i+1, c)
+		}
+
+		if i+bigFontHeight >= len(lines) {
+			return nil, fmt.Errorf("line %v: truncated glyph for %q", i+1, c)
+		}
+
+		var g bigGlyph
+		for j := range g.rows {
+			row := lines[i+1+j]
+			if strings.Trim(row, "#.") != "" || row == "" || (j != 0 && len(row) != g.width) {
+				return nil, fmt.Errorf("line %v: malformed row of glyph %q", i+2+j, c)
+			}
+
+			g.rows[j] = row
+			g.width = len(row)
+		}
+
+		font[c] = g
+		i += bigFontHeight
+	}
+
+	return font, nil
+}
+
+func isCapital(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+// renderBig renders the password in block letters, wrapping to at most width
+// columns, and returns the number of lines written. Capitals get a marker row
+// under them. The rendered output is wiped after it has been written.
+func renderBig(w io.Writer, pw []byte, width int) (int, error) {
+	font, err := loadBigFont()
+	if err != nil {
+		return 0, errors.Wrap(err, "bug: parse embedded big font")
+	}
+
+	glyphs := make([]bigGlyph, len(pw))
+	size := 0
+	for i, c := range pw {
+		g, ok := font[c]
+		if !ok {
+			return 0, fmt.Errorf("no big glyph for character %q", c)
+		}
+
+		glyphs[i] = g
+		size += (bigFontHeight+1)*(g.width*len(bigPixel)+len(bigGlyphGap)) + bigFontHeight + 1
+	}
+
+	// Allocate the whole buffer upfront so that appending never leaves
+	// unwiped copies of the rendered password behind.
+	buf := make([]byte, 0, size)
+	defer func() { wipe(buf) }()
+
+	var lines int
+	for start := 0; start < len(pw); {
+		// Fit as many glyphs on this line as the width allows, but always at
+		// least one so that narrow terminals still make progress.
+		end, used := start, 0
+		for end < len(pw) {
+			cols := glyphs[end].width*len(bigBlank) + len(bigGlyphGap)
+			if end != start && used+cols > width {
+				break
+			}
+
+			used += cols
+			end++
+		}
+
+		for row := 0; row <= bigFontHeight; row++ {
+			for i := start; i < end; i++ {
+				g := glyphs[i]
+				for x := 0; x < g.width; x++ {
+					switch {
+					case row == bigFontHeight && isCapital(pw[i]):
+						buf = append(buf, bigCapitalMark...)
+					case row < bigFontHeight && g.rows[row][x] == '#':
+						buf = append(buf, bigPixel...)
+					default:
+						buf = append(buf, bigBlank...)
+					}
+				}
+
+				buf = append(buf, bigGlyphGap...)
+			}
+
+			buf = append(buf, '\n')
+			lines++
+		}
+
+		start = end
+	}
+
+	_, err = w.Write(buf)
+	if err != nil {
+		return 0, errors.Wrap(err, "write big text")
+	}
+
+	return lines, nil
+}
+
+// showBig displays the password in block letters. When stdout is a terminal,
+// the banner is cleared from the screen once the user presses Enter, the ttl
+// expires, or cpass is interrupted.
+func showBig(p *prompter, pw []byte, ttl time.Duration) (clearReason, error) {
+	lines, err := renderBig(os.Stdout, pw, terminalWidth())
+	if err != nil {
+		return clearNone, err
+	}
+
+	if !stdoutIsTerminal() {
+		return clearNone, nil
+	}
+
+	reason, err := waitForClear(p, ttl, clearFromScreen)
+	if err != nil {
+		return clearNone, err
+	}
+
+	clearLinesAbove(lines + 1)
+
+	return reason, nil
+}
@@ -0,0 +1,87 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRenderBigGolden(t *testing.T) {
+	var out bytes.Buffer
+	for _, tc := range []struct {
+		pw    string
+		width int
+	}{
+		{"aB3$", 200},
+		// The look-alikes are drawn distinctly, and the zero is slashed.
+		{"Il1|0O", 200},
+		// Wraps after as many glyphs as fit, and at least one per line.
+		{"Xy7-Zq", 40},
+		{"Ab", 1},
+	} {
+		var buf bytes.Buffer
+		lines, err := renderBig(&buf, []byte(tc.pw), tc.width)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.pw, err)
+		}
+
+		if n := bytes.Count(buf.Bytes(), []byte("\n")); n != lines {
+			t.Errorf("%q: reported %v lines, wrote %v", tc.pw, lines, n)
+		}
+
+		fmt.Fprintf(&out, "== %q at width %v, %v lines\n", tc.pw, tc.width, lines)
+		out.Write(buf.Bytes())
+	}
+
+	checkGolden(t, "bigtext.golden", out.Bytes())
+}
+
+func TestRenderBigCoversPrintableASCII(t *testing.T) {
+	for c := byte('!'); c <= '~'; c++ {
+		_, err := renderBig(&bytes.Buffer{}, []byte{c}, 80)
+		if err != nil {
+			t.Errorf("%q: %v", c, err)
+		}
+	}
+
+	_, err := renderBig(&bytes.Buffer{}, []byte("é"), 80)
+	if err == nil {
+		t.Error("a non-ASCII character rendered")
+	}
+}
+
+func TestParseBigFontErrors(t *testing.T) {
+	glyph := ": a\n.#.\n#.#\n###\n#.#\n#.#\n...\n"
+
+	for _, tc := range []struct {
+		name, data, want string
+	}{
+		{"header", "a\n", "line 1: expected a glyph header"},
+		{"duplicate", glyph + glyph, `line 8: duplicate glyph for 'a'`},
+		{"truncated", ": a\n.#.\n", `line 1: truncated glyph for 'a'`},
+		{"uneven rows", strings.Replace(glyph, "###", "####", 1), `line 4: malformed row of glyph 'a'`},
+		{"bad pixel", strings.Replace(glyph, "###", "#x#", 1), `line 4: malformed row of glyph 'a'`},
+	} {
+		_, err := parseBigFont(tc.data)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%v: got %v, want an error containing %q", tc.name, err, tc.want)
+		}
+	}
+}
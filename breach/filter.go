@@ -0,0 +1,208 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package breach implements the Bloom filter file cpass uses to check
+// passwords against known breaches offline.
+package breach
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	filterMagic = "cpassbf\x00"
+	// FilterVersion is the version of the filter file format written by
+	// WriteTo.
+	FilterVersion = 1
+	headerSize    = len(filterMagic) + 4 + 4 + 8 + 8 + 8
+)
+
+// FilterParams describes the shape of a filter.
+type FilterParams struct {
+	// Bits is the size of the filter in bits, always a multiple of 64.
+	Bits uint64
+	// Hashes is the number of bits set for every entry.
+	Hashes uint32
+	// Entries is the number of entries the filter was sized for.
+	Entries uint64
+	// TargetFPR is the false positive rate the filter was sized for.
+	TargetFPR float64
+}
+
+// ParamsFor returns the parameters of the smallest filter that holds entries
+// hashes with a false positive rate of at most fpr.
+func ParamsFor(entries uint64, fpr float64) (FilterParams, error) {
+	if entries == 0 {
+		return FilterParams{}, fmt.Errorf("the filter needs at least one entry")
+	}
+
+	if fpr <= 0 || fpr >= 1 {
+		return FilterParams{}, fmt.Errorf("false positive rate must be between 0 and 1")
+	}
+
+	bits := math.Ceil(-float64(entries) * math.Log(fpr) / (math.Ln2 * math.Ln2))
+	m := (uint64(bits) + 63) / 64 * 64
+	k := max(1, math.Round(float64(m)/float64(entries)*math.Ln2))
+
+	return FilterParams{
+		Bits:      m,
+		Hashes:    uint32(k),
+		Entries:   entries,
+		TargetFPR: fpr,
+	}, nil
+}
+
+// ExpectedFPR returns the false positive rate of the filter once it holds
+// the number of entries it was sized for.
+func (p FilterParams) ExpectedFPR() float64 {
+	k := float64(p.Hashes)
+	return math.Pow(1-math.Exp(-k*float64(p.Entries)/float64(p.Bits)), k)
+}
+
+// Filter is a Bloom filter over SHA-1 password hashes.
+type Filter struct {
+	FilterParams
+	bits []byte
+}
+
+func NewFilter(p FilterParams) *Filter {
+	return &Filter{
+		FilterParams: p,
+		bits:         make([]byte, p.Bits/8),
+	}
+}
+
+// positions calls fn with every bit position of hash. The SHA-1 hash is
+// already uniformly distributed, so it is split into the two halves of a
+// double hashing scheme instead of being hashed again.
+func (f *Filter) positions(hash [20]byte, fn func(uint64) bool) bool {
+	h1 := binary.LittleEndian.Uint64(hash[0:8])
+	h2 := binary.LittleEndian.Uint64(hash[8:16]) | 1
+
+	for i := uint64(0); i < uint64(f.Hashes); i++ {
+		if !fn((h1 + i*h2) % f.Bits) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f *Filter) Add(hash [20]byte) {
+	f.positions(hash, func(pos uint64) bool {
+		f.bits[pos/8] |= 1 << (pos % 8)
+		return true
+	})
+}
+
+// Contains reports whether hash may be in the filter. False positives happen
+// at roughly the rate the filter was sized for, false negatives never do.
+func (f *Filter) Contains(hash [20]byte) bool {
+	return f.positions(hash, func(pos uint64) bool {
+		return f.bits[pos/8]&(1<<(pos%8)) != 0
+	})
+}
+
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	var header [headerSize]byte
+	copy(header[:], filterMagic)
+	binary.LittleEndian.PutUint32(header[8:], FilterVersion)
+	binary.LittleEndian.PutUint32(header[12:], f.Hashes)
+	binary.LittleEndian.PutUint64(header[16:], f.Bits)
+	binary.LittleEndian.PutUint64(header[24:], f.Entries)
+	binary.LittleEndian.PutUint64(header[32:], math.Float64bits(f.TargetFPR))
+
+	n, err := w.Write(header[:])
+	if err != nil {
+		return int64(n), errors.Wrap(err, "write header")
+	}
+
+	m, err := w.Write(f.bits)
+
+	return int64(n + m), errors.Wrap(err, "write bits")
+}
+
+// ReadFilterHeader reads the parameters from the start of a filter file.
+func ReadFilterHeader(r io.Reader) (FilterParams, error) {
+	var header [headerSize]byte
+	_, err := io.ReadFull(r, header[:])
+	if err != nil {
+		return FilterParams{}, errors.Wrap(err, "read header")
+	}
+
+	if string(header[:8]) != filterMagic {
+		return FilterParams{}, fmt.Errorf("not a cpass breach filter")
+	}
+
+	if v := binary.LittleEndian.Uint32(header[8:]); v != FilterVersion {
+		return FilterParams{}, fmt.Errorf("unsupported filter version %v, expected %v", v, FilterVersion)
+	}
+
+	p := FilterParams{
+		Hashes:    binary.LittleEndian.Uint32(header[12:]),
+		Bits:      binary.LittleEndian.Uint64(header[16:]),
+		Entries:   binary.LittleEndian.Uint64(header[24:]),
+		TargetFPR: math.Float64frombits(binary.LittleEndian.Uint64(header[32:])),
+	}
+
+	if p.Bits == 0 || p.Bits%64 != 0 || p.Hashes == 0 {
+		return FilterParams{}, fmt.Errorf("corrupted filter header")
+	}
+
+	return p, nil
+}
+
+func ReadFilter(r io.Reader) (*Filter, error) {
+	p, err := ReadFilterHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	f := NewFilter(p)
+
+	_, err = io.ReadFull(r, f.bits)
+	if err != nil {
+		return nil, errors.Wrap(err, "read bits")
+	}
+
+	return f, nil
+}
+
+// ParseHashLine parses a line of the Have I Been Pwned SHA-1 export, which is
+// the uppercase hex hash optionally followed by ":" and the breach count.
+func ParseHashLine(line []byte) ([20]byte, error) {
+	var hash [20]byte
+
+	line = bytes.TrimRight(line, "\r\n")
+	if i := bytes.IndexByte(line, ':'); i != -1 {
+		line = line[:i]
+	}
+
+	if len(line) != hex.EncodedLen(len(hash)) {
+		return hash, fmt.Errorf("expected a %v character SHA-1 hash", hex.EncodedLen(len(hash)))
+	}
+
+	_, err := hex.Decode(hash[:], line)
+
+	return hash, errors.Wrap(err, "decode hash")
+}
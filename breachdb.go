@@ -0,0 +1,382 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AlexSSD7/cpass/breach"
+	"github.com/pkg/errors"
+)
+
+const (
+	breachCheckpointInterval = 5 * time.Minute
+	breachProgressInterval   = time.Second
+)
+
+const breachdbUsage = `Usage:
+  cpass breachdb build -input <pwned-sha1-ordered.txt> [-fpr 0.001] [-entries n] -out <path>
+  cpass breachdb info <path>
+`
+
+// breachCheckpoint records how far a filter build got, so that an interrupted
+// build can be resumed. The filter itself is saved next to it.
+type breachCheckpoint struct {
+	Input        string              `json:"input"`
+	InputSize    int64               `json:"input_size"`
+	InputModTime time.Time           `json:"input_mod_time"`
+	Offset       int64               `json:"offset"`
+	Line         uint64              `json:"line"`
+	Params       breach.FilterParams `json:"params"`
+}
+
+func runBreachDB(args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, breachdbUsage)
+		os.Exit(2)
+	}
+
+	var err error
+
+	switch args[0] {
+	case "build":
+		err = runBreachDBBuild(args[1:])
+	case "info":
+		err = runBreachDBInfo(args[1:])
+	default:
+		fmt.Fprint(os.Stderr, breachdbUsage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: breachdb %v: %s\n", args[0], err)
+		os.Exit(1)
+	}
+}
+
+func runBreachDBInfo(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected exactly one filter file")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return errors.Wrap(err, "open filter")
+	}
+	defer f.Close()
+
+	p, err := breach.ReadFilterHeader(f)
+	if err != nil {
+		return errors.Wrap(err, "read filter header")
+	}
+
+	fmt.Printf("Version: %v\n", breach.FilterVersion)
+	fmt.Printf("Entries: %v\n", p.Entries)
+	fmt.Printf("Size: %v bits (%.1f MiB)\n", p.Bits, float64(p.Bits)/8/(1<<20))
+	fmt.Printf("Hash functions: %v\n", p.Hashes)
+	fmt.Printf("False positive rate: %.6f target, %.6f expected\n", p.TargetFPR, p.ExpectedFPR())
+
+	return nil
+}
+
+func runBreachDBBuild(args []string) error {
+	fs := flag.NewFlagSet("breachdb build", flag.ExitOnError)
+	input := fs.String("input", "", "Have I Been Pwned SHA-1 export, one HASH:COUNT per line")
+	fpr := fs.Float64("fpr", 0.001, "Target false positive rate")
+	entries := fs.Uint64("entries", 0, "Number of hashes in the input, to skip counting them first")
+	out := fs.String("out", "", "Path to write the filter to")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		return errors.Wrap(err, "parse flags")
+	}
+
+	if *input == "" || *out == "" {
+		return fmt.Errorf("both -input and -out are required")
+	}
+
+	inputPath, err := filepath.Abs(*input)
+	if err != nil {
+		return errors.Wrap(err, "get absolute input path")
+	}
+
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return errors.Wrap(err, "open input")
+	}
+	defer in.Close()
+
+	stat, err := in.Stat()
+	if err != nil {
+		return errors.Wrap(err, "stat input")
+	}
+
+	ckptPath := *out + ".partial.json"
+	partialPath := *out + ".partial"
+
+	ckpt, filter, err := loadBreachCheckpoint(ckptPath, partialPath)
+	if err != nil {
+		return errors.Wrap(err, "load checkpoint")
+	}
+
+	if ckpt != nil && (ckpt.Input != inputPath || ckpt.InputSize != stat.Size() || !ckpt.InputModTime.Equal(stat.ModTime()) ||
+		ckpt.Params.TargetFPR != *fpr || (*entries != 0 && ckpt.Params.Entries != *entries)) {
+		fmt.Fprintf(os.Stderr, "WARN: Ignoring the checkpoint at %v, it belongs to a different build.\n", ckptPath)
+		ckpt, filter = nil, nil
+	}
+
+	if ckpt != nil {
+		fmt.Fprintf(os.Stderr, "Resuming from line %v.\n", ckpt.Line)
+
+		_, err = in.Seek(ckpt.Offset, io.SeekStart)
+		if err != nil {
+			return errors.Wrap(err, "seek input")
+		}
+	} else {
+		n := *entries
+		if n == 0 {
+			n, err = countLines(in, stat.Size())
+			if err != nil {
+				return errors.Wrap(err, "count input lines")
+			}
+
+			_, err = in.Seek(0, io.SeekStart)
+			if err != nil {
+				return errors.Wrap(err, "seek input")
+			}
+		}
+
+		params, err := breach.ParamsFor(n, *fpr)
+		if err != nil {
+			return errors.Wrap(err, "size filter")
+		}
+
+		fmt.Fprintf(os.Stderr, "Building a %.1f MiB filter with %v hash functions for %v hashes.\n", float64(params.Bits)/8/(1<<20), params.Hashes, n)
+
+		ckpt = &breachCheckpoint{
+			Input:        inputPath,
+			InputSize:    stat.Size(),
+			InputModTime: stat.ModTime(),
+			Params:       params,
+		}
+		filter = breach.NewFilter(params)
+	}
+
+	err = insertHashes(in, filter, ckpt, func() error {
+		return saveBreachCheckpoint(ckptPath, partialPath, ckpt, filter)
+	})
+	if err != nil {
+		return err
+	}
+
+	if ckpt.Line > ckpt.Params.Entries {
+		fmt.Fprintf(os.Stderr, "WARN: The input has %v hashes, more than the %v the filter was sized for. The false positive rate will be higher than the target.\n", ckpt.Line, ckpt.Params.Entries)
+	}
+
+	err = writeFilterFile(*out, filter)
+	if err != nil {
+		return errors.Wrap(err, "write filter")
+	}
+
+	for _, path := range []string{ckptPath, partialPath} {
+		err = os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errors.Wrap(err, "remove checkpoint")
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote the filter with %v hashes to %v.\n", ckpt.Line, *out)
+
+	return nil
+}
+
+func countLines(r io.Reader, size int64) (uint64, error) {
+	var n uint64
+	var read int64
+
+	buf := make([]byte, 1<<20)
+	lastProgress := time.Now()
+	progressShown := false
+	for {
+		m, err := r.Read(buf)
+		n += uint64(bytes.Count(buf[:m], []byte{'\n'}))
+		read += int64(m)
+
+		if time.Since(lastProgress) >= breachProgressInterval {
+			fmt.Fprintf(os.Stderr, "\rCounting hashes: %.1f%%", float64(read)/float64(max(size, 1))*100)
+			lastProgress, progressShown = time.Now(), true
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return 0, errors.Wrap(err, "read")
+		}
+	}
+
+	if progressShown {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	return n, nil
+}
+
+// insertHashes adds every hash from r to the filter, updating the checkpoint
+// as it goes and saving it every breachCheckpointInterval.
+func insertHashes(r io.Reader, filter *breach.Filter, ckpt *breachCheckpoint, save func() error) error {
+	br := bufio.NewReaderSize(r, 1<<20)
+
+	lastProgress, lastCheckpoint := time.Now(), time.Now()
+	progressShown := false
+	for {
+		line, err := br.ReadSlice('\n')
+		if len(bytes.TrimSpace(line)) != 0 {
+			hash, parseErr := breach.ParseHashLine(line)
+			if parseErr != nil {
+				return errors.Wrapf(parseErr, "parse line %v", ckpt.Line+1)
+			}
+
+			filter.Add(hash)
+			ckpt.Line++
+		}
+
+		ckpt.Offset += int64(len(line))
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "read input")
+		}
+
+		if ckpt.Line%65536 != 0 {
+			continue
+		}
+
+		if time.Since(lastProgress) >= breachProgressInterval {
+			fmt.Fprintf(os.Stderr, "\rInserted %v of %v hashes (%.1f%%)", ckpt.Line, ckpt.Params.Entries, float64(ckpt.Offset)/float64(max(ckpt.InputSize, 1))*100)
+			lastProgress, progressShown = time.Now(), true
+		}
+
+		if time.Since(lastCheckpoint) >= breachCheckpointInterval {
+			err = save()
+			if err != nil {
+				return errors.Wrap(err, "save checkpoint")
+			}
+
+			lastCheckpoint = time.Now()
+		}
+	}
+
+	if progressShown {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	return nil
+}
+
+func writeFilterFile(path string, filter *breach.Filter) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return errors.Wrap(err, "create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	w := bufio.NewWriterSize(f, 1<<20)
+	_, err = filter.WriteTo(w)
+	if err == nil {
+		err = w.Flush()
+	}
+
+	// The filter only holds hashes that are public anyway.
+	if err == nil {
+		err = f.Chmod(0o644)
+	}
+
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "write temp file")
+	}
+
+	return errors.Wrap(os.Rename(f.Name(), path), "rename temp file")
+}
+
+// saveBreachCheckpoint saves the filter before the checkpoint. If cpass stops
+// in between, the old checkpoint is used with the newer filter, which only
+// means re-adding some hashes, and adding a hash twice is harmless.
+func saveBreachCheckpoint(ckptPath, partialPath string, ckpt *breachCheckpoint, filter *breach.Filter) error {
+	err := writeFilterFile(partialPath, filter)
+	if err != nil {
+		return errors.Wrap(err, "write partial filter")
+	}
+
+	data, err := json.Marshal(ckpt)
+	if err != nil {
+		return errors.Wrap(err, "marshal checkpoint")
+	}
+
+	return writeFileAtomic(ckptPath, data)
+}
+
+func loadBreachCheckpoint(ckptPath, partialPath string) (*breachCheckpoint, *breach.Filter, error) {
+	data, err := os.ReadFile(ckptPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, errors.Wrap(err, "read checkpoint")
+	}
+
+	var ckpt breachCheckpoint
+	err = json.Unmarshal(data, &ckpt)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unmarshal checkpoint")
+	}
+
+	f, err := os.Open(partialPath)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "open partial filter")
+	}
+	defer f.Close()
+
+	filter, err := breach.ReadFilter(bufio.NewReader(f))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "read partial filter")
+	}
+
+	if filter.FilterParams != ckpt.Params {
+		return nil, nil, fmt.Errorf("partial filter does not match the checkpoint")
+	}
+
+	return &ckpt, filter, nil
+}
@@ -0,0 +1,102 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/AlexSSD7/cpass/generator"
+	"github.com/pkg/errors"
+)
+
+// runCharsets lists the named charsets with their sizes, so that users
+// don't have to look them up to pick one for -charset.
+func runCharsets(args []string) {
+	fs := flag.NewFlagSet("charsets", flag.ExitOnError)
+	verbose := fs.Bool("v", false, "Also list the characters of every class")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	if fs.NArg() != 0 {
+		fmt.Fprint(os.Stderr, "Usage: cpass charsets [-v]\n")
+		os.Exit(2)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "Name\tSize\tBits per character\tDescription\n")
+
+	for _, c := range generator.Charsets() {
+		fmt.Fprintf(tw, "%v\t%v\t%.2f\t%v\n", c.Name, c.Size(), math.Log2(float64(c.Size())), c.Description)
+
+		if *verbose {
+			uppercase := "no"
+			if c.Uppercase {
+				uppercase = "yes"
+			}
+
+			fmt.Fprintf(tw, "\t\t\tletters: %v (uppercase: %v)\n", c.Letters, uppercase)
+			fmt.Fprintf(tw, "\t\t\tdigits: %v\n", c.Digits)
+			fmt.Fprintf(tw, "\t\t\tspecial: %v\n", c.Special)
+		}
+	}
+
+	err = tw.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: write charsets: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadCharsetFile reads a charset with generator.LoadCharset. Unless the
+// file names the charset, it is named after the file.
+func loadCharsetFile(path string) (generator.Charset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return generator.Charset{}, errors.Wrap(err, "open")
+	}
+	defer f.Close()
+
+	c, err := generator.LoadCharset(f, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	if err != nil {
+		return generator.Charset{}, errors.Wrapf(err, "parse %v", path)
+	}
+
+	return c, nil
+}
+
+// describeCharsetClasses returns the sizes of the classes of c.
+func describeCharsetClasses(c generator.Charset) string {
+	letters := utf8.RuneCountInString(c.Letters)
+
+	uppercase := 0
+	if c.Uppercase {
+		uppercase = letters
+	}
+
+	return fmt.Sprintf("%v lowercase, %v uppercase, %v digits, %v special characters", letters, uppercase, utf8.RuneCountInString(c.Digits), utf8.RuneCountInString(c.Special))
+}
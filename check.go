@@ -0,0 +1,519 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/AlexSSD7/cpass/breach"
+	"github.com/AlexSSD7/cpass/generator"
+	"github.com/pkg/errors"
+)
+
+// ratingNames are the ratings of getRatingString, from the weakest.
+var ratingNames = []string{"Very Poor", "Poor", "Weak", "Good", "Excellent", "Overkill"}
+
+// checkColumns are the columns the report adds to those of the input.
+var checkColumns = []string{"line", "verdict", "bits", "rating", "findings", "breached", "policy"}
+
+// checkPolicy is what a policy string of cpass check requires of a password:
+// at least the length and the counts, only characters of the charset, if
+// given, no character more often than maxRepeats, and at least minClasses
+// character classes.
+type checkPolicy struct {
+	length, upper, digits, special uint
+	charset                        string
+	maxRepeats, minClasses         uint
+}
+
+// parseCheckPolicy parses a policy string like -policy does for generated
+// passwords, with the same keys.
+func parseCheckPolicy(s string) (*checkPolicy, error) {
+	var p checkPolicy
+
+	fs := flag.NewFlagSet("policy", flag.ContinueOnError)
+	fs.UintVar(&p.length, "length", 0, "")
+	fs.UintVar(&p.upper, "upper", 0, "")
+	fs.UintVar(&p.digits, "digits", 0, "")
+	fs.UintVar(&p.special, "special", 0, "")
+	fs.StringVar(&p.charset, "charset", "", "")
+	fs.UintVar(&p.maxRepeats, "max-repeats", 0, "")
+	fs.UintVar(&p.minClasses, "min-classes", 0, "")
+
+	err := applyPolicy(fs, passwordPolicyKeys, s)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.charset != "" {
+		_, err = generator.CharsetByName(p.charset)
+		if err != nil {
+			return nil, errors.Wrap(err, "look up charset")
+		}
+	}
+
+	return &p, nil
+}
+
+// violations returns the policy keys pw doesn't meet.
+func (p *checkPolicy) violations(pw string) []string {
+	var ret []string
+
+	var upper, digits, special, lower uint
+	counts := make(map[rune]uint)
+	for _, c := range pw {
+		counts[c]++
+
+		switch {
+		case unicode.IsUpper(c):
+			upper++
+		case unicode.IsLower(c):
+			lower++
+		case unicode.IsDigit(c):
+			digits++
+		default:
+			special++
+		}
+	}
+
+	classes := uint(0)
+	for _, n := range []uint{lower, upper, digits, special} {
+		if n != 0 {
+			classes++
+		}
+	}
+
+	for _, check := range []struct {
+		key string
+		ok  bool
+	}{
+		{"length", uint(utf8.RuneCountInString(pw)) >= p.length},
+		{"upper", upper >= p.upper},
+		{"digits", digits >= p.digits},
+		{"special", special >= p.special},
+		{"charset", p.charset == "" || inCharset(pw, p.charset)},
+		{"maxrepeat", p.maxRepeats == 0 || slices.Max(append(mapValues(counts), 0)) <= p.maxRepeats},
+		{"classes", classes >= p.minClasses},
+	} {
+		if !check.ok {
+			ret = append(ret, check.key)
+		}
+	}
+
+	return ret
+}
+
+func mapValues(m map[rune]uint) []uint {
+	ret := make([]uint, 0, len(m))
+	for _, v := range m {
+		ret = append(ret, v)
+	}
+
+	return ret
+}
+
+// inCharset reports whether every character of pw is in the named charset,
+// which parseCheckPolicy has looked up before.
+func inCharset(pw, name string) bool {
+	c, err := generator.CharsetByName(name)
+	if err != nil {
+		return false
+	}
+
+	chars := c.Letters + c.Digits + c.Special
+	if c.Uppercase {
+		chars += strings.ToUpper(c.Letters)
+	}
+
+	for _, ch := range pw {
+		if !strings.ContainsRune(chars, ch) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkWords are the dictionary words estimatePasswordEntropy looks for.
+var checkWords = func() map[string]struct{} {
+	words := make(map[string]struct{})
+	for _, list := range [][]string{generator.WordlistEFFLong(), generator.CommonEnglishWordlist()} {
+		for _, w := range list {
+			if len(w) >= 4 {
+				words[w] = struct{}{}
+			}
+		}
+	}
+
+	return words
+}()
+
+// estimatePasswordEntropy estimates the entropy of a password someone chose,
+// that cpass didn't generate, and names the patterns it found. Every
+// character counts as drawn from the classes the password uses, except for
+// the patterns. A dictionary word counts as one choice out of a wordlist of
+// the size of the EFF long one, and every character that continues a run of
+// repeats or a sequence like abc or 321 counts as one bit. The findings never
+// contain any part of the password.
+func estimatePasswordEntropy(pw string) (float64, []string) {
+	runes := []rune(pw)
+
+	var lower, upper, digit, special, other bool
+	for _, c := range runes {
+		switch {
+		case c >= utf8.RuneSelf:
+			other = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= '0' && c <= '9':
+			digit = true
+		default:
+			special = true
+		}
+	}
+
+	pool := 0
+	classes := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{
+		{lower, 26}, {upper, 26}, {digit, 10}, {special, 33}, {other, 100},
+	} {
+		if class.used {
+			pool += class.size
+			classes++
+		}
+	}
+
+	bits := make([]float64, len(runes))
+	for i := range bits {
+		bits[i] = math.Log2(float64(pool))
+	}
+
+	var findings []string
+	if len(runes) < 12 {
+		findings = append(findings, "short")
+	}
+
+	if classes == 1 {
+		findings = append(findings, "one class")
+	}
+
+	// The longest dictionary word at every position, ignoring case.
+	lowered := []rune(strings.ToLower(pw))
+	foundWord := false
+	for i := 0; i < len(lowered); i++ {
+		for j := len(lowered); j >= i+4; j-- {
+			if _, ok := checkWords[string(lowered[i:j])]; !ok {
+				continue
+			}
+
+			bits[i] = generator.BitsPerWord(len(generator.WordlistEFFLong()))
+			for k := i + 1; k < j; k++ {
+				bits[k] = 0
+			}
+
+			foundWord = true
+			i = j - 1
+
+			break
+		}
+	}
+
+	if foundWord {
+		findings = append(findings, "dictionary word")
+	}
+
+	for _, pattern := range []struct {
+		name string
+		step func(a, b rune) bool
+	}{
+		{"repeats", func(a, b rune) bool { return a == b }},
+		{"sequence", func(a, b rune) bool { return b == a+1 || b == a-1 }},
+	} {
+		found := false
+		for i := 0; i+2 < len(runes); i++ {
+			j := i + 1
+			for j < len(runes) && pattern.step(runes[j-1], runes[j]) && (j == i+1 || runes[j]-runes[j-1] == runes[i+1]-runes[i]) {
+				j++
+			}
+
+			if j-i < 3 {
+				continue
+			}
+
+			for k := i + 1; k < j; k++ {
+				bits[k] = min(bits[k], 1)
+			}
+
+			found = true
+			i = j - 1
+		}
+
+		if found {
+			findings = append(findings, pattern.name)
+		}
+	}
+
+	var total float64
+	for _, b := range bits {
+		total += b
+	}
+
+	return total, findings
+}
+
+// checkSummary counts the verdicts and ratings of a run of cpass check.
+type checkSummary struct {
+	rows, failed, malformed, breached, policy int
+	ratings                                   map[string]int
+}
+
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	csvPath := fs.String("csv", "", "CSV file of the passwords to check, with a header row; - for stdin")
+	column := fs.Int("password-column", 0, "Column of the CSV file with the passwords, counting from 1")
+	reportPath := fs.String("report", "", "Write the report CSV to this file instead of stdout")
+	filterPath := fs.String("breach-filter", "", "Look the passwords up in this breach filter (see cpass breachdb)")
+	policyString := fs.String("policy", "", "Policy string the passwords must meet, with the keys of -policy, e.g. \"length=16 upper=1 classes>=3\"; lengths and counts are minimums")
+	minRating := fs.String("min-rating", "Good", "Rating every password must reach: "+strings.Join(ratingNames, ", "))
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	if *csvPath == "" || *column < 1 {
+		fmt.Fprint(os.Stderr, "Error: -csv and -password-column are required\n")
+		os.Exit(2)
+	}
+
+	minRank := slices.Index(ratingNames, *minRating)
+	if minRank < 0 {
+		fmt.Fprintf(os.Stderr, "Error: unknown rating %q (available: %v)\n", *minRating, strings.Join(ratingNames, ", "))
+		os.Exit(2)
+	}
+
+	var policy *checkPolicy
+	if *policyString != "" {
+		policy, err = parseCheckPolicy(*policyString)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: parse policy: %s\n", err)
+			os.Exit(2)
+		}
+	}
+
+	var filter *breach.Filter
+	if *filterPath != "" {
+		filter, err = readBreachFilter(*filterPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: read breach filter: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	in := os.Stdin
+	if *csvPath != "-" {
+		in, err = os.Open(*csvPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: open CSV file: %s\n", err)
+			os.Exit(1)
+		}
+		defer in.Close()
+	}
+
+	out := os.Stdout
+	if *reportPath != "" {
+		out, err = os.OpenFile(*reportPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: create report: %s\n", err)
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+
+	summary, err := checkCSV(in, out, *column-1, filter, policy, minRank)
+	if err == nil && out != os.Stdout {
+		err = errors.Wrap(out.Close(), "close report")
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: check passwords: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "Checked %v passwords, %v failed by being below %v, failing the policy, breached, or malformed. Ratings:\n", summary.rows, summary.failed, *minRating)
+	for _, r := range ratingNames {
+		fmt.Fprintf(os.Stderr, "  %v: %v\n", r, summary.ratings[r])
+	}
+
+	if filter != nil {
+		fmt.Fprintf(os.Stderr, "  Breached: %v\n", summary.breached)
+	}
+
+	if policy != nil {
+		fmt.Fprintf(os.Stderr, "  Failing the policy: %v\n", summary.policy)
+	}
+
+	fmt.Fprintf(os.Stderr, "  Malformed rows: %v\n", summary.malformed)
+
+	if summary.failed != 0 {
+		os.Exit(1)
+	}
+}
+
+func readBreachFilter(path string) (*breach.Filter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "open filter")
+	}
+	defer f.Close()
+
+	return breach.ReadFilter(bufio.NewReader(f))
+}
+
+// checkCSV streams the rows of in, checks the password in the given column
+// of each, and writes them to out without the password and with the verdict.
+// A row that can't be read or has no password is reported as malformed, and
+// the run goes on.
+func checkCSV(in io.Reader, out io.Writer, column int, filter *breach.Filter, policy *checkPolicy, minRank int) (*checkSummary, error) {
+	r := csv.NewReader(bufio.NewReader(in))
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "read header")
+	}
+
+	if column >= len(header) {
+		return nil, fmt.Errorf("the header has %v columns, so there is no password column %v", len(header), column+1)
+	}
+
+	width := len(header)
+	w := csv.NewWriter(out)
+
+	err = w.Write(append(slices.Delete(slices.Clone(header), column, column+1), checkColumns...))
+	if err != nil {
+		return nil, errors.Wrap(err, "write report header")
+	}
+
+	summary := &checkSummary{ratings: make(map[string]int)}
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var line int
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			line = parseErr.StartLine
+			record = nil
+		case err != nil:
+			return nil, errors.Wrap(err, "read row")
+		default:
+			line, _ = r.FieldPos(0)
+		}
+
+		summary.rows++
+
+		// The other columns are kept as they are, padded or cut to the
+		// width of the header.
+		row := make([]string, width)
+		copy(row, record)
+		row = slices.Delete(row, column, column+1)
+
+		var verdict []string
+		switch {
+		case record == nil:
+			summary.malformed++
+			verdict = []string{"malformed", "", "", "unreadable row", "", ""}
+		case len(record) != width:
+			summary.malformed++
+			verdict = []string{"malformed", "", "", fmt.Sprintf("%v of %v columns", len(record), width), "", ""}
+		case record[column] == "":
+			summary.malformed++
+			verdict = []string{"malformed", "", "", "no password", "", ""}
+		default:
+			verdict = checkPassword(record[column], filter, policy, minRank, summary)
+		}
+
+		if verdict[0] != "ok" {
+			summary.failed++
+		}
+
+		err = w.Write(append(append(row, strconv.Itoa(line)), verdict...))
+		if err != nil {
+			return nil, errors.Wrap(err, "write report row")
+		}
+	}
+
+	w.Flush()
+
+	return summary, errors.Wrap(w.Error(), "write report")
+}
+
+// checkPassword returns the verdict columns for pw and counts it in summary.
+func checkPassword(pw string, filter *breach.Filter, policy *checkPolicy, minRank int, summary *checkSummary) []string {
+	bits, findings := estimatePasswordEntropy(pw)
+	rating := getRatingString(bits)
+	summary.ratings[rating]++
+
+	verdict := "ok"
+	if slices.Index(ratingNames, rating) < minRank {
+		verdict = "weak"
+	}
+
+	var violations []string
+	if policy != nil {
+		violations = policy.violations(pw)
+		if len(violations) != 0 {
+			summary.policy++
+			verdict = "policy"
+		}
+	}
+
+	breached := ""
+	if filter != nil {
+		breached = "no"
+		if filter.Contains(sha1.Sum([]byte(pw))) {
+			summary.breached++
+			breached = "yes"
+			verdict = "breached"
+		}
+	}
+
+	return []string{verdict, strconv.FormatFloat(bits, 'f', 2, 64), rating, strings.Join(findings, "; "), breached, strings.Join(violations, "; ")}
+}
@@ -0,0 +1,173 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/AlexSSD7/cpass/breach"
+)
+
+func TestEstimatePasswordEntropy(t *testing.T) {
+	for _, tc := range []struct {
+		pw       string
+		bits     float64
+		findings []string
+	}{
+		// The first a, and a bit for every repeat.
+		{"aaaaaaaaaaaaaaaa", math.Log2(26) + 15, []string{"one class", "repeats"}},
+		{"abcdefgh", math.Log2(26) + 7, []string{"short", "one class", "sequence"}},
+		{"87654321", math.Log2(10) + 7, []string{"short", "one class", "sequence"}},
+		// A word of the EFF long list, in any case.
+		{"PassWord", math.Log2(7776), []string{"short", "dictionary word"}},
+		{"password1234", math.Log2(7776) + math.Log2(36) + 3, []string{"dictionary word", "sequence"}},
+		{"Xk9#mQ2$vL7!pR4@wN8z", 20 * math.Log2(95), nil},
+		{"café-café-café", 14 * math.Log2(26+33+100), nil},
+	} {
+		bits, findings := estimatePasswordEntropy(tc.pw)
+		if math.Abs(bits-tc.bits) > 1e-9 || !slices.Equal(findings, tc.findings) {
+			t.Errorf("%q: got %.2f bits and %q, want %.2f and %q", tc.pw, bits, findings, tc.bits, tc.findings)
+		}
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	p, err := parseCheckPolicy("length=12 upper=1 digits=2 special=1 charset=default maxrepeat=2 classes>=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		pw   string
+		want []string
+	}{
+		{"Abcdefgh23!k", nil},
+		{"abc", []string{"length", "upper", "digits", "special", "classes"}},
+		// l and o are not in the default charset.
+		{"Abcdefgh23!l", []string{"charset"}},
+		{"Abcdeeeh23!k", []string{"maxrepeat"}},
+	} {
+		got := p.violations(tc.pw)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%q: got %q, want %q", tc.pw, got, tc.want)
+		}
+	}
+
+	for _, s := range []string{"length>=3", "classes=3", "colour=red", "charset=nope"} {
+		_, err := parseCheckPolicy(s)
+		if err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+const checkInput = `name,password,notes
+a,password123,"quoted, with a comma"
+b,Xk9#mQ2$vL7!pR4@wN8z,
+c,Xk9#mQ2$vL7!pR4@wN8y,too,many
+bad "row,Xk9#mQ2$vL7!pR4@wN8x,
+e,,empty
+f,Xk9#mQ2$vL7!pR4@wN8w,last
+`
+
+const checkReport = `name,notes,line,verdict,bits,rating,findings,breached,policy
+a,"quoted, with a comma",2,breached,20.09,Very Poor,short; dictionary word; sequence,yes,classes
+b,,3,ok,131.40,Overkill,,no,
+c,too,4,malformed,,,4 of 3 columns,,
+,,5,malformed,,,unreadable row,,
+e,empty,6,malformed,,,no password,,
+f,last,7,ok,131.40,Overkill,,no,
+`
+
+func TestCheckCSV(t *testing.T) {
+	params, err := breach.ParamsFor(10, 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filter := breach.NewFilter(params)
+	filter.Add(sha1.Sum([]byte("password123")))
+
+	policy, err := parseCheckPolicy("classes>=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	summary, err := checkCSV(strings.NewReader(checkInput), &out, 1, filter, policy, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != checkReport {
+		t.Errorf("got the report\n%s\nwant\n%s", out.String(), checkReport)
+	}
+
+	want := checkSummary{rows: 6, failed: 4, malformed: 3, breached: 1, policy: 1}
+	if summary.rows != want.rows || summary.failed != want.failed || summary.malformed != want.malformed || summary.breached != want.breached || summary.policy != want.policy {
+		t.Errorf("got %+v, want %+v", *summary, want)
+	}
+
+	if summary.ratings["Overkill"] != 2 || summary.ratings["Very Poor"] != 1 {
+		t.Errorf("got the ratings %v", summary.ratings)
+	}
+
+	for _, pw := range []string{"password123", "wN8"} {
+		if strings.Contains(out.String(), pw) {
+			t.Errorf("the report contains %q", pw)
+		}
+	}
+}
+
+func TestCheckExitStatus(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	report := filepath.Join(dir, "report.csv")
+
+	err := os.WriteFile(input, []byte("name,password\nb,Xk9#mQ2$vL7!pR4@wN8z\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, stderr, err := runCpass(t, "", "check", "-csv", input, "-password-column", "2", "-report", report)
+	if err != nil {
+		t.Fatalf("cpass: %v\n%s", err, stderr)
+	}
+
+	fi, err := os.Stat(report)
+	if err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("report: %v, %v", fi, err)
+	}
+
+	// The password is rated Overkill, so it reaches any rating.
+	_, stderr, err = runCpass(t, "", "check", "-csv", input, "-password-column", "2", "-report", report, "-min-rating", "Overkill")
+	if err != nil {
+		t.Fatalf("cpass: %v\n%s", err, stderr)
+	}
+
+	_, _, err = runCpass(t, "", "check", "-csv", input, "-password-column", "2", "-report", report, "-min-rating", "Excellent", "-policy", "length=24")
+	if err == nil {
+		t.Error("a password failing the policy passed")
+	}
+}
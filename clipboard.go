@@ -0,0 +1,192 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/AlexSSD7/cpass/clipboard"
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+)
+
+// clearClipboardLater waits for ttl after pw has been copied, and then clears
+// the clipboard, but only if it still holds pw. With previous contents from
+// capturePrevious, they are restored instead. A countdown is shown, and if
+// readInput is set, pressing Enter clears the clipboard right away. An
+// interrupt clears it too before cpass exits.
+func clearClipboardLater(cb clipboard.Clipboard, p *prompter, pw []byte, ttl time.Duration, readInput bool, previous []byte) error {
+	// Only a hash is kept to recognize the contents later, so that pw can be
+	// wiped as usual.
+	sum := sha256.Sum256(pw)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+
+	var in <-chan inputByte
+	if readInput {
+		in = p.input()
+	}
+
+	deadline := time.Now().Add(ttl)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	timer := time.NewTimer(ttl)
+	defer timer.Stop()
+
+	// The countdown is only redrawn in place on a terminal.
+	f, ok := ui.(*os.File)
+	redraw := ok && term.IsTerminal(int(f.Fd()))
+
+	prompt := func(left time.Duration) {
+		stdoutScreen().clearLine()
+		fmt.Fprintf(ui, "Copied the password to the clipboard, clearing it in %v", left.Round(time.Second))
+		if readInput {
+			fmt.Fprint(ui, " (press Enter to clear it now)")
+		}
+
+		fmt.Fprintf(ui, "%s > ", p.timeLeft())
+	}
+
+	if redraw {
+		prompt(ttl)
+	} else {
+		fmt.Fprintf(ui, "Copied the password to the clipboard, clearing it in %v.\n", ttl)
+	}
+
+	interrupted, entered := false, false
+	var readErr error
+
+wait:
+	for {
+		select {
+		case c := <-in:
+			var done bool
+			_, done, readErr = p.feed(c)
+			if !done {
+				continue
+			}
+
+			// Without input, only the countdown is left.
+			if errors.Is(readErr, io.EOF) {
+				readErr = nil
+				in = nil
+				continue
+			}
+
+			entered = readErr == nil
+			break wait
+		case <-sigCh:
+			interrupted = true
+			break wait
+		case <-timer.C:
+			break wait
+		case now := <-ticker.C:
+			if left := deadline.Sub(now).Round(time.Second); redraw && left > 0 {
+				prompt(left)
+			}
+		}
+	}
+
+	// Enter has already moved the cursor to the next line.
+	if redraw && !entered {
+		fmt.Fprintln(ui)
+	}
+
+	err := clearClipboard(cb, sum, previous)
+	if interrupted {
+		if err != nil {
+			fmt.Fprintf(ui, "Error: clear clipboard: %s\n", err)
+		}
+
+		os.Exit(130)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// The clipboard is cleared before the session timeout is reported.
+	if errors.Is(readErr, errSessionTimeout) {
+		return readErr
+	}
+
+	return errors.Wrap(readErr, "read line")
+}
+
+// clearClipboard empties the clipboard, or puts back the previous contents if
+// there are any, if it still holds the contents with the given hash, and
+// otherwise leaves whatever was copied since alone.
+func clearClipboard(cb clipboard.Clipboard, sum [sha256.Size]byte, previous []byte) error {
+	current, err := cb.Read()
+	switch {
+	case errors.Is(err, clipboard.ErrUnreadable):
+		// There is no telling whether it has changed, so clear it anyway.
+	case err != nil:
+		return errors.Wrap(err, "read clipboard")
+	default:
+		currentSum := sha256.Sum256(current)
+		wipe(current)
+
+		if subtle.ConstantTimeCompare(sum[:], currentSum[:]) != 1 {
+			fmt.Fprint(ui, "The clipboard has changed since, so it was left alone.\n")
+			return nil
+		}
+	}
+
+	if len(previous) != 0 {
+		err = cb.Restore(previous)
+		if err != nil {
+			return errors.Wrap(err, "restore clipboard")
+		}
+
+		fmt.Fprint(ui, "Restored the previous clipboard contents.\n")
+
+		return nil
+	}
+
+	err = cb.Write(nil)
+	if err != nil {
+		return errors.Wrap(err, "write clipboard")
+	}
+
+	fmt.Fprint(ui, "Cleared the clipboard.\n")
+
+	return nil
+}
+
+// capturePrevious returns the clipboard contents for -copy-restore, before
+// the password replaces them. If they can't be read, nil is returned and the
+// clipboard is cleared as usual. The caller wipes them.
+func capturePrevious(cb clipboard.Clipboard) []byte {
+	previous, err := cb.Read()
+	if err != nil {
+		fmt.Fprintf(ui, "WARN: Cannot read the clipboard to restore it later, so it will be cleared instead: %s.\n", err)
+		return nil
+	}
+
+	return previous
+}
@@ -0,0 +1,189 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package clipboard puts passwords on the clipboard of the system or the
+// terminal. Where the platform has a way to do so, the contents are marked as
+// sensitive, so that clipboard managers and clipboard history leave them out.
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnreadable is returned by Read for clipboards that can only be written.
+var ErrUnreadable = fmt.Errorf("the clipboard cannot be read back")
+
+// Clipboard is a clipboard that can hold text.
+type Clipboard interface {
+	// Name describes the clipboard, e.g. the tool used to access it.
+	Name() string
+	// Write replaces the contents with b, marked as sensitive where
+	// supported. An empty b clears the clipboard.
+	Write(b []byte) error
+	// Read returns the current contents. The caller wipes them.
+	Read() ([]byte, error)
+	// Restore puts back contents returned by Read earlier. Unlike Write,
+	// it doesn't mark them as sensitive, since cpass didn't copy them.
+	Restore(b []byte) error
+	// MarksSensitive reports whether Write keeps the contents out of
+	// clipboard managers and history.
+	MarksSensitive() bool
+}
+
+// RunsPrograms reports whether c runs external programs to access the
+// clipboard.
+func RunsPrograms(c Clipboard) bool {
+	_, ok := c.(*command)
+	return ok
+}
+
+// command uses a pair of helper programs, e.g. xclip or pbcopy. The contents
+// are always passed on stdin and stdout, never as arguments.
+type command struct {
+	copyPath      string
+	copyArgs      []string
+	clearArgs     []string
+	sensitiveArgs []string
+	pastePath     string
+	pasteArgs     []string
+}
+
+type tool struct {
+	copyName  string
+	copyArgs  []string
+	pasteName string
+	pasteArgs []string
+	// clearArgs replace copyArgs to clear the clipboard, if the tool needs
+	// that.
+	clearArgs []string
+	// sensitive returns the arguments added to copyArgs to mark the contents
+	// as sensitive, or nil if the installed version can't do that.
+	sensitive func(copyPath string) []string
+}
+
+// findCommand returns the first of the tools that is installed.
+func findCommand(tools []tool) (Clipboard, error) {
+	names := make([]string, len(tools))
+	for i, t := range tools {
+		names[i] = t.copyName
+
+		copyPath, err := exec.LookPath(t.copyName)
+		if err != nil {
+			continue
+		}
+
+		pastePath, err := exec.LookPath(t.pasteName)
+		if err != nil {
+			continue
+		}
+
+		c := &command{
+			copyPath:  copyPath,
+			copyArgs:  t.copyArgs,
+			clearArgs: t.clearArgs,
+			pastePath: pastePath,
+			pasteArgs: t.pasteArgs,
+		}
+
+		if t.sensitive != nil {
+			c.sensitiveArgs = t.sensitive(copyPath)
+		}
+
+		return c, nil
+	}
+
+	return nil, fmt.Errorf("no clipboard tool found (tried %v)", strings.Join(names, ", "))
+}
+
+func (c *command) Name() string {
+	return c.copyPath
+}
+
+func (c *command) MarksSensitive() bool {
+	return c.sensitiveArgs != nil
+}
+
+func (c *command) Write(b []byte) error {
+	return c.write(b, c.sensitiveArgs)
+}
+
+func (c *command) Restore(b []byte) error {
+	return c.write(b, nil)
+}
+
+func (c *command) write(b []byte, extraArgs []string) error {
+	args := append(c.copyArgs[:len(c.copyArgs):len(c.copyArgs)], extraArgs...)
+	if len(b) == 0 && c.clearArgs != nil {
+		args = c.clearArgs
+	}
+
+	// xclip and wl-copy fork to keep serving the selection. The fork would
+	// hold on to a pipe, so stderr is passed through instead of captured.
+	cmd := exec.Command(c.copyPath, args...)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stderr = os.Stderr
+
+	return errors.Wrapf(cmd.Run(), "run %v", c.copyPath)
+}
+
+func (c *command) Read() ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	defer func() {
+		wipe(stdout.Bytes()[:stdout.Cap()])
+	}()
+
+	cmd := exec.Command(c.pastePath, c.pasteArgs...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := commandError(cmd.Run(), &stderr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "run %v", c.pastePath)
+	}
+
+	return bytes.Clone(stdout.Bytes()), nil
+}
+
+// supportsFlag reports whether the help output of the program at path lists
+// flag.
+func supportsFlag(path, flag string) bool {
+	out, _ := exec.Command(path, "--help").CombinedOutput()
+	return bytes.Contains(out, []byte(flag))
+}
+
+func commandError(err error, stderr *bytes.Buffer) error {
+	if err == nil {
+		return nil
+	}
+
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return errors.Wrap(err, msg)
+	}
+
+	return err
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
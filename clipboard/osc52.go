@@ -0,0 +1,89 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package clipboard
+
+import (
+	"encoding/base64"
+	"io"
+)
+
+// osc52 sets the clipboard of the terminal cpass runs in with the OSC 52
+// escape sequence, which also works over SSH. Terminals rarely allow reading
+// the clipboard back, so it is not attempted, and there is no way to mark the
+// contents as sensitive.
+type osc52 struct {
+	out  io.Writer
+	tmux bool
+}
+
+// NewOSC52 returns a clipboard that writes the escape sequence to out, which
+// should be the terminal. If tmux is set, the sequence is wrapped for tmux
+// passthrough.
+func NewOSC52(out io.Writer, tmux bool) Clipboard {
+	return &osc52{out: out, tmux: tmux}
+}
+
+func (c *osc52) Name() string {
+	return "the terminal (OSC 52)"
+}
+
+// Write sends the sequence in a single write from a buffer that is wiped
+// afterwards. Empty contents clear the clipboard.
+func (c *osc52) Write(b []byte) error {
+	enc := base64.StdEncoding
+
+	buf := make([]byte, 0, 32+enc.EncodedLen(len(b)))
+	defer func() {
+		wipe(buf[:cap(buf)])
+	}()
+
+	// tmux only passes the sequence on to the outer terminal when it is
+	// wrapped in a DCS passthrough, with the escape characters doubled.
+	esc := "\x1b"
+	if c.tmux {
+		buf = append(buf, "\x1bPtmux;"...)
+		esc = "\x1b\x1b"
+	}
+
+	buf = append(buf, esc+"]52;c;"...)
+	n := len(buf)
+	buf = buf[:n+enc.EncodedLen(len(b))]
+	enc.Encode(buf[n:], b)
+	buf = append(buf, '\a')
+
+	if c.tmux {
+		buf = append(buf, "\x1b\\"...)
+	}
+
+	_, err := c.out.Write(buf)
+
+	return err
+}
+
+// Restore is Write, as there is no marker to leave out. Since the clipboard
+// can't be read, there is nothing to restore in the first place.
+func (c *osc52) Restore(b []byte) error {
+	return c.Write(b)
+}
+
+func (c *osc52) Read() ([]byte, error) {
+	return nil, ErrUnreadable
+}
+
+func (c *osc52) MarksSensitive() bool {
+	return false
+}
@@ -0,0 +1,64 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestOSC52(t *testing.T) {
+	for _, tc := range []struct {
+		tmux bool
+		in   string
+		want string
+	}{
+		{false, "hunter2", "\x1b]52;c;aHVudGVyMg==\a"},
+		{true, "hunter2", "\x1bPtmux;\x1b\x1b]52;c;aHVudGVyMg==\a\x1b\\"},
+		{false, "", "\x1b]52;c;\a"},
+		{true, "", "\x1bPtmux;\x1b\x1b]52;c;\a\x1b\\"},
+	} {
+		for _, restore := range []bool{false, true} {
+			var out bytes.Buffer
+			c := NewOSC52(&out, tc.tmux)
+
+			fn := c.Write
+			if restore {
+				fn = c.Restore
+			}
+
+			err := fn([]byte(tc.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != tc.want {
+				t.Errorf("%q, tmux %v, restore %v: got %q, want %q", tc.in, tc.tmux, restore, out.String(), tc.want)
+			}
+		}
+	}
+
+	c := NewOSC52(&bytes.Buffer{}, false)
+	if _, err := c.Read(); !errors.Is(err, ErrUnreadable) {
+		t.Errorf("read: got %v", err)
+	}
+
+	if c.MarksSensitive() || RunsPrograms(c) {
+		t.Error("OSC 52 marks the contents as sensitive or runs programs")
+	}
+}
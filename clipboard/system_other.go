@@ -0,0 +1,73 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+//go:build !windows
+
+package clipboard
+
+import (
+	"os"
+	"runtime"
+)
+
+// Find returns the system clipboard. On macOS, pbcopy can't mark the contents
+// as sensitive. On X11, xclip and xsel offer a single target, so there is no
+// room for the x-kde-passwordManagerHint target next to the text. On
+// Wayland, wl-copy marks the contents with it if the installed version
+// supports --sensitive.
+func Find() (Clipboard, error) {
+	return findCommand(systemTools(runtime.GOOS, os.Getenv("WAYLAND_DISPLAY") != ""))
+}
+
+// systemTools returns the clipboard tools to look for on goos, in order of
+// preference.
+func systemTools(goos string, wayland bool) []tool {
+	if goos == "darwin" {
+		return []tool{{copyName: "pbcopy", pasteName: "pbpaste"}}
+	}
+
+	var tools []tool
+	if wayland {
+		tools = append(tools, tool{
+			copyName:  "wl-copy",
+			pasteName: "wl-paste",
+			pasteArgs: []string{"--no-newline"},
+			clearArgs: []string{"--clear"},
+			sensitive: func(copyPath string) []string {
+				if supportsFlag(copyPath, "--sensitive") {
+					return []string{"--sensitive"}
+				}
+
+				return nil
+			},
+		})
+	}
+
+	return append(tools,
+		tool{
+			copyName:  "xclip",
+			copyArgs:  []string{"-selection", "clipboard"},
+			pasteName: "xclip",
+			pasteArgs: []string{"-selection", "clipboard", "-o"},
+		},
+		tool{
+			copyName:  "xsel",
+			copyArgs:  []string{"--clipboard", "--input"},
+			pasteName: "xsel",
+			pasteArgs: []string{"--clipboard", "--output"},
+		},
+	)
+}
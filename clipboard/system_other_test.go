@@ -0,0 +1,193 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+//go:build !windows
+
+package clipboard
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeTool is a clipboard tool that logs its arguments, one line per run,
+// saves what it is given on stdin, and pastes "pasted".
+const fakeTool = `#!/bin/sh
+if [ "$1" = --help ]; then
+	echo "$FAKE_HELP"
+	exit 0
+fi
+
+echo "${0##*/} $*" >> "$FAKE_DIR/log"
+case "${0##*/} $*" in
+*paste*|*" -o"|*--output) printf pasted ;;
+*) cat > "$FAKE_DIR/stdin" ;;
+esac
+`
+
+// installFakeTools puts fake tools with the given names alone on PATH.
+func installFakeTools(t *testing.T, help string, names ...string) string {
+	t.Helper()
+
+	// The tools need cat, which won't be on PATH anymore.
+	cat, err := exec.LookPath("cat")
+	if err != nil {
+		t.Skip("no cat to build the fake tools with")
+	}
+
+	dir := t.TempDir()
+	err = os.Symlink(cat, filepath.Join(dir, "cat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range names {
+		err = os.WriteFile(filepath.Join(dir, name), []byte(fakeTool), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("PATH", dir)
+	t.Setenv("FAKE_DIR", dir)
+	t.Setenv("FAKE_HELP", help)
+
+	return dir
+}
+
+func TestSystemTools(t *testing.T) {
+	for _, tc := range []struct {
+		goos    string
+		wayland bool
+		want    []string
+	}{
+		{"linux", true, []string{"wl-copy", "xclip", "xsel"}},
+		{"linux", false, []string{"xclip", "xsel"}},
+		{"freebsd", false, []string{"xclip", "xsel"}},
+		{"darwin", true, []string{"pbcopy"}},
+	} {
+		var got []string
+		for _, tl := range systemTools(tc.goos, tc.wayland) {
+			got = append(got, tl.copyName)
+		}
+
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%v, wayland %v: got %q, want %q", tc.goos, tc.wayland, got, tc.want)
+		}
+	}
+}
+
+func TestCommandArgs(t *testing.T) {
+	tools := make(map[string]tool)
+	for _, tl := range append(systemTools("linux", true), systemTools("darwin", false)...) {
+		tools[tl.copyName] = tl
+	}
+
+	for _, tc := range []struct {
+		name, help string
+		sensitive  bool
+		// The command lines of a write, a clear, a restore and a read.
+		want []string
+	}{
+		{"wl-copy", "  -s, --sensitive", true, []string{"wl-copy --sensitive", "wl-copy --clear", "wl-copy", "wl-paste --no-newline"}},
+		{"wl-copy", "  -o, --paste-once", false, []string{"wl-copy", "wl-copy --clear", "wl-copy", "wl-paste --no-newline"}},
+		{"xclip", "", false, []string{"xclip -selection clipboard", "xclip -selection clipboard", "xclip -selection clipboard", "xclip -selection clipboard -o"}},
+		{"xsel", "", false, []string{"xsel --clipboard --input", "xsel --clipboard --input", "xsel --clipboard --input", "xsel --clipboard --output"}},
+		{"pbcopy", "", false, []string{"pbcopy", "pbcopy", "pbcopy", "pbpaste"}},
+	} {
+		tl := tools[tc.name]
+		dir := installFakeTools(t, tc.help, tl.copyName, tl.pasteName)
+
+		c, err := findCommand([]tool{tl})
+		if err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+
+		if !RunsPrograms(c) || c.Name() != filepath.Join(dir, tc.name) {
+			t.Errorf("%v: got %v, which runs programs: %v", tc.name, c.Name(), RunsPrograms(c))
+		}
+
+		if c.MarksSensitive() != tc.sensitive {
+			t.Errorf("%v: marks sensitive: %v, want %v", tc.name, c.MarksSensitive(), tc.sensitive)
+		}
+
+		for _, step := range []struct {
+			name  string
+			fn    func([]byte) error
+			stdin string
+		}{
+			{"write", c.Write, "pw"},
+			{"clear", c.Write, ""},
+			{"restore", c.Restore, "old"},
+		} {
+			err = step.fn([]byte(step.stdin))
+			if err != nil {
+				t.Fatalf("%v: %v: %v", tc.name, step.name, err)
+			}
+
+			got, err := os.ReadFile(filepath.Join(dir, "stdin"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != step.stdin {
+				t.Errorf("%v: %v: got %q on stdin, want %q", tc.name, step.name, got, step.stdin)
+			}
+		}
+
+		pasted, err := c.Read()
+		if err != nil || string(pasted) != "pasted" {
+			t.Errorf("%v: read: got %q, %v", tc.name, pasted, err)
+		}
+
+		log, err := os.ReadFile(filepath.Join(dir, "log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := strings.Split(string(log), "\n")
+		got = slices.DeleteFunc(got, func(s string) bool { return s == "" })
+		for i := range got {
+			got[i] = strings.TrimSuffix(got[i], " ")
+		}
+
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%v: got the command lines %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFindCommandMissing(t *testing.T) {
+	installFakeTools(t, "", "xclip")
+
+	_, err := findCommand([]tool{
+		{copyName: "wl-copy", pasteName: "wl-paste"},
+		{copyName: "xsel", pasteName: "xsel"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "tried wl-copy, xsel") {
+		t.Errorf("got %v", err)
+	}
+
+	// A copy tool without its paste tool doesn't count either.
+	_, err = findCommand([]tool{{copyName: "xclip", pasteName: "xclip-paste"}})
+	if err == nil {
+		t.Error("a tool without its paste tool was found")
+	}
+}
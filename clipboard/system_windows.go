@@ -0,0 +1,236 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+//go:build windows
+
+package clipboard
+
+import (
+	"runtime"
+	"unicode/utf16"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	cfUnicodeText = 13
+	gmemMoveable  = 0x0002
+)
+
+var (
+	user32                       = windows.NewLazySystemDLL("user32.dll")
+	procOpenClipboard            = user32.NewProc("OpenClipboard")
+	procCloseClipboard           = user32.NewProc("CloseClipboard")
+	procEmptyClipboard           = user32.NewProc("EmptyClipboard")
+	procGetClipboardData         = user32.NewProc("GetClipboardData")
+	procSetClipboardData         = user32.NewProc("SetClipboardData")
+	procRegisterClipboardFormatW = user32.NewProc("RegisterClipboardFormatW")
+	kernel32                     = windows.NewLazySystemDLL("kernel32.dll")
+	procGlobalAlloc              = kernel32.NewProc("GlobalAlloc")
+	procGlobalFree               = kernel32.NewProc("GlobalFree")
+	procGlobalLock               = kernel32.NewProc("GlobalLock")
+	procGlobalUnlock             = kernel32.NewProc("GlobalUnlock")
+	procGlobalSize               = kernel32.NewProc("GlobalSize")
+	procMoveMemory               = kernel32.NewProc("RtlMoveMemory")
+)
+
+// sensitiveFormats are set next to the text to keep it out of clipboard
+// monitors, the clipboard history (Win+V), and the cloud clipboard. The
+// first one only needs to be present, the others hold a DWORD of zero.
+var sensitiveFormats = []string{
+	"ExcludeClipboardContentFromMonitorProcessing",
+	"CanIncludeInClipboardHistory",
+	"CanUploadToCloudClipboard",
+}
+
+// win uses the Win32 clipboard API with CF_UNICODETEXT.
+type win struct{}
+
+// Find returns the system clipboard.
+func Find() (Clipboard, error) {
+	return win{}, nil
+}
+
+func (win) Name() string {
+	return "the Windows clipboard"
+}
+
+func (win) MarksSensitive() bool {
+	return true
+}
+
+// open opens the clipboard on a locked thread, since the clipboard belongs
+// to the thread that opened it. The returned function closes it.
+func (win) open() (func(), error) {
+	runtime.LockOSThread()
+
+	r, _, err := procOpenClipboard.Call(0)
+	if r == 0 {
+		runtime.UnlockOSThread()
+		return nil, errors.Wrap(err, "open clipboard")
+	}
+
+	return func() {
+		_, _, _ = procCloseClipboard.Call()
+		runtime.UnlockOSThread()
+	}, nil
+}
+
+func (c win) Write(b []byte) error {
+	return c.write(b, true)
+}
+
+func (c win) Restore(b []byte) error {
+	return c.write(b, false)
+}
+
+func (c win) write(b []byte, sensitive bool) error {
+	closeFn, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	r, _, err := procEmptyClipboard.Call()
+	if r == 0 {
+		return errors.Wrap(err, "empty clipboard")
+	}
+
+	if len(b) == 0 {
+		return nil
+	}
+
+	if sensitive {
+		for _, name := range sensitiveFormats {
+			err = setSensitiveFormat(name)
+			if err != nil {
+				return errors.Wrapf(err, "set %v", name)
+			}
+		}
+	}
+
+	runes := []rune(string(b))
+	defer wipeRunes(runes)
+
+	text := append(utf16.Encode(runes), 0)
+	defer wipeUint16(text)
+
+	return errors.Wrap(setData(cfUnicodeText, unsafe.Pointer(&text[0]), uintptr(len(text))*2), "set text")
+}
+
+func setSensitiveFormat(name string) error {
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+
+	format, _, err := procRegisterClipboardFormatW.Call(uintptr(unsafe.Pointer(namePtr)))
+	if format == 0 {
+		return errors.Wrap(err, "register clipboard format")
+	}
+
+	var zero uint32
+
+	return setData(format, unsafe.Pointer(&zero), unsafe.Sizeof(zero))
+}
+
+// setData copies size bytes from src to global memory and puts it on the
+// opened clipboard in the given format.
+func setData(format uintptr, src unsafe.Pointer, size uintptr) error {
+	h, _, err := procGlobalAlloc.Call(gmemMoveable, size)
+	if h == 0 {
+		return errors.Wrap(err, "allocate clipboard memory")
+	}
+
+	ptr, _, err := procGlobalLock.Call(h)
+	if ptr == 0 {
+		_, _, _ = procGlobalFree.Call(h)
+		return errors.Wrap(err, "lock clipboard memory")
+	}
+
+	// The memory is copied with RtlMoveMemory since ptr is not Go memory.
+	_, _, _ = procMoveMemory.Call(ptr, uintptr(src), size)
+	_, _, _ = procGlobalUnlock.Call(h)
+
+	// The system owns the memory once SetClipboardData succeeds.
+	r, _, err := procSetClipboardData.Call(format, h)
+	if r == 0 {
+		_, _, _ = procGlobalFree.Call(h)
+		return errors.Wrap(err, "set clipboard data")
+	}
+
+	return nil
+}
+
+func (c win) Read() ([]byte, error) {
+	closeFn, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	h, _, _ := procGetClipboardData.Call(cfUnicodeText)
+	if h == 0 {
+		// Empty or not text.
+		return nil, nil
+	}
+
+	ptr, _, err := procGlobalLock.Call(h)
+	if ptr == 0 {
+		return nil, errors.Wrap(err, "lock clipboard memory")
+	}
+	defer procGlobalUnlock.Call(h)
+
+	size, _, _ := procGlobalSize.Call(h)
+	if size < 2 {
+		return nil, nil
+	}
+
+	src := make([]uint16, size/2)
+	defer wipeUint16(src)
+
+	_, _, _ = procMoveMemory.Call(uintptr(unsafe.Pointer(&src[0])), ptr, size/2*2)
+
+	n := 0
+	for n < len(src) && src[n] != 0 {
+		n++
+	}
+
+	runes := utf16.Decode(src[:n])
+	defer wipeRunes(runes)
+
+	ret := make([]byte, 0, 4*len(runes))
+	for _, r := range runes {
+		ret = utf8.AppendRune(ret, r)
+	}
+
+	return ret, nil
+}
+
+func wipeRunes(r []rune) {
+	for i := range r {
+		r[i] = 0
+	}
+}
+
+func wipeUint16(s []uint16) {
+	for i := range s {
+		s[i] = 0
+	}
+}
@@ -0,0 +1,135 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+type fakeClipboard struct {
+	contents []byte
+	// restored is set once Restore is called.
+	restored bool
+}
+
+func (c *fakeClipboard) Name() string { return "fake" }
+
+func (c *fakeClipboard) Write(b []byte) error {
+	c.contents = append([]byte(nil), b...)
+	return nil
+}
+
+func (c *fakeClipboard) Read() ([]byte, error) {
+	return append([]byte(nil), c.contents...), nil
+}
+
+func (c *fakeClipboard) Restore(b []byte) error {
+	c.restored = true
+	return c.Write(b)
+}
+
+func (c *fakeClipboard) MarksSensitive() bool { return true }
+
+func TestClearClipboardLaterKeepsNextAnswer(t *testing.T) {
+	ui = io.Discard
+
+	r, w := io.Pipe()
+	defer w.Close()
+
+	p := newPrompter(r)
+	pw := []byte("secret")
+	cb := &fakeClipboard{contents: []byte("secret")}
+
+	// The countdown expires without input, so the line typed afterwards is
+	// the answer to the next prompt.
+	err := clearClipboardLater(cb, p, pw, 50*time.Millisecond, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cb.contents) != 0 {
+		t.Errorf("clipboard holds %q, want it cleared", cb.contents)
+	}
+
+	go func() {
+		_, _ = io.WriteString(w, "y\n17\n")
+	}()
+
+	yes, err := p.askYesNo("Generate another?")
+	if err != nil || !yes {
+		t.Fatalf("askYesNo = %v, %v, want true", yes, err)
+	}
+
+	n, err := p.askUint32("Password length", nil)
+	if err != nil || n != 17 {
+		t.Fatalf("askUint32 = %v, %v, want 17", n, err)
+	}
+}
+
+func TestClearClipboardLaterLeavesChangedContents(t *testing.T) {
+	ui = io.Discard
+
+	p := newPrompter(bytes.NewReader(nil))
+	cb := &fakeClipboard{contents: []byte("copied since")}
+
+	err := clearClipboardLater(cb, p, []byte("secret"), 10*time.Millisecond, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(cb.contents) != "copied since" {
+		t.Errorf("clipboard holds %q, want it left alone", cb.contents)
+	}
+}
+
+func TestClearClipboardLaterRestoresPrevious(t *testing.T) {
+	ui = io.Discard
+
+	p := newPrompter(bytes.NewReader(nil))
+	cb := &fakeClipboard{contents: []byte("before")}
+
+	previous := capturePrevious(cb)
+	_ = cb.Write([]byte("secret"))
+
+	err := clearClipboardLater(cb, p, []byte("secret"), 10*time.Millisecond, false, previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cb.restored || string(cb.contents) != "before" {
+		t.Errorf("clipboard holds %q (restored %v), want %q restored", cb.contents, cb.restored, "before")
+	}
+}
+
+func TestClearClipboardLaterKeepsChangedOverPrevious(t *testing.T) {
+	ui = io.Discard
+
+	p := newPrompter(bytes.NewReader(nil))
+	cb := &fakeClipboard{contents: []byte("copied since")}
+
+	err := clearClipboardLater(cb, p, []byte("secret"), 10*time.Millisecond, false, []byte("before"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cb.restored || string(cb.contents) != "copied since" {
+		t.Errorf("clipboard holds %q (restored %v), want it left alone", cb.contents, cb.restored)
+	}
+}
@@ -0,0 +1,159 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+
+	"github.com/AlexSSD7/cpass/generator"
+	"github.com/pkg/errors"
+)
+
+const (
+	// compareGuessesPerSecond is the attacker speed the crack times in the
+	// comparison table are given for: an offline attack on a fast hash.
+	compareGuessesPerSecond = 1e10
+)
+
+type policyComparison struct {
+	name       string
+	entropyMin float64
+	entropyAvg float64
+}
+
+func comparePolicy(name string, params passwordParams, opts []generator.Option) (policyComparison, bool) {
+	g, err := generator.NewGenerator(params.length, params.uppercaseCount, params.digitCount, params.specialCount, opts...)
+	if err != nil {
+		// The policy is not possible, e.g. too long for the generator.
+		return policyComparison{}, false
+	}
+
+	entropyMin, err := g.EntropyMin()
+	if err != nil {
+		return policyComparison{}, false
+	}
+
+	return policyComparison{
+		name:       name,
+		entropyMin: float64(entropyMin),
+		entropyAvg: (float64(g.EntropyMax()) + float64(entropyMin)) / 2,
+	}, true
+}
+
+// policyComparisons computes the entropy of the current policy and a few
+// nearby ones. It does not generate any passwords.
+func policyComparisons(params passwordParams, charset generator.Charset, opts []generator.Option) []policyComparison {
+	var ret []policyComparison
+
+	if c, ok := comparePolicy("Current policy", params, opts); ok {
+		ret = append(ret, c)
+	}
+
+	longer := params
+	longer.length += 2
+	if c, ok := comparePolicy(fmt.Sprintf("Length %v (+2)", longer.length), longer, opts); ok {
+		ret = append(ret, c)
+	}
+
+	// Add the first character class the current policy does not use yet.
+	extra := params
+	var extraName string
+	switch {
+	case params.specialCount == 0 && charset.Special != "":
+		extra.specialCount, extraName = 1, "+1 special character"
+	case params.digitCount == 0 && charset.Digits != "":
+		extra.digitCount, extraName = 1, "+1 digit"
+	case params.uppercaseCount == 0 && charset.Uppercase:
+		extra.uppercaseCount, extraName = 1, "+1 uppercase character"
+	}
+
+	if extraName != "" {
+		if c, ok := comparePolicy(extraName, extra, opts); ok {
+			ret = append(ret, c)
+		}
+	}
+
+	for words := uint32(5); words <= 7; words++ {
+		// A space separator keeps all of the EFF long wordlist.
+		g, err := generator.NewPassphraseGenerator(words, generator.WordlistEFFLong(), " ")
+		if err != nil {
+			continue
+		}
+
+		ret = append(ret, policyComparison{
+			name:       fmt.Sprintf("%v diceware words", words),
+			entropyMin: g.Entropy(),
+			entropyAvg: g.Entropy(),
+		})
+	}
+
+	return ret
+}
+
+// formatCrackTime returns the average time it takes to find a password with
+// the given entropy at compareGuessesPerSecond.
+func formatCrackTime(bits float64) string {
+	seconds := math.Pow(2, bits-1) / compareGuessesPerSecond
+
+	units := []struct {
+		name    string
+		seconds float64
+	}{
+		{"years", 365.25 * 24 * 3600},
+		{"days", 24 * 3600},
+		{"hours", 3600},
+		{"minutes", 60},
+	}
+
+	for _, u := range units {
+		if seconds >= u.seconds {
+			v := seconds / u.seconds
+			if v >= 1e6 {
+				return fmt.Sprintf("%.1e %v", v, u.name)
+			}
+
+			return fmt.Sprintf("%.0f %v", v, u.name)
+		}
+	}
+
+	if seconds < 1 {
+		return "less than a second"
+	}
+
+	return fmt.Sprintf("%.0f seconds", seconds)
+}
+
+func writeComparisonTable(w io.Writer, comparisons []policyComparison) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprint(tw, "Policy\tMin bits\tRealistic bits\tRating\tCrack time\n")
+	for _, c := range comparisons {
+		fmt.Fprintf(tw, "%v\t%.1f\t%.1f\t%v\t%v\n", c.name, c.entropyMin, c.entropyAvg, getRatingString(c.entropyAvg), formatCrackTime(c.entropyMin))
+	}
+
+	err := tw.Flush()
+	if err != nil {
+		return errors.Wrap(err, "flush table")
+	}
+
+	_, err = fmt.Fprintf(w, "Crack times are averages for an attacker who knows the policy and makes %.0e guesses per second.\n", compareGuessesPerSecond)
+
+	return errors.Wrap(err, "write footnote")
+}
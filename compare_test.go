@@ -0,0 +1,71 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/AlexSSD7/cpass/generator"
+)
+
+func TestComparisonTableGolden(t *testing.T) {
+	var out bytes.Buffer
+	for _, tc := range []struct {
+		params  passwordParams
+		charset generator.Charset
+	}{
+		// Every class is used, so there is no row for one more.
+		{passwordParams{length: 16, uppercaseCount: 2, digitCount: 2, specialCount: 2}, generator.DefaultCharset},
+		{passwordParams{length: 12}, generator.DefaultCharset},
+		{passwordParams{length: 14, digitCount: 3}, generator.NoShiftCharset},
+		// The longer policy is over the maximum length, and only uppercase can
+		// be added.
+		{passwordParams{length: 127}, generator.LayoutPortableCharset},
+	} {
+		fmt.Fprintf(&out, "== %v %+v\n", tc.charset.Name, tc.params)
+
+		err := writeComparisonTable(&out, policyComparisons(tc.params, tc.charset, []generator.Option{generator.WithCharset(tc.charset)}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkGolden(t, "compare.golden", out.Bytes())
+}
+
+func TestFormatCrackTime(t *testing.T) {
+	for _, tc := range []struct {
+		bits float64
+		want string
+	}{
+		{1, "less than a second"},
+		{35, "2 seconds"},
+		{40, "55 seconds"},
+		{41, "2 minutes"},
+		{50, "16 hours"},
+		{58, "167 days"},
+		{60, "2 years"},
+		{70, "1871 years"},
+		{100, "2.0e+12 years"},
+	} {
+		if got := formatCrackTime(tc.bits); got != tc.want {
+			t.Errorf("%v bits: got %q, want %q", tc.bits, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	settingsFile   = "settings.json"
+	lastParamsFile = "last.json"
+)
+
+type settings struct {
+	// RememberLast makes cpass save the parameters of the last password and
+	// offer them as defaults on the next run.
+	RememberLast bool `json:"remember_last"`
+}
+
+// lastParams are the parameters of the last generated password. They never
+// include the password itself.
+type lastParams struct {
+	Length         uint32 `json:"length"`
+	UppercaseCount uint32 `json:"uppercase_count"`
+	DigitCount     uint32 `json:"digit_count"`
+	SpecialCount   uint32 `json:"special_count"`
+}
+
+// configDir returns the directory cpass keeps its configuration in. It can be
+// overridden with the CPASS_CONFIG_DIR environment variable.
+func configDir() (string, error) {
+	if dir := os.Getenv("CPASS_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", errors.Wrap(err, "get user config dir")
+	}
+
+	return filepath.Join(dir, "cpass"), nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// over path, so that readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return errors.Wrap(err, "create directory")
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return errors.Wrap(err, "create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(0o600)
+	}
+
+	if err == nil {
+		err = f.Sync()
+	}
+
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "write temp file")
+	}
+
+	return errors.Wrap(os.Rename(f.Name(), path), "rename temp file")
+}
+
+// readConfigFile decodes the JSON config file name into v. It reports false if
+// the file does not exist.
+func readConfigFile(name string, v any) (bool, error) {
+	dir, err := configDir()
+	if err != nil {
+		return false, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, errors.Wrap(err, "read file")
+	}
+
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return false, errors.Wrapf(err, "decode %v", name)
+	}
+
+	return true, nil
+}
+
+func writeConfigFile(name string, v any) error {
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal")
+	}
+
+	return writeFileAtomic(filepath.Join(dir, name), append(data, '\n'))
+}
+
+func removeConfigFile(name string) error {
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(dir, name))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(err, "remove file")
+	}
+
+	return nil
+}
+
+func loadSettings() (settings, error) {
+	var s settings
+	_, err := readConfigFile(settingsFile, &s)
+
+	return s, err
+}
+
+// loadLastParams returns the remembered parameters, or nil if nothing has
+// been remembered yet. Callers check settings.RememberLast first.
+func loadLastParams() (*passwordParams, error) {
+	var last lastParams
+	ok, err := readConfigFile(lastParamsFile, &last)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return &passwordParams{
+		length:         last.Length,
+		uppercaseCount: last.UppercaseCount,
+		digitCount:     last.DigitCount,
+		specialCount:   last.SpecialCount,
+	}, nil
+}
+
+// saveLastParams remembers params for the next run.
+func saveLastParams(params passwordParams) error {
+	return writeConfigFile(lastParamsFile, lastParams{
+		Length:         params.length,
+		UppercaseCount: params.uppercaseCount,
+		DigitCount:     params.digitCount,
+		SpecialCount:   params.specialCount,
+	})
+}
+
+const configUsage = `Usage:
+  cpass config remember-last on|off
+  cpass config clear-last
+`
+
+func runConfig(args []string) {
+	var err error
+
+	switch {
+	case len(args) == 2 && args[0] == "remember-last" && (args[1] == "on" || args[1] == "off"):
+		err = writeConfigFile(settingsFile, settings{RememberLast: args[1] == "on"})
+		if err == nil && args[1] == "off" {
+			err = removeConfigFile(lastParamsFile)
+		}
+	case len(args) == 1 && args[0] == "clear-last":
+		err = removeConfigFile(lastParamsFile)
+	default:
+		fmt.Fprint(os.Stderr, configUsage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: config %v: %s\n", args[0], err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,261 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/AlexSSD7/cpass/generator"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/term"
+)
+
+const maxPassphraseLength = 1024
+
+func runDerive(args []string) {
+	fs := flag.NewFlagSet("derive", flag.ExitOnError)
+	memory := fs.Uint("memory", 64*1024, "Argon2id memory cost in KiB")
+	iterations := fs.Uint("iterations", 3, "Argon2id number of passes over the memory")
+	parallelism := fs.Uint("parallelism", 4, "Argon2id degree of parallelism (1-255)")
+	keyLen := fs.Uint("key-len", 32, "Length of the derived key in bytes")
+	saltHex := fs.String("salt", "", "Use this hex-encoded salt instead of generating a random 16-byte one")
+	encoding := fs.String("encoding", "hex", "Key output encoding: raw, hex or base64")
+	out := fs.String("out", "", "Write the key to this file instead of stdout")
+	words := fs.Uint("words", 0, "Generate a passphrase of this many words, show it on stderr, and derive the key from it instead of reading one")
+	list := fs.String("list", generator.DefaultWordlistName, "Embedded wordlist to choose the words from with -words: "+strings.Join(generator.WordlistNames(), ", "))
+	separator := fs.String("separator", "-", "Separator between the words with -words")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	switch {
+	case *iterations < 1:
+		err = fmt.Errorf("iterations must be at least 1")
+	case *parallelism < 1 || *parallelism > 255:
+		err = fmt.Errorf("parallelism must be between 1 and 255")
+	case *memory < 8*(*parallelism):
+		err = fmt.Errorf("memory must be at least 8 KiB per degree of parallelism")
+	case *memory > 1<<32-1:
+		err = fmt.Errorf("memory must fit into 32 bits")
+	case *keyLen < 16 || *keyLen > 1024:
+		err = fmt.Errorf("key length must be between 16 and 1024 bytes")
+	case *encoding != "raw" && *encoding != "hex" && *encoding != "base64":
+		err = fmt.Errorf("unknown encoding %q (available: raw, hex, base64)", *encoding)
+	case *encoding == "raw" && *out == "" && term.IsTerminal(int(os.Stdout.Fd())):
+		err = fmt.Errorf("refusing to write a raw key to a terminal, use -out or another -encoding")
+	case *words > generator.MaxPassphraseWords:
+		err = fmt.Errorf("at most %v words are supported", generator.MaxPassphraseWords)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	var salt []byte
+	if *saltHex != "" {
+		salt, err = hex.DecodeString(*saltHex)
+		if err == nil && len(salt) < 8 {
+			err = fmt.Errorf("salt must be at least 8 bytes long")
+		}
+	} else {
+		salt = make([]byte, 16)
+		_, err = rand.Read(salt)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: get salt: %s\n", err)
+		os.Exit(1)
+	}
+
+	var passphrase []byte
+	if *words != 0 {
+		passphrase, err = generateDerivePassphrase(uint32(*words), *list, *separator)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: generate passphrase: %s\n", err)
+			os.Exit(1)
+		}
+	} else {
+		passphrase, err = readPassphrase()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: read passphrase: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if len(passphrase) == 0 {
+		fmt.Fprint(os.Stderr, "Error: the passphrase is empty\n")
+		os.Exit(1)
+	}
+
+	key := deriveKey(passphrase, salt, deriveParams{memory: uint32(*memory), iterations: uint32(*iterations), parallelism: uint8(*parallelism), keyLen: uint32(*keyLen)})
+	wipe(passphrase)
+
+	err = writeKey(key, *encoding, *out)
+	wipe(key)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: write key: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "Salt: %x\n", salt)
+	fmt.Fprintf(os.Stderr, "Parameters: $argon2id$v=%v$m=%v,t=%v,p=%v$%v\n", argon2.Version, *memory, *iterations, *parallelism, base64.RawStdEncoding.EncodeToString(salt))
+}
+
+// deriveParams are the Argon2id parameters of derive.
+type deriveParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	keyLen      uint32
+}
+
+// deriveKey derives a key from passphrase with Argon2id. The caller wipes
+// both.
+func deriveKey(passphrase, salt []byte, p deriveParams) []byte {
+	return argon2.IDKey(passphrase, salt, p.iterations, p.memory, p.parallelism, p.keyLen)
+}
+
+// generateDerivePassphrase generates a passphrase of the given number of
+// words and shows it on stderr, since stdout may take the key. The caller
+// wipes it.
+func generateDerivePassphrase(words uint32, list, separator string) ([]byte, error) {
+	wordlist, err := generator.WordlistByName(list)
+	if err != nil {
+		return nil, errors.Wrap(err, "select wordlist")
+	}
+
+	g, err := generator.NewPassphraseGenerator(words, wordlist, separator)
+	if err != nil {
+		return nil, errors.Wrap(err, "create passphrase generator")
+	}
+
+	passphrase, err := g.Generate()
+	if err != nil {
+		return nil, errors.Wrap(err, "generate passphrase")
+	}
+
+	fmt.Fprint(os.Stderr, passphraseReportPrefix)
+	err = writeLine(os.Stderr, passphrase)
+	if err != nil {
+		wipe(passphrase)
+		return nil, errors.Wrap(err, "write passphrase")
+	}
+
+	fmt.Fprintf(os.Stderr, "%v\nKeep the passphrase, the key can only be derived again from it and the salt.\n", passphraseEntropyString(g.Entropy()))
+
+	return passphrase, nil
+}
+
+// readPassphrase reads the passphrase without echo from a terminal, or as the
+// first line of stdin otherwise. The caller must wipe the returned slice.
+func readPassphrase() ([]byte, error) {
+	return readSecret("Passphrase")
+}
+
+// readSecret is readPassphrase with the given prompt.
+func readSecret(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprintf(os.Stderr, "%v > ", prompt)
+		passphrase, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+
+		return passphrase, errors.Wrap(err, "read password")
+	}
+
+	// Read into a fixed buffer so that no unwiped copies are left behind.
+	buf := make([]byte, maxPassphraseLength+1)
+
+	n := 0
+	for {
+		if i := bytes.IndexByte(buf[:n], '\n'); i != -1 {
+			wipe(buf[i:n])
+			n = i
+			break
+		}
+
+		if n == len(buf) {
+			wipe(buf)
+			return nil, fmt.Errorf("%v is longer than %v bytes", strings.ToLower(prompt), maxPassphraseLength)
+		}
+
+		m, err := os.Stdin.Read(buf[n:])
+		n += m
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			wipe(buf)
+			return nil, errors.Wrap(err, "read stdin")
+		}
+	}
+
+	return bytes.TrimSuffix(buf[:n], []byte("\r")), nil
+}
+
+func writeKey(key []byte, encoding, path string) error {
+	var encoded []byte
+	switch encoding {
+	case "hex":
+		encoded = make([]byte, hex.EncodedLen(len(key))+1)
+		hex.Encode(encoded, key)
+	case "base64":
+		encoded = make([]byte, base64.StdEncoding.EncodedLen(len(key))+1)
+		base64.StdEncoding.Encode(encoded, key)
+	default:
+		encoded = key
+	}
+
+	if encoding != "raw" {
+		encoded[len(encoded)-1] = '\n'
+		defer wipe(encoded)
+	}
+
+	if path == "" {
+		_, err := os.Stdout.Write(encoded)
+		return errors.Wrap(err, "write to stdout")
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return errors.Wrap(err, "open key file")
+	}
+
+	_, err = f.Write(encoded)
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return errors.Wrap(err, "write key file")
+}
@@ -0,0 +1,81 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The vectors pin the output of derive, so that a key derived today can
+// still be derived again after future changes.
+func TestDeriveKeyVectors(t *testing.T) {
+	for _, tc := range []struct {
+		passphrase string
+		salt       string
+		params     deriveParams
+		want       string
+	}{
+		{
+			"immobile-zippy-sweep-widget-subdivide-colt",
+			"00112233445566778899aabbccddeeff",
+			deriveParams{memory: 64 * 1024, iterations: 3, parallelism: 4, keyLen: 32},
+			"9b2bd82c83f1ef0aa567b574050f7a17af455a69770f1331d12ec7629d95f38b",
+		},
+		{
+			"correct horse battery staple",
+			"73616c7473616c7473616c7473616c74",
+			deriveParams{memory: 1024, iterations: 2, parallelism: 1, keyLen: 32},
+			"442e4963fd348572a59d767ff8f09570983ce27b824306e3210dc35424e898e4",
+		},
+		{
+			"pässwörd",
+			"0001020304050607",
+			deriveParams{memory: 256, iterations: 1, parallelism: 2, keyLen: 16},
+			"033a4a060a0758673e42ac04d3ffadae",
+		},
+	} {
+		salt, err := hex.DecodeString(tc.salt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := hex.EncodeToString(deriveKey([]byte(tc.passphrase), salt, tc.params))
+		if got != tc.want {
+			t.Errorf("%q with salt %v: got %v, want %v", tc.passphrase, tc.salt, got, tc.want)
+		}
+	}
+}
+
+func TestGenerateDerivePassphrase(t *testing.T) {
+	passphrase, err := generateDerivePassphrase(5, "eff-long", " ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wipe(passphrase)
+
+	words := 1
+	for _, c := range passphrase {
+		if c == ' ' {
+			words++
+		}
+	}
+
+	if words != 5 {
+		t.Errorf("got %v words, want 5", words)
+	}
+}
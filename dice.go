@@ -0,0 +1,171 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/sha3"
+	"golang.org/x/term"
+)
+
+// diceDomain separates the dice stream from other uses of SHAKE256.
+const diceDomain = "cpass dice v1\x00"
+
+var bitsPerRoll = math.Log2(6)
+
+var errDiceAborted = fmt.Errorf("dice roll input aborted")
+
+// rollsNeeded returns how many rolls of a six-sided die provide at least the
+// given bits of entropy.
+func rollsNeeded(bits float64) int {
+	return int(math.Ceil(bits / bitsPerRoll))
+}
+
+// newDiceSource whitens the dice rolls, given as the digits 1 to 6, into a
+// byte stream for generator.WithRandSource. The stream is SHAKE256 of the
+// rolls, so it holds no more entropy than the rolls themselves.
+func newDiceSource(rolls []byte) io.Reader {
+	h := sha3.NewShake256()
+	_, _ = h.Write([]byte(diceDomain))
+	_, _ = h.Write(rolls)
+
+	return h
+}
+
+// maxExtraRolls is how many rolls beyond the needed ones are accepted. The
+// buffer for the rolls never grows, so that no copies of them are left
+// behind.
+const maxExtraRolls = 64
+
+// readDiceRolls asks for at least needed dice rolls. On a terminal, the
+// rolls are read key by key without echoing them, and a counter of the bits
+// collected so far is updated as they are typed. Elsewhere, they are read
+// line by line. The caller wipes the returned rolls.
+func readDiceRolls(p *prompter, needed int) ([]byte, error) {
+	fmt.Fprintf(ui, "Roll a six-sided die %v times (%.1f bits) and type the results, 1 to 6.\n", needed, float64(needed)*bitsPerRoll)
+
+	if term.IsTerminal(int(os.Stdin.Fd())) && stdoutIsTerminal() {
+		return readDiceRollsRaw(p, needed)
+	}
+
+	rolls := make([]byte, 0, needed+maxExtraRolls)
+	for len(rolls) < needed {
+		fmt.Fprintf(ui, "%v > ", diceProgress(len(rolls), needed))
+
+		line, err := p.readLine()
+		if err != nil {
+			wipe(rolls)
+			return nil, errors.Wrap(err, "read line")
+		}
+
+		for _, c := range []byte(strings.Join(strings.Fields(line), "")) {
+			if c < '1' || c > '6' {
+				wipe(rolls)
+				return nil, fmt.Errorf("invalid die roll %q, expected 1 to 6", c)
+			}
+
+			if len(rolls) < cap(rolls) {
+				rolls = append(rolls, c)
+			}
+		}
+	}
+
+	return rolls, nil
+}
+
+func readDiceRollsRaw(p *prompter, needed int) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, errors.Wrap(err, "make terminal raw")
+	}
+
+	restore := func() {
+		_ = term.Restore(fd, state)
+	}
+
+	// Raw mode turns Ctrl-C into a regular key press, but cpass can still be
+	// terminated from the outside, so restore the terminal then too.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-sigCh:
+			restore()
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	defer restore()
+
+	// The rolls are never echoed, only counted.
+	rolls := make([]byte, 0, needed+maxExtraRolls)
+	for {
+		stdoutScreen().clearLine()
+		fmt.Fprintf(ui, "%v", diceProgress(len(rolls), needed))
+		if len(rolls) >= needed {
+			fmt.Fprint(ui, ", press Enter to continue")
+		}
+
+		fmt.Fprintf(ui, "%s > ", p.timeLeft())
+
+		key, err := p.readByte()
+		if err != nil {
+			wipe(rolls)
+			return nil, errors.Wrap(err, "read key")
+		}
+
+		switch {
+		case key >= '1' && key <= '6' && len(rolls) < cap(rolls):
+			rolls = append(rolls, key)
+		case key == 0x7f || key == 0x08:
+			if len(rolls) != 0 {
+				rolls[len(rolls)-1] = 0
+				rolls = rolls[:len(rolls)-1]
+			}
+		case key == '\r' || key == '\n':
+			if len(rolls) >= needed {
+				fmt.Fprint(ui, "\r\n")
+				return rolls, nil
+			}
+		case key == 0x03 || key == 0x04:
+			fmt.Fprint(ui, "\r\n")
+			wipe(rolls)
+
+			return nil, errDiceAborted
+		}
+	}
+}
+
+func diceProgress(rolls, needed int) string {
+	return fmt.Sprintf("Dice rolls: %v of %v (%.1f / %.1f bits)", rolls, needed, float64(rolls)*bitsPerRoll, float64(needed)*bitsPerRoll)
+}
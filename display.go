@@ -0,0 +1,210 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+)
+
+type clearReason int
+
+const (
+	clearNone clearReason = iota
+	clearEnter
+	clearExpired
+	clearInterrupted
+	clearTimedOut
+)
+
+func stdoutIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// terminalWidth returns the width of the terminal on stdout, or 80 if stdout
+// is not a terminal.
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return 80
+	}
+
+	return width
+}
+
+// terminalRows returns the number of terminal rows a line of n columns takes
+// up once wrapped.
+func terminalRows(n int) int {
+	width := terminalWidth()
+	if n <= width {
+		return 1
+	}
+
+	return (n + width - 1) / width
+}
+
+// screen performs the cursor movement and erasing the display features need.
+type screen interface {
+	// clearLinesAbove moves the cursor to the start of the line n rows up
+	// and erases everything from there to the end of the screen.
+	clearLinesAbove(n int)
+	// clearLine erases the current line and moves the cursor to its start.
+	clearLine()
+	// clearScreen erases the whole screen and moves the cursor to the top
+	// left corner.
+	clearScreen()
+	// enterAltScreen and leaveAltScreen switch to a separate screen buffer
+	// and back, where the terminal supports it.
+	enterAltScreen()
+	leaveAltScreen()
+}
+
+// stdoutScreen returns the screen for stdout. It uses ANSI escape sequences
+// wherever the terminal supports them, see newScreen.
+var stdoutScreen = sync.OnceValue(newScreen)
+
+type ansiScreen struct{}
+
+func (ansiScreen) clearLinesAbove(n int) { fmt.Fprintf(ui, "\r\x1b[%dA\x1b[J", n) }
+func (ansiScreen) clearLine()            { fmt.Fprint(ui, "\r\x1b[K") }
+func (ansiScreen) clearScreen()          { fmt.Fprint(ui, "\x1b[H\x1b[2J") }
+func (ansiScreen) enterAltScreen()       { fmt.Fprint(ui, "\x1b[?1049h") }
+func (ansiScreen) leaveAltScreen()       { fmt.Fprint(ui, "\x1b[?1049l") }
+
+func clearLinesAbove(n int) {
+	stdoutScreen().clearLinesAbove(n)
+}
+
+// clearFromScreen and clearFromScreenAndClipboard name what waitForClear
+// clears.
+const (
+	clearFromScreen             = "the password from the screen"
+	clearFromScreenAndClipboard = "the password from the screen and the clipboard"
+)
+
+// waitForClear asks the user to press Enter to clear what, the password from
+// the screen, or with the clipboard too. If ttl is non-zero, a countdown is
+// shown and the wait ends when it expires. An interrupt or the session timeout
+// ends it too. The cursor is left at the start of the line below the prompt.
+func waitForClear(p *prompter, ttl time.Duration, what string) (clearReason, error) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+
+	prompt := func(left time.Duration) {
+		stdoutScreen().clearLine()
+		fmt.Fprintf(ui, "Press Enter to clear %v", what)
+		if ttl != 0 {
+			fmt.Fprintf(ui, " (clearing in %v)", left.Round(time.Second))
+		}
+
+		fmt.Fprintf(ui, "%s > ", p.timeLeft())
+	}
+
+	var tickCh <-chan time.Time
+	var deadline time.Time
+	if ttl != 0 {
+		deadline = time.Now().Add(ttl)
+	}
+
+	// The session timeout is counted down in the prompt too.
+	if ttl != 0 || p.timeout != nil {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		tickCh = ticker.C
+	}
+
+	prompt(ttl)
+
+	for {
+		select {
+		case c := <-p.input():
+			_, done, err := p.feed(c)
+			if !done {
+				continue
+			}
+
+			if errors.Is(err, errSessionTimeout) {
+				fmt.Fprintln(ui)
+				return clearTimedOut, nil
+			}
+
+			if err != nil && !errors.Is(err, io.EOF) {
+				return clearNone, errors.Wrap(err, "read line")
+			}
+
+			if errors.Is(err, io.EOF) {
+				fmt.Fprintln(ui)
+			}
+
+			return clearEnter, nil
+		case <-sigCh:
+			fmt.Fprintln(ui)
+			return clearInterrupted, nil
+		case now := <-tickCh:
+			left := deadline.Sub(now)
+			if ttl != 0 && left <= 0 {
+				fmt.Fprintln(ui)
+				return clearExpired, nil
+			}
+
+			prompt(left)
+		}
+	}
+}
+
+// revealOnce is the fallback for -hidden when copying the password fails. It
+// shows pw once Enter is pressed, and clears it from the screen again like
+// waitForClear. It needs a terminal to do so.
+func revealOnce(p *prompter, pw []byte, ttl time.Duration) (clearReason, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !stdoutIsTerminal() {
+		return clearNone, fmt.Errorf("it can only be revealed on a terminal")
+	}
+
+	fmt.Fprintf(ui, "Press Enter to reveal the password%s > ", p.timeLeft())
+
+	_, err := p.readLine()
+	if err != nil {
+		return clearNone, errors.Wrap(err, "read line")
+	}
+
+	const prefix = "Password: "
+
+	fmt.Fprint(ui, prefix)
+	err = writeLine(ui, pw)
+	if err != nil {
+		return clearNone, errors.Wrap(err, "write password")
+	}
+
+	reason, err := waitForClear(p, ttl, clearFromScreen)
+	if err != nil {
+		return clearNone, err
+	}
+
+	clearLinesAbove(terminalRows(len(prefix)+len(pw)) + 2)
+	fmt.Fprint(ui, prefix+"[cleared]\n")
+
+	return reason, nil
+}
@@ -0,0 +1,70 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PrintableASCIICharset has all the 94 printable ASCII characters other
+// than space, for backends that accept anything. Some of them are bound to be
+// rejected or mangled by less lenient ones. All of them are base characters,
+// so every character of the password is drawn from the full range, which
+// is log2(94) ≈ 6.55 bits per character, and the counts of the other classes
+// must be zero. See WithSpace to add the space as well.
+var PrintableASCIICharset = Charset{
+	Name:        "ascii",
+	Description: "All printable ASCII characters other than space, as base characters",
+
+	Letters: printableASCII(),
+}
+
+func printableASCII() string {
+	var sb strings.Builder
+	for c := rune(' ' + 1); c < 0x7f; c++ {
+		sb.WriteRune(c)
+	}
+
+	return sb.String()
+}
+
+// WithSpace adds the space, which Validate rejects in charsets as it is
+// easily lost at the start or end of a password. It joins the special
+// characters, or the letters of charsets without special characters and
+// uppercase, such as PrintableASCIICharset.
+func WithSpace() Option {
+	return func(g *Generator) {
+		g.space = true
+	}
+}
+
+// addSpace adds the space as described in WithSpace.
+func (g *Generator) addSpace() error {
+	switch {
+	case g.charset.Special != "":
+		g.charset.Special += " "
+	case !g.charset.Uppercase:
+		g.charset.Letters += " "
+	default:
+		return fmt.Errorf("charset %q has no special characters and allows uppercase, so the space cannot be added", g.charset.Name)
+	}
+
+	g.charset.Name += "+space"
+
+	return nil
+}
@@ -0,0 +1,103 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// MaxBatchSize is the largest number of passwords GenerateMany generates at
+// once.
+const MaxBatchSize = 100000
+
+// GenerateMany generates n distinct passwords. The passwords are only
+// compared by their SHA-256 hashes, so no copies are kept that the caller
+// can't wipe. On error, every password generated so far is wiped.
+func (g *Generator) GenerateMany(n int) ([][]byte, error) {
+	err := g.checkBatchSize(n)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([][]byte, 0, n)
+	seen := make(map[[sha256.Size]byte]struct{}, n)
+
+	fail := func(err error) ([][]byte, error) {
+		for _, b := range ret {
+			wipe(b)
+		}
+
+		clear(seen)
+
+		return nil, err
+	}
+
+	for attempts := 0; len(ret) < n; attempts++ {
+		if attempts >= maxBatchAttempts(n) {
+			return fail(errTooManyAttempts)
+		}
+
+		b, err := g.Generate()
+		if err != nil {
+			return fail(errors.Wrapf(err, "generate password #%v", len(ret)+1))
+		}
+
+		sum := sha256.Sum256(b)
+		if _, ok := seen[sum]; ok {
+			wipe(b)
+			continue
+		}
+
+		seen[sum] = struct{}{}
+		ret = append(ret, b)
+	}
+
+	return ret, nil
+}
+
+// checkBatchSize checks that n distinct passwords can be generated.
+func (g *Generator) checkBatchSize(n int) error {
+	if n < 0 || n > MaxBatchSize {
+		return fmt.Errorf("batch size must be between 0 and %v", MaxBatchSize)
+	}
+
+	// With a repeat limit, there are fewer, so running out is only caught
+	// by the limit on attempts.
+	if g.combinations().Cmp(big.NewInt(int64(n))) < 0 {
+		return fmt.Errorf("the policy has fewer than %v distinct passwords", n)
+	}
+
+	return nil
+}
+
+var errTooManyAttempts = fmt.Errorf("exceeded the maximum amount of attempts generating distinct passwords")
+
+// maxBatchAttempts is a safety net against generating forever, only
+// reachable when the policy barely has n distinct passwords.
+func maxBatchAttempts(n int) int {
+	return 100000 + 10*n
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,85 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func TestGenerateMany(t *testing.T) {
+	g, err := NewGenerator(16, 2, 3, 1, WithMaxCharRepeats(2), WithRandSource(testSource(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{0, 1, 20000} {
+		passwords, err := g.GenerateMany(n)
+		if err != nil {
+			t.Fatalf("n=%v: %v", n, err)
+		}
+
+		if len(passwords) != n {
+			t.Fatalf("n=%v: got %v passwords", n, len(passwords))
+		}
+
+		seen := make(map[[sha256.Size]byte]struct{}, n)
+		for _, pw := range passwords {
+			err := g.Validate(pw)
+			if err != nil {
+				t.Fatalf("n=%v: %q: %v", n, pw, err)
+			}
+
+			lower, upper, digit, special := classCounts(g, pw)
+			if len(pw) != 16 || lower != 10 || upper != 2 || digit != 3 || special != 1 {
+				t.Fatalf("n=%v: %q doesn't have the length and class counts of the policy", n, pw)
+			}
+
+			sum := sha256.Sum256(pw)
+			if _, ok := seen[sum]; ok {
+				t.Fatalf("n=%v: %q was generated twice", n, pw)
+			}
+
+			seen[sum] = struct{}{}
+		}
+	}
+}
+
+func TestGenerateManyErrors(t *testing.T) {
+	g, err := NewGenerator(16, 2, 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{-1, MaxBatchSize + 1} {
+		passwords, err := g.GenerateMany(n)
+		if err == nil || passwords != nil {
+			t.Errorf("n=%v: got %v passwords and error %v", n, len(passwords), err)
+		}
+	}
+
+	// Two digits have fewer than 1000 distinct passwords.
+	g, err = NewGenerator(2, 0, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	passwords, err := g.GenerateMany(1000)
+	if err == nil || passwords != nil {
+		t.Errorf("small policy: got %v passwords and error %v", len(passwords), err)
+	}
+}
@@ -0,0 +1,210 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// RandomCapital is how many letters WithRandomCapital uppercases.
+type RandomCapital int
+
+const (
+	// RandomCapitalNone uppercases no letter.
+	RandomCapitalNone RandomCapital = iota
+	// RandomCapitalPhrase uppercases one random letter of the passphrase.
+	RandomCapitalPhrase
+	// RandomCapitalWord uppercases one random letter of every word.
+	RandomCapitalWord
+)
+
+// noCapital marks a word without a random capital.
+const noCapital = math.MaxUint32
+
+// WithRandomCapital uppercases a letter chosen uniformly from the letters of
+// the passphrase, or with RandomCapitalWord, of every word. Only letters with
+// an uppercase form that turns back into them are chosen, never digits or
+// the separator. Unlike capitalizing the first letter, this adds the bits of
+// where the capital is, and it still satisfies policies that require an
+// uppercase character. The words must be lowercase, and every word must have
+// such a letter. It cannot be combined with WithCasing, WithClassCounts,
+// WithMutations or WithTotalLength.
+func WithRandomCapital(c RandomCapital) PassphraseOption {
+	return func(g *PassphraseGenerator) {
+		g.randomCapital = c
+	}
+}
+
+// capitalEligible reports whether r may be uppercased by WithRandomCapital.
+func capitalEligible(r rune) bool {
+	upper := unicode.ToUpper(r)
+	return unicode.IsLower(r) && upper != r && unicode.ToLower(upper) == r
+}
+
+// capitalLetters returns the number of letters in w that may be uppercased by
+// WithRandomCapital.
+func capitalLetters(w string) int {
+	var n int
+	for _, r := range w {
+		if capitalEligible(r) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// initRandomCapital checks that a random capital can be told apart in every
+// passphrase of the words and computes its entropy.
+func (g *PassphraseGenerator) initRandomCapital() error {
+	switch g.randomCapital {
+	case RandomCapitalNone:
+		return nil
+	case RandomCapitalPhrase, RandomCapitalWord:
+	default:
+		return fmt.Errorf("unknown random capital %v", g.randomCapital)
+	}
+
+	if g.casing != CasingLower || g.classCounts != nil || g.mutations != 0 || g.totalLength != 0 {
+		return fmt.Errorf("a random capital cannot be combined with a casing, class counts, mutations, or a length limit")
+	}
+
+	if strings.IndexFunc(g.separator+g.insertChars, unicode.IsUpper) != -1 {
+		return fmt.Errorf("a random capital needs a separator and inserted characters without uppercase letters")
+	}
+
+	var bits float64
+	for _, w := range g.words {
+		if strings.IndexFunc(w, unicode.IsUpper) != -1 {
+			return fmt.Errorf("a random capital needs every word to be lowercase, but %q isn't", w)
+		}
+
+		n := capitalLetters(w)
+		if n == 0 {
+			return fmt.Errorf("a random capital needs every word to have a letter with an uppercase form, but %q doesn't", w)
+		}
+
+		bits += math.Log2(float64(n))
+	}
+
+	g.capitalBits = bits / float64(len(g.words))
+
+	return nil
+}
+
+// RandomCapitalEntropy returns the bits a random capital adds to a
+// passphrase. Since the words are chosen uniformly, one per word adds the
+// average of log2 of the letters of a word for every word. One per phrase
+// adds log2 of the letters of the passphrase on average, which is at least
+// log2 of the word count plus the average for a word.
+func (g *PassphraseGenerator) RandomCapitalEntropy() float64 {
+	switch g.randomCapital {
+	case RandomCapitalPhrase:
+		return math.Log2(float64(g.wordCount)) + g.capitalBits
+	case RandomCapitalWord:
+		return float64(g.wordCount) * g.capitalBits
+	default:
+		return 0
+	}
+}
+
+// chooseRandomCapitals returns, for every word of choices, which of its
+// eligible letters is uppercased, or noCapital.
+func (g *PassphraseGenerator) chooseRandomCapitals(choices []uint32) ([]uint32, error) {
+	ret := make([]uint32, len(choices))
+	for i := range ret {
+		ret[i] = noCapital
+	}
+
+	switch g.randomCapital {
+	case RandomCapitalPhrase:
+		var total uint32
+		for _, c := range choices {
+			total += uint32(capitalLetters(g.words[c]))
+		}
+
+		pos, err := g.rnd.intn("passphrase random capital", 0, total)
+		if err != nil {
+			wipePositions(ret)
+			return nil, errors.Wrap(err, "choose secure random letter to capitalize")
+		}
+
+		for i, c := range choices {
+			n := uint32(capitalLetters(g.words[c]))
+			if pos < n {
+				ret[i] = pos
+				break
+			}
+
+			pos -= n
+		}
+	case RandomCapitalWord:
+		for i, c := range choices {
+			pos, err := g.rnd.intn("passphrase random capital", uint32(i), uint32(capitalLetters(g.words[c])))
+			if err != nil {
+				wipePositions(ret)
+				return nil, errors.Wrapf(err, "choose secure random letter to capitalize #%v", i)
+			}
+
+			ret[i] = pos
+		}
+	}
+
+	return ret, nil
+}
+
+// randomCapitalSize returns how many bytes longer w is with its nth eligible
+// letter uppercased.
+func randomCapitalSize(w string, n uint32) int {
+	for _, r := range w {
+		if !capitalEligible(r) {
+			continue
+		}
+
+		if n == 0 {
+			return utf8.RuneLen(unicode.ToUpper(r)) - utf8.RuneLen(r)
+		}
+
+		n--
+	}
+
+	return 0
+}
+
+// appendRandomCapital appends w to dst with its nth eligible letter
+// uppercased.
+func appendRandomCapital(dst []byte, w string, n uint32) []byte {
+	for _, r := range w {
+		if capitalEligible(r) {
+			if n == 0 {
+				r = unicode.ToUpper(r)
+			}
+
+			n--
+		}
+
+		dst = utf8.AppendRune(dst, r)
+	}
+
+	return dst
+}
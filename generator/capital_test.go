@@ -0,0 +1,204 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// exactCapitalEntropy returns the entropy of two words from words joined by
+// "-" with a random capital, computed from the distribution of every
+// possible passphrase.
+func exactCapitalEntropy(words []string, c RandomCapital) float64 {
+	dist := make(map[string]float64)
+	pw := 1 / float64(len(words)*len(words))
+
+	for _, a := range words {
+		for _, b := range words {
+			na, nb := capitalLetters(a), capitalLetters(b)
+
+			switch c {
+			case RandomCapitalPhrase:
+				for i := 0; i < na; i++ {
+					dist[string(appendRandomCapital(nil, a, uint32(i)))+"-"+b] += pw / float64(na+nb)
+				}
+
+				for i := 0; i < nb; i++ {
+					dist[a+"-"+string(appendRandomCapital(nil, b, uint32(i)))] += pw / float64(na+nb)
+				}
+			case RandomCapitalWord:
+				for i := 0; i < na; i++ {
+					for j := 0; j < nb; j++ {
+						dist[string(appendRandomCapital(nil, a, uint32(i)))+"-"+string(appendRandomCapital(nil, b, uint32(j)))] += pw / float64(na*nb)
+					}
+				}
+			}
+		}
+	}
+
+	var bits float64
+	for _, p := range dist {
+		bits -= p * math.Log2(p)
+	}
+
+	return bits
+}
+
+func TestRandomCapitalEntropy(t *testing.T) {
+	// "ß" has no uppercase form of its own, and digits have none at all.
+	words := []string{"ab", "b1", "cde", "ßx", "éfgh", "i"}
+
+	for _, tc := range []struct {
+		c     RandomCapital
+		exact bool
+	}{
+		{RandomCapitalPhrase, false},
+		{RandomCapitalWord, true},
+	} {
+		g, err := NewPassphraseGenerator(2, words, "-", WithRandomCapital(tc.c))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := exactCapitalEntropy(words, tc.c)
+		got := g.Entropy()
+
+		if tc.exact && math.Abs(got-want) > 1e-9 {
+			t.Errorf("random capital %v: got %v bits, want exactly %v", tc.c, got, want)
+		}
+
+		if got > want+1e-9 {
+			t.Errorf("random capital %v: got %v bits, more than the exact %v", tc.c, got, want)
+		}
+	}
+}
+
+func TestRandomCapitalGenerate(t *testing.T) {
+	words := []string{"apple", "b2b", "über", "ßa", "kiwi"}
+
+	for _, tc := range []struct {
+		c       RandomCapital
+		perWord int
+	}{
+		{RandomCapitalPhrase, 0},
+		{RandomCapitalWord, 1},
+	} {
+		g, err := NewPassphraseGenerator(4, words, " ", WithRandomCapital(tc.c))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g.rnd = randSource{reader: testSource(t)}
+
+		for i := 0; i < 500; i++ {
+			b, err := g.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !utf8.Valid(b) {
+				t.Fatalf("invalid UTF-8 %q", b)
+			}
+
+			var total int
+			for _, w := range strings.Split(string(b), " ") {
+				if strings.IndexFunc(w, unicode.IsUpper) == -1 {
+					if tc.perWord != 0 {
+						t.Fatalf("word %q of %q has no capital", w, b)
+					}
+
+					continue
+				}
+
+				total++
+
+				var capitals int
+				for _, r := range w {
+					if unicode.IsUpper(r) {
+						capitals++
+					}
+				}
+
+				if capitals != 1 {
+					t.Fatalf("word %q of %q has %v capitals, want 1", w, b, capitals)
+				}
+
+				if !slices.Contains(words, strings.ToLower(w)) {
+					t.Fatalf("word %q of %q is not a capitalized word", w, b)
+				}
+			}
+
+			if tc.perWord == 0 && total != 1 {
+				t.Fatalf("%q has %v capitals, want 1", b, total)
+			}
+		}
+	}
+}
+
+func TestRandomCapitalUniform(t *testing.T) {
+	g, err := NewPassphraseGenerator(1, []string{"abcdefgh", "ijklmnop"}, "-", WithRandomCapital(RandomCapitalPhrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.rnd = randSource{reader: testSource(t)}
+
+	counts := make([]int, 8)
+	for i := 0; i < 4000; i++ {
+		b, err := g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		counts[strings.IndexFunc(string(b), unicode.IsUpper)]++
+	}
+
+	if chi := chiSquare(counts); chi > chiSquareLimit(len(counts)) {
+		t.Errorf("capital positions are not uniform: %v, chi-square %v", counts, chi)
+	}
+}
+
+func TestRandomCapitalRejects(t *testing.T) {
+	words := []string{"apple", "kiwi", "plum"}
+
+	for _, tc := range []struct {
+		name      string
+		words     []string
+		separator string
+		opts      []PassphraseOption
+	}{
+		{"uppercase word", []string{"apple", "Kiwi"}, "-", nil},
+		{"word without letters", []string{"apple", "123"}, "-", nil},
+		{"uppercase separator", words, "X", nil},
+		{"casing", words, "-", []PassphraseOption{WithCasing(CasingTitle)}},
+		{"class counts", words, "-", []PassphraseOption{WithClassCounts(1, 0, 0)}},
+		{"mutations", words, "-", []PassphraseOption{WithMutations(1)}},
+		{"length limit", words, "-", []PassphraseOption{WithTotalLength(20, false)}},
+	} {
+		opts := append([]PassphraseOption{WithRandomCapital(RandomCapitalPhrase)}, tc.opts...)
+
+		_, err := NewPassphraseGenerator(2, tc.words, tc.separator, opts...)
+		if err == nil {
+			t.Errorf("%v: expected an error", tc.name)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Charset describes the characters available to each character class.
+type Charset struct {
+	Name        string
+	Description string
+
+	Letters string
+	// Uppercase reports whether base letters may be turned uppercase.
+	Uppercase bool
+	Digits    string
+	Special   string
+	// Unicode allows printable non-ASCII characters, see Validate.
+	Unicode bool
+}
+
+// DefaultCharset has lowercase letters without l and o, which are easily
+// mistaken for 1 and 0, their uppercase variants, digits, and the special
+// characters that are safe to type in most places. See WithFullAlphabet.
+var DefaultCharset = Charset{
+	Name:        "default",
+	Description: "Letters without l and o, digits, and common special characters",
+
+	Letters:   letterCharset,
+	Uppercase: true,
+	Digits:    digitCharset,
+	Special:   specialCharset,
+}
+
+// NoShiftCharset only contains characters that can be typed without holding
+// Shift on a standard US keyboard.
+var NoShiftCharset = Charset{
+	Name:        "no-shift",
+	Description: "Characters typed without Shift on a US keyboard",
+
+	Letters:   letterCharset,
+	Uppercase: false,
+	Digits:    digitCharset,
+	Special:   filterCharset(KeyboardLayoutUS.Unshifted(), isSpecialChar),
+}
+
+var layoutPortableChars = KeyboardLayoutUS.Common(KeyboardLayoutDE, KeyboardLayoutFR)
+
+// LayoutPortableCharset only contains characters that are typed with the
+// same key and modifier on US QWERTY, German QWERTZ and French AZERTY
+// layouts. This leaves a subset of the letters and no digits or special
+// characters at all. Uppercase is kept, because these letters stay on the
+// same key with Shift held on all three layouts.
+var LayoutPortableCharset = Charset{
+	Name:        "layout-portable",
+	Description: "Letters on the same key on QWERTY, QWERTZ and AZERTY keyboards",
+
+	Letters: filterCharset(letterCharset, func(c rune) bool {
+		return strings.ContainsRune(layoutPortableChars, c)
+	}),
+	Uppercase: true,
+}
+
+var (
+	charsetPresetsMu sync.RWMutex
+	charsetPresets   = []Charset{
+		DefaultCharset,
+		NoShiftCharset,
+		LayoutPortableCharset,
+		WiFiCharset,
+		ShellSafeCharset,
+		QuoteSafeCharset,
+		PrintableASCIICharset,
+		EmojiCharset,
+	}
+)
+
+// Charsets returns the named charsets CharsetByName knows of, the presets
+// followed by the ones added with RegisterCharset.
+func Charsets() []Charset {
+	charsetPresetsMu.RLock()
+	defer charsetPresetsMu.RUnlock()
+
+	return append([]Charset(nil), charsetPresets...)
+}
+
+// RegisterCharset makes c available by its name to CharsetByName, and so to
+// site policies and the -charset flag of the CLI. The charset must be valid,
+// and its name must be new and must not contain "+", which joins names for
+// intersections.
+func RegisterCharset(c Charset) error {
+	if c.Name == "" || strings.Contains(c.Name, "+") {
+		return fmt.Errorf("invalid charset name %q", c.Name)
+	}
+
+	err := c.Validate()
+	if err != nil {
+		return err
+	}
+
+	charsetPresetsMu.Lock()
+	defer charsetPresetsMu.Unlock()
+
+	for _, other := range charsetPresets {
+		if other.Name == c.Name {
+			return fmt.Errorf("charset %q is already registered", c.Name)
+		}
+	}
+
+	charsetPresets = append(charsetPresets, c)
+
+	return nil
+}
+
+// Validate checks that c can be generated from. Letters must not be empty,
+// while Digits and Special may be, which leaves the class unavailable. Every
+// character must be printable ASCII other than space, and appear only once
+// across all classes. With Uppercase, the letters must be lowercase, so that
+// their uppercase variants are distinct characters that don't appear
+// anywhere else either.
+//
+// With Unicode, the characters may also be printable non-ASCII characters
+// other than spaces and combining marks, which would merge with their
+// neighbors, e.g. precomposed accented letters, Cyrillic, or CJK. With
+// Uppercase, every letter must then have a distinct uppercase variant.
+func (c Charset) Validate() error {
+	if c.Letters == "" {
+		return fmt.Errorf("charset %q has no letters", c.Name)
+	}
+
+	classOf := make(map[rune]string)
+
+	check := func(class, chars string) error {
+		if !utf8.ValidString(chars) {
+			return fmt.Errorf("charset %q has %v characters that are not valid UTF-8", c.Name, class)
+		}
+
+		for _, ch := range chars {
+			if !c.allowsChar(ch) {
+				if c.Unicode {
+					return fmt.Errorf("charset %q has %v character %q, but only printable characters other than spaces and combining marks are allowed", c.Name, class, ch)
+				}
+
+				return fmt.Errorf("charset %q has %v character %q, but only printable ASCII characters other than space are allowed", c.Name, class, ch)
+			}
+
+			if other := classOf[ch]; other != "" {
+				if other == class {
+					return fmt.Errorf("charset %q has %v character %q more than once", c.Name, class, ch)
+				}
+
+				return fmt.Errorf("charset %q has %q in both the %v and the %v class", c.Name, ch, other, class)
+			}
+
+			classOf[ch] = class
+		}
+
+		return nil
+	}
+
+	err := check("letter", c.Letters)
+	if err == nil && c.Uppercase {
+		for _, ch := range c.Letters {
+			switch {
+			case c.Unicode && (!unicode.IsLower(ch) || unicode.ToUpper(ch) == ch || unicode.ToLower(unicode.ToUpper(ch)) != ch):
+				return fmt.Errorf("charset %q allows uppercase, but has letter %q that is not a lowercase letter with a distinct uppercase variant", c.Name, ch)
+			case !c.Unicode && (ch < 'a' || ch > 'z'):
+				return fmt.Errorf("charset %q allows uppercase, but has letter %q that is not a lowercase ASCII letter", c.Name, ch)
+			}
+		}
+
+		err = check("uppercase", strings.ToUpper(c.Letters))
+	}
+
+	if err == nil {
+		err = check("digit", c.Digits)
+	}
+
+	if err == nil {
+		err = check("special", c.Special)
+	}
+
+	return err
+}
+
+func (c Charset) allowsChar(ch rune) bool {
+	if ch < utf8.RuneSelf {
+		return ch > ' ' && ch < 0x7f
+	}
+
+	return c.Unicode && unicode.IsGraphic(ch) && !unicode.IsSpace(ch) && !unicode.IsMark(ch)
+}
+
+// Size returns the number of distinct characters the charset can produce.
+func (c Charset) Size() int {
+	letters := utf8.RuneCountInString(c.Letters)
+
+	size := letters + utf8.RuneCountInString(c.Digits) + utf8.RuneCountInString(c.Special)
+	if c.Uppercase {
+		size += letters
+	}
+
+	return size
+}
+
+// CharsetByName returns the named charset with the given name, see Charsets. Several preset names
+// joined with "+" return their intersection, see IntersectCharsets.
+func CharsetByName(name string) (Charset, error) {
+	if strings.Contains(name, "+") {
+		var charsets []Charset
+		for _, n := range strings.Split(name, "+") {
+			c, err := CharsetByName(n)
+			if err != nil {
+				return Charset{}, err
+			}
+
+			charsets = append(charsets, c)
+		}
+
+		return IntersectCharsets(charsets...)
+	}
+
+	charsets := Charsets()
+	for _, c := range charsets {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+
+	names := make([]string, len(charsets))
+	for i, c := range charsets {
+		names[i] = c.Name
+	}
+
+	return Charset{}, fmt.Errorf("unknown charset %q (available: %v)", name, strings.Join(names, ", "))
+}
+
+// IntersectCharsets returns the charset of the characters that every given
+// charset allows in the same class, so that a password generated from it
+// satisfies all of them. Uppercase is only kept if all of them allow it. The
+// intersection must have letters, but may lose digits or special characters.
+func IntersectCharsets(charsets ...Charset) (Charset, error) {
+	if len(charsets) == 0 {
+		return Charset{}, fmt.Errorf("no charsets to intersect")
+	}
+
+	ret := charsets[0]
+	for _, c := range charsets[1:] {
+		ret.Name += "+" + c.Name
+		ret.Description = ""
+		ret.Uppercase = ret.Uppercase && c.Uppercase
+		ret.Letters = intersectChars(ret.Letters, c.Letters)
+		ret.Digits = intersectChars(ret.Digits, c.Digits)
+		ret.Special = intersectChars(ret.Special, c.Special)
+	}
+
+	if ret.Letters == "" {
+		return Charset{}, fmt.Errorf("charsets %v have no letters in common", strings.ReplaceAll(ret.Name, "+", ", "))
+	}
+
+	return ret, nil
+}
+
+func intersectChars(a, b string) string {
+	return filterCharset(a, func(c rune) bool {
+		return strings.ContainsRune(b, c)
+	})
+}
+
+func filterCharset(charset string, keep func(rune) bool) string {
+	var sb strings.Builder
+	for _, c := range charset {
+		if keep(c) {
+			sb.WriteRune(c)
+		}
+	}
+
+	return sb.String()
+}
+
+func isSpecialChar(c rune) bool {
+	return c > ' ' && c < 0x7f && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9')
+}
@@ -0,0 +1,125 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIntersectCharsetsConflicting(t *testing.T) {
+	a := Charset{Name: "a", Letters: "abcdef", Uppercase: true, Digits: "0123", Special: "!@#"}
+	b := Charset{Name: "b", Letters: "uvwxyz", Digits: "0123", Special: "!@#"}
+
+	_, err := IntersectCharsets(a, b)
+	if err == nil || !strings.Contains(err.Error(), "a, b have no letters in common") {
+		t.Errorf("disjoint letters: got %v", err)
+	}
+
+	// The intersection exists, but lacks a class the counts need, which
+	// NewGenerator explains.
+	for _, tc := range []struct {
+		a, b                  Charset
+		upper, digit, special uint32
+		want                  string
+	}{
+		{DefaultCharset, LayoutPortableCharset, 0, 2, 0, "digit"},
+		{DefaultCharset, LayoutPortableCharset, 0, 0, 1, "special"},
+		{DefaultCharset, NoShiftCharset, 1, 0, 0, "uppercase"},
+		{a, Charset{Name: "c", Letters: "abc", Uppercase: true, Digits: "0", Special: "$%"}, 0, 0, 1, "special"},
+	} {
+		c, err := IntersectCharsets(tc.a, tc.b)
+		if err != nil {
+			t.Errorf("%v, %v: %v", tc.a.Name, tc.b.Name, err)
+			continue
+		}
+
+		_, err = NewGenerator(16, tc.upper, tc.digit, tc.special, WithCharset(c))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%v: got %v, want an error about %v characters", c.Name, err, tc.want)
+		}
+	}
+}
+
+func TestIntersectCharsetsCompatible(t *testing.T) {
+	for _, tc := range []struct {
+		a, b                  Charset
+		upper, digit, special uint32
+	}{
+		{DefaultCharset, NoShiftCharset, 0, 2, 2},
+		{NoShiftCharset, LayoutPortableCharset, 0, 0, 0},
+		{DefaultCharset, ShellSafeCharset, 2, 2, 2},
+		{ShellSafeCharset, QuoteSafeCharset, 1, 1, 1},
+		{WiFiCharset, ShellSafeCharset, 3, 3, 3},
+	} {
+		c, err := IntersectCharsets(tc.a, tc.b)
+		if err != nil {
+			t.Fatalf("%v, %v: %v", tc.a.Name, tc.b.Name, err)
+		}
+
+		g, err := NewGenerator(16, tc.upper, tc.digit, tc.special, WithCharset(c), WithRandSource(testSource(t)))
+		if err != nil {
+			t.Fatalf("%v: %v", c.Name, err)
+		}
+
+		// Validate against each original policy accepts everything the
+		// intersection generates.
+		var originals []*Generator
+		for _, orig := range []Charset{tc.a, tc.b} {
+			o, err := NewGenerator(16, tc.upper, tc.digit, tc.special, WithCharset(orig))
+			if err != nil {
+				t.Fatalf("%v: %v", orig.Name, err)
+			}
+
+			originals = append(originals, o)
+		}
+
+		for i := 0; i < 500; i++ {
+			pw, err := g.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, o := range originals {
+				err = o.Validate(pw)
+				if err != nil {
+					t.Fatalf("%q from %v is not valid for %v: %v", pw, c.Name, o.charset.Name, err)
+				}
+			}
+		}
+	}
+}
+
+func TestCharsetByNameIntersects(t *testing.T) {
+	c, err := CharsetByName("no-shift+layout-portable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := IntersectCharsets(NoShiftCharset, LayoutPortableCharset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Name != want.Name || c.Letters != want.Letters || c.Uppercase || c.Digits != "" || c.Special != "" {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+
+	if _, err := CharsetByName("no-shift+nope"); err == nil {
+		t.Error("unknown name in an intersection accepted")
+	}
+}
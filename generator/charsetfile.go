@@ -0,0 +1,190 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// LoadCharset reads a charset definition of one "field = value" line per
+// field, e.g. as distributed by a security team:
+//
+//	# Approved characters.
+//	name = corp
+//	letters = abcdefghijkmnpqrstuvwxyz
+//	uppercase = yes
+//	digits = 23456789
+//	special = !#%+-=?@_
+//
+// Only letters is required, and name defaults to defaultName. uppercase is
+// yes or no, or the uppercase variants of all the letters, in any order.
+// unicode = yes allows non-ASCII characters, see Charset.Validate. Leaving
+// out digits or special leaves the class unavailable, but a field that is
+// given must not be empty. Lines starting with "#" are comments. The errors
+// name the line of the offending field.
+func LoadCharset(r io.Reader, defaultName string) (Charset, error) {
+	values := make(map[string]string)
+	fieldLines := make(map[string]int)
+
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+
+		text = strings.TrimSpace(text)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		field, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return Charset{}, fmt.Errorf("line %v: expected \"field = value\", got %q", line, text)
+		}
+
+		field = strings.TrimSpace(field)
+		value = strings.TrimSpace(value)
+
+		switch field {
+		case "name", "description", "letters", "uppercase", "digits", "special", "unicode":
+		default:
+			return Charset{}, fmt.Errorf("line %v: unknown field %q", line, field)
+		}
+
+		if first, ok := fieldLines[field]; ok {
+			return Charset{}, fmt.Errorf("line %v: field %v repeats line %v", line, field, first)
+		}
+
+		if value == "" {
+			return Charset{}, fmt.Errorf("line %v: field %v is empty", line, field)
+		}
+
+		fieldLines[field] = line
+		values[field] = value
+	}
+
+	err := sc.Err()
+	if err != nil {
+		return Charset{}, errors.Wrap(err, "read charset")
+	}
+
+	c := Charset{
+		Name:        defaultName,
+		Description: values["description"],
+	}
+
+	if name, ok := values["name"]; ok {
+		if strings.Contains(name, "+") {
+			return Charset{}, fmt.Errorf("line %v: name %q must not contain '+'", fieldLines["name"], name)
+		}
+
+		c.Name = name
+	}
+
+	switch values["unicode"] {
+	case "", "no":
+	case "yes":
+		c.Unicode = true
+	default:
+		return Charset{}, fmt.Errorf("line %v: unicode must be yes or no", fieldLines["unicode"])
+	}
+
+	for _, f := range []struct {
+		name  string
+		class *string
+	}{{"letters", &c.Letters}, {"digits", &c.Digits}, {"special", &c.Special}} {
+		value, ok := values[f.name]
+		if !ok {
+			continue
+		}
+
+		err := c.checkField(value)
+		if err != nil {
+			return Charset{}, fmt.Errorf("line %v: %v %v", fieldLines[f.name], f.name, err)
+		}
+
+		*f.class = value
+	}
+
+	if c.Letters == "" {
+		return Charset{}, fmt.Errorf("missing field letters")
+	}
+
+	switch upperValue := values["uppercase"]; upperValue {
+	case "", "no":
+	case "yes":
+		c.Uppercase = true
+	default:
+		err := c.checkField(upperValue)
+		if err != nil {
+			return Charset{}, fmt.Errorf("line %v: uppercase %v", fieldLines["uppercase"], err)
+		}
+
+		isVariant := func(ch rune) bool {
+			lower := unicode.ToLower(ch)
+			return lower != ch && strings.ContainsRune(c.Letters, lower)
+		}
+
+		if utf8.RuneCountInString(upperValue) != utf8.RuneCountInString(c.Letters) || filterCharset(upperValue, isVariant) != upperValue {
+			return Charset{}, fmt.Errorf("line %v: uppercase must be yes, no, or the uppercase variants of all the letters", fieldLines["uppercase"])
+		}
+
+		c.Uppercase = true
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return Charset{}, err
+	}
+
+	return c, nil
+}
+
+// checkField checks the characters of a single class, so that the problem
+// can be reported with its line. Validate checks the classes against each
+// other afterwards.
+func (c Charset) checkField(value string) error {
+	if !utf8.ValidString(value) {
+		return fmt.Errorf("is not valid UTF-8")
+	}
+
+	pos := 0
+	for i, ch := range value {
+		pos++
+		if !c.allowsChar(ch) {
+			if c.Unicode {
+				return fmt.Errorf("has character %q at position %v, but only printable characters other than spaces and combining marks are allowed", ch, pos)
+			}
+
+			return fmt.Errorf("has character %q at position %v, but only printable ASCII characters other than space are allowed without unicode = yes", ch, pos)
+		}
+
+		if j := strings.IndexRune(value[:i], ch); j != -1 {
+			return fmt.Errorf("has %q at both position %v and %v", ch, utf8.RuneCountInString(value[:j])+1, pos)
+		}
+	}
+
+	return nil
+}
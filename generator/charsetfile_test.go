@@ -0,0 +1,92 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadCharset(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		file string
+		want Charset
+	}{
+		{
+			"full",
+			"# Approved characters.\nname = corp\ndescription = Corp policy\nletters = abcdefghijkmnpqrstuvwxyz\nuppercase = yes\ndigits = 23456789\nspecial = !#%+-=?@_\n",
+			Charset{Name: "corp", Description: "Corp policy", Letters: "abcdefghijkmnpqrstuvwxyz", Uppercase: true, Digits: "23456789", Special: "!#%+-=?@_"},
+		},
+		{
+			"letters only",
+			"letters = abc\n",
+			Charset{Name: "file", Letters: "abc"},
+		},
+		{
+			"byte order mark, spaces and blank lines",
+			"\ufeff  letters=abc  \n\n\tdigits =  01\n",
+			Charset{Name: "file", Letters: "abc", Digits: "01"},
+		},
+		{
+			"uppercase variants",
+			"letters = abc\nuppercase = CAB\n",
+			Charset{Name: "file", Letters: "abc", Uppercase: true},
+		},
+		{
+			"unicode",
+			"unicode = yes\nletters = абвгд\nspecial = €§\n",
+			Charset{Name: "file", Letters: "абвгд", Special: "€§", Unicode: true},
+		},
+	} {
+		got, err := LoadCharset(strings.NewReader(tc.file), "file")
+		if err != nil {
+			t.Errorf("%v: %v", tc.name, err)
+			continue
+		}
+
+		if got != tc.want {
+			t.Errorf("%v: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestLoadCharsetErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		file string
+		err  string
+	}{
+		{"no equals sign", "letters abc\n", `line 1: expected "field = value"`},
+		{"unknown field", "letters = abc\nsymbols = !\n", `line 2: unknown field "symbols"`},
+		{"repeated field", "letters = abc\n# comment\nletters = def\n", "line 3: field letters repeats line 1"},
+		{"empty field", "letters = abc\ndigits =\n", "line 2: field digits is empty"},
+		{"missing letters", "digits = 0123\n", "missing field letters"},
+		{"duplicate character", "letters = abca\n", "line 1: letters has 'a' at both position 1 and 4"},
+		{"non-ASCII without unicode", "letters = abc\nspecial = !€\n", "line 2: special has character '€' at position 2"},
+		{"space", "letters = a c\n", "line 1: letters has character ' ' at position 2"},
+		{"bad unicode value", "unicode = maybe\nletters = abc\n", "line 1: unicode must be yes or no"},
+		{"bad uppercase", "letters = abc\nuppercase = AB\n", "line 2: uppercase must be yes, no, or the uppercase variants"},
+		{"plus in name", "name = a+b\nletters = abc\n", `line 1: name "a+b" must not contain '+'`},
+		{"class overlap", "letters = abc\nspecial = !a\n", `charset "file" has 'a' in both the letter and the special class`},
+	} {
+		_, err := LoadCharset(strings.NewReader(tc.file), "file")
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: got error %v, want one containing %q", tc.name, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// MaxChunkGroups is the largest number of groups a ChunkedGenerator joins.
+const MaxChunkGroups = 8
+
+// appleGroupLength is the length of every group of an Apple-style password.
+const appleGroupLength = 6
+
+// ChunkedGenerator generates passwords of groups of characters joined by
+// hyphens, like Safari's strong passwords, e.g. "mupric-gexwe7-zyHnod". The
+// groups are a single password from a Generator, split at fixed positions.
+type ChunkedGenerator struct {
+	inner     *Generator
+	groups    uint32
+	groupLen  uint32
+	separator byte
+}
+
+// NewAppleStyleGenerator returns a generator of Apple-style passwords: groups
+// of six lowercase letters joined by hyphens, with exactly one uppercase
+// letter and one digit placed at random positions across all of them.
+func NewAppleStyleGenerator(groups uint32) (*ChunkedGenerator, error) {
+	if groups < 1 || groups > MaxChunkGroups {
+		return nil, fmt.Errorf("group count must be between 1 and %v", MaxChunkGroups)
+	}
+
+	// One group has room for the uppercase letter and the digit, as long as
+	// it has at least two characters.
+	inner, err := NewGenerator(groups*appleGroupLength, 1, 1, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "create generator")
+	}
+
+	return &ChunkedGenerator{
+		inner:     inner,
+		groups:    groups,
+		groupLen:  appleGroupLength,
+		separator: '-',
+	}, nil
+}
+
+// Entropy returns the exact entropy of the passwords. The hyphens are at
+// fixed positions, so they add nothing.
+func (g *ChunkedGenerator) Entropy() float64 {
+	n := int64(g.groups * g.groupLen)
+	letters := int64(len(g.inner.charset.Letters))
+
+	// The uppercase letter and the digit take two distinct positions, and
+	// every other character is a lowercase letter.
+	combos := new(big.Int).Exp(big.NewInt(letters), big.NewInt(n-1), nil)
+	combos.Mul(combos, big.NewInt(n*(n-1)*int64(len(g.inner.charset.Digits))))
+
+	return log2BigInt(combos)
+}
+
+func (g *ChunkedGenerator) Generate() ([]byte, error) {
+	b, err := g.inner.Generate()
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(b)
+
+	ret := make([]byte, 0, len(b)+int(g.groups)-1)
+	for i := uint32(0); i < g.groups; i++ {
+		if i != 0 {
+			ret = append(ret, g.separator)
+		}
+
+		ret = append(ret, b[i*g.groupLen:(i+1)*g.groupLen]...)
+	}
+
+	return ret, nil
+}
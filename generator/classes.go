@@ -0,0 +1,279 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+	"slices"
+)
+
+// classSet is a set of the optional character classes.
+type classSet uint8
+
+const (
+	classUpper classSet = 1 << iota
+	classDigit
+	classSpecial
+)
+
+var optionalClasses = []struct {
+	class classSet
+	name  string
+}{
+	{classUpper, "uppercase"},
+	{classDigit, "digit"},
+	{classSpecial, "special"},
+}
+
+// CountMode is how the uppercase, digit and special counts of a Generator
+// are met.
+type CountMode int
+
+const (
+	// CountsExact gives every password exactly the given number of
+	// characters of each class. The other characters are lowercase letters.
+	CountsExact CountMode = iota
+	// CountsMinimum gives every password at least the given numbers. The
+	// other characters are drawn from the lowercase letters and every class
+	// with a non-zero count, so that a password with at least 2 digits may
+	// have more of them by chance.
+	CountsMinimum
+)
+
+// WithCountMode sets how the class counts are met, CountsExact by default.
+// CountsMinimum has more possible passwords for the same counts, unless the
+// counts fill the whole length.
+func WithCountMode(m CountMode) Option {
+	return func(g *Generator) {
+		g.countMode = m
+	}
+}
+
+// WithMinClasses makes the generator include characters from at least n of
+// the four classes (lowercase, uppercase, digit, special). Classes that the
+// counts already require are kept, and if that is not enough, the missing
+// classes are chosen uniformly at random among the ones the charset allows
+// and get one character each. Zero means no requirement.
+func WithMinClasses(n uint32) Option {
+	return func(g *Generator) {
+		g.minClasses = n
+	}
+}
+
+// initClassCombos checks that the class requirement can be met and lists
+// every set of additional classes a password may be given.
+func (g *Generator) initClassCombos() error {
+	g.classCombos = nil
+
+	if g.minClasses == 0 {
+		return nil
+	}
+
+	if g.minClasses > 4 {
+		return fmt.Errorf("at most 4 character classes exist, but at least %v are required", g.minClasses)
+	}
+
+	lower, upper, digit, special := g.classCounts()
+
+	var present uint32
+	for _, count := range []uint32{lower, upper, digit, special} {
+		if count != 0 {
+			present++
+		}
+	}
+
+	if present >= g.minClasses {
+		return nil
+	}
+
+	missing := g.minClasses - present
+
+	var available classSet
+	var availableCount uint32
+	for _, c := range optionalClasses {
+		if g.classCount(c.class) == 0 && g.charsetHasClass(c.class) {
+			available |= c.class
+			availableCount++
+		}
+	}
+
+	if availableCount < missing {
+		return fmt.Errorf("charset %q and the given counts allow characters from at most %v classes, but at least %v are required", g.charset.Name, present+availableCount, g.minClasses)
+	}
+
+	// One lowercase letter has to remain, or the lowercase class would be
+	// traded for another one.
+	if lower <= missing {
+		return fmt.Errorf("length %v leaves no room for characters from %v more classes", g.length, missing)
+	}
+
+	if lower-missing < g.minLowercase {
+		return fmt.Errorf("length %v leaves room for %v lowercase letters next to the characters from %v more classes, but at least %v are required", g.length, lower-missing, missing, g.minLowercase)
+	}
+
+	for set := classSet(1); set <= available; set++ {
+		if set&^available == 0 && uint32(bits.OnesCount8(uint8(set))) == missing {
+			g.classCombos = append(g.classCombos, set)
+		}
+	}
+
+	return nil
+}
+
+func (g *Generator) classCount(class classSet) uint32 {
+	switch class {
+	case classUpper:
+		return g.uppercaseCount
+	case classDigit:
+		return g.digitCount
+	default:
+		return g.specialCount
+	}
+}
+
+func (g *Generator) charsetHasClass(class classSet) bool {
+	switch class {
+	case classUpper:
+		return g.charset.Uppercase
+	case classDigit:
+		return g.charset.Digits != ""
+	default:
+		return g.charset.Special != ""
+	}
+}
+
+// usesClass reports whether a password from g may contain characters of the
+// given optional class.
+func (g *Generator) usesClass(class classSet) bool {
+	if g.classCount(class) != 0 {
+		return true
+	}
+
+	for _, set := range g.classCombos {
+		if set&class != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// comboCounts returns the uppercase, digit and special counts of a password
+// that is given the additional classes of set.
+func (g *Generator) comboCounts(set classSet) (upper, digit, special uint32) {
+	upper, digit, special = g.uppercaseCount, g.digitCount, g.specialCount
+	if set&classUpper != 0 {
+		upper++
+	}
+
+	if set&classDigit != 0 {
+		digit++
+	}
+
+	if set&classSpecial != 0 {
+		special++
+	}
+
+	return upper, digit, special
+}
+
+// drawCounts picks the class counts for the next password, choosing the
+// additional classes uniformly at random if there are any to choose.
+func (g *Generator) drawCounts() (upper, digit, special uint32, err error) {
+	if len(g.classCombos) == 0 {
+		return g.uppercaseCount, g.digitCount, g.specialCount, nil
+	}
+
+	i, err := g.rnd.intn("class combination", 0, uint32(len(g.classCombos)))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	upper, digit, special = g.comboCounts(g.classCombos[i])
+
+	return upper, digit, special, nil
+}
+
+// countSets returns the sets of additional classes a password may be given,
+// which is only the empty set without a class requirement.
+func (g *Generator) countSets() []classSet {
+	if len(g.classCombos) == 0 {
+		return []classSet{0}
+	}
+
+	return g.classCombos
+}
+
+// reservedLowercase returns how many of the characters that are not given to
+// the counted classes stay lowercase letters. With CountsMinimum, the others
+// are drawn from freePool. One is kept for the class requirement, which
+// counts the lowercase class as present.
+func (g *Generator) reservedLowercase(upper, digit, special uint32) uint32 {
+	lower := g.length - upper - digit - special
+	if g.countMode != CountsMinimum {
+		return lower
+	}
+
+	if g.minClasses != 0 {
+		return min(lower, max(g.minLowercase, 1))
+	}
+
+	return g.minLowercase
+}
+
+// freePool returns the characters the unreserved positions of a password
+// with the given counts are drawn from with CountsMinimum.
+func (g *Generator) freePool(upper, digit, special uint32) []rune {
+	pool := slices.Clip(g.chars.letters)
+	if upper != 0 {
+		pool = append(pool, g.chars.upper...)
+	}
+
+	if digit != 0 {
+		pool = append(pool, g.chars.digits...)
+	}
+
+	if special != 0 {
+		pool = append(pool, g.chars.special...)
+	}
+
+	return pool
+}
+
+// possibleCombinations returns the number of passwords with the given class
+// counts, counting an absent character as one more possibility the same way
+// EntropyMin does.
+func (g *Generator) possibleCombinations(upper, digit, special uint32) *big.Int {
+	ret := big.NewInt(1)
+
+	addFn := func(charset []rune, count uint32) {
+		// Start with one because it is possible for a character to be empty.
+		charsetLength := 1 + int64(len(charset))
+		ret.Mul(ret, new(big.Int).Exp(big.NewInt(charsetLength), big.NewInt(int64(count)), nil))
+	}
+
+	lower := g.reservedLowercase(upper, digit, special)
+	addFn(g.freePool(upper, digit, special), g.length-upper-digit-special-lower)
+	addFn(g.chars.letters, lower)
+	addFn(g.chars.letters, upper)
+	addFn(g.chars.digits, digit)
+	addFn(g.chars.special, special)
+
+	return ret
+}
@@ -0,0 +1,244 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"slices"
+	"testing"
+)
+
+// classCounts returns the number of lowercase, uppercase, digit and special
+// characters of pw.
+func classCounts(g *Generator, pw []byte) (lower, upper, digit, special int) {
+	for _, c := range string(pw) {
+		switch {
+		case slices.Contains(g.chars.letters, c):
+			lower++
+		case slices.Contains(g.chars.upper, c):
+			upper++
+		case slices.Contains(g.chars.digits, c):
+			digit++
+		case slices.Contains(g.chars.special, c):
+			special++
+		}
+	}
+
+	return lower, upper, digit, special
+}
+
+func TestCountModes(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		mode    CountMode
+		special uint32
+	}{
+		{"exact", CountsExact, 2},
+		{"minimum", CountsMinimum, 2},
+		{"minimum without special", CountsMinimum, 0},
+	} {
+		g, err := NewGenerator(16, 2, 2, tc.special, WithCountMode(tc.mode), WithMinLowercase(3), WithRandSource(testSource(t)))
+		if err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+
+		// more counts how often the uppercase, digit and special classes had
+		// more characters than their count.
+		var more [3]int
+		for i := 0; i < 5000; i++ {
+			pw, err := g.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = g.Validate(pw)
+			if err != nil {
+				t.Fatalf("%v: %q: %v", tc.name, pw, err)
+			}
+
+			lower, upper, digit, special := classCounts(g, pw)
+			if lower < 3 || upper < 2 || digit < 2 || special < int(tc.special) {
+				t.Fatalf("%v: %q has fewer characters of a class than required", tc.name, pw)
+			}
+
+			if tc.special == 0 && special != 0 {
+				t.Fatalf("%v: %q has special characters, which have a count of 0", tc.name, pw)
+			}
+
+			for class, n := range []int{upper - 2, digit - 2, special - int(tc.special)} {
+				if n > 0 {
+					more[class]++
+				}
+			}
+		}
+
+		for class, name := range []string{"uppercase", "digit", "special"} {
+			switch {
+			case tc.mode == CountsExact && more[class] != 0:
+				t.Errorf("%v: %v passwords had more %v characters than the count", tc.name, more[class], name)
+			case tc.mode == CountsMinimum && more[class] == 0 && (name != "special" || tc.special != 0):
+				t.Errorf("%v: no password had more %v characters than the count", tc.name, name)
+			}
+		}
+	}
+}
+
+func TestCountModeEntropy(t *testing.T) {
+	var entropy [2]uint64
+	for i, mode := range []CountMode{CountsExact, CountsMinimum} {
+		g, err := NewGenerator(16, 2, 2, 2, WithCountMode(mode))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entropy[i], err = g.EntropyMin()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if entropy[1] <= entropy[0] {
+		t.Errorf("minimum counts have %v bits of entropy, not more than the %v of exact counts", entropy[1], entropy[0])
+	}
+}
+
+func TestValidateCountModes(t *testing.T) {
+	for _, tc := range []struct {
+		pw             string
+		exact, minimum bool
+	}{
+		{"aB3$aaaa", true, true},
+		{"aB34$aaa", false, true},
+		{"aBC3$aaa", false, true},
+		{"aB$aaaaa", false, false},
+		{"abc3$aaa", false, false},
+	} {
+		for _, mode := range []CountMode{CountsExact, CountsMinimum} {
+			g, err := NewGenerator(8, 1, 1, 1, WithCountMode(mode))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := tc.exact
+			if mode == CountsMinimum {
+				want = tc.minimum
+			}
+
+			if err := g.Validate([]byte(tc.pw)); (err == nil) != want {
+				t.Errorf("mode %v, %q: got error %v", mode, tc.pw, err)
+			}
+		}
+	}
+}
+
+func TestCountModeConstraints(t *testing.T) {
+	// The reserved lowercase letters count towards the length in both
+	// modes.
+	for _, mode := range []CountMode{CountsExact, CountsMinimum} {
+		_, err := NewGenerator(8, 2, 2, 2, WithCountMode(mode), WithMinLowercase(3))
+		if err == nil {
+			t.Errorf("mode %v: counts adding up to more than the length succeeded", mode)
+		}
+	}
+}
+
+func TestMinClassesDistribution(t *testing.T) {
+	for _, tc := range []struct {
+		name                  string
+		upper, digit, special uint32
+		minClasses            uint32
+		combos                []classSet
+	}{
+		{"one of three", 0, 0, 0, 2, []classSet{classUpper, classDigit, classSpecial}},
+		{"two of three", 0, 0, 0, 3, []classSet{classUpper | classDigit, classUpper | classSpecial, classDigit | classSpecial}},
+		{"one of two", 2, 0, 0, 3, []classSet{classDigit, classSpecial}},
+		{"all", 0, 0, 0, 4, []classSet{classUpper | classDigit | classSpecial}},
+	} {
+		g, err := NewGenerator(12, tc.upper, tc.digit, tc.special, WithMinClasses(tc.minClasses), WithRandSource(testSource(t)))
+		if err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+
+		counts := make([]int, len(tc.combos))
+		for i := 0; i < 3000; i++ {
+			pw, err := g.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = g.Validate(pw)
+			if err != nil {
+				t.Fatalf("%v: %q: %v", tc.name, pw, err)
+			}
+
+			// The counts are exact, so the additional classes are the ones
+			// with one more character than their count.
+			_, upper, digit, special := classCounts(g, pw)
+
+			var set classSet
+			for _, c := range []struct {
+				class      classSet
+				got, count int
+			}{
+				{classUpper, upper, int(tc.upper)},
+				{classDigit, digit, int(tc.digit)},
+				{classSpecial, special, int(tc.special)},
+			} {
+				switch c.got - c.count {
+				case 0:
+				case 1:
+					set |= c.class
+				default:
+					t.Fatalf("%v: %q has %v characters of class %v with a count of %v", tc.name, pw, c.got, c.class, c.count)
+				}
+			}
+
+			combo := slices.Index(tc.combos, set)
+			if combo == -1 {
+				t.Fatalf("%v: %q got the additional classes %v", tc.name, pw, set)
+			}
+
+			counts[combo]++
+		}
+
+		if len(counts) == 1 {
+			continue
+		}
+
+		x := chiSquare(counts)
+		if x > chiSquareLimit(len(counts)) {
+			t.Errorf("%v: chi-square %.2f over the limit of %.2f, counts %v", tc.name, x, chiSquareLimit(len(counts)), counts)
+		}
+	}
+}
+
+func TestMinClassesConstraints(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		length     uint32
+		minClasses uint32
+		opts       []Option
+	}{
+		{"more than 4", 12, 5, nil},
+		{"charset without uppercase", 12, 4, []Option{WithCharset(NoShiftCharset)}},
+		{"no room", 3, 4, nil},
+	} {
+		_, err := NewGenerator(tc.length, 0, 0, 0, append(tc.opts, WithMinClasses(tc.minClasses))...)
+		if err == nil {
+			t.Errorf("%v: succeeded", tc.name)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ConfusableChars are the groups of characters that are easily misread as
+// each other on paper or in some fonts. WithUnambiguous leaves out all of
+// them. Library users may append groups of their own before creating the
+// generator.
+var ConfusableChars = []string{
+	"0Oo",  // zero and the letter O
+	"1lI|", // one, lowercase L, uppercase i, and the pipe
+	",.",   // comma and period
+	";:",   // semicolon and colon
+	"'`",   // apostrophe and backtick
+}
+
+// WithUnambiguous leaves out the characters of ConfusableChars from every
+// class. As the uppercase letters are the uppercase variants of the
+// letters, a letter goes if either variant is confusable, e.g. i for I. The
+// counts of classes that become empty are rejected.
+func WithUnambiguous() Option {
+	return func(g *Generator) {
+		g.unambiguous = true
+	}
+}
+
+// withoutChars returns c without the characters in chars, as the
+// characters of every class that g may produce. A letter goes if either
+// its lowercase or uppercase variant is in chars.
+func (c Charset) withoutChars(chars string) Charset {
+	keep := func(ch rune) bool {
+		return !strings.ContainsRune(chars, ch)
+	}
+
+	letters := c.Letters
+	if c.Uppercase {
+		letters = filterCharset(letters, func(ch rune) bool {
+			return keep(unicode.ToUpper(ch))
+		})
+	}
+
+	c.Letters = filterCharset(letters, keep)
+	c.Digits = filterCharset(c.Digits, keep)
+	c.Special = filterCharset(c.Special, keep)
+
+	return c
+}
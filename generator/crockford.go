@@ -0,0 +1,242 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// crockfordAlphabet is the Crockford base32 alphabet, which leaves out I, L,
+// O and U. See https://www.crockford.com/base32.html.
+var crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// crockfordCheckSymbols are the symbols for the check values 0 to 36.
+var crockfordCheckSymbols = crockfordAlphabet + "*~$=U"
+
+// CrockfordOptions configure FormatCrockford.
+type CrockfordOptions struct {
+	// Group inserts a hyphen every Group characters, for readability. Zero
+	// means no hyphens.
+	Group int
+	// Check appends the check symbol, the value of the token modulo 37, so
+	// that a typo in a single character is detected.
+	Check bool
+}
+
+// encodeCrockford encodes raw as a big-endian number in Crockford base32,
+// zero-padded to the ceil(8n/5) characters any n bytes need.
+func encodeCrockford(raw []byte) []byte {
+	ret := make([]byte, (len(raw)*8+4)/5)
+
+	// The padding bits come first, so the token has the value of raw.
+	var acc uint
+	bits := len(ret)*5 - len(raw)*8
+	i := 0
+	for _, b := range raw {
+		acc = acc<<8 | uint(b)
+		bits += 8
+
+		for bits >= 5 {
+			bits -= 5
+			ret[i] = crockfordAlphabet[acc>>bits]
+			acc &= 1<<bits - 1
+			i++
+		}
+	}
+
+	return ret
+}
+
+// FormatCrockford returns token, a Crockford base32 token without hyphens,
+// with the hyphens and the check symbol of opts. token is wiped.
+func FormatCrockford(token []byte, opts CrockfordOptions) ([]byte, error) {
+	defer wipe(token)
+
+	if opts.Group < 0 {
+		return nil, fmt.Errorf("group size must not be negative")
+	}
+
+	size := len(token)
+	if opts.Group != 0 && len(token) != 0 {
+		size += (len(token) - 1) / opts.Group
+	}
+
+	if opts.Check {
+		size++
+	}
+
+	ret := make([]byte, 0, size)
+	for i, c := range token {
+		if opts.Group != 0 && i != 0 && i%opts.Group == 0 {
+			ret = append(ret, '-')
+		}
+
+		ret = append(ret, c)
+	}
+
+	if opts.Check {
+		check, err := crockfordCheck(token)
+		if err != nil {
+			wipe(ret)
+			return nil, err
+		}
+
+		ret = append(ret, crockfordCheckSymbols[check])
+	}
+
+	return ret, nil
+}
+
+// crockfordCheck returns the value of the canonical symbols modulo 37.
+func crockfordCheck(symbols []byte) (int, error) {
+	check := 0
+	for i, c := range symbols {
+		v := crockfordValue(c)
+		if v < 0 {
+			return 0, fmt.Errorf("character #%v is not a Crockford base32 symbol", i+1)
+		}
+
+		check = (check*32 + v) % 37
+	}
+
+	return check, nil
+}
+
+// crockfordValue returns the value of a canonical Crockford symbol, or -1.
+func crockfordValue(c byte) int {
+	for v := 0; v < len(crockfordAlphabet); v++ {
+		if crockfordAlphabet[v] == c {
+			return v
+		}
+	}
+
+	return -1
+}
+
+// normalizeCrockford returns the canonical symbols of s the way Crockford
+// decodes them: hyphens are dropped, lowercase is read as uppercase, I and L
+// as 1, and O as 0. If check is set, the last character must be the check
+// symbol of the others, and it is left out. The caller must wipe the result.
+func normalizeCrockford(s []byte, check bool) ([]byte, error) {
+	last := bytes.LastIndexFunc(s, func(r rune) bool { return r != '-' })
+
+	ret := make([]byte, 0, len(s))
+	for i, c := range s {
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+
+		switch c {
+		case '-':
+			continue
+		case 'I', 'L':
+			c = '1'
+		case 'O':
+			c = '0'
+		}
+
+		isCheck := check && i == last
+		if (!isCheck && crockfordValue(c) < 0) || (isCheck && strings.IndexByte(crockfordCheckSymbols, c) < 0) {
+			wipe(ret)
+			return nil, fmt.Errorf("character #%v is not a Crockford base32 symbol", i+1)
+		}
+
+		ret = append(ret, c)
+	}
+
+	if !check {
+		if len(ret) == 0 {
+			return nil, fmt.Errorf("the token is empty")
+		}
+
+		return ret, nil
+	}
+
+	if len(ret) < 2 {
+		wipe(ret)
+		return nil, fmt.Errorf("the token is too short to have a check symbol")
+	}
+
+	symbols, got := ret[:len(ret)-1], ret[len(ret)-1]
+	want, err := crockfordCheck(symbols)
+	if err != nil {
+		wipe(ret)
+		return nil, err
+	}
+
+	if got != crockfordCheckSymbols[want] {
+		wipe(ret)
+		return nil, fmt.Errorf("the check symbol doesn't match, the token was mistyped")
+	}
+
+	ret[len(ret)-1] = 0
+
+	return symbols, nil
+}
+
+// VerifyCrockford checks that s is well-formed Crockford base32, ignoring
+// hyphens and case, and, if check is set, that it ends with its check
+// symbol.
+func VerifyCrockford(s []byte, check bool) error {
+	symbols, err := normalizeCrockford(s, check)
+	if err != nil {
+		return err
+	}
+
+	wipe(symbols[:cap(symbols)])
+
+	return nil
+}
+
+// DecodeCrockford returns the bytes encoded in s, a token of random bytes
+// from GenerateToken with TokenCrockford, after checking it like
+// VerifyCrockford does. The caller must wipe the result.
+func DecodeCrockford(s []byte, check bool) ([]byte, error) {
+	symbols, err := normalizeCrockford(s, check)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(symbols[:cap(symbols)])
+
+	ret := make([]byte, len(symbols)*5/8)
+
+	// The padding bits come first and must be zero. acc only holds the bits
+	// that are not written to ret yet.
+	var acc uint
+	bits := -(len(symbols)*5 - len(ret)*8)
+	i := 0
+	for _, c := range symbols {
+		acc = acc<<5 | uint(crockfordValue(c))
+		bits += 5
+
+		if acc>>bits != 0 {
+			wipe(ret)
+			return nil, fmt.Errorf("the token doesn't encode whole bytes")
+		}
+
+		for bits >= 8 {
+			bits -= 8
+			ret[i] = byte(acc >> bits)
+			acc &= 1<<bits - 1
+			i++
+		}
+	}
+
+	return ret, nil
+}
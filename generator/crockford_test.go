@@ -0,0 +1,168 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCrockfordRoundTrip(t *testing.T) {
+	src := testSource(t)
+
+	for n := 1; n <= 40; n++ {
+		raw := make([]byte, n)
+		_, err := io.ReadFull(src, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, opts := range []CrockfordOptions{{}, {Check: true}, {Group: 4}, {Group: 5, Check: true}} {
+			token, err := FormatCrockford(encodeCrockford(raw), opts)
+			if err != nil {
+				t.Fatalf("%x, %+v: %v", raw, opts, err)
+			}
+
+			// The check symbol follows the last group without a hyphen.
+			if opts.Group != 0 {
+				body := token
+				if opts.Check {
+					body = token[:len(token)-1]
+				}
+
+				for i, c := range body {
+					if (c == '-') != (i%(opts.Group+1) == opts.Group) {
+						t.Fatalf("%x, %+v: %q has a misplaced hyphen", raw, opts, token)
+					}
+				}
+			}
+
+			for _, s := range [][]byte{token, bytes.ToLower(token)} {
+				err = VerifyCrockford(s, opts.Check)
+				if err != nil {
+					t.Errorf("%x, %+v: verify %q: %v", raw, opts, s, err)
+				}
+
+				got, err := DecodeCrockford(s, opts.Check)
+				if err != nil {
+					t.Errorf("%x, %+v: decode %q: %v", raw, opts, s, err)
+				} else if !bytes.Equal(got, raw) {
+					t.Errorf("%x, %+v: %q decoded to %x", raw, opts, s, got)
+				}
+			}
+		}
+	}
+}
+
+func TestFormatCrockfordCheckSymbol(t *testing.T) {
+	for _, tc := range []struct {
+		raw  []byte
+		want string
+	}{
+		{[]byte{0}, "000"},
+		{[]byte{1}, "011"},
+		{[]byte{0xff}, "7Z~"},
+		{[]byte{0, 32}, "0010*"},
+		{[]byte{0, 36}, "0014U"},
+	} {
+		got, err := FormatCrockford(encodeCrockford(tc.raw), CrockfordOptions{Check: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != tc.want {
+			t.Errorf("%x: got %q, want %q", tc.raw, got, tc.want)
+		}
+	}
+}
+
+// A check symbol detects every mistyped character and every transposition of
+// two different adjacent characters.
+func TestVerifyCrockfordCorrupted(t *testing.T) {
+	token, err := FormatCrockford([]byte("7ZQ3M0XD"), CrockfordOptions{Group: 4, Check: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = VerifyCrockford(token, true)
+	if err != nil {
+		t.Fatalf("%q: %v", token, err)
+	}
+
+	for i, c := range token {
+		if c == '-' {
+			continue
+		}
+
+		symbols := crockfordAlphabet
+		if i == len(token)-1 {
+			symbols = crockfordCheckSymbols
+		}
+
+		for j := 0; j < len(symbols); j++ {
+			if symbols[j] == c {
+				continue
+			}
+
+			corrupted := bytes.Clone(token)
+			corrupted[i] = symbols[j]
+			if VerifyCrockford(corrupted, true) == nil {
+				t.Errorf("%q with %q for %q at #%v passed", corrupted, symbols[j], c, i+1)
+			}
+		}
+
+		if i+1 < len(token)-1 && token[i+1] != '-' && token[i+1] != c {
+			swapped := bytes.Clone(token)
+			swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+			if VerifyCrockford(swapped, true) == nil {
+				t.Errorf("%q with #%v and #%v swapped passed", swapped, i+1, i+2)
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		name, token, want string
+	}{
+		{"u in the body", "7ZU3~", "character #3 is not"},
+		{"unknown symbol", "7Z!3~", "character #3 is not"},
+		{"check symbol in the body", "7*~", "character #2 is not"},
+		{"check symbol only", "~", "too short"},
+		{"no check symbol", "7Z", "doesn't match"},
+	} {
+		err := VerifyCrockford([]byte(tc.token), true)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%v: got %v, want an error containing %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+// I, L and O are read as the digits they look like.
+func TestDecodeCrockfordLookalikes(t *testing.T) {
+	want, err := DecodeCrockford([]byte("0110"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"OIL0", "oil0", "0-1-1-O"} {
+		got, err := DecodeCrockford([]byte(s), false)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%q: got %x, %v, want %x", s, got, err, want)
+		}
+	}
+}
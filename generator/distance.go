@@ -0,0 +1,113 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// HammingDistance returns the number of characters at which the UTF-8
+// encoded a and b differ. If the lengths differ, every character past the
+// end of the shorter one counts as different.
+func HammingDistance(a, b []byte) int {
+	ra, rb := decodeRunes(a), decodeRunes(b)
+	defer wipeRunes(ra)
+	defer wipeRunes(rb)
+
+	return hammingDistance(ra, rb)
+}
+
+func hammingDistance(a, b []rune) int {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	d := len(b) - len(a)
+	for i := range a {
+		if a[i] != b[i] {
+			d++
+		}
+	}
+
+	return d
+}
+
+// EditDistance returns the Levenshtein distance between the UTF-8 encoded a
+// and b: the number of single-character insertions, deletions and
+// substitutions needed to turn one into the other.
+func EditDistance(a, b []byte) int {
+	ra, rb := decodeRunes(a), decodeRunes(b)
+	defer wipeRunes(ra)
+	defer wipeRunes(rb)
+
+	return editDistance(ra, rb)
+}
+
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := range a {
+		cur[0] = i + 1
+		for j := range b {
+			cost := 1
+			if a[i] == b[j] {
+				cost = 0
+			}
+
+			cur[j+1] = min(prev[j+1]+1, cur[j]+1, prev[j]+cost)
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+// decodeRunes decodes b into a slice the caller wipes, as a conversion
+// through string would leave a copy behind.
+func decodeRunes(b []byte) []rune {
+	ret := make([]rune, 0, utf8.RuneCount(b))
+	for len(b) != 0 {
+		r, size := utf8.DecodeRune(b)
+		ret = append(ret, r)
+		b = b[size:]
+	}
+
+	return ret
+}
+
+// CheckDistance returns an error if pw is closer to previous than the given
+// minimum Hamming and edit distances. A minimum of zero disables the check.
+// The error never includes either password.
+func CheckDistance(pw, previous []byte, minHamming, minEdit int) error {
+	if d := HammingDistance(pw, previous); d < minHamming {
+		return fmt.Errorf("differs from the previous password in %v positions, at least %v required", d, minHamming)
+	}
+
+	if minEdit != 0 {
+		if d := EditDistance(pw, previous); d < minEdit {
+			return fmt.Errorf("edit distance to the previous password is %v, at least %v required", d, minEdit)
+		}
+	}
+
+	return nil
+}
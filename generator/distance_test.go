@@ -0,0 +1,43 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import "testing"
+
+func TestDistanceCountsCharacters(t *testing.T) {
+	for _, tc := range []struct {
+		a, b          string
+		hamming, edit int
+	}{
+		{"abcd", "abcd", 0, 0},
+		{"abcd", "abce", 1, 1},
+		{"abcd", "abc", 1, 1},
+		{"abcd", "bcda", 4, 2},
+		{"😀😁😂", "😀😃😂", 1, 1},
+		{"😀😁😂", "😀😁", 1, 1},
+		{"äöü", "aöü", 1, 1},
+		{"日本語", "本語", 3, 1},
+	} {
+		if d := HammingDistance([]byte(tc.a), []byte(tc.b)); d != tc.hamming {
+			t.Errorf("HammingDistance(%q, %q) = %v, want %v", tc.a, tc.b, d, tc.hamming)
+		}
+
+		if d := EditDistance([]byte(tc.a), []byte(tc.b)); d != tc.edit {
+			t.Errorf("EditDistance(%q, %q) = %v, want %v", tc.a, tc.b, d, tc.edit)
+		}
+	}
+}
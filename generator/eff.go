@@ -0,0 +1,148 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// The EFF wordlists for passphrases, with the dice rolls that select each
+// word. See https://www.eff.org/dice (CC BY 3.0 US).
+var (
+	//go:embed data/eff_large_wordlist.txt
+	effLongData string
+	//go:embed data/eff_short_wordlist_2_0.txt
+	effShortData string
+)
+
+var effLongWords = mustParseDicewareList("eff-long", effLongData, 5)
+var effShortWords = mustParseDicewareList("eff-short", effShortData, 4)
+
+// WordlistEFFLong returns the EFF long wordlist of 7776 words, for 5 dice.
+func WordlistEFFLong() []string {
+	return append([]string(nil), effLongWords...)
+}
+
+// WordlistEFFShort returns the EFF short wordlist 2.0 of 1296 words, for 4
+// dice. Every word has a unique three-letter prefix and stands apart from the
+// others by at least three letters, which makes it suited for autocompletion
+// and typo correction, at 10.34 bits per word.
+func WordlistEFFShort() []string {
+	return append([]string(nil), effShortWords...)
+}
+
+// DefaultWordlistName names the wordlist passphrases are made of by default.
+const DefaultWordlistName = "eff-long"
+
+var wordlistPresets = []struct {
+	name  string
+	words func() []string
+}{
+	{"eff-long", WordlistEFFLong},
+	{"eff-short", WordlistEFFShort},
+	{"common-english", CommonEnglishWordlist},
+	{"de-diceware", WordlistGermanDiceware},
+	{"es-bip39", WordlistSpanishBIP39},
+	{"fr-bip39", WordlistFrenchBIP39},
+}
+
+// WordlistNames returns the names of the embedded wordlists.
+func WordlistNames() []string {
+	names := make([]string, len(wordlistPresets))
+	for i, w := range wordlistPresets {
+		names[i] = w.name
+	}
+
+	return names
+}
+
+// WordlistByName returns the embedded wordlist with the given name.
+func WordlistByName(name string) ([]string, error) {
+	for _, w := range wordlistPresets {
+		if w.name == name {
+			return w.words(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown wordlist %q (available: %v)", name, strings.Join(WordlistNames(), ", "))
+}
+
+// mustParseDicewareList parses an embedded diceware list of lines of dice
+// rolls and a word, separated by a tab. It only panics if the embedded data
+// is corrupt, which can't happen in a correct build.
+func mustParseDicewareList(name, data string, dice int) []string {
+	words, err := parseDicewareList(data, dice)
+	if err != nil {
+		panic(fmt.Sprintf("embedded wordlist %v is corrupt: %s", name, err))
+	}
+
+	return words
+}
+
+// parseDicewareList checks that every roll of the dice appears once, in
+// order, and that the words are distinct. The words are normalized to NFC
+// before they are compared, so that the same word in two encodings counts as
+// a duplicate.
+func parseDicewareList(data string, dice int) ([]string, error) {
+	want := 1
+	for i := 0; i < dice; i++ {
+		want *= 6
+	}
+
+	lines := strings.Split(strings.TrimSuffix(data, "\n"), "\n")
+	if len(lines) != want {
+		return nil, fmt.Errorf("expected %v words for %v dice, got %v", want, dice, len(lines))
+	}
+
+	rolls := []byte(strings.Repeat("1", dice))
+	words := make([]string, len(lines))
+	seen := make(map[string]struct{}, len(lines))
+	for i, line := range lines {
+		roll, word, ok := strings.Cut(line, "\t")
+		if !ok || roll != string(rolls) {
+			return nil, fmt.Errorf("line %v: expected the rolls %s and a word", i+1, rolls)
+		}
+
+		if word == "" {
+			return nil, fmt.Errorf("line %v: empty word", i+1)
+		}
+
+		word = norm.NFC.String(word)
+		if _, ok := seen[word]; ok {
+			return nil, fmt.Errorf("line %v: duplicate word %q", i+1, word)
+		}
+
+		seen[word] = struct{}{}
+		words[i] = word
+
+		// Advance to the next roll, like an odometer with faces 1 to 6.
+		for j := dice - 1; j >= 0; j-- {
+			if rolls[j] < '6' {
+				rolls[j]++
+				break
+			}
+
+			rolls[j] = '1'
+		}
+	}
+
+	return words, nil
+}
@@ -0,0 +1,108 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+// rollIndex returns the index of the word the dice rolls select.
+func rollIndex(rolls string) int {
+	var i int
+	for _, r := range rolls {
+		i = i*6 + int(r-'1')
+	}
+
+	return i
+}
+
+func TestEFFWordlists(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		count int
+		spot  map[string]string
+	}{
+		{"eff-long", 7776, map[string]string{
+			"11111": "abacus",
+			"23456": "dispatch",
+			"44444": "playlist",
+			"66666": "zoom",
+		}},
+		{"eff-short", 1296, map[string]string{
+			"1111": "aardvark",
+			"2345": "droppings",
+			"4444": "nebula",
+			"6666": "zucchini",
+		}},
+	} {
+		words, err := WordlistByName(tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(words) != tc.count {
+			t.Errorf("%v has %v words, want %v", tc.name, len(words), tc.count)
+		}
+
+		for rolls, want := range tc.spot {
+			if got := words[rollIndex(rolls)]; got != want {
+				t.Errorf("%v: %v is %q, want %q", tc.name, rolls, got, want)
+			}
+		}
+	}
+}
+
+func TestWordlistIsCopied(t *testing.T) {
+	words := WordlistEFFLong()
+	words[0] = "changed"
+
+	if WordlistEFFLong()[0] != "abacus" {
+		t.Error("changing the returned wordlist changed the embedded one")
+	}
+}
+
+func TestParseDicewareListErrors(t *testing.T) {
+	valid := "11\ta\n12\tb\n13\tc\n14\td\n15\te\n16\tf\n21\tg\n22\th\n23\ti\n24\tj\n25\tk\n26\tl\n" +
+		"31\tm\n32\tn\n33\to\n34\tp\n35\tq\n36\tr\n41\ts\n42\tt\n43\tu\n44\tv\n45\tw\n46\tx\n" +
+		"51\ty\n52\tz\n53\taa\n54\tab\n55\tac\n56\tad\n61\tae\n62\taf\n63\tag\n64\tah\n65\tai\n66\taj\n"
+
+	words, err := parseDicewareList(valid, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(words) != 36 || words[0] != "a" || words[35] != "aj" {
+		t.Errorf("got %v words from %q to %q", len(words), words[0], words[len(words)-1])
+	}
+
+	for _, tc := range []struct {
+		name, data, err string
+	}{
+		{"missing word", strings.Replace(valid, "66\taj\n", "", 1), "expected 36 words"},
+		{"out of order", strings.Replace(valid, "12\tb", "21\tb", 1), "line 2: expected the rolls 12"},
+		{"no tab", strings.Replace(valid, "13\tc", "13 c", 1), "line 3: expected the rolls 13"},
+		{"empty word", strings.Replace(valid, "14\td", "14\t", 1), "line 4: empty word"},
+		{"duplicate", strings.Replace(valid, "15\te", "15\ta", 1), `line 5: duplicate word "a"`},
+		{"duplicate after nfc", strings.Replace(strings.Replace(valid, "15\te", "15\tb\u00e4r", 1), "16\tf", "16\tba\u0308r", 1), "line 6: duplicate word \"b\u00e4r\""},
+	} {
+		_, err := parseDicewareList(tc.data, 2)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: got error %v, want one containing %q", tc.name, err, tc.err)
+		}
+	}
+}
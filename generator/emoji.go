@@ -0,0 +1,55 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import "strings"
+
+// emojiRanges are blocks of emoji that are a single code point each and are
+// shown as emoji without a variation selector: faces and gestures, plants
+// and food, animals, and transport. Skin tone modifiers, regional
+// indicators, and anything that needs a ZWJ sequence are left out, so no two
+// characters of a password can merge into one, and normalization leaves
+// them alone.
+var emojiRanges = [][2]rune{
+	{0x1F600, 0x1F64F},
+	{0x1F337, 0x1F37C},
+	{0x1F400, 0x1F43E},
+	{0x1F680, 0x1F6C5},
+}
+
+// EmojiCharset has 283 emoji, which is about 8.14 bits per character, for
+// password fields that accept them. The length of a password counts emoji,
+// each of which takes up 4 bytes in UTF-8. All of them are base characters,
+// so the counts of the other classes must be zero.
+var EmojiCharset = Charset{
+	Name:        "emoji",
+	Description: "Single code point emoji, as base characters",
+
+	Letters: emojiChars(),
+	Unicode: true,
+}
+
+func emojiChars() string {
+	var sb strings.Builder
+	for _, r := range emojiRanges {
+		for c := r[0]; c <= r[1]; c++ {
+			sb.WriteRune(c)
+		}
+	}
+
+	return sb.String()
+}
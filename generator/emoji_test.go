@@ -0,0 +1,111 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"math"
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestEmojiCharset(t *testing.T) {
+	chars := []rune(EmojiCharset.Letters)
+	if len(chars) != 283 {
+		t.Errorf("got %v emoji, want 283", len(chars))
+	}
+
+	seen := make(map[rune]struct{}, len(chars))
+	for _, c := range chars {
+		if _, ok := seen[c]; ok {
+			t.Errorf("%q appears twice", c)
+		}
+
+		seen[c] = struct{}{}
+
+		// Variation selectors, the ZWJ, skin tone modifiers and regional
+		// indicators would merge with their neighbors.
+		switch {
+		case c == 0x200d, c >= 0xfe00 && c <= 0xfe0f, c >= 0x1f3fb && c <= 0x1f3ff, c >= 0x1f1e6 && c <= 0x1f1ff:
+			t.Errorf("%U combines with other characters", c)
+		case utf8.RuneLen(c) != 4:
+			t.Errorf("%U takes up %v bytes", c, utf8.RuneLen(c))
+		case !norm.NFC.IsNormalString(string(c)) || !norm.NFKC.IsNormalString(string(c)):
+			t.Errorf("%U changes under normalization", c)
+		}
+	}
+
+	err := EmojiCharset.Validate()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGenerateEmoji(t *testing.T) {
+	for _, length := range []uint32{1, 8, 16} {
+		g, err := NewGenerator(length, 0, 0, 0, WithCharset(EmojiCharset), WithRandSource(testSource(t)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 500; i++ {
+			pw, err := g.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !utf8.Valid(pw) {
+				t.Fatalf("%q is not valid UTF-8", pw)
+			}
+
+			if n := utf8.RuneCount(pw); n != int(length) {
+				t.Fatalf("%q has %v code points, want %v", pw, n, length)
+			}
+
+			if len(pw) != 4*int(length) {
+				t.Fatalf("%q takes up %v bytes, want %v", pw, len(pw), 4*length)
+			}
+
+			err = g.Validate(pw)
+			if err != nil {
+				t.Fatalf("%q: %v", pw, err)
+			}
+		}
+
+		// The entropy counts emoji from a set of 283, not bytes.
+		bits := float64(length) * math.Log2(283)
+
+		entropy, err := g.EntropyMin()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if float64(entropy) < math.Floor(bits) || float64(entropy) > math.Ceil(bits) {
+			t.Errorf("length %v: got %v bits of entropy, want %.2f", length, entropy, bits)
+		}
+	}
+}
+
+func TestEmojiCharsetRejectsCounts(t *testing.T) {
+	for _, counts := range [][3]uint32{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}} {
+		_, err := NewGenerator(8, counts[0], counts[1], counts[2], WithCharset(EmojiCharset))
+		if err == nil {
+			t.Errorf("counts %v succeeded with the emoji charset", counts)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	empiricalBatches = 20
+	// empiricalZ is how many standard errors the confidence bounds of an
+	// empirical estimate span on either side, roughly 99.7% confidence.
+	empiricalZ = 3
+)
+
+// EntropyEstimate is an empirical estimate of the per-position entropy.
+type EntropyEstimate struct {
+	Samples int
+	// Bits is the estimated sum of the Shannon entropies of the character
+	// distributions at every position.
+	Bits float64
+	// Lower and Upper are the confidence bounds of Bits.
+	Lower float64
+	Upper float64
+}
+
+// PositionEntropy returns the sum of the Shannon entropies of the character
+// distributions at every position. Every position gets a character of each
+// class with probability proportional to the class count, counting the free
+// characters of CountsMinimum in proportion to the class sizes in the free
+// pool, and the character is uniform within its class. The repeat limit is
+// not accounted for.
+//
+// This is an upper bound of the password entropy, since the positions are
+// not independent, but it can be checked empirically, see EntropyEmpirical.
+func (g *Generator) PositionEntropy() float64 {
+	sets := g.countSets()
+	sizes := [4]int{len(g.chars.letters), len(g.chars.letters), len(g.chars.digits), len(g.chars.special)}
+
+	// With a class requirement, the counts depend on the randomly chosen
+	// classes, so the class probabilities are averaged over the choices.
+	var probs [4]float64
+	for _, set := range sets {
+		upper, digit, special := g.comboCounts(set)
+		lower := g.reservedLowercase(upper, digit, special)
+		free := float64(g.length - upper - digit - special - lower)
+		poolSize := float64(len(g.freePool(upper, digit, special)))
+
+		for i, count := range []uint32{lower, upper, digit, special} {
+			expected := float64(count)
+			if i == 0 || count != 0 {
+				expected += free * float64(sizes[i]) / poolSize
+			}
+
+			probs[i] += expected / float64(g.length) / float64(len(sets))
+		}
+	}
+
+	var h float64
+	for i, p := range probs {
+		if p == 0 {
+			continue
+		}
+
+		h += p * (math.Log2(float64(sizes[i])) - math.Log2(p))
+	}
+
+	return h * float64(g.length)
+}
+
+// EntropyEmpirical generates samples passwords and estimates the per-position
+// entropy from the observed character frequencies. A correct generator
+// without a repeat limit should have PositionEntropy within the confidence
+// bounds, while biased character draws pull the estimate below it.
+//
+// The samples are split into batches, each estimated with the Miller-Madow
+// bias correction, and the spread of the batch estimates gives the bounds.
+func (g *Generator) EntropyEmpirical(samples int) (EntropyEstimate, error) {
+	if samples < empiricalBatches*100 {
+		return EntropyEstimate{}, fmt.Errorf("at least %v samples are needed", empiricalBatches*100)
+	}
+
+	alphabet := g.alphabet()
+
+	indexOf := make(map[rune]int, len(alphabet))
+	for i, c := range alphabet {
+		indexOf[c] = i
+	}
+
+	perBatch := samples / empiricalBatches
+	counts := make([][]int, g.length)
+	for i := range counts {
+		counts[i] = make([]int, len(alphabet))
+	}
+
+	estimates := make([]float64, empiricalBatches)
+	for batch := range estimates {
+		for i := range counts {
+			clear(counts[i])
+		}
+
+		for i := 0; i < perBatch; i++ {
+			pw, err := g.Generate()
+			if err != nil {
+				return EntropyEstimate{}, errors.Wrap(err, "generate sample")
+			}
+
+			pos := 0
+			for _, c := range string(pw) {
+				counts[pos][indexOf[c]]++
+				pos++
+			}
+
+			wipe(pw)
+		}
+
+		for pos := range counts {
+			estimates[batch] += millerMadowEntropy(counts[pos], perBatch)
+		}
+	}
+
+	var mean, variance float64
+	for _, e := range estimates {
+		mean += e
+	}
+
+	mean /= empiricalBatches
+
+	for _, e := range estimates {
+		variance += (e - mean) * (e - mean)
+	}
+
+	variance /= empiricalBatches - 1
+	margin := empiricalZ * math.Sqrt(variance/empiricalBatches)
+
+	return EntropyEstimate{
+		Samples: perBatch * empiricalBatches,
+		Bits:    mean,
+		Lower:   mean - margin,
+		Upper:   mean + margin,
+	}, nil
+}
+
+// millerMadowEntropy estimates the Shannon entropy in bits from n observations
+// with the given counts, correcting the downward bias of the plug-in estimate.
+func millerMadowEntropy(counts []int, n int) float64 {
+	var h float64
+	var observed int
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+
+		p := float64(c) / float64(n)
+		h -= p * math.Log2(p)
+		observed++
+	}
+
+	return h + float64(observed-1)/(2*float64(n)*math.Ln2)
+}
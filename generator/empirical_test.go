@@ -0,0 +1,102 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"io"
+	"testing"
+)
+
+// evenReader clears the lowest bit of every byte of r, so that every draw
+// modulo an even bound is even.
+type evenReader struct {
+	r io.Reader
+}
+
+func (e evenReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	for i := range p[:n] {
+		p[i] &^= 1
+	}
+
+	return n, err
+}
+
+// The characters of the classes that only get one character from the class
+// requirement are rare, so it takes enough samples for the bias correction
+// to hold.
+const empiricalTestSamples = 60000
+
+func TestEntropyEmpirical(t *testing.T) {
+	for _, tc := range []struct {
+		name                  string
+		upper, digit, special uint32
+		opts                  []Option
+	}{
+		{"exact", 2, 2, 2, nil},
+		{"minimum", 2, 2, 2, []Option{WithCountMode(CountsMinimum)}},
+		{"min classes", 0, 0, 0, []Option{WithMinClasses(3)}},
+		{"lowercase only", 0, 0, 0, nil},
+	} {
+		g, err := NewGenerator(12, tc.upper, tc.digit, tc.special, append(tc.opts, WithRandSource(testSource(t)))...)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+
+		est, err := g.EntropyEmpirical(empiricalTestSamples)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+
+		want := g.PositionEntropy()
+		if want < est.Lower || want > est.Upper {
+			t.Errorf("%v: position entropy %.3f outside the estimate %.3f [%.3f, %.3f]", tc.name, want, est.Bits, est.Lower, est.Upper)
+		}
+
+		if est.Samples != empiricalTestSamples {
+			t.Errorf("%v: got %v samples, want %v", tc.name, est.Samples, empiricalTestSamples)
+		}
+	}
+}
+
+func TestEntropyEmpiricalBiased(t *testing.T) {
+	g, err := NewGenerator(12, 2, 2, 2, WithRandSource(evenReader{testSource(t)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	est, err := g.EntropyEmpirical(empiricalTestSamples)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := g.PositionEntropy(); est.Upper >= want {
+		t.Errorf("a biased source gave the estimate %.3f [%.3f, %.3f], not below the position entropy %.3f", est.Bits, est.Lower, est.Upper, want)
+	}
+}
+
+func TestEntropyEmpiricalTooFewSamples(t *testing.T) {
+	g, err := NewGenerator(12, 2, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = g.EntropyEmpirical(empiricalBatches*100 - 1)
+	if err == nil {
+		t.Error("too few samples succeeded")
+	}
+}
@@ -17,12 +17,12 @@
 package generator
 
 import (
-	"crypto/rand"
-	"crypto/sha512"
 	"fmt"
+	"io"
 	"math/big"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
 )
@@ -31,48 +31,348 @@ var letterCharset = "abcdefghijkmnpqrstuvwxyz"
 var digitCharset = "0123456789"
 var specialCharset = "~!@#$%^&*_+[]/?<>."
 
+const maxLength = 128
+
+// Generator generates passwords of a fixed policy. It is not modified after
+// construction, so a single Generator may be used from several goroutines,
+// as long as its Tracer allows that.
 type Generator struct {
-	length uint32
+	length  uint32
+	charset Charset
+	chars   classChars
+	rnd     randSource
 
 	uppercaseCount uint32
 	digitCount     uint32
 	specialCount   uint32
+	minLowercase   uint32
+	countMode      CountMode
+
+	maxRepeats uint32
+
+	minClasses  uint32
+	classCombos []classSet
+
+	maxBytes uint32
+
+	wpa bool
+
+	fullAlphabet bool
+	unambiguous  bool
+	excluded     string
+	space        bool
+
+	letterChars  *string
+	digitChars   *string
+	specialChars *string
+
+	// optErrs collects the errors of options that could not be applied, for
+	// New to report them together with the other problems.
+	optErrs []string
+}
+
+type Option func(*Generator)
+
+// WithUppercase sets the number of uppercase characters, 0 by default.
+func WithUppercase(n uint32) Option {
+	return func(g *Generator) {
+		g.uppercaseCount = n
+	}
+}
+
+// WithMinLowercase makes sure the password has at least n lowercase letters,
+// e.g. for policies that reject passwords without any. With CountsExact, the
+// rest of the password is lowercase letters anyway, and with CountsMinimum, n
+// positions are reserved for them. New rejects counts that leave fewer than n
+// of them, including the characters WithMinClasses may add.
+func WithMinLowercase(n uint32) Option {
+	return func(g *Generator) {
+		g.minLowercase = n
+	}
+}
+
+// WithDigits sets the number of digit characters, 0 by default.
+func WithDigits(n uint32) Option {
+	return func(g *Generator) {
+		g.digitCount = n
+	}
+}
+
+// WithSpecial sets the number of special characters, 0 by default.
+func WithSpecial(n uint32) Option {
+	return func(g *Generator) {
+		g.specialCount = n
+	}
+}
+
+// WithCharsets makes the generator draw from the intersection of charsets,
+// see IntersectCharsets.
+func WithCharsets(charsets ...Charset) Option {
+	return func(g *Generator) {
+		c, err := IntersectCharsets(charsets...)
+		if err != nil {
+			g.optErrs = append(g.optErrs, err.Error())
+			return
+		}
+
+		g.charset = c
+	}
+}
+
+// WithCharset makes the generator draw from c instead of DefaultCharset, e.g.
+// a custom charset for a site that only allows some symbols. NewGenerator
+// rejects charsets that don't pass Charset.Validate.
+func WithCharset(c Charset) Option {
+	return func(g *Generator) {
+		g.charset = c
+	}
 }
 
-func NewGenerator(length, uppercaseCount, digitCount, specialCount uint32) (*Generator, error) {
+// WithLetterCharset replaces the letters of the charset with chars, and
+// leaves the other classes as they are, whichever order the options are
+// given in. With uppercase allowed, chars must be lowercase letters, and
+// the uppercase class is made of their uppercase variants.
+func WithLetterCharset(chars string) Option {
+	return func(g *Generator) {
+		g.letterChars = &chars
+	}
+}
+
+// WithDigitCharset replaces the digits of the charset with chars. See
+// WithLetterCharset. An empty chars leaves the class unavailable.
+func WithDigitCharset(chars string) Option {
+	return func(g *Generator) {
+		g.digitChars = &chars
+	}
+}
+
+// WithSpecialCharset replaces the special characters of the charset with
+// chars, e.g. with the few a bank allows. See WithLetterCharset. An empty
+// chars leaves the class unavailable.
+func WithSpecialCharset(chars string) Option {
+	return func(g *Generator) {
+		g.specialChars = &chars
+	}
+}
+
+// WithRandSource makes the generator read its random bytes from r instead of
+// crypto/rand. This is meant for tests and reproducing a generation from a
+// known byte stream. Passwords are only as unpredictable as r, so anything
+// but a cryptographically secure source makes them weak. The generator is
+// safe for concurrent use only if r is.
+func WithRandSource(r io.Reader) Option {
+	return func(g *Generator) {
+		g.rnd.reader = r
+	}
+}
+
+// WithTracer makes the generator report every consumption of randomness to
+// t. The events reveal how each character was chosen, so they are as
+// sensitive as the generated password itself.
+func WithTracer(t Tracer) Option {
+	return func(g *Generator) {
+		g.rnd.tracer = t
+	}
+}
+
+// WithMaxCharRepeats limits how many times any single character may appear
+// in the generated password. Zero means no limit.
+func WithMaxCharRepeats(n uint32) Option {
+	return func(g *Generator) {
+		g.maxRepeats = n
+	}
+}
+
+// WithMaxBytes limits the UTF-8 encoded length of the generated password, for
+// backends that limit passwords by bytes rather than characters. The limit is
+// checked against the widest character the password may contain, so every
+// password of the configured length fits. Zero means no limit.
+func WithMaxBytes(n uint32) Option {
+	return func(g *Generator) {
+		g.maxBytes = n
+	}
+}
+
+// WithFullAlphabet adds back the letters l and o, which the charset presets
+// leave out as they are easily mistaken for 1 and 0. This is about 0.12 more
+// bits per letter. Charsets that leave out other letters as well are
+// rejected.
+func WithFullAlphabet() Option {
+	return func(g *Generator) {
+		g.fullAlphabet = true
+	}
+}
+
+// WithExcludedChars leaves out the given characters from whichever class
+// they belong to, e.g. for backends that reject some of them. Like with
+// WithUnambiguous, a letter goes in both cases if either is given.
+// Characters that are in no class are ignored, and the counts of classes
+// that become empty are rejected.
+func WithExcludedChars(chars string) Option {
+	return func(g *Generator) {
+		g.excluded += chars
+	}
+}
+
+// NewGenerator is New with the character counts as positional arguments.
+func NewGenerator(length, uppercaseCount, digitCount, specialCount uint32, opts ...Option) (*Generator, error) {
+	return New(length, append([]Option{WithUppercase(uppercaseCount), WithDigits(digitCount), WithSpecial(specialCount)}, opts...)...)
+}
+
+// New returns a generator of passwords with the given length. Without
+// options, they are made of lowercase letters from DefaultCharset only,
+// which leaves out l and o unless WithFullAlphabet is given. The
+// options are checked together, and all the problems found are reported in a
+// single error.
+func New(length uint32, opts ...Option) (*Generator, error) {
 	g := &Generator{
-		length: length,
+		length:  length,
+		charset: DefaultCharset,
+	}
 
-		uppercaseCount: uppercaseCount,
-		digitCount:     digitCount,
-		specialCount:   specialCount,
+	for _, opt := range opts {
+		opt(g)
 	}
 
-	if g.length > 128 {
-		return nil, fmt.Errorf("exceeded the maximum length of 128")
+	problems := g.optErrs
+	g.optErrs = nil
+
+	if g.length == 0 {
+		problems = append(problems, "length must be at least 1")
+	}
+
+	if g.length > maxLength {
+		problems = append(problems, fmt.Sprintf("exceeded the maximum length of %v", maxLength))
+	}
+
+	g.applyClassOverrides()
+
+	if g.fullAlphabet {
+		if err := g.restoreFullAlphabet(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if g.unambiguous {
+		if g.fullAlphabet {
+			problems = append(problems, "the full alphabet cannot be combined with unambiguous characters, which leave out l and o")
+		}
+
+		g.charset = g.charset.withoutChars(strings.Join(ConfusableChars, ""))
+		g.charset.Name += "+unambiguous"
+	}
+
+	if g.excluded != "" {
+		g.charset = g.charset.withoutChars(g.excluded)
+		g.charset.Name += "+excluded"
+	}
+
+	if g.wpa {
+		if err := g.checkWPA(); err != nil {
+			problems = append(problems, err.Error())
+		}
 	}
 
 	if g.uppercaseCount+g.digitCount+g.specialCount > g.length {
-		return nil, fmt.Errorf("uppercase count (%v) + digit count (%v) + special count (%v) > length (%v)", g.uppercaseCount, g.digitCount, g.specialCount, g.length)
+		problems = append(problems, fmt.Sprintf("uppercase count (%v) + digit count (%v) + special count (%v) > length (%v)", g.uppercaseCount, g.digitCount, g.specialCount, g.length))
+	} else if uint64(g.uppercaseCount)+uint64(g.digitCount)+uint64(g.specialCount)+uint64(g.minLowercase) > uint64(g.length) {
+		problems = append(problems, fmt.Sprintf("uppercase count (%v) + digit count (%v) + special count (%v) + minimum lowercase count (%v) > length (%v)", g.uppercaseCount, g.digitCount, g.specialCount, g.minLowercase, g.length))
+	}
+
+	if g.countMode != CountsExact && g.countMode != CountsMinimum {
+		problems = append(problems, fmt.Sprintf("unknown count mode %v", g.countMode))
+	}
+
+	if err := g.charset.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		if g.space {
+			if err := g.addSpace(); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+
+		if g.uppercaseCount != 0 && !g.charset.Uppercase {
+			problems = append(problems, fmt.Sprintf("charset %q does not allow uppercase characters, but uppercase count is %v", g.charset.Name, g.uppercaseCount))
+		}
+
+		if g.digitCount != 0 && g.charset.Digits == "" {
+			problems = append(problems, fmt.Sprintf("charset %q has no digits, but digit count is %v", g.charset.Name, g.digitCount))
+		}
+
+		if g.specialCount != 0 && g.charset.Special == "" {
+			problems = append(problems, fmt.Sprintf("charset %q has no special characters, but special count is %v", g.charset.Name, g.specialCount))
+		}
+	}
+
+	if len(problems) != 0 {
+		return nil, fmt.Errorf("%v", strings.Join(problems, "; "))
+	}
+
+	g.chars = newClassChars(g.charset)
+
+	// These only make sense to check for otherwise valid settings.
+	err := g.initClassCombos()
+	if err != nil {
+		return nil, err
+	}
+
+	err = g.checkMaxRepeatsFeasible()
+	if err != nil {
+		return nil, err
+	}
+
+	if g.maxBytes != 0 && g.MaxByteLength() > uint64(g.maxBytes) {
+		return nil, fmt.Errorf("a password of %v characters from charset %q may take up to %v bytes, but at most %v bytes are allowed", g.length, g.charset.Name, g.MaxByteLength(), g.maxBytes)
 	}
 
 	return g, nil
 }
 
+// ambiguousLetters are the letters letterCharset leaves out.
+var ambiguousLetters = "lo"
+
+func (g *Generator) restoreFullAlphabet() error {
+	for i := 0; i < len(letterCharset); i++ {
+		if strings.IndexByte(g.charset.Letters, letterCharset[i]) == -1 {
+			return fmt.Errorf("charset %q leaves out other letters than %v, so the full alphabet can't be restored", g.charset.Name, strings.Join(strings.Split(ambiguousLetters, ""), " and "))
+		}
+	}
+
+	for i := 0; i < len(ambiguousLetters); i++ {
+		if strings.IndexByte(g.charset.Letters, ambiguousLetters[i]) == -1 {
+			g.charset.Letters += ambiguousLetters[i : i+1]
+		}
+	}
+
+	return nil
+}
+
+// MaxByteLength returns the longest UTF-8 encoded length a password from g
+// may have.
+func (g *Generator) MaxByteLength() uint64 {
+	width := 1
+	for _, r := range g.alphabet() {
+		width = max(width, utf8.RuneLen(r))
+	}
+
+	return uint64(g.length) * uint64(width)
+}
+
 func (g *Generator) EntropyMax() uint64 {
 	// Start with one because it is possible for a character to be empty.
-	possibleChars := 1 + uint64(len(letterCharset))
-	if g.uppercaseCount != 0 {
+	possibleChars := 1 + uint64(len(g.chars.letters))
+	if g.usesClass(classUpper) {
 		// Uppercase doubles the letter charset variety.
-		possibleChars += uint64(len(letterCharset))
+		possibleChars += uint64(len(g.chars.letters))
 	}
 
-	if g.digitCount != 0 {
-		possibleChars += uint64(len(digitCharset))
+	if g.usesClass(classDigit) {
+		possibleChars += uint64(len(g.chars.digits))
 	}
 
-	if g.specialCount != 0 {
-		possibleChars += uint64(len(specialCharset))
+	if g.usesClass(classSpecial) {
+		possibleChars += uint64(len(g.chars.special))
 	}
 
 	possibleCombinations := big.NewInt(0).Exp(big.NewInt(0).SetUint64(possibleChars), big.NewInt(0).SetUint64(uint64(g.length)), big.NewInt(0))
@@ -80,164 +380,388 @@ func (g *Generator) EntropyMax() uint64 {
 	// Subtract one to remove the assumption of an empty password.
 	possibleCombinations.Sub(possibleCombinations, big.NewInt(1))
 
-	return uint64(possibleCombinations.BitLen())
+	return subtractBits(uint64(possibleCombinations.BitLen()), g.MaxRepeatsPenalty())
 }
 
-func (g *Generator) EntropyMin() (uint64, error) {
-	possibleCombinations := big.NewInt(1)
+// combinations returns the number of passwords g may generate, not taking
+// the repeat limit into account.
+func (g *Generator) combinations() *big.Int {
+	if len(g.classCombos) == 0 {
+		return g.possibleCombinations(g.uppercaseCount, g.digitCount, g.specialCount)
+	}
 
+	// The additional classes are chosen at random, so every combination adds
+	// its own passwords. With CountsMinimum, the passwords of different
+	// combinations overlap, so only the largest one is counted.
+	ret := big.NewInt(0)
+	for _, set := range g.classCombos {
+		n := g.possibleCombinations(g.comboCounts(set))
+		if g.countMode != CountsMinimum {
+			ret.Add(ret, n)
+		} else if n.Cmp(ret) > 0 {
+			ret = n
+		}
+	}
+
+	return ret
+}
+
+func (g *Generator) EntropyMin() (uint64, error) {
 	nonBaseCount := g.uppercaseCount + g.digitCount + g.specialCount
 	if nonBaseCount > g.length {
 		return 0, fmt.Errorf("non-base letter character count exceeds the total length")
 	}
 
-	addPossibleCombinationsFn := func(charset string, count uint64) {
-		// Start with one because it is possible for a character to be empty.
-		charsetLength := 1 + uint64(len(charset))
-		possibleCombinations = possibleCombinations.Mul(possibleCombinations,
-			big.NewInt(0).Exp(big.NewInt(0).SetUint64(charsetLength), big.NewInt(0).SetUint64(count), big.NewInt(0)),
-		)
+	possibleCombinations := g.combinations()
+
+	// Subtract one to remove the assumption of an empty password.
+	possibleCombinations.Sub(possibleCombinations, big.NewInt(1))
+
+	return subtractBits(uint64(possibleCombinations.BitLen()), g.MaxRepeatsPenalty()), nil
+}
+
+// LengthForEntropy returns the shortest password length for which a generator
+// with the given parameters has at least the specified minimum entropy.
+func LengthForEntropy(bits uint64, uppercaseCount, digitCount, specialCount uint32, opts ...Option) (uint32, error) {
+	// Shorter lengths can't fit the minimum lowercase count.
+	var probe Generator
+	for _, opt := range opts {
+		opt(&probe)
 	}
 
-	baseChars := g.length - nonBaseCount
+	for length := uppercaseCount + digitCount + specialCount + probe.minLowercase; length <= maxLength; length++ {
+		if length == 0 {
+			continue
+		}
 
-	addPossibleCombinationsFn(letterCharset, uint64(baseChars))
-	addPossibleCombinationsFn(letterCharset, uint64(g.uppercaseCount))
-	addPossibleCombinationsFn(digitCharset, uint64(g.digitCount))
-	addPossibleCombinationsFn(specialCharset, uint64(g.specialCount))
+		g, err := NewGenerator(length, uppercaseCount, digitCount, specialCount, opts...)
+		if err != nil {
+			return 0, errors.Wrapf(err, "create generator with length %v", length)
+		}
 
-	// Subtract one to remove the assumption of an empty password.
-	possibleCombinations.Sub(possibleCombinations, big.NewInt(1))
+		entropyMin, err := g.EntropyMin()
+		if err != nil {
+			return 0, errors.Wrapf(err, "get min entropy at length %v", length)
+		}
 
-	return uint64(possibleCombinations.BitLen()), nil
+		if entropyMin >= bits {
+			return length, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%v bits of minimum entropy cannot be reached within the maximum length of %v", bits, maxLength)
 }
 
+// Generate returns a new password. See GenerateInto for generating into a
+// buffer the caller controls.
 func (g *Generator) Generate() ([]byte, error) {
-	b, err := g.generateBase()
+	pw := make([]rune, g.length)
+	defer wipeRunes(pw)
+
+	err := g.generateRunes(pw)
 	if err != nil {
-		return nil, errors.Wrap(err, "generate letter base")
+		return nil, err
 	}
 
-	err = g.applyUppercase(b)
-	if err != nil {
-		return nil, errors.Wrap(err, "apply uppercase")
+	b := make([]byte, 0, encodedLen(pw))
+	for _, c := range pw {
+		b = utf8.AppendRune(b, c)
 	}
 
-	err = g.applyDigits(b)
+	return b, nil
+}
+
+// GenerateInto generates a password into dst, which must be exactly as long
+// as the password. The characters are drawn into a buffer that is wiped
+// right after copying them to dst, so no other copy of the password is left
+// in memory. dst is wiped on error, which wraps ErrEntropyUnavailable if the
+// source of randomness failed. As the byte length of passwords with
+// multi-byte characters varies, their charsets can only be used with
+// Generate.
+func (g *Generator) GenerateInto(dst []byte) error {
+	if len(dst) != int(g.length) {
+		return fmt.Errorf("buffer has %v bytes, but the password has %v", len(dst), g.length)
+	}
+
+	if g.chars.multiByte {
+		return fmt.Errorf("charset %q has multi-byte characters, so passwords can't be generated into a buffer of fixed length", g.charset.Name)
+	}
+
+	pw := make([]rune, g.length)
+	defer wipeRunes(pw)
+
+	err := g.generateRunes(pw)
 	if err != nil {
-		return nil, errors.Wrap(err, "apply digits")
+		wipe(dst)
+		return err
+	}
+
+	for i, c := range pw {
+		dst[i] = byte(c)
 	}
 
-	err = g.applySpecial(b)
+	return nil
+}
+
+// WriteTo generates a password and writes it to w, e.g. a file or a pipe,
+// without returning it. It is wiped from memory before WriteTo returns. A
+// short write is reported as an error.
+func (g *Generator) WriteTo(w io.Writer) (int64, error) {
+	b, err := g.Generate()
 	if err != nil {
-		return nil, errors.Wrap(err, "apply special")
+		return 0, err
 	}
+	defer wipe(b)
 
-	return b, nil
+	n, err := w.Write(b)
+	if err == nil && n != len(b) {
+		err = io.ErrShortWrite
+	}
+
+	return int64(n), errors.Wrap(err, "write password")
 }
 
-func (g *Generator) generateBase() ([]byte, error) {
-	ret := make([]byte, g.length)
+// generateRunes generates a password into pw, one character per element.
+// The characters are drawn by index into the runes of their class, so the
+// same random bytes give the same password whether the charset has
+// multi-byte characters or not.
+func (g *Generator) generateRunes(pw []rune) error {
+	err := g.generateBase(pw)
+	if err != nil {
+		return errors.Wrap(err, "generate letter base")
+	}
+
+	upper, digit, special, err := g.drawCounts()
+	if err != nil {
+		return errors.Wrap(err, "draw class combination")
+	}
+
+	// The reserved lowercase letters only need positions of their own when
+	// the rest is drawn from the free pool.
+	counted := upper + digit + special
+	reserved := uint32(0)
+	if g.countMode == CountsMinimum {
+		reserved = g.reservedLowercase(upper, digit, special)
+	}
+
+	positions, err := g.choosePositions(counted + reserved)
+	if err != nil {
+		return errors.Wrap(err, "choose positions")
+	}
+
+	// The positions tell where the classes are, so they are as secret as
+	// the password.
+	defer wipePositions(positions[:cap(positions)])
+
+	err = g.applyUppercase(pw, positions[:upper])
+	if err != nil {
+		return errors.Wrap(err, "apply uppercase")
+	}
+
+	err = g.applyDigits(pw, positions[upper:upper+digit])
+	if err != nil {
+		return errors.Wrap(err, "apply digits")
+	}
+
+	err = g.applySpecial(pw, positions[upper+digit:counted])
+	if err != nil {
+		return errors.Wrap(err, "apply special")
+	}
+
+	// The free positions are left out of the repeat limit until they are
+	// drawn, so that the limit is enforced on the rest first.
+	free := positions[counted+reserved : g.length]
+	if g.countMode == CountsMinimum {
+		for _, pos := range free {
+			pw[pos] = 0
+		}
+	}
+
+	err = g.enforceMaxRepeats(pw)
+	if err != nil {
+		return errors.Wrap(err, "enforce max repeats")
+	}
 
-	for i := uint32(0); i < g.length; i++ {
-		b, err := secureRandomChar(letterCharset)
+	if g.countMode == CountsMinimum {
+		err = g.applyFree(pw, free, g.freePool(upper, digit, special))
 		if err != nil {
-			return nil, errors.Wrapf(err, "generate secure random letter char #%v", i)
+			return errors.Wrap(err, "apply free characters")
 		}
+	}
 
-		ret[i] = b
+	if n := encodedLen(pw); g.maxBytes != 0 && uint32(n) > g.maxBytes {
+		return fmt.Errorf("bug: generated password takes up %v bytes, more than the allowed %v", n, g.maxBytes)
 	}
 
-	return ret, nil
+	return nil
 }
 
-func (g *Generator) seekNonBaseLetterAndApply(ptr []byte, count uint32, applyFn func(byte) (byte, error)) error {
-	for i := uint32(0); i < count; i++ {
-		// Limiting the search to 10k chars. This is mostly a band-aid, but
-		// without it, there is a risk of deadlock.
-		var ok bool
+func (g *Generator) generateBase(dst []rune) error {
+	for i := range dst {
+		c, err := g.rnd.char("base char", uint32(i), g.chars.letters)
+		if err != nil {
+			return errors.Wrapf(err, "generate secure random letter char #%v", i)
+		}
 
-		for ii := 0; ii < 100000 && !ok; ii++ {
-			pos, err := rand.Int(rand.Reader, big.NewInt(0).SetUint64(uint64(g.length)))
-			if err != nil {
-				return errors.Wrapf(err, "generate random pos for uppercase char #%v", i)
-			}
+		dst[i] = c
+	}
 
-			char := ptr[pos.Uint64()]
+	return nil
+}
 
-			if !strings.Contains(letterCharset, string(char)) {
-				continue
-			}
+func (g *Generator) choosePositions(n uint32) ([]uint32, error) {
+	positions := make([]uint32, g.length)
+	for i := range positions {
+		positions[i] = uint32(i)
+	}
+
+	for i := uint32(0); i < n; i++ {
+		j, err := g.rnd.intn("class position", i, g.length-i)
+		if err != nil {
+			wipePositions(positions)
+			return nil, errors.Wrapf(err, "generate random pos #%v", i)
+		}
+
+		positions[i], positions[i+j] = positions[i+j], positions[i]
+	}
 
-			newChar, err := applyFn(char)
+	return positions[:n], nil
+}
+
+func wipePositions(positions []uint32) {
+	for i := range positions {
+		positions[i] = 0
+	}
+}
+
+func (g *Generator) applyUppercase(pw []rune, positions []uint32) error {
+	for i, pos := range positions {
+		c := unicode.ToUpper(pw[pos])
+		if g.repeatLimitReached(pw, c) {
+			// Uppercasing this letter would exceed the repeat limit, so draw
+			// a different uppercase letter for the position instead.
+			pw[pos] = 0
+
+			var err error
+			c, err = g.drawChar(pw, "uppercase char", uint32(i), g.chars.upper)
 			if err != nil {
-				return errors.Wrap(err, "call apply func")
+				return errors.Wrap(err, "generate secure random uppercase char")
 			}
-
-			ptr[pos.Uint64()] = newChar
-			ok = true
 		}
 
-		if !ok {
-			return fmt.Errorf("bug: anti-deadlock code reached: exceeded the maximum amount of attempts looking for a free character")
-		}
+		pw[pos] = c
 	}
 
 	return nil
 }
 
-func (g *Generator) applyUppercase(ptr []byte) error {
-	return g.seekNonBaseLetterAndApply(ptr, g.uppercaseCount, func(b byte) (byte, error) {
-		return byte(unicode.ToUpper(rune(b))), nil
-	})
+func (g *Generator) applyDigits(pw []rune, positions []uint32) error {
+	for i, pos := range positions {
+		c, err := g.drawChar(pw, "digit char", uint32(i), g.chars.digits)
+		if err != nil {
+			return errors.Wrap(err, "generate secure random digit char")
+		}
+
+		pw[pos] = c
+	}
+
+	return nil
 }
 
-func (g *Generator) applyDigits(ptr []byte) error {
-	return g.seekNonBaseLetterAndApply(ptr, g.digitCount, func(b byte) (byte, error) {
-		c, err := secureRandomChar(digitCharset)
+func (g *Generator) applySpecial(pw []rune, positions []uint32) error {
+	for i, pos := range positions {
+		c, err := g.drawChar(pw, "special char", uint32(i), g.chars.special)
 		if err != nil {
-			return 0, errors.Wrap(err, "generate secure random digit char")
+			return errors.Wrap(err, "generate secure random special char")
 		}
 
-		return c, nil
-	})
+		pw[pos] = c
+	}
+
+	return nil
 }
 
-func (g *Generator) applySpecial(ptr []byte) error {
-	return g.seekNonBaseLetterAndApply(ptr, g.specialCount, func(b byte) (byte, error) {
-		c, err := secureRandomChar(specialCharset)
+func (g *Generator) applyFree(pw []rune, positions []uint32, pool []rune) error {
+	for i, pos := range positions {
+		c, err := g.drawChar(pw, "free char", uint32(i), pool)
 		if err != nil {
-			return 0, errors.Wrap(err, "generate secure random special char")
+			return errors.Wrap(err, "generate secure random free char")
 		}
 
-		return c, nil
-	})
+		pw[pos] = c
+	}
+
+	return nil
 }
 
-func secureRandomChar(charset string) (byte, error) {
-	b, err := secureRandomByte()
-	if err != nil {
-		return 0, errors.Wrap(err, "get secure random byte")
+// applyClassOverrides replaces the classes given with WithLetterCharset,
+// WithDigitCharset and WithSpecialCharset. Validate rejects characters that
+// end up in two classes, which would make the class of a character
+// ambiguous.
+func (g *Generator) applyClassOverrides() {
+	if g.letterChars == nil && g.digitChars == nil && g.specialChars == nil {
+		return
+	}
+
+	if g.letterChars != nil {
+		g.charset.Letters = *g.letterChars
 	}
 
-	return charset[b%byte(len(charset))], nil
+	if g.digitChars != nil {
+		g.charset.Digits = *g.digitChars
+	}
+
+	if g.specialChars != nil {
+		g.charset.Special = *g.specialChars
+	}
+
+	g.charset.Name += "+custom"
 }
 
-func secureRandomByte() (byte, error) {
-	bufLen, err := rand.Int(rand.Reader, big.NewInt(1024))
-	if err != nil {
-		return 0, errors.Wrap(err, "random-read buffer length")
+// classChars holds the characters of every class of a charset as runes, as
+// the generation draws them by index.
+type classChars struct {
+	letters []rune
+	upper   []rune
+	digits  []rune
+	special []rune
+
+	// multiByte reports whether any of them takes up more than one byte.
+	multiByte bool
+}
+
+func newClassChars(c Charset) classChars {
+	ret := classChars{
+		letters: []rune(c.Letters),
+		digits:  []rune(c.Digits),
+		special: []rune(c.Special),
 	}
 
-	b := make([]byte, bufLen.Uint64())
+	if c.Uppercase {
+		ret.upper = []rune(strings.ToUpper(c.Letters))
+	}
 
-	_, err = rand.Read(b)
-	if err != nil {
-		return 0, errors.Wrap(err, "random-read")
+	for _, class := range [][]rune{ret.letters, ret.upper, ret.digits, ret.special} {
+		for _, r := range class {
+			ret.multiByte = ret.multiByte || r >= utf8.RuneSelf
+		}
 	}
 
-	h := sha512.Sum512(b)
+	return ret
+}
+
+// encodedLen returns the UTF-8 encoded length of pw.
+func encodedLen(pw []rune) int {
+	n := 0
+	for _, c := range pw {
+		n += utf8.RuneLen(c)
+	}
 
-	pos := h[5] % byte(len(h))
-	return h[pos], nil
+	return n
+}
+
+func wipeRunes(pw []rune) {
+	for i := range pw {
+		pw[i] = 0
+	}
 }
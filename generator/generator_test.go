@@ -0,0 +1,414 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+
+	return n, err
+}
+
+// The passwords are pinned, so that a change to how the randomness is drawn
+// can't go unnoticed: the same seed and parameters must keep giving the same
+// passwords.
+func TestGenerateFixedStream(t *testing.T) {
+	for _, tc := range []struct {
+		length, upper, digit, special uint32
+		opts                          []Option
+		want                          []string
+	}{
+		{16, 0, 0, 0, nil, []string{"enfebywsmbnxxhxz", "ahdameiunnnppued"}},
+		{20, 2, 3, 4, nil, []string{"@n9.byws#b41xh<ZahDa", "Y%.sc24xc~+1dxjhwSfi"}},
+		{32, 4, 4, 4, []Option{WithFullAlphabet()}, []string{"Sa]a~gSl@Tct4rh3hpuxci1A1f%usttb", "/Zqf+e+4ab8v1clBZZgowk8utww*mggw"}},
+		{12, 0, 0, 0, []Option{WithCharset(EmojiCharset)}, []string{"🐽🐡😷😻🐚🍍🙇🐝🙍🍜😿😁", "🚒🐬🍹🚲🐖🌻🐵🐅😖😲😚🐾"}},
+	} {
+		src, err := NewDeterministicSource([]byte("cpass"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g, err := NewGenerator(tc.length, tc.upper, tc.digit, tc.special, append(tc.opts, WithRandSource(src))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, want := range tc.want {
+			pw, err := g.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(pw) != want {
+				t.Errorf("got %q, want %q", pw, want)
+			}
+		}
+	}
+}
+
+func TestGenerateIntoMatchesGenerate(t *testing.T) {
+	var passwords [2][]byte
+	for i := range passwords {
+		src, err := NewDeterministicSource([]byte("cpass"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g, err := NewGenerator(20, 2, 3, 4, WithRandSource(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if i == 0 {
+			passwords[i], err = g.Generate()
+		} else {
+			passwords[i] = make([]byte, 20)
+			err = g.GenerateInto(passwords[i])
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !bytes.Equal(passwords[0], passwords[1]) {
+		t.Errorf("Generate gave %q, GenerateInto %q", passwords[0], passwords[1])
+	}
+}
+
+// A character takes a byte, plus 4 bytes for each counted position, and
+// the odd byte drawn again to avoid bias.
+func TestGenerateRandomBytes(t *testing.T) {
+	src := &countingReader{r: testSource(t)}
+
+	g, err := NewGenerator(128, 4, 4, 4, WithRandSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		src.n = 0
+
+		pw, err := g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wipe(pw)
+
+		if src.n < 128 || src.n > 256 {
+			t.Fatalf("a password of 128 characters took %v random bytes", src.n)
+		}
+	}
+}
+
+func benchmarkGenerateLength(b *testing.B, length uint32) {
+	g, err := NewGenerator(length, length/8, length/8, length/8)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		pw, err := g.Generate()
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		wipe(pw)
+	}
+
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N)/float64(length), "ns/char")
+}
+
+func BenchmarkGenerateLength16(b *testing.B)  { benchmarkGenerateLength(b, 16) }
+func BenchmarkGenerateLength128(b *testing.B) { benchmarkGenerateLength(b, 128) }
+
+func TestChoosePositionsUniform(t *testing.T) {
+	g, err := NewGenerator(6, 0, 0, 0, WithRandSource(testSource(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every ordered pair of distinct positions must be equally likely.
+	counts := make([]int, 6*6)
+	for i := 0; i < 100000; i++ {
+		positions, err := g.choosePositions(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if positions[0] == positions[1] {
+			t.Fatalf("position %v was chosen twice", positions[0])
+		}
+
+		counts[positions[0]*6+positions[1]]++
+	}
+
+	var pairs []int
+	for i, c := range counts {
+		if i/6 != i%6 {
+			pairs = append(pairs, c)
+		}
+	}
+
+	if x := chiSquare(pairs); x > chiSquareLimit(len(pairs)) {
+		t.Errorf("chi-square statistic %.1f exceeds %.1f", x, chiSquareLimit(len(pairs)))
+	}
+}
+
+func TestGenerateCountsExact(t *testing.T) {
+	g, err := NewGenerator(20, 3, 4, 5, WithRandSource(testSource(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digitPositions := make([]int, 20)
+	for i := 0; i < 20000; i++ {
+		pw, err := g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var upper, digit, special int
+		for pos, c := range []rune(string(pw)) {
+			switch {
+			case slices.Contains(g.chars.upper, c):
+				upper++
+			case slices.Contains(g.chars.digits, c):
+				digit++
+				digitPositions[pos]++
+			case slices.Contains(g.chars.special, c):
+				special++
+			}
+		}
+
+		if upper != 3 || digit != 4 || special != 5 {
+			t.Fatalf("%q has %v uppercase, %v digit and %v special characters, want 3, 4 and 5", pw, upper, digit, special)
+		}
+	}
+
+	if x := chiSquare(digitPositions); x > chiSquareLimit(len(digitPositions)) {
+		t.Errorf("digit positions: chi-square statistic %.1f exceeds %.1f", x, chiSquareLimit(len(digitPositions)))
+	}
+}
+
+// BenchmarkGenerateLength128Unbuffered reads crypto/rand directly, for
+// comparison with the buffered BenchmarkGenerateLength128.
+func BenchmarkGenerateLength128Unbuffered(b *testing.B) {
+	g, err := NewGenerator(128, 16, 16, 16, WithRandSource(rand.Reader))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		pw, err := g.Generate()
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		wipe(pw)
+	}
+}
+
+func BenchmarkGenerateMany1000(b *testing.B) {
+	g, err := NewGenerator(24, 2, 2, 2)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		passwords, err := g.GenerateMany(1000)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for _, pw := range passwords {
+			wipe(pw)
+		}
+	}
+}
+
+func TestGenerateEntropyUnavailable(t *testing.T) {
+	newGenerator := func(opts ...Option) *Generator {
+		g, err := NewGenerator(20, 2, 3, 4, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return g
+	}
+
+	// Find how many bytes are read before the first draw of each purpose.
+	before := make(map[string]int)
+	var total int
+	src, err := NewDeterministicSource([]byte("cpass"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = newGenerator(WithRandSource(src), WithTracer(func(e TraceEvent) {
+		if _, ok := before[e.Purpose]; !ok {
+			before[e.Purpose] = total
+		}
+
+		total += e.Bytes
+	})).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		purpose string
+		// extra is how many bytes of the failing draw are still read.
+		extra int
+	}{
+		{"base char", 0},
+		{"base char", 7},
+		{"class position", 0},
+		{"class position", 2},
+		{"digit char", 0},
+	} {
+		n, ok := before[tc.purpose]
+		if !ok {
+			t.Fatalf("no %q draw", tc.purpose)
+		}
+
+		for _, into := range []bool{false, true} {
+			src, err := NewDeterministicSource([]byte("cpass"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := &failingReader{src: src, n: n + tc.extra}
+			g := newGenerator(WithRandSource(r))
+
+			var pw []byte
+			if into {
+				dst := bytes.Repeat([]byte{'x'}, 20)
+				err = g.GenerateInto(dst)
+				if !isZero(dst) {
+					t.Errorf("%v: GenerateInto left %q in the buffer", tc.purpose, dst)
+				}
+			} else {
+				pw, err = g.Generate()
+			}
+
+			if !errors.Is(err, ErrEntropyUnavailable) {
+				t.Errorf("%v: got error %v, want ErrEntropyUnavailable", tc.purpose, err)
+			}
+
+			if pw != nil {
+				t.Errorf("%v: got %q along with the error", tc.purpose, pw)
+			}
+
+			for _, b := range r.bufs {
+				if !isZero(b[:cap(b)]) {
+					t.Errorf("%v: random bytes were left in a buffer", tc.purpose)
+				}
+			}
+		}
+	}
+}
+
+func TestExcludedChars(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		excluded string
+		opts     []Option
+		special  string
+		digits   string
+		letters  string
+	}{
+		// Every excluded character is special, and € is in no class.
+		{"special", "!#$%€", nil, "~@^&*_+[]/?<>.", digitCharset, letterCharset},
+		{"special override", "#_", []Option{WithSpecialCharset("!#_")}, "!", digitCharset, letterCharset},
+		{"mixed classes", "A0$", nil, "~!@#%^&*_+[]/?<>.", "123456789", "bcdefghijkmnpqrstuvwxyz"},
+		{"all digits but one", "012345678", nil, specialCharset, "9", letterCharset},
+	} {
+		g, err := NewGenerator(24, 4, 2, 4, append(tc.opts, WithExcludedChars(tc.excluded), WithRandSource(testSource(t)))...)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+
+		if g.charset.Special != tc.special || g.charset.Digits != tc.digits || g.charset.Letters != tc.letters {
+			t.Errorf("%v: got letters %q, digits %q and special %q, want %q, %q and %q", tc.name, g.charset.Letters, g.charset.Digits, g.charset.Special, tc.letters, tc.digits, tc.special)
+		}
+
+		for i := 0; i < 1000; i++ {
+			pw, err := g.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if i := bytes.IndexAny(pw, tc.excluded); i != -1 {
+				t.Fatalf("%v: %q has the excluded %q", tc.name, pw, pw[i])
+			}
+
+			if tc.excluded == "A0$" && bytes.IndexByte(pw, 'a') != -1 {
+				t.Fatalf("%v: %q has the other case of the excluded A", tc.name, pw)
+			}
+		}
+	}
+}
+
+func TestExcludedCharsEmptyClass(t *testing.T) {
+	for _, tc := range []struct {
+		name                  string
+		excluded              string
+		upper, digit, special uint32
+		opts                  []Option
+		want                  string
+	}{
+		{"digits", digitCharset, 0, 2, 0, nil, "has no digits, but digit count is 2"},
+		{"special", specialCharset, 0, 0, 1, nil, "has no special characters, but special count is 1"},
+		{"special override", "!#_", 0, 0, 1, []Option{WithSpecialCharset("!#_")}, "has no special characters, but special count is 1"},
+		{"letters", letterCharset, 0, 0, 0, nil, "letters"},
+	} {
+		_, err := NewGenerator(16, tc.upper, tc.digit, tc.special, append(tc.opts, WithExcludedChars(tc.excluded))...)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%v: got %v, want an error containing %q", tc.name, err, tc.want)
+		}
+	}
+
+	// A class that is not used may be excluded entirely.
+	g, err := NewGenerator(16, 2, 0, 2, WithExcludedChars(digitCharset))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.charset.Digits != "" {
+		t.Errorf("got digits %q, want none", g.charset.Digits)
+	}
+}
@@ -0,0 +1,265 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// The part-of-speech wordlists of grammar passphrases. They were picked by
+// hand for common, concrete and inoffensive words of 3 to 9 letters. The
+// verbs are in the third person singular, so that a noun, a verb and a noun
+// read as a sentence.
+var (
+	//go:embed data/grammar_adjectives.txt
+	grammarAdjectivesData string
+	//go:embed data/grammar_nouns.txt
+	grammarNounsData string
+	//go:embed data/grammar_verbs.txt
+	grammarVerbsData string
+	//go:embed data/grammar_adverbs.txt
+	grammarAdverbsData string
+)
+
+// PartOfSpeech is a slot of a grammar passphrase template.
+type PartOfSpeech int
+
+const (
+	Adjective PartOfSpeech = iota
+	Noun
+	Verb
+	Adverb
+)
+
+var partsOfSpeech = []struct {
+	name  string
+	short string
+	words []string
+}{
+	Adjective: {"adjective", "adj", mustParseWordLines("grammar-adjectives", grammarAdjectivesData, 316)},
+	Noun:      {"noun", "noun", mustParseWordLines("grammar-nouns", grammarNounsData, 438)},
+	Verb:      {"verb", "verb", mustParseWordLines("grammar-verbs", grammarVerbsData, 196)},
+	Adverb:    {"adverb", "adv", mustParseWordLines("grammar-adverbs", grammarAdverbsData, 105)},
+}
+
+func (p PartOfSpeech) String() string {
+	if p < 0 || int(p) >= len(partsOfSpeech) {
+		return fmt.Sprintf("PartOfSpeech(%d)", int(p))
+	}
+
+	return partsOfSpeech[p].name
+}
+
+// Words returns the embedded wordlist of the part of speech.
+func (p PartOfSpeech) Words() []string {
+	return append([]string(nil), partsOfSpeech[p].words...)
+}
+
+// DefaultGrammarTemplate is the template of grammar passphrases, e.g.
+// "purple-falcon-devours-mountain".
+const DefaultGrammarTemplate = "adjective,noun,verb,noun"
+
+// GrammarTemplate is the sequence of parts of speech a grammar passphrase is
+// made of, one word each.
+type GrammarTemplate []PartOfSpeech
+
+// ParseGrammarTemplate parses a template of comma-separated parts of speech:
+// adjective (or adj), noun, verb and adverb (or adv). It is a single word
+// without spaces, so that it can be stored along with other options.
+func ParseGrammarTemplate(s string) (GrammarTemplate, error) {
+	var t GrammarTemplate
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		pos := PartOfSpeech(-1)
+		for i, p := range partsOfSpeech {
+			if name == p.name || name == p.short {
+				pos = PartOfSpeech(i)
+				break
+			}
+		}
+
+		if pos < 0 {
+			return nil, fmt.Errorf("unknown part of speech %q in template, expected adjective, noun, verb, or adverb", name)
+		}
+
+		t = append(t, pos)
+	}
+
+	if len(t) > MaxPassphraseWords {
+		return nil, fmt.Errorf("the template has %v words, more than the maximum of %v", len(t), MaxPassphraseWords)
+	}
+
+	return t, nil
+}
+
+// String returns the template in the form ParseGrammarTemplate parses.
+func (t GrammarTemplate) String() string {
+	names := make([]string, len(t))
+	for i, p := range t {
+		names[i] = p.String()
+	}
+
+	return strings.Join(names, ",")
+}
+
+// GrammarGenerator generates passphrases of one word per slot of a template,
+// each chosen uniformly from the wordlist of its part of speech.
+type GrammarGenerator struct {
+	template  GrammarTemplate
+	slots     [][]string
+	separator string
+	casing    PassphraseCasing
+	rnd       randSource
+}
+
+// NewGrammarGenerator returns a generator of passphrases following template,
+// joined by separator. Words that contain the separator are left out, like
+// with NewPassphraseGenerator.
+func NewGrammarGenerator(template GrammarTemplate, separator string, casing PassphraseCasing) (*GrammarGenerator, error) {
+	if len(template) == 0 || len(template) > MaxPassphraseWords {
+		return nil, fmt.Errorf("the template must have between 1 and %v words", MaxPassphraseWords)
+	}
+
+	switch casing {
+	case CasingLower, CasingCapitalizeOne, CasingTitle:
+	default:
+		return nil, fmt.Errorf("unknown casing %v", casing)
+	}
+
+	g := &GrammarGenerator{
+		template:  template,
+		slots:     make([][]string, len(template)),
+		separator: separator,
+		casing:    casing,
+	}
+
+	for i, p := range template {
+		if p < 0 || int(p) >= len(partsOfSpeech) {
+			return nil, fmt.Errorf("unknown part of speech %v in template", p)
+		}
+
+		var kept []string
+		for _, w := range partsOfSpeech[p].words {
+			if separator == "" || !strings.Contains(w, separator) {
+				kept = append(kept, w)
+			}
+		}
+
+		if len(kept) < 2 {
+			return nil, fmt.Errorf("fewer than 2 %vs are left without the separator", p)
+		}
+
+		g.slots[i] = kept
+	}
+
+	return g, nil
+}
+
+// Template returns the template passphrases follow.
+func (g *GrammarGenerator) Template() GrammarTemplate {
+	return append(GrammarTemplate(nil), g.template...)
+}
+
+// SlotSizes returns the number of words each slot is chosen from.
+func (g *GrammarGenerator) SlotSizes() []int {
+	sizes := make([]int, len(g.slots))
+	for i, s := range g.slots {
+		sizes[i] = len(s)
+	}
+
+	return sizes
+}
+
+// Entropy returns the entropy of a passphrase in bits: the sum of log2 of
+// the size of every slot's wordlist. The part-of-speech lists are far shorter
+// than diceware lists, so this is less than a diceware passphrase of as many
+// words. Capitalizing a random word adds the bits of which one.
+func (g *GrammarGenerator) Entropy() float64 {
+	var bits float64
+	for _, s := range g.slots {
+		bits += math.Log2(float64(len(s)))
+	}
+
+	if g.casing == CasingCapitalizeOne {
+		bits += math.Log2(float64(len(g.slots)))
+	}
+
+	return bits
+}
+
+// Generate returns a new passphrase. It is allocated at its final size, so
+// that wiping it leaves no copies behind.
+func (g *GrammarGenerator) Generate() ([]byte, error) {
+	choices := make([]uint32, len(g.slots))
+	defer wipePositions(choices)
+
+	for i, s := range g.slots {
+		c, err := g.rnd.intn("grammar word", uint32(i), uint32(len(s)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "choose secure random %v #%v", g.template[i], i)
+		}
+
+		choices[i] = c
+	}
+
+	capital := -1
+	if g.casing == CasingCapitalizeOne {
+		c, err := g.rnd.intn("grammar capital", 0, uint32(len(g.slots)))
+		if err != nil {
+			return nil, errors.Wrap(err, "choose secure random word to capitalize")
+		}
+
+		capital = int(c)
+	}
+
+	capitalized := func(i int) bool {
+		return g.casing == CasingTitle || i == capital
+	}
+
+	size := len(g.separator) * (len(choices) - 1)
+	for i, c := range choices {
+		size += len(g.slots[i][c])
+	}
+
+	// The embedded words are lowercase ASCII, so capitalizing them keeps the
+	// size.
+	ret := make([]byte, 0, size)
+	for i, c := range choices {
+		if i != 0 {
+			ret = append(ret, g.separator...)
+		}
+
+		w := g.slots[i][c]
+		if capitalized(i) {
+			first, n := utf8.DecodeRuneInString(w)
+			ret = utf8.AppendRune(ret, unicode.ToUpper(first))
+			w = w[n:]
+		}
+
+		ret = append(ret, w...)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,215 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestGrammarWordlists(t *testing.T) {
+	for p := range partsOfSpeech {
+		pos := PartOfSpeech(p)
+		words := pos.Words()
+
+		seen := make(map[string]bool)
+		for _, w := range words {
+			if len(w) < 3 || len(w) > 9 || strings.Trim(w, "abcdefghijklmnopqrstuvwxyz") != "" {
+				t.Errorf("%v %q is not 3 to 9 lowercase ASCII letters", pos, w)
+			}
+
+			if seen[w] {
+				t.Errorf("%v %q appears more than once", pos, w)
+			}
+
+			seen[w] = true
+		}
+
+		if pos == Verb {
+			for _, w := range words {
+				if !strings.HasSuffix(w, "s") {
+					t.Errorf("verb %q is not in the third person singular", w)
+				}
+			}
+		}
+	}
+}
+
+func TestParseGrammarTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want GrammarTemplate
+	}{
+		{DefaultGrammarTemplate, GrammarTemplate{Adjective, Noun, Verb, Noun}},
+		{"adj,noun,adv,verb", GrammarTemplate{Adjective, Noun, Adverb, Verb}},
+		{" Noun , VERB ", GrammarTemplate{Noun, Verb}},
+	} {
+		got, err := ParseGrammarTemplate(tc.in)
+		if err != nil {
+			t.Errorf("ParseGrammarTemplate(%q): %s", tc.in, err)
+			continue
+		}
+
+		if got.String() != tc.want.String() {
+			t.Errorf("ParseGrammarTemplate(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+
+		again, err := ParseGrammarTemplate(got.String())
+		if err != nil || again.String() != got.String() {
+			t.Errorf("%q does not round-trip: %v, %v", got, again, err)
+		}
+	}
+
+	for _, in := range []string{"", "adj,,noun", "adj,pronoun", "adj noun", strings.Repeat("noun,", MaxPassphraseWords) + "noun"} {
+		if _, err := ParseGrammarTemplate(in); err == nil {
+			t.Errorf("ParseGrammarTemplate(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestGrammarEntropy(t *testing.T) {
+	template := GrammarTemplate{Adjective, Noun, Verb, Noun}
+	want := math.Log2(316) + math.Log2(438) + math.Log2(196) + math.Log2(438)
+
+	for _, tc := range []struct {
+		casing PassphraseCasing
+		want   float64
+	}{
+		{CasingLower, want},
+		{CasingTitle, want},
+		{CasingCapitalizeOne, want + 2},
+	} {
+		g, err := NewGrammarGenerator(template, "-", tc.casing)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := g.Entropy(); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("casing %v: entropy %v, want %v", tc.casing, got, tc.want)
+		}
+
+		if diceware := 4 * BitsPerWord(len(effLongWords)); g.Entropy() >= diceware {
+			t.Errorf("casing %v: entropy %v is not below the %v bits of 4 diceware words", tc.casing, g.Entropy(), diceware)
+		}
+	}
+
+	// With "e" as the separator, only the words without an e are left.
+	g, err := NewGrammarGenerator(GrammarTemplate{Noun}, "e", CasingLower)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	for _, w := range partsOfSpeech[Noun].words {
+		if !strings.Contains(w, "e") {
+			n++
+		}
+	}
+
+	if g.SlotSizes()[0] != n || g.Entropy() != math.Log2(float64(n)) {
+		t.Errorf("slot of %v words with %v bits, want %v words", g.SlotSizes()[0], g.Entropy(), n)
+	}
+}
+
+func TestGrammarGenerate(t *testing.T) {
+	template := GrammarTemplate{Adjective, Noun, Adverb, Verb, Noun}
+
+	for _, tc := range []struct {
+		casing PassphraseCasing
+		// capitals is the number of capitalized words.
+		capitals int
+	}{
+		{CasingLower, 0},
+		{CasingCapitalizeOne, 1},
+		{CasingTitle, len(template)},
+	} {
+		g, err := NewGrammarGenerator(template, ".", tc.casing)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g.rnd = randSource{reader: testSource(t)}
+
+		for i := 0; i < 200; i++ {
+			b, err := g.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(b) != cap(b) {
+				t.Errorf("%q has length %v but capacity %v", b, len(b), cap(b))
+			}
+
+			words := strings.Split(string(b), ".")
+			if len(words) != len(template) {
+				t.Fatalf("%q has %v words, want %v", b, len(words), len(template))
+			}
+
+			var capitals int
+			for j, w := range words {
+				lower := strings.ToLower(w)
+				if lower != w {
+					capitals++
+				}
+
+				found := false
+				for _, v := range partsOfSpeech[template[j]].words {
+					found = found || v == lower
+				}
+
+				if !found {
+					t.Errorf("%q: word #%v %q is not a %v", b, j, w, template[j])
+				}
+			}
+
+			if capitals != tc.capitals {
+				t.Errorf("%q has %v capitalized words, want %v", b, capitals, tc.capitals)
+			}
+		}
+	}
+}
+
+func TestGrammarEntropyUnavailable(t *testing.T) {
+	g, err := NewGrammarGenerator(GrammarTemplate{Adjective, Noun}, "-", CasingLower)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.rnd = randSource{reader: &failingReader{src: testSource(t), n: 4}}
+
+	b, err := g.Generate()
+	if b != nil || !errors.Is(err, ErrEntropyUnavailable) {
+		t.Errorf("Generate = %q, %v, want nil and ErrEntropyUnavailable", b, err)
+	}
+}
+
+func TestNewGrammarGeneratorErrors(t *testing.T) {
+	for _, tc := range []struct {
+		template GrammarTemplate
+		casing   PassphraseCasing
+	}{
+		{nil, CasingLower},
+		{GrammarTemplate{Noun, PartOfSpeech(7)}, CasingLower},
+		{GrammarTemplate{Noun}, PassphraseCasing(9)},
+	} {
+		if _, err := NewGrammarGenerator(tc.template, "-", tc.casing); err == nil {
+			t.Errorf("NewGrammarGenerator(%v, %v) succeeded, want an error", tc.template, tc.casing)
+		}
+	}
+}
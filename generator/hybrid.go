@@ -0,0 +1,98 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// HybridOrder is where a HybridGenerator puts the character block.
+type HybridOrder int
+
+const (
+	// HybridSuffix puts the character block after the passphrase.
+	HybridSuffix HybridOrder = iota
+	// HybridPrefix puts the character block before the passphrase.
+	HybridPrefix
+)
+
+// HybridGenerator generates a passphrase joined with a short block of random
+// characters, e.g. "ocean-rigid-tusk-K7#q", for sites with complexity rules
+// that a passphrase alone doesn't meet.
+type HybridGenerator struct {
+	passphrase *PassphraseGenerator
+	block      *Generator
+	order      HybridOrder
+	separator  string
+}
+
+// NewHybridGenerator returns a generator that joins the passphrases of p and
+// the passwords of block with separator, in the given order.
+func NewHybridGenerator(p *PassphraseGenerator, block *Generator, order HybridOrder, separator string) (*HybridGenerator, error) {
+	if order != HybridSuffix && order != HybridPrefix {
+		return nil, fmt.Errorf("unknown order %v", order)
+	}
+
+	return &HybridGenerator{
+		passphrase: p,
+		block:      block,
+		order:      order,
+		separator:  separator,
+	}, nil
+}
+
+// Entropy returns the entropy of the passphrase plus the minimum entropy of
+// the character block. The two are chosen independently, so their entropies
+// add up.
+func (g *HybridGenerator) Entropy() (float64, error) {
+	blockBits, err := g.block.EntropyMin()
+	if err != nil {
+		return 0, errors.Wrap(err, "get block min entropy")
+	}
+
+	return g.passphrase.Entropy() + float64(blockBits), nil
+}
+
+// Generate returns a new hybrid password. The parts are wiped once they are
+// copied, so only the returned buffer needs to be wiped by the caller.
+func (g *HybridGenerator) Generate() ([]byte, error) {
+	phrase, err := g.passphrase.Generate()
+	if err != nil {
+		return nil, errors.Wrap(err, "generate passphrase")
+	}
+	defer wipe(phrase)
+
+	block, err := g.block.Generate()
+	if err != nil {
+		return nil, errors.Wrap(err, "generate character block")
+	}
+	defer wipe(block)
+
+	first, second := phrase, block
+	if g.order == HybridPrefix {
+		first, second = block, phrase
+	}
+
+	ret := make([]byte, 0, len(first)+len(g.separator)+len(second))
+	ret = append(ret, first...)
+	ret = append(ret, g.separator...)
+	ret = append(ret, second...)
+
+	return ret, nil
+}
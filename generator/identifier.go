@@ -0,0 +1,138 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// MaxIdentifierLength is the maximum length of an RFC 1123 DNS label.
+const MaxIdentifierLength = 63
+
+var identifierLetterCharset = "abcdefghijklmnopqrstuvwxyz"
+var identifierAlnumCharset = identifierLetterCharset + digitCharset
+var identifierInnerCharset = identifierAlnumCharset + "-"
+
+// IdentifierGenerator generates random RFC 1123 DNS labels: lowercase letters,
+// digits and hyphens, neither starting nor ending with a hyphen. Every valid
+// label of the configured length is equally likely.
+type IdentifierGenerator struct {
+	length          uint32
+	startWithLetter bool
+	rnd             randSource
+}
+
+func NewIdentifierGenerator(length uint32, startWithLetter bool) (*IdentifierGenerator, error) {
+	if length == 0 {
+		return nil, fmt.Errorf("length must be at least 1")
+	}
+
+	if length > MaxIdentifierLength {
+		return nil, fmt.Errorf("length (%v) exceeds the maximum DNS label length of %v", length, MaxIdentifierLength)
+	}
+
+	return &IdentifierGenerator{
+		length:          length,
+		startWithLetter: startWithLetter,
+	}, nil
+}
+
+func (g *IdentifierGenerator) charsetAt(pos uint32) string {
+	switch {
+	case pos == 0 && g.startWithLetter:
+		return identifierLetterCharset
+	case pos == 0 || pos == g.length-1:
+		return identifierAlnumCharset
+	default:
+		return identifierInnerCharset
+	}
+}
+
+func (g *IdentifierGenerator) Entropy() float64 {
+	var bits float64
+	for i := uint32(0); i < g.length; i++ {
+		bits += math.Log2(float64(len(g.charsetAt(i))))
+	}
+
+	return bits
+}
+
+func (g *IdentifierGenerator) combinations() *big.Int {
+	ret := big.NewInt(1)
+	for i := uint32(0); i < g.length; i++ {
+		ret.Mul(ret, big.NewInt(int64(len(g.charsetAt(i)))))
+	}
+
+	return ret
+}
+
+func (g *IdentifierGenerator) Generate() ([]byte, error) {
+	ret := make([]byte, g.length)
+
+	for i := uint32(0); i < g.length; i++ {
+		charset := g.charsetAt(i)
+
+		c, err := g.rnd.pick("identifier char", i, charset)
+		if err != nil {
+			return nil, errors.Wrapf(err, "generate secure random identifier char #%v", i)
+		}
+
+		ret[i] = c
+	}
+
+	return ret, nil
+}
+
+// GenerateUnique generates n distinct identifiers.
+func (g *IdentifierGenerator) GenerateUnique(n int) ([][]byte, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("count must not be negative")
+	}
+
+	if g.combinations().Cmp(big.NewInt(int64(n))) < 0 {
+		return nil, fmt.Errorf("there are fewer than %v distinct identifiers of length %v", n, g.length)
+	}
+
+	seen := make(map[string]struct{}, n)
+	ret := make([][]byte, 0, n)
+
+	for attempts := 0; len(ret) < n; attempts++ {
+		// A safety net against generating forever, only reachable when n is
+		// close to the total number of combinations.
+		if attempts >= 100000+10*n {
+			return nil, fmt.Errorf("exceeded the maximum amount of attempts generating unique identifiers")
+		}
+
+		b, err := g.Generate()
+		if err != nil {
+			return nil, errors.Wrapf(err, "generate identifier #%v", len(ret))
+		}
+
+		if _, ok := seen[string(b)]; ok {
+			continue
+		}
+
+		seen[string(b)] = struct{}{}
+		ret = append(ret, b)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,143 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestIdentifierEdges(t *testing.T) {
+	for _, tc := range []struct {
+		length          uint32
+		startWithLetter bool
+	}{
+		{1, false}, {1, true}, {2, false}, {2, true}, {3, false}, {3, true}, {MaxIdentifierLength, true},
+	} {
+		g, err := NewIdentifierGenerator(tc.length, tc.startWithLetter)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g.rnd = randSource{reader: testSource(t)}
+
+		first := identifierAlnumCharset
+		if tc.startWithLetter {
+			first = identifierLetterCharset
+		}
+
+		// seen counts the characters drawn at the first, an inner and the
+		// last position.
+		var seen [3]map[byte]int
+		for i := range seen {
+			seen[i] = make(map[byte]int)
+		}
+
+		for i := 0; i < 5000; i++ {
+			b, err := g.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(b) != int(tc.length) {
+				t.Fatalf("%+v: %q has the wrong length", tc, b)
+			}
+
+			for pos, c := range b {
+				charset := identifierInnerCharset
+				slot := 1
+				switch {
+				case pos == 0:
+					charset, slot = first, 0
+				case pos == len(b)-1:
+					charset, slot = identifierAlnumCharset, 2
+				}
+
+				if strings.IndexByte(charset, c) == -1 {
+					t.Fatalf("%+v: %q has %q at #%v, not one of %q", tc, b, c, pos+1, charset)
+				}
+
+				seen[slot][c]++
+			}
+		}
+
+		// Every allowed character shows up, so the edges aren't narrower
+		// than the documented charsets either.
+		for slot, charset := range []string{first, identifierInnerCharset, identifierAlnumCharset} {
+			if (slot == 1 && tc.length < 3) || (slot == 2 && tc.length < 2) {
+				continue
+			}
+
+			counts := make([]int, len(charset))
+			for i := range charset {
+				counts[i] = seen[slot][charset[i]]
+			}
+
+			if chi := chiSquare(counts); chi > chiSquareLimit(len(counts)) {
+				t.Errorf("%+v: position %v is not uniform over %q: %v", tc, slot, charset, counts)
+			}
+		}
+	}
+}
+
+func TestIdentifierEntropy(t *testing.T) {
+	for _, tc := range []struct {
+		length          uint32
+		startWithLetter bool
+		want            float64
+	}{
+		{1, false, math.Log2(36)},
+		{1, true, math.Log2(26)},
+		{2, true, math.Log2(26 * 36)},
+		{3, false, math.Log2(36 * 37 * 36)},
+		{3, true, math.Log2(26 * 37 * 36)},
+	} {
+		g, err := NewIdentifierGenerator(tc.length, tc.startWithLetter)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := g.Entropy(); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%+v: got %v bits, want %v", tc, got, tc.want)
+		}
+	}
+}
+
+func TestIdentifierErrors(t *testing.T) {
+	for _, length := range []uint32{0, MaxIdentifierLength + 1} {
+		_, err := NewIdentifierGenerator(length, false)
+		if err == nil {
+			t.Errorf("length %v succeeded", length)
+		}
+	}
+
+	g, err := NewIdentifierGenerator(1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := g.GenerateUnique(26)
+	if err != nil || len(ids) != 26 {
+		t.Errorf("all 26 identifiers of length 1: got %v, %v", len(ids), err)
+	}
+
+	_, err = g.GenerateUnique(27)
+	if err == nil {
+		t.Error("27 distinct identifiers of length 1 succeeded")
+	}
+}
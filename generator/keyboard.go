@@ -0,0 +1,128 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import "strings"
+
+// KeyboardLayout maps the physical keys of a keyboard to the characters they
+// produce without and with Shift held. Each row string is indexed by the
+// physical key position within that row, and a space marks a key that is
+// absent from the layout or doesn't produce a character on its own (e.g. a
+// dead key).
+type KeyboardLayout struct {
+	Name string
+
+	// Rows from the top (number row) to the bottom (Z row).
+	Base    [4]string
+	Shifted [4]string
+}
+
+var KeyboardLayoutUS = KeyboardLayout{
+	Name: "us",
+
+	Base: [4]string{
+		"`1234567890-=",
+		"qwertyuiop[]\\",
+		"asdfghjkl;' ",
+		" zxcvbnm,./",
+	},
+	Shifted: [4]string{
+		"~!@#$%^&*()_+",
+		"QWERTYUIOP{}|",
+		"ASDFGHJKL:\" ",
+		" ZXCVBNM<>?",
+	},
+}
+
+var KeyboardLayoutDE = KeyboardLayout{
+	Name: "de",
+
+	Base: [4]string{
+		" 1234567890ß ",
+		"qwertzuiopü+ ",
+		"asdfghjklöä#",
+		"<yxcvbnm,.-",
+	},
+	Shifted: [4]string{
+		"°!\"§$%&/()=? ",
+		"QWERTZUIOPÜ* ",
+		"ASDFGHJKLÖÄ'",
+		">YXCVBNM;:_",
+	},
+}
+
+var KeyboardLayoutFR = KeyboardLayout{
+	Name: "fr",
+
+	Base: [4]string{
+		"²&é\"'(-è_çà)=",
+		"azertyuiop $ ",
+		"qsdfghjklmù*",
+		"<wxcvbn,;:!",
+	},
+	Shifted: [4]string{
+		" 1234567890°+",
+		"AZERTYUIOP £ ",
+		"QSDFGHJKLM%µ",
+		">WXCVBN?./§",
+	},
+}
+
+// Unshifted returns every character that can be typed on the layout without
+// any modifier key.
+func (l KeyboardLayout) Unshifted() string {
+	return strings.ReplaceAll(strings.Join(l.Base[:], ""), " ", "")
+}
+
+// Common returns the characters that are produced by the same physical key
+// with the same modifier on l and on every one of the other layouts.
+func (l KeyboardLayout) Common(others ...KeyboardLayout) string {
+	var sb strings.Builder
+
+	for _, shifted := range []bool{false, true} {
+		for row := range l.Base {
+			for col, r := range l.row(row, shifted) {
+				if r == ' ' {
+					continue
+				}
+
+				common := true
+				for _, other := range others {
+					otherRow := other.row(row, shifted)
+					if col >= len(otherRow) || otherRow[col] != r {
+						common = false
+						break
+					}
+				}
+
+				if common {
+					sb.WriteRune(r)
+				}
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+func (l KeyboardLayout) row(row int, shifted bool) []rune {
+	if shifted {
+		return []rune(l.Shifted[row])
+	}
+
+	return []rune(l.Base[row])
+}
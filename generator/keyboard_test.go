@@ -0,0 +1,94 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// keyPosition is where a character is typed on a layout.
+type keyPosition struct {
+	row, col int
+	shifted  bool
+}
+
+// keyPositions returns every position that types each character of l.
+func keyPositions(l KeyboardLayout) map[rune][]keyPosition {
+	ret := make(map[rune][]keyPosition)
+	for row := range l.Base {
+		for _, shifted := range []bool{false, true} {
+			for col, r := range l.row(row, shifted) {
+				if r != ' ' {
+					ret[r] = append(ret[r], keyPosition{row, col, shifted})
+				}
+			}
+		}
+	}
+
+	return ret
+}
+
+// The layout-portable characters are the documented intersection: the
+// letters ertuiop, sdfghjkl and xcvbn in both cases, and nothing else.
+func TestLayoutPortableChars(t *testing.T) {
+	const want = "ertuiopsdfghjklxcvbnERTUIOPSDFGHJKLXCVBN"
+	if layoutPortableChars != want {
+		t.Errorf("got %q, want %q", layoutPortableChars, want)
+	}
+
+	// Every character of the US layout is portable if and only if it is
+	// typed at the same place on the other layouts.
+	us, de, fr := keyPositions(KeyboardLayoutUS), keyPositions(KeyboardLayoutDE), keyPositions(KeyboardLayoutFR)
+	for r, positions := range us {
+		portable := len(positions) == 1 && slices.Equal(de[r], positions) && slices.Equal(fr[r], positions)
+		if portable != strings.ContainsRune(want, r) {
+			t.Errorf("%q: portable is %v, positions us %v, de %v, fr %v", r, portable, positions, de[r], fr[r])
+		}
+	}
+}
+
+func TestLayoutPortableCharset(t *testing.T) {
+	c := LayoutPortableCharset
+	if c.Letters != "bcdefghijknprstuvx" || !c.Uppercase || c.Digits != "" || c.Special != "" {
+		t.Errorf("got letters %q, uppercase %v, digits %q and special %q", c.Letters, c.Uppercase, c.Digits, c.Special)
+	}
+
+	g, err := NewGenerator(16, 4, 0, 0, WithCharset(c), WithRandSource(testSource(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "bcdefghijknprstuvxBCDEFGHIJKNPRSTUVX"
+	if got := string(g.alphabet()); got != want {
+		t.Errorf("got the alphabet %q, want %q", got, want)
+	}
+
+	for i := 0; i < 1000; i++ {
+		pw, err := g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, r := range string(pw) {
+			if !strings.ContainsRune(layoutPortableChars, r) {
+				t.Fatalf("%q has %q, which is not layout-portable", pw, r)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Wordlists in other languages than English. The German diceware list is
+// the one shipped with goxkcdpwgen (MIT license). The Spanish and French ones
+// are the BIP 39 wordlists (MIT license), whose words were picked to be
+// distinct by their first four letters.
+var (
+	//go:embed data/de_diceware_7776.txt
+	germanDicewareData string
+	//go:embed data/bip39_spanish.txt
+	spanishBIP39Data string
+	//go:embed data/bip39_french.txt
+	frenchBIP39Data string
+)
+
+var germanDicewareWords = mustParseDicewareList("de-diceware", germanDicewareData, 5)
+var spanishBIP39Words = mustParseWordLines("es-bip39", spanishBIP39Data, 2048)
+var frenchBIP39Words = mustParseWordLines("fr-bip39", frenchBIP39Data, 2048)
+
+// WordlistGermanDiceware returns a German diceware wordlist of 7776 words,
+// for 5 dice.
+func WordlistGermanDiceware() []string {
+	return append([]string(nil), germanDicewareWords...)
+}
+
+// WordlistSpanishBIP39 returns the Spanish BIP 39 wordlist of 2048 words.
+func WordlistSpanishBIP39() []string {
+	return append([]string(nil), spanishBIP39Words...)
+}
+
+// WordlistFrenchBIP39 returns the French BIP 39 wordlist of 2048 words.
+func WordlistFrenchBIP39() []string {
+	return append([]string(nil), frenchBIP39Words...)
+}
+
+// wordlistLanguages maps ISO 639-1 language codes to the embedded wordlist
+// used for them.
+var wordlistLanguages = map[string]string{
+	"en": DefaultWordlistName,
+	"de": "de-diceware",
+	"es": "es-bip39",
+	"fr": "fr-bip39",
+}
+
+// WordlistLanguages returns the language codes WordlistByLanguage knows,
+// sorted.
+func WordlistLanguages() []string {
+	langs := make([]string, 0, len(wordlistLanguages))
+	for lang := range wordlistLanguages {
+		langs = append(langs, lang)
+	}
+
+	sort.Strings(langs)
+
+	return langs
+}
+
+// WordlistByLanguage returns the embedded wordlist for the language with the
+// given ISO 639-1 code, e.g. "de".
+func WordlistByLanguage(lang string) ([]string, error) {
+	name, ok := wordlistLanguages[strings.ToLower(lang)]
+	if !ok {
+		return nil, fmt.Errorf("no wordlist for language %q (available: %v)", lang, strings.Join(WordlistLanguages(), ", "))
+	}
+
+	return WordlistByName(name)
+}
+
+// mustParseWordLines parses an embedded list of one word per line,
+// normalized to NFC. Like mustParseDicewareList, it only panics if the
+// embedded data is corrupt.
+func mustParseWordLines(name, data string, want int) []string {
+	words := normalizeWords(strings.Split(strings.TrimSuffix(data, "\n"), "\n"))
+	if len(words) != want {
+		panic(fmt.Sprintf("embedded wordlist %v is corrupt: expected %v words, got %v", name, want, len(words)))
+	}
+
+	seen := make(map[string]struct{}, len(words))
+	for i, w := range words {
+		if _, ok := seen[w]; ok || w == "" {
+			panic(fmt.Sprintf("embedded wordlist %v is corrupt: line %v: empty or duplicate word %q", name, i+1, w))
+		}
+
+		seen[w] = struct{}{}
+	}
+
+	return words
+}
+
+// normalizeWords normalizes words to NFC in place and returns them. Decomposed
+// accents would otherwise count as characters of their own, and the same word
+// could appear in two encodings.
+func normalizeWords(words []string) []string {
+	for i, w := range words {
+		words[i] = norm.NFC.String(w)
+	}
+
+	return words
+}
@@ -0,0 +1,105 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestWordlistByLanguage(t *testing.T) {
+	for _, tc := range []struct {
+		lang  string
+		count int
+		first string
+	}{
+		{"en", 7776, "abacus"},
+		{"de", 7776, "aalen"},
+		{"DE", 7776, "aalen"},
+		{"es", 2048, "ábaco"},
+		{"fr", 2048, "abaisser"},
+	} {
+		words, err := WordlistByLanguage(tc.lang)
+		if err != nil {
+			t.Errorf("%v: %v", tc.lang, err)
+			continue
+		}
+
+		if len(words) != tc.count || words[0] != tc.first {
+			t.Errorf("%v: got %v words starting with %q, want %v starting with %q", tc.lang, len(words), words[0], tc.count, tc.first)
+		}
+
+		seen := make(map[string]struct{}, len(words))
+		for _, w := range words {
+			if !norm.NFC.IsNormalString(w) {
+				t.Errorf("%v: %q is not NFC", tc.lang, w)
+			}
+
+			if _, ok := seen[w]; ok {
+				t.Errorf("%v: %q appears twice", tc.lang, w)
+			}
+
+			seen[w] = struct{}{}
+		}
+	}
+
+	_, err := WordlistByLanguage("xx")
+	if err == nil || !strings.Contains(err.Error(), "available: de, en, es, fr") {
+		t.Errorf("unknown language: got %v", err)
+	}
+}
+
+// The BIP 39 lists have accented words, whose characters take up more than
+// one byte each. The lengths count characters.
+func TestMultiByteWordlist(t *testing.T) {
+	for _, lang := range []string{"es", "fr"} {
+		words, err := WordlistByLanguage(lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g, err := NewPassphraseGenerator(4, words, "-", WithTotalLength(30, true))
+		if err != nil {
+			t.Fatalf("%v: %v", lang, err)
+		}
+
+		g.rnd = randSource{reader: testSource(t)}
+
+		var multiByte int
+		for i := 0; i < 500; i++ {
+			b, err := g.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if n := utf8.RuneCount(b); n != 30 {
+				t.Fatalf("%v: %q has %v characters, want 30", lang, b, n)
+			}
+
+			if len(b) != 30 {
+				multiByte++
+			}
+		}
+
+		if multiByte == 0 {
+			t.Errorf("%v: no passphrase had multi-byte characters", lang)
+		}
+	}
+}
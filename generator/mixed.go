@@ -0,0 +1,77 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"crypto/sha256"
+	"io"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/hkdf"
+)
+
+// mixInfo separates the extra entropy key from other uses of HKDF.
+const mixInfo = "cpass extra entropy v1"
+
+// mixedSource is the system randomness XORed with a ChaCha20 keystream
+// keyed from extra entropy.
+type mixedSource struct {
+	c *chacha20.Cipher
+}
+
+// NewMixedSource returns a source for WithRandSource that augments the
+// system randomness with extra entropy, such as keystroke timings. The key
+// of a ChaCha20 keystream is derived with HKDF-SHA256 from the extra entropy
+// and a random salt, and the keystream is XORed with the system randomness.
+// The extra entropy never replaces the system randomness, so the output is
+// at least as unpredictable as the system randomness even if the extra
+// entropy is known or all zeros.
+func NewMixedSource(extra []byte) (io.Reader, error) {
+	salt := make([]byte, sha256.Size)
+
+	err := randSource{}.read(salt)
+	if err != nil {
+		return nil, errors.Wrap(err, "read salt")
+	}
+
+	key := make([]byte, chacha20.KeySize)
+	defer wipe(key)
+
+	_, err = io.ReadFull(hkdf.New(sha256.New, extra, salt, []byte(mixInfo)), key)
+	if err != nil {
+		return nil, errors.Wrap(err, "derive key")
+	}
+
+	c, err := chacha20.NewUnauthenticatedCipher(key, make([]byte, chacha20.NonceSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "create chacha20 cipher")
+	}
+
+	return &mixedSource{c: c}, nil
+}
+
+func (s *mixedSource) Read(p []byte) (int, error) {
+	err := randBuf.read(p)
+	if err != nil {
+		return 0, err
+	}
+
+	s.c.XORKeyStream(p, p)
+
+	return len(p), nil
+}
@@ -0,0 +1,132 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// newZeroMixedSource returns a mixed source with all-zero extra entropy on
+// top of a deterministic system source derived from seed.
+func newZeroMixedSource(t *testing.T, seed string) io.Reader {
+	t.Helper()
+
+	system, err := NewDeterministicSource([]byte(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	DiscardBufferedRandomness()
+	setRandReader(t, system)
+	t.Cleanup(DiscardBufferedRandomness)
+
+	src, err := NewMixedSource(make([]byte, 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return src
+}
+
+func TestMixedSourceDistribution(t *testing.T) {
+	src := newZeroMixedSource(t, t.Name())
+
+	buf := make([]byte, 256*400)
+	_, err := io.ReadFull(src, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make([]int, 256)
+	for _, b := range buf {
+		counts[b]++
+	}
+
+	x := chiSquare(counts)
+	if x > chiSquareLimit(len(counts)) {
+		t.Errorf("bytes: chi-square %.2f over the limit of %.2f", x, chiSquareLimit(len(counts)))
+	}
+
+	g, err := NewGenerator(1, 0, 1, 0, WithRandSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digits := make(map[rune]int)
+	for i := 0; i < 5000; i++ {
+		pw, err := g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		digits[rune(pw[0])]++
+	}
+
+	if len(digits) != len(g.chars.digits) {
+		t.Fatalf("got %v distinct digits, want %v", len(digits), len(g.chars.digits))
+	}
+
+	counts = counts[:0]
+	for _, c := range g.chars.digits {
+		counts = append(counts, digits[c])
+	}
+
+	x = chiSquare(counts)
+	if x > chiSquareLimit(len(counts)) {
+		t.Errorf("digits: chi-square %.2f over the limit of %.2f, counts %v", x, chiSquareLimit(len(counts)), counts)
+	}
+}
+
+// With extra entropy that is known to be all zeros, the output still follows
+// the system randomness rather than the extra entropy.
+func TestMixedSourceFollowsSystem(t *testing.T) {
+	read := func(seed string) []byte {
+		buf := make([]byte, 64)
+		_, err := io.ReadFull(newZeroMixedSource(t, seed), buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return buf
+	}
+
+	a, b, other := read("a"), read("a"), read("b")
+	if !bytes.Equal(a, b) {
+		t.Error("the same system randomness gave different output")
+	}
+
+	if bytes.Equal(a, other) {
+		t.Error("different system randomness gave the same output")
+	}
+
+	system, err := NewDeterministicSource([]byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw := make([]byte, 64+len(randBuf.buf))
+	_, err = io.ReadFull(system, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(raw, a[:16]) {
+		t.Error("the output is the system randomness as-is")
+	}
+}
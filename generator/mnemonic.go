@@ -0,0 +1,114 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// mnemonicWords holds the words of the EFF long wordlist made up of
+// lowercase ASCII letters only, by their first letter.
+var mnemonicWords = wordsByInitial(effLongWords)
+
+func wordsByInitial(words []string) [26][]string {
+	var ret [26][]string
+	for _, w := range words {
+		plain := true
+		for i := 0; i < len(w); i++ {
+			plain = plain && w[i] >= 'a' && w[i] <= 'z'
+		}
+
+		if plain {
+			ret[w[0]-'a'] = append(ret[w[0]-'a'], w)
+		}
+	}
+
+	return ret
+}
+
+// Mnemonic returns a memorization aid for pw: a sentence of one word per
+// character, separated by spaces. Every ASCII letter becomes a word of the
+// EFF long wordlist starting with it, capitalized for an uppercase letter,
+// and digits, symbols and all other characters stand for themselves, e.g.
+// "Tundra 7 quilt !" for "T7q!". The words are chosen at random, so that the
+// aid follows no fixed mapping, but it spells out pw and must be kept as
+// secret as pw itself. It is allocated at its final size, so that wiping it
+// leaves no copies behind.
+func Mnemonic(pw []byte) ([]byte, error) {
+	n := utf8.RuneCount(pw)
+
+	choices := make([]string, 0, n)
+	defer func() {
+		// Strings can't be wiped, but the references to the words can.
+		clear(choices[:cap(choices)])
+	}()
+
+	size := n - 1
+	for i, b := 0, pw; len(b) != 0; i++ {
+		r, rl := utf8.DecodeRune(b)
+		b = b[rl:]
+
+		lower := r
+		if r >= 'A' && r <= 'Z' {
+			lower = r - 'A' + 'a'
+		}
+
+		if lower < 'a' || lower > 'z' {
+			// An empty choice stands for the character itself.
+			choices = append(choices, "")
+			size += rl
+
+			continue
+		}
+
+		words := mnemonicWords[lower-'a']
+
+		c, err := randSource{}.intn("mnemonic word", uint32(i), uint32(len(words)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "choose secure random word #%v", i)
+		}
+
+		choices = append(choices, words[c])
+		size += len(words[c])
+	}
+
+	ret := make([]byte, 0, max(size, 0))
+	for i, b := 0, pw; len(b) != 0; i++ {
+		_, rl := utf8.DecodeRune(b)
+
+		if i != 0 {
+			ret = append(ret, ' ')
+		}
+
+		w := choices[i]
+		switch {
+		case w == "":
+			ret = append(ret, b[:rl]...)
+		case b[0] <= 'Z':
+			ret = append(ret, w[0]-'a'+'A')
+			ret = append(ret, w[1:]...)
+		default:
+			ret = append(ret, w...)
+		}
+
+		b = b[rl:]
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,115 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// isMnemonicWord reports whether w is one of the words for its first letter.
+func isMnemonicWord(w string) bool {
+	first := strings.ToLower(w[:1])[0]
+	if first < 'a' || first > 'z' {
+		return false
+	}
+
+	for _, v := range mnemonicWords[first-'a'] {
+		if v == strings.ToLower(w) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestMnemonic(t *testing.T) {
+	for _, pw := range []string{"T7q!", "abcXYZ", "a", "0123456789", `~!@#$%^&*()_+-=[]{};':",./<>?`, "xQzJk", "hé€9"} {
+		aid, err := Mnemonic([]byte(pw))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(aid) != cap(aid) {
+			t.Errorf("%q: aid %q has length %v but capacity %v", pw, aid, len(aid), cap(aid))
+		}
+
+		words := strings.Split(string(aid), " ")
+		if len(words) != utf8.RuneCountInString(pw) {
+			t.Fatalf("%q: aid %q has %v words, want one per character", pw, aid, len(words))
+		}
+
+		for i, r := range []rune(pw) {
+			w := words[i]
+
+			isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+			if !isLetter {
+				if w != string(r) {
+					t.Errorf("%q: character #%v %q became %q, want it kept", pw, i, r, w)
+				}
+
+				continue
+			}
+
+			if first, _ := utf8.DecodeRuneInString(w); first != r {
+				t.Errorf("%q: word #%v %q doesn't start with %q", pw, i, w, r)
+			}
+
+			if !isMnemonicWord(w) {
+				t.Errorf("%q: word #%v %q is not in the wordlist", pw, i, w)
+			}
+		}
+	}
+}
+
+func TestMnemonicWordsAreRandom(t *testing.T) {
+	for i, words := range mnemonicWords {
+		if len(words) == 0 {
+			t.Errorf("no words start with %q", 'a'+i)
+		}
+	}
+
+	// The words for the same letter are chosen anew every time.
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		aid, err := Mnemonic([]byte("ss"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		first, second, _ := strings.Cut(string(aid), " ")
+		seen[first] = true
+		seen[second] = true
+	}
+
+	if len(seen) < 50 {
+		t.Errorf("only %v distinct words for s in 200 draws", len(seen))
+	}
+}
+
+func TestMnemonicEntropyUnavailable(t *testing.T) {
+	DiscardBufferedRandomness()
+	setRandReader(t, &failingReader{src: testSource(t)})
+	t.Cleanup(DiscardBufferedRandomness)
+
+	aid, err := Mnemonic([]byte("pw"))
+	if aid != nil || !errors.Is(err, ErrEntropyUnavailable) {
+		t.Errorf("Mnemonic = %q, %v, want nil and ErrEntropyUnavailable", aid, err)
+	}
+}
@@ -0,0 +1,271 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// The kinds of mutations. Each leaves a mark in the passphrase that tells
+// it apart from the words and from the other kinds, so that it can be
+// counted without guessing.
+const (
+	// mutationToggle turns a letter uppercase.
+	mutationToggle = iota
+	// mutationSwap replaces a letter with a digit.
+	mutationSwap
+	// mutationInsert inserts a special character.
+	mutationInsert
+
+	mutationKinds
+)
+
+// WithMutations applies k random mutations to every passphrase, each of a
+// random kind: turning a random letter uppercase, swapping a random letter
+// for a random digit, or inserting a random special character at a random
+// position. Letters are only ever mutated once. The words must be lowercase
+// and must not contain digits or special characters, so that every mutation
+// shows. It cannot be combined with WithCasing, WithInsertedChar,
+// WithClassCounts or WithTotalLength.
+func WithMutations(k uint32) PassphraseOption {
+	return func(g *PassphraseGenerator) {
+		g.mutations = k
+	}
+}
+
+// initMutations checks that the mutations can be applied to every
+// passphrase of the words and prepares counting their entropy.
+func (g *PassphraseGenerator) initMutations() error {
+	if g.casing != CasingLower || g.insertChars != "" || g.classCounts != nil || g.totalLength != 0 {
+		return fmt.Errorf("mutations cannot be combined with a casing, an inserted character, class counts, or a length limit")
+	}
+
+	if g.mutations > maxLength {
+		return fmt.Errorf("at most %v mutations can be applied", maxLength)
+	}
+
+	// Special characters in the separator would look like inserted ones.
+	for _, c := range specialCharset {
+		if !strings.ContainsRune(g.separator, c) {
+			g.mutationChars += string(c)
+		}
+	}
+
+	if strings.ContainsAny(g.separator, digitCharset) || strings.IndexFunc(g.separator, unicode.IsUpper) != -1 {
+		return fmt.Errorf("mutations need a separator without digits and uppercase letters")
+	}
+
+	minLetters, minRunes := math.MaxInt, math.MaxInt
+	for _, w := range g.words {
+		if strings.IndexFunc(w, unicode.IsUpper) != -1 || strings.ContainsAny(w, digitCharset+specialCharset) {
+			return fmt.Errorf("mutations need every word to be lowercase without digits or special characters, but %q isn't", w)
+		}
+
+		minLetters = min(minLetters, mutableLetters(w))
+		minRunes = min(minRunes, utf8.RuneCountInString(w))
+	}
+
+	g.minLetters = int(g.wordCount) * minLetters
+	g.minRunes = int(g.wordCount)*minRunes + int(g.wordCount-1)*utf8.RuneCountInString(g.separator)
+	if g.minLetters < int(g.mutations) {
+		return fmt.Errorf("the shortest passphrases have %v letters to mutate, fewer than the %v mutations", g.minLetters, g.mutations)
+	}
+
+	g.maxHoleMatches = maxHoleMatches(g.words)
+
+	return nil
+}
+
+// mutableLetters returns the number of letters in w that can be mutated.
+func mutableLetters(w string) int {
+	var n int
+	for i := 0; i < len(w); i++ {
+		if w[i] >= 'a' && w[i] <= 'z' {
+			n++
+		}
+	}
+
+	return n
+}
+
+// maxHoleMatches returns the largest number of words that are the same but
+// for one mutable letter at the same position. It bounds how many words a
+// word with one letter swapped for a digit could have been.
+func maxHoleMatches(words []string) int {
+	counts := make(map[string]int)
+	ret := 1
+	for _, w := range words {
+		b := []byte(w)
+		for i, c := range b {
+			if c < 'a' || c > 'z' {
+				continue
+			}
+
+			b[i] = 0
+			counts[string(b)]++
+			ret = max(ret, counts[string(b)])
+			b[i] = c
+		}
+	}
+
+	return ret
+}
+
+// MutationEntropy returns the bits the mutations add to a passphrase, at
+// least. It is less than the bits of the random choices: a letter swapped
+// for a digit is lost, so up to log2 of the words it could have been is
+// subtracted for the first one, and log2(26) for every further one. The
+// letter positions are counted for the shortest passphrases.
+func (g *PassphraseGenerator) MutationEntropy() float64 {
+	k := g.mutations
+	if k == 0 {
+		return 0
+	}
+
+	// Every kind is equally likely for every mutation, and the passphrase
+	// shows how many of each kind there are, but not in which order they
+	// were applied. So the bits of the counts are added, and the bits of
+	// where they go are averaged over the counts.
+	lgK, _ := math.Lgamma(float64(k) + 1)
+	var bits float64
+	for t := uint32(0); t <= k; t++ {
+		for s := uint32(0); s <= k-t; s++ {
+			i := k - t - s
+			lgT, _ := math.Lgamma(float64(t) + 1)
+			lgS, _ := math.Lgamma(float64(s) + 1)
+			lgI, _ := math.Lgamma(float64(i) + 1)
+			p := math.Exp(lgK - lgT - lgS - lgI - float64(k)*math.Log(mutationKinds))
+
+			choices := log2Binomial(uint32(g.minLetters), t+s) + log2Binomial(t+s, t)
+			choices += float64(s) * math.Log2(float64(len(digitCharset)))
+			choices += log2Binomial(uint32(g.minRunes)+i, i) + float64(i)*math.Log2(float64(len(g.mutationChars)))
+
+			if s != 0 {
+				choices -= math.Log2(float64(g.maxHoleMatches)) + float64(s-1)*math.Log2(26)
+			}
+
+			bits += p * (choices - math.Log2(p))
+		}
+	}
+
+	return bits
+}
+
+// mutate applies the mutations to pw and returns the result, allocated at its
+// final size. pw is wiped.
+func (g *PassphraseGenerator) mutate(pw []byte) ([]byte, error) {
+	defer wipe(pw)
+
+	var counts [mutationKinds]uint32
+	for j := uint32(0); j < g.mutations; j++ {
+		kind, err := g.rnd.intn("passphrase mutation kind", j, mutationKinds)
+		if err != nil {
+			return nil, errors.Wrapf(err, "choose secure random mutation kind #%v", j)
+		}
+
+		counts[kind]++
+	}
+
+	runes := []rune(string(pw))
+	defer wipeRunes(runes)
+
+	var letters []uint32
+	for i, r := range runes {
+		if r >= 'a' && r <= 'z' {
+			letters = append(letters, uint32(i))
+		}
+	}
+	defer wipePositions(letters)
+
+	// The first letters of a random permutation are toggled, the next ones
+	// swapped.
+	toggles, swaps := counts[mutationToggle], counts[mutationSwap]
+	for j := uint32(0); j < toggles+swaps; j++ {
+		c, err := g.rnd.intn("passphrase mutation letter", j, uint32(len(letters))-j)
+		if err != nil {
+			return nil, errors.Wrapf(err, "choose secure random letter to mutate #%v", j)
+		}
+
+		letters[j], letters[j+c] = letters[j+c], letters[j]
+
+		pos := letters[j]
+		if j < toggles {
+			runes[pos] = unicode.ToUpper(runes[pos])
+			continue
+		}
+
+		d, err := g.rnd.pick("passphrase mutation digit", j, digitCharset)
+		if err != nil {
+			return nil, errors.Wrapf(err, "choose secure random digit #%v", j)
+		}
+
+		runes[pos] = rune(d)
+	}
+
+	// The inserted characters go to random distinct positions of the
+	// result.
+	inserts := counts[mutationInsert]
+	total := uint32(len(runes)) + inserts
+
+	positions := make([]uint32, total)
+	defer wipePositions(positions)
+
+	for i := range positions {
+		positions[i] = uint32(i)
+	}
+
+	inserted := make([]byte, total)
+	defer wipe(inserted)
+
+	for j := uint32(0); j < inserts; j++ {
+		c, err := g.rnd.intn("passphrase mutation position", j, total-j)
+		if err != nil {
+			return nil, errors.Wrapf(err, "choose secure random insert position #%v", j)
+		}
+
+		positions[j], positions[j+c] = positions[j+c], positions[j]
+
+		ch, err := g.rnd.pick("passphrase mutation char", j, g.mutationChars)
+		if err != nil {
+			return nil, errors.Wrapf(err, "choose secure random inserted char #%v", j)
+		}
+
+		inserted[positions[j]] = ch
+	}
+
+	// Toggling and swapping ASCII letters keeps the size, and the inserted
+	// characters are ASCII.
+	ret := make([]byte, 0, len(pw)+int(inserts))
+	next := 0
+	for i := range inserted {
+		if inserted[i] != 0 {
+			ret = append(ret, inserted[i])
+			continue
+		}
+
+		ret = utf8.AppendRune(ret, runes[next])
+		next++
+	}
+
+	return ret, nil
+}
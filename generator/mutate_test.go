@@ -0,0 +1,285 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// subsets calls f with every subset of k of the indexes in idx.
+func subsets(idx []int, k int, f func([]int)) {
+	var rec func(start int, chosen []int)
+	rec = func(start int, chosen []int) {
+		if len(chosen) == k {
+			f(chosen)
+			return
+		}
+
+		for i := start; i < len(idx); i++ {
+			rec(i+1, append(chosen, idx[i]))
+		}
+	}
+
+	rec(0, nil)
+}
+
+// assignments calls f with every string of n characters from chars.
+func assignments(chars string, n int, f func(string)) {
+	if n == 0 {
+		f("")
+		return
+	}
+
+	for _, c := range chars {
+		assignments(chars, n-1, func(s string) {
+			f(string(c) + s)
+		})
+	}
+}
+
+// exactMutatedEntropy returns the entropy of one word from words with k
+// mutations, computed from the distribution of every possible result.
+func exactMutatedEntropy(words []string, k int, inserted string) float64 {
+	dist := make(map[string]float64)
+
+	for _, w := range words {
+		pw := 1 / float64(len(words))
+
+		var letters []int
+		for i, c := range w {
+			if c >= 'a' && c <= 'z' {
+				letters = append(letters, i)
+			}
+		}
+
+		for t := 0; t <= k; t++ {
+			for s := 0; s <= k-t; s++ {
+				i := k - t - s
+				pc := pw * math.Exp(lgammaInt(k)-lgammaInt(t)-lgammaInt(s)-lgammaInt(i)-float64(k)*math.Log(3))
+
+				subsets(letters, t, func(toggled []int) {
+					var rest []int
+					for _, l := range letters {
+						if !containsInt(toggled, l) {
+							rest = append(rest, l)
+						}
+					}
+
+					subsets(rest, s, func(swapped []int) {
+						assignments(digitCharset, s, func(digits string) {
+							b := []byte(w)
+							for _, pos := range toggled {
+								b[pos] = byte(unicode.ToUpper(rune(b[pos])))
+							}
+
+							for j, pos := range swapped {
+								b[pos] = digits[j]
+							}
+
+							total := len(b) + i
+							all := make([]int, total)
+							for j := range all {
+								all[j] = j
+							}
+
+							p := pc / (binomial(len(letters), t) * binomial(len(letters)-t, s) * math.Pow(10, float64(s)))
+							p /= binomial(total, i) * math.Pow(float64(len(inserted)), float64(i))
+
+							subsets(all, i, func(positions []int) {
+								assignments(inserted, i, func(chars string) {
+									out := make([]byte, 0, total)
+									next, c := 0, 0
+									for j := 0; j < total; j++ {
+										if containsInt(positions, j) {
+											out = append(out, chars[c])
+											c++
+										} else {
+											out = append(out, b[next])
+											next++
+										}
+									}
+
+									dist[string(out)] += p
+								})
+							})
+						})
+					})
+				})
+			}
+		}
+	}
+
+	var h, total float64
+	for _, p := range dist {
+		h -= p * math.Log2(p)
+		total += p
+	}
+
+	if math.Abs(total-1) > 1e-9 {
+		panic("the probabilities don't add up to 1")
+	}
+
+	return h
+}
+
+func lgammaInt(n int) float64 {
+	v, _ := math.Lgamma(float64(n) + 1)
+	return v
+}
+
+func binomial(n, k int) float64 {
+	return math.Exp(lgammaInt(n) - lgammaInt(k) - lgammaInt(n-k))
+}
+
+func containsInt(s []int, v int) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestMutationEntropyIsLowerBound(t *testing.T) {
+	// Most of the words differ in one letter only, which costs the swaps.
+	words := []string{"bat", "cat", "hat", "mat", "dog", "frog"}
+
+	for k := uint32(1); k <= 3; k++ {
+		g, err := NewPassphraseGenerator(1, words, "-", WithMutations(k))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		exact := exactMutatedEntropy(words, int(k), g.mutationChars)
+		if g.Entropy() > exact+1e-9 {
+			t.Errorf("%v mutations: reported %.3f bits, more than the exact %.3f", k, g.Entropy(), exact)
+		}
+
+		if g.MutationEntropy() <= 0 {
+			t.Errorf("%v mutations add %.3f bits, want more than nothing", k, g.MutationEntropy())
+		}
+
+		t.Logf("%v mutations: %.3f bits reported, %.3f exact", k, g.Entropy(), exact)
+	}
+}
+
+func TestMutate(t *testing.T) {
+	const k = 4
+
+	g, err := NewPassphraseGenerator(4, WordlistEFFShort(), " ", WithMutations(k))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.rnd = randSource{reader: testSource(t)}
+
+	words := make(map[string]bool)
+	for _, w := range WordlistEFFShort() {
+		words[w] = true
+	}
+
+	// Where in its word the first mutated letter of every passphrase is.
+	offsets := make(map[int]int)
+	for n := 0; n < 2000; n++ {
+		pw, err := g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(pw) != cap(pw) {
+			t.Errorf("%q has length %v but capacity %v", pw, len(pw), cap(pw))
+		}
+
+		var marks int
+		var undone []byte
+		for _, c := range pw {
+			switch {
+			case strings.IndexByte(g.mutationChars, c) != -1:
+				marks++
+				continue
+			case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+				marks++
+			}
+
+			undone = append(undone, c)
+		}
+
+		if marks != k {
+			t.Fatalf("%q shows %v mutations, want %v", pw, marks, k)
+		}
+
+		// A word with its letters lowercased again and its digits as holes
+		// must match a word of the list.
+		for _, w := range strings.Split(string(undone), " ") {
+			if !matchesWithHoles(strings.ToLower(w), words) {
+				t.Errorf("%q: %q is no word of the list with mutations", pw, w)
+			}
+
+			if i := strings.IndexFunc(w, func(r rune) bool { return unicode.IsUpper(r) || unicode.IsDigit(r) }); i != -1 {
+				offsets[i]++
+			}
+		}
+	}
+
+	// The mutations go anywhere in the words, not only at their ends.
+	for i := 0; i < 3; i++ {
+		if offsets[i] == 0 {
+			t.Errorf("no word was mutated at offset %v: %v", i, offsets)
+		}
+	}
+}
+
+// matchesWithHoles reports whether w matches a word of words, with any
+// letter in place of its digits.
+func matchesWithHoles(w string, words map[string]bool) bool {
+	i := strings.IndexAny(w, digitCharset)
+	if i == -1 {
+		return words[w]
+	}
+
+	for c := byte('a'); c <= 'z'; c++ {
+		if matchesWithHoles(w[:i]+string(c)+w[i+1:], words) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestMutationsErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		words     []string
+		separator string
+		opts      []PassphraseOption
+	}{
+		{"too many", []string{"ab", "cd"}, "-", []PassphraseOption{WithMutations(3)}},
+		{"casing", []string{"ab", "cd"}, "-", []PassphraseOption{WithMutations(1), WithCasing(CasingTitle)}},
+		{"class counts", []string{"ab", "cd"}, "-", []PassphraseOption{WithMutations(1), WithClassCounts(1, 0, 0)}},
+		{"digit word", []string{"ab", "c4"}, "-", []PassphraseOption{WithMutations(1)}},
+		{"uppercase word", []string{"ab", "Cd"}, "-", []PassphraseOption{WithMutations(1)}},
+		{"digit separator", []string{"ab", "cd"}, "0", []PassphraseOption{WithMutations(1)}},
+	} {
+		if _, err := NewPassphraseGenerator(1, tc.words, tc.separator, tc.opts...); err == nil {
+			t.Errorf("%v: NewPassphraseGenerator succeeded, want an error", tc.name)
+		}
+	}
+}
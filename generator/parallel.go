@@ -0,0 +1,147 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// GenerateManyParallel is GenerateMany spread over workers goroutines, for
+// large batches. Each worker reads crypto/rand through a buffer of its own,
+// so that they don't contend for the shared one, and the passwords are
+// returned in order. If ctx is canceled or a worker fails, every password
+// generated so far is wiped.
+//
+// A source set with WithRandSource or a Tracer may not be safe for
+// concurrent use, and the order of their draws matters, so with either one
+// the passwords are generated serially.
+func (g *Generator) GenerateManyParallel(ctx context.Context, n, workers int) ([][]byte, error) {
+	if workers < 1 {
+		return nil, fmt.Errorf("there must be at least one worker")
+	}
+
+	if workers == 1 || g.rnd.reader != nil || g.rnd.tracer != nil {
+		err := ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+
+		return g.GenerateMany(n)
+	}
+
+	err := g.checkBatchSize(n)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ret := make([][]byte, n)
+
+	fail := func(err error) ([][]byte, error) {
+		for _, b := range ret {
+			wipe(b)
+		}
+
+		return nil, err
+	}
+
+	var next atomic.Int64
+	var errOnce sync.Once
+	var workerErr error
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			buf := &randBuffer{}
+			defer buf.wipe()
+
+			local := *g
+			local.rnd.buf = buf
+
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+
+				b, err := local.Generate()
+				if err != nil {
+					errOnce.Do(func() {
+						workerErr = errors.Wrapf(err, "generate password #%v", i+1)
+					})
+					cancel()
+
+					return
+				}
+
+				ret[i] = b
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if workerErr != nil {
+		return fail(workerErr)
+	}
+
+	for _, b := range ret {
+		if b == nil {
+			return fail(ctx.Err())
+		}
+	}
+
+	// The workers can't see each other's passwords, so duplicates are
+	// replaced afterwards.
+	seen := make(map[[sha256.Size]byte]struct{}, n)
+	defer clear(seen)
+
+	for i := range ret {
+		for attempts := 0; ; attempts++ {
+			sum := sha256.Sum256(ret[i])
+			if _, ok := seen[sum]; !ok {
+				seen[sum] = struct{}{}
+				break
+			}
+
+			if attempts >= maxBatchAttempts(n) {
+				return fail(errTooManyAttempts)
+			}
+
+			wipe(ret[i])
+
+			ret[i], err = g.Generate()
+			if err != nil {
+				return fail(errors.Wrapf(err, "generate password #%v", i+1))
+			}
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,149 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestGenerateManyParallel(t *testing.T) {
+	g, err := NewGenerator(12, 1, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	passwords, err := g.GenerateManyParallel(context.Background(), 5000, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(passwords) != 5000 {
+		t.Fatalf("got %v passwords, want 5000", len(passwords))
+	}
+
+	seen := make(map[[sha256.Size]byte]struct{}, len(passwords))
+	for _, pw := range passwords {
+		err := g.Validate(pw)
+		if err != nil {
+			t.Fatalf("%q: %v", pw, err)
+		}
+
+		sum := sha256.Sum256(pw)
+		if _, ok := seen[sum]; ok {
+			t.Fatalf("%q was generated twice", pw)
+		}
+
+		seen[sum] = struct{}{}
+	}
+}
+
+// With a source of its own, the batch is generated serially, in the order of
+// the source.
+func TestGenerateManyParallelWithSource(t *testing.T) {
+	var batches [2][][]byte
+	for i := range batches {
+		src, err := NewDeterministicSource([]byte("cpass"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g, err := NewGenerator(16, 0, 0, 0, WithRandSource(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if i == 0 {
+			batches[i], err = g.GenerateMany(100)
+		} else {
+			batches[i], err = g.GenerateManyParallel(context.Background(), 100, 4)
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := range batches[0] {
+		if string(batches[0][i]) != string(batches[1][i]) {
+			t.Fatalf("password #%v: GenerateMany gave %q, GenerateManyParallel %q", i+1, batches[0][i], batches[1][i])
+		}
+	}
+}
+
+func TestGenerateManyParallelCanceled(t *testing.T) {
+	g, err := NewGenerator(64, 8, 8, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	passwords, err := g.GenerateManyParallel(ctx, 1000, 4)
+	if !errors.Is(err, context.Canceled) || passwords != nil {
+		t.Errorf("before the start: got %v passwords and error %v, want none and context.Canceled", len(passwords), err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(5*time.Millisecond, cancel)
+
+	passwords, err = g.GenerateManyParallel(ctx, MaxBatchSize, 4)
+	if !errors.Is(err, context.Canceled) || passwords != nil {
+		t.Errorf("midway: got %v passwords and error %v, want none and context.Canceled", len(passwords), err)
+	}
+}
+
+func BenchmarkGenerateMany10000(b *testing.B) {
+	g, err := NewGenerator(24, 2, 2, 2)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		passwords, err := g.GenerateMany(10000)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for _, pw := range passwords {
+			wipe(pw)
+		}
+	}
+}
+
+func BenchmarkGenerateManyParallel10000(b *testing.B) {
+	g, err := NewGenerator(24, 2, 2, 2)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		passwords, err := g.GenerateManyParallel(context.Background(), 10000, runtime.NumCPU())
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for _, pw := range passwords {
+			wipe(pw)
+		}
+	}
+}
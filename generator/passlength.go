@@ -0,0 +1,200 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// MaxPassphraseLength is the largest total length, in characters, that
+// passphrases can be limited to.
+const MaxPassphraseLength = 1024
+
+// WithTotalLength limits passphrases to at most n characters in total, or
+// with exact, to exactly n. The words are drawn uniformly among all the
+// sequences of words that fit, so the entropy is that of the number of these
+// sequences, which is less than without the limit.
+func WithTotalLength(n int, exact bool) PassphraseOption {
+	return func(g *PassphraseGenerator) {
+		g.totalLength = n
+		g.exactLength = exact
+	}
+}
+
+// lengthPlan draws the words of passphrases of a limited total length
+// uniformly among all the sequences of words that fit.
+type lengthPlan struct {
+	// lengths are the distinct word lengths, and byLength the indexes of
+	// the words of every length.
+	lengths  []int
+	byLength map[int][]uint32
+	// ways[k][t] is the number of sequences of k words that are t
+	// characters long together.
+	ways [][]*big.Int
+	// minTotal and maxTotal bound the length of the words together.
+	minTotal, maxTotal int
+	// count is the number of sequences that fit.
+	count *big.Int
+}
+
+func (g *PassphraseGenerator) initLengthPlan() error {
+	if g.totalLength == 0 {
+		return nil
+	}
+
+	if g.totalLength < 0 || g.totalLength > MaxPassphraseLength {
+		return fmt.Errorf("total length must be between 1 and %v", MaxPassphraseLength)
+	}
+
+	// The separators and the inserted characters take up the same space in
+	// every passphrase, and capitalizing doesn't change the length.
+	budget := g.totalLength - utf8.RuneCountInString(g.separator)*int(g.wordCount-1)
+	for _, c := range g.block {
+		budget -= int(c.n)
+	}
+
+	plan := &lengthPlan{
+		byLength: make(map[int][]uint32),
+		maxTotal: budget,
+	}
+
+	if g.exactLength {
+		plan.minTotal = budget
+	}
+
+	for i, w := range g.words {
+		n := utf8.RuneCountInString(w)
+		if len(plan.byLength[n]) == 0 {
+			plan.lengths = append(plan.lengths, n)
+		}
+
+		plan.byLength[n] = append(plan.byLength[n], uint32(i))
+	}
+
+	sort.Ints(plan.lengths)
+
+	plan.count = new(big.Int)
+	if budget >= 0 {
+		plan.ways = make([][]*big.Int, g.wordCount+1)
+		for k := range plan.ways {
+			plan.ways[k] = make([]*big.Int, budget+1)
+			for t := range plan.ways[k] {
+				plan.ways[k][t] = new(big.Int)
+			}
+		}
+
+		plan.ways[0][0].SetInt64(1)
+
+		var term big.Int
+		for k := 1; k <= int(g.wordCount); k++ {
+			for t := 0; t <= budget; t++ {
+				for _, l := range plan.lengths {
+					if l > t {
+						break
+					}
+
+					term.SetInt64(int64(len(plan.byLength[l])))
+					term.Mul(&term, plan.ways[k-1][t-l])
+					plan.ways[k][t].Add(plan.ways[k][t], &term)
+				}
+			}
+		}
+
+		for t := plan.minTotal; t <= plan.maxTotal; t++ {
+			plan.count.Add(plan.count, plan.ways[g.wordCount][t])
+		}
+	}
+
+	if plan.count.Sign() == 0 {
+		limit := "at most"
+		if g.exactLength {
+			limit = "exactly"
+		}
+
+		return fmt.Errorf("no passphrase of %v words from the wordlist is %v %v characters long", g.wordCount, limit, g.totalLength)
+	}
+
+	g.lengthPlan = plan
+
+	return nil
+}
+
+// chooseWordsWithLength draws the words into choices, uniformly among the
+// sequences of words that fit. It first draws the total length, weighted by
+// the number of sequences of that length, and then every word, weighted by
+// the number of ways to complete the sequence after it.
+func (g *PassphraseGenerator) chooseWordsWithLength(choices []uint32) error {
+	plan := g.lengthPlan
+
+	r, err := g.rnd.bigIntn("passphrase total length", 0, plan.count)
+	if err != nil {
+		return errors.Wrap(err, "choose secure random total length")
+	}
+
+	total := plan.maxTotal
+	for t := plan.minTotal; t <= plan.maxTotal; t++ {
+		ways := plan.ways[g.wordCount][t]
+		if r.Cmp(ways) < 0 {
+			total = t
+			break
+		}
+
+		r.Sub(r, ways)
+	}
+
+	var weight, word big.Int
+	for i := range choices {
+		left := len(choices) - i
+
+		r, err := g.rnd.bigIntn("passphrase word", uint32(i), plan.ways[left][total])
+		if err != nil {
+			return errors.Wrapf(err, "choose secure random word #%v", i)
+		}
+
+		for _, l := range plan.lengths {
+			if l > total {
+				break
+			}
+
+			rest := plan.ways[left-1][total-l]
+			weight.SetInt64(int64(len(plan.byLength[l])))
+			weight.Mul(&weight, rest)
+
+			if r.Cmp(&weight) >= 0 {
+				r.Sub(r, &weight)
+				continue
+			}
+
+			// Every word of this length is followed by the same number of
+			// ways to complete the sequence, so r picks the word uniformly.
+			word.Quo(r, rest)
+			choices[i] = plan.byLength[l][word.Int64()]
+			total -= l
+
+			break
+		}
+
+		r.SetInt64(0)
+	}
+
+	return nil
+}
@@ -0,0 +1,613 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// MaxPassphraseWords is the largest number of words in a passphrase.
+const MaxPassphraseWords = 64
+
+// CommonEnglishWordlist returns the built-in wordlist: the most common English
+// words of 3 to 9 letters, sorted.
+func CommonEnglishWordlist() []string {
+	var ret []string
+	for _, w := range strings.Fields(commonEnglishData) {
+		if n := utf8.RuneCountInString(w); n >= 3 && n <= 9 {
+			ret = append(ret, w)
+		}
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
+
+// PassphraseCasing is how the words of a passphrase are capitalized.
+type PassphraseCasing int
+
+const (
+	// CasingLower leaves the words as they are in the wordlist.
+	CasingLower PassphraseCasing = iota
+	// CasingCapitalizeOne capitalizes a random word.
+	CasingCapitalizeOne
+	// CasingTitle capitalizes every word.
+	CasingTitle
+)
+
+// PassphraseGenerator generates passphrases of words chosen uniformly and
+// independently from a wordlist, joined by a separator.
+type PassphraseGenerator struct {
+	wordCount uint32
+	words     []string
+	separator string
+	rnd       randSource
+
+	casing PassphraseCasing
+
+	// insertChars are the characters one of which is inserted after a
+	// word, the last one unless insertAnywhere is set.
+	insertChars    string
+	insertAnywhere bool
+
+	// classCounts holds the uppercase, digit and special counts of
+	// WithClassCounts, if set.
+	classCounts *[3]uint32
+
+	// minWordLength and maxWordLength bound the word length in characters.
+	// Zero means no bound.
+	minWordLength int
+	maxWordLength int
+
+	// totalLength limits the length of the passphrases in characters, to
+	// exactly that with exactLength. Zero means no limit.
+	totalLength int
+	exactLength bool
+	lengthPlan  *lengthPlan
+
+	// capitals is the number of words whose first letter is capitalized.
+	capitals uint32
+	// block lists the characters inserted after a word, by class.
+	block []blockClass
+
+	// mutations is the number of random mutations of WithMutations, and
+	// mutationChars the characters they insert.
+	mutations     uint32
+	mutationChars string
+	// minLetters and minRunes are the number of mutable letters and of
+	// characters of the shortest passphrases, and maxHoleMatches the most
+	// words a word with a swapped letter could have been.
+	minLetters     int
+	minRunes       int
+	maxHoleMatches int
+
+	// randomCapital is which letters WithRandomCapital uppercases, and
+	// capitalBits the average of log2 of the letters it may choose from
+	// in a word.
+	randomCapital RandomCapital
+	capitalBits   float64
+}
+
+// blockClass is a class of the characters inserted into a passphrase: n of
+// them are drawn from chars.
+type blockClass struct {
+	chars string
+	n     uint32
+}
+
+type PassphraseOption func(*PassphraseGenerator)
+
+// WithCasing sets how the words are capitalized. Capitalizing needs every
+// word to start with a lowercase letter.
+func WithCasing(c PassphraseCasing) PassphraseOption {
+	return func(g *PassphraseGenerator) {
+		g.casing = c
+	}
+}
+
+// WithInsertedChar inserts a random character from chars after the last
+// word, or with anywhere, after a random word. None of the words may contain
+// any of chars, so that the character can always be told apart from them.
+func WithInsertedChar(chars string, anywhere bool) PassphraseOption {
+	return func(g *PassphraseGenerator) {
+		g.insertChars = chars
+		g.insertAnywhere = anywhere
+	}
+}
+
+// WithClassCounts makes passphrases satisfy a character class policy with
+// the same counts as the character Generator: the first letters of upper
+// random words are capitalized, and a block of digit random digits and
+// special random special characters, in random order, is inserted after a
+// random word. The words themselves stay intact. The words must be lowercase
+// and must not contain any digits or special characters, so that the
+// passphrases have exactly these counts. It cannot be combined with
+// WithCasing or WithInsertedChar.
+func WithClassCounts(upper, digit, special uint32) PassphraseOption {
+	return func(g *PassphraseGenerator) {
+		g.classCounts = &[3]uint32{upper, digit, special}
+	}
+}
+
+// WithWordLength only keeps the words of min to max characters, e.g. for
+// passphrases typed on a phone. Zero means no bound. The entropy is computed
+// from the words that are left, and there must be at least MinWordlistSize
+// of them.
+func WithWordLength(min, max int) PassphraseOption {
+	return func(g *PassphraseGenerator) {
+		g.minWordLength = min
+		g.maxWordLength = max
+	}
+}
+
+// NewPassphraseGenerator returns a generator of passphrases of wordCount
+// words from words, which must be distinct. Words that contain the separator
+// are left out, so that every passphrase can only be made up of the words one
+// way, and the entropy only counts the words that are left.
+func NewPassphraseGenerator(wordCount uint32, words []string, separator string, opts ...PassphraseOption) (*PassphraseGenerator, error) {
+	g := &PassphraseGenerator{
+		wordCount: wordCount,
+		separator: separator,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	if wordCount == 0 || wordCount > MaxPassphraseWords {
+		return nil, fmt.Errorf("word count must be between 1 and %v", MaxPassphraseWords)
+	}
+
+	if g.minWordLength < 0 || g.maxWordLength < 0 {
+		return nil, fmt.Errorf("word length bounds must not be negative")
+	}
+
+	if g.maxWordLength != 0 && g.minWordLength > g.maxWordLength {
+		return nil, fmt.Errorf("min word length (%v) > max word length (%v)", g.minWordLength, g.maxWordLength)
+	}
+
+	err := g.initClasses()
+	if err != nil {
+		return nil, err
+	}
+
+	var blockChars string
+	for _, c := range g.block {
+		blockChars += c.chars
+	}
+
+	kept := make([]string, 0, len(words))
+	seen := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		if w == "" {
+			return nil, fmt.Errorf("the wordlist has an empty word")
+		}
+
+		if _, ok := seen[w]; ok {
+			return nil, fmt.Errorf("the wordlist has %q more than once", w)
+		}
+
+		seen[w] = struct{}{}
+
+		if g.capitals != 0 {
+			first, _ := utf8.DecodeRuneInString(w)
+			if !unicode.IsLower(first) || unicode.ToUpper(first) == first {
+				return nil, fmt.Errorf("capitalizing needs every word to start with a lowercase letter, but %q doesn't", w)
+			}
+
+			if g.classCounts != nil && strings.IndexFunc(w, unicode.IsUpper) != -1 {
+				return nil, fmt.Errorf("an exact uppercase count needs every word to be lowercase, but %q isn't", w)
+			}
+		}
+
+		if strings.ContainsAny(w, blockChars) {
+			return nil, fmt.Errorf("word %q contains characters that may be inserted", w)
+		}
+
+		n := utf8.RuneCountInString(w)
+		if n < g.minWordLength || (g.maxWordLength != 0 && n > g.maxWordLength) {
+			continue
+		}
+
+		if separator == "" || !strings.Contains(w, separator) {
+			kept = append(kept, w)
+		}
+	}
+
+	if len(kept) > math.MaxUint32 {
+		return nil, fmt.Errorf("the wordlist has more than %v words", uint32(math.MaxUint32))
+	}
+
+	if len(kept) < 2 {
+		return nil, fmt.Errorf("the wordlist must have at least 2 words without the separator and within the length bounds, but has %v", len(kept))
+	}
+
+	if (g.minWordLength != 0 || g.maxWordLength != 0) && len(kept) < MinWordlistSize {
+		return nil, fmt.Errorf("only %v words are within the length bounds, fewer than the minimum of %v", len(kept), MinWordlistSize)
+	}
+
+	g.words = kept
+
+	err = g.initLengthPlan()
+	if err != nil {
+		return nil, err
+	}
+
+	if g.mutations != 0 {
+		err = g.initMutations()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = g.initRandomCapital()
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// initClasses turns the casing, the inserted character and the class counts
+// into the number of capitalized words and the inserted block.
+func (g *PassphraseGenerator) initClasses() error {
+	switch g.casing {
+	case CasingLower:
+	case CasingCapitalizeOne:
+		g.capitals = 1
+	case CasingTitle:
+		g.capitals = g.wordCount
+	default:
+		return fmt.Errorf("unknown casing %v", g.casing)
+	}
+
+	for i := 0; i < len(g.insertChars); i++ {
+		c := g.insertChars[i]
+		if c <= ' ' || c > '~' {
+			return fmt.Errorf("inserted characters must be printable ASCII other than space")
+		}
+
+		if strings.IndexByte(g.insertChars[i+1:], c) != -1 {
+			return fmt.Errorf("inserted character %q appears more than once", c)
+		}
+	}
+
+	if g.insertChars != "" {
+		g.block = []blockClass{{chars: g.insertChars, n: 1}}
+	}
+
+	if g.classCounts == nil {
+		return nil
+	}
+
+	if g.casing != CasingLower || g.insertChars != "" {
+		return fmt.Errorf("class counts cannot be combined with a casing or an inserted character")
+	}
+
+	upper, digit, special := g.classCounts[0], g.classCounts[1], g.classCounts[2]
+	if upper > g.wordCount {
+		return fmt.Errorf("cannot capitalize %v of %v words", upper, g.wordCount)
+	}
+
+	if digit+special > maxLength {
+		return fmt.Errorf("at most %v digits and special characters can be inserted", maxLength)
+	}
+
+	g.capitals = upper
+	g.insertAnywhere = true
+
+	for _, c := range []blockClass{{digitCharset, digit}, {specialCharset, special}} {
+		if c.n != 0 {
+			g.block = append(g.block, c)
+		}
+	}
+
+	return nil
+}
+
+// WordCount returns the number of words in a passphrase.
+func (g *PassphraseGenerator) WordCount() uint32 {
+	return g.wordCount
+}
+
+// WordlistSize returns the number of words passphrases are made up from.
+func (g *PassphraseGenerator) WordlistSize() int {
+	return len(g.words)
+}
+
+// Entropy returns the entropy of a passphrase in bits. Capitalizing random
+// words and inserting random characters add the bits of these choices, which
+// are all distinguishable in the passphrase, since the words never start
+// with a capital or contain the inserted characters. Title case adds nothing.
+// Mutations add MutationEntropy, and a random capital RandomCapitalEntropy.
+func (g *PassphraseGenerator) Entropy() float64 {
+	bits := float64(g.wordCount) * BitsPerWord(len(g.words))
+	if g.lengthPlan != nil {
+		bits = log2BigInt(g.lengthPlan.count)
+	}
+
+	// Which words are capitalized.
+	bits += log2Binomial(g.wordCount, g.capitals)
+
+	var blockLen uint32
+	for _, c := range g.block {
+		bits += float64(c.n) * math.Log2(float64(len(c.chars)))
+
+		// Where the characters of the class go in the block.
+		blockLen += c.n
+		bits += log2Binomial(blockLen, c.n)
+	}
+
+	if blockLen != 0 && g.insertAnywhere {
+		bits += math.Log2(float64(g.wordCount))
+	}
+
+	return bits + g.MutationEntropy() + g.RandomCapitalEntropy()
+}
+
+// log2Binomial returns log2 of n choose k.
+func log2Binomial(n, k uint32) float64 {
+	lg := func(x uint32) float64 {
+		v, _ := math.Lgamma(float64(x) + 1)
+		return v
+	}
+
+	return (lg(n) - lg(k) - lg(n-k)) / math.Ln2
+}
+
+// Generate returns a new passphrase. It is allocated at its final size, so
+// that wiping it leaves no copies behind.
+func (g *PassphraseGenerator) Generate() ([]byte, error) {
+	choices := make([]uint32, g.wordCount)
+	defer wipePositions(choices)
+
+	if g.lengthPlan != nil {
+		err := g.chooseWordsWithLength(choices)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for i := range choices {
+			c, err := g.chooseWord(uint32(i))
+			if err != nil {
+				return nil, err
+			}
+
+			choices[i] = c
+		}
+	}
+
+	capitalized := make([]bool, g.wordCount)
+	if g.capitals == g.wordCount {
+		for i := range capitalized {
+			capitalized[i] = true
+		}
+	} else if g.capitals != 0 {
+		positions, err := g.chooseWords(g.capitals)
+		if err != nil {
+			return nil, errors.Wrap(err, "choose secure random words to capitalize")
+		}
+
+		for _, pos := range positions {
+			capitalized[pos] = true
+		}
+
+		wipePositions(positions[:cap(positions)])
+	}
+
+	capitalAt, err := g.chooseRandomCapitals(choices)
+	if err != nil {
+		return nil, err
+	}
+	defer wipePositions(capitalAt)
+
+	block, err := g.generateBlock()
+	if err != nil {
+		return nil, errors.Wrap(err, "generate inserted chars")
+	}
+	defer wipe(block)
+
+	insertAfter := -1
+	if len(block) != 0 {
+		insertAfter = int(g.wordCount) - 1
+		if g.insertAnywhere {
+			c, err := g.rnd.intn("passphrase inserted position", 0, g.wordCount)
+			if err != nil {
+				return nil, errors.Wrap(err, "choose secure random inserted position")
+			}
+
+			insertAfter = int(c)
+		}
+	}
+
+	size := len(g.separator)*(len(choices)-1) + len(block)
+	for i, c := range choices {
+		w := g.words[c]
+		if capitalized[i] {
+			first, n := utf8.DecodeRuneInString(w)
+			size += utf8.RuneLen(unicode.ToUpper(first)) - n
+		}
+
+		if capitalAt[i] != noCapital {
+			size += randomCapitalSize(w, capitalAt[i])
+		}
+
+		size += len(w)
+	}
+
+	ret := make([]byte, 0, size)
+	for i, c := range choices {
+		if i != 0 {
+			ret = append(ret, g.separator...)
+		}
+
+		w := g.words[c]
+		if capitalized[i] {
+			first, n := utf8.DecodeRuneInString(w)
+			ret = utf8.AppendRune(ret, unicode.ToUpper(first))
+			w = w[n:]
+		}
+
+		if capitalAt[i] != noCapital {
+			ret = appendRandomCapital(ret, w, capitalAt[i])
+		} else {
+			ret = append(ret, w...)
+		}
+
+		if i == insertAfter {
+			ret = append(ret, block...)
+		}
+	}
+
+	if g.mutations != 0 {
+		return g.mutate(ret)
+	}
+
+	return ret, nil
+}
+
+// chooseWord returns the index of a word chosen uniformly from the wordlist.
+// All words of passphrases and of GenerateWords are chosen this way.
+func (g *PassphraseGenerator) chooseWord(i uint32) (uint32, error) {
+	c, err := g.rnd.intn("passphrase word", i, uint32(len(g.words)))
+	if err != nil {
+		return 0, errors.Wrapf(err, "choose secure random word #%v", i)
+	}
+
+	return c, nil
+}
+
+// GenerateWords returns n words chosen like the words of a passphrase, for
+// uses that need the words on their own. With unique, no word is chosen
+// twice, and n must not exceed the number of words. The word count, casing,
+// inserted characters and length limits of g don't apply.
+func (g *PassphraseGenerator) GenerateWords(n uint32, unique bool) ([]string, error) {
+	if unique && int(n) > len(g.words) {
+		return nil, fmt.Errorf("cannot choose %v unique words from %v", n, len(g.words))
+	}
+
+	choices := make([]uint32, 0, n)
+	defer func() {
+		wipePositions(choices[:cap(choices)])
+	}()
+
+	for uint32(len(choices)) < n {
+		c, err := g.chooseWord(uint32(len(choices)))
+		if err != nil {
+			return nil, err
+		}
+
+		// Drawing again on a repeat keeps every sequence of distinct words
+		// equally likely.
+		if unique && slices.Contains(choices, c) {
+			continue
+		}
+
+		choices = append(choices, c)
+	}
+
+	words := make([]string, n)
+	for i, c := range choices {
+		words[i] = g.words[c]
+	}
+
+	return words, nil
+}
+
+// WordsEntropy returns the entropy of n words from GenerateWords in bits.
+// Unique words carry a little less, as every word leaves one fewer to choose
+// from.
+func (g *PassphraseGenerator) WordsEntropy(n uint32, unique bool) float64 {
+	if !unique {
+		return float64(n) * BitsPerWord(len(g.words))
+	}
+
+	var bits float64
+	for i := 0; i < int(n) && i < len(g.words); i++ {
+		bits += math.Log2(float64(len(g.words) - i))
+	}
+
+	return bits
+}
+
+// chooseWords returns n distinct random word indexes.
+func (g *PassphraseGenerator) chooseWords(n uint32) ([]uint32, error) {
+	positions := make([]uint32, g.wordCount)
+	for i := range positions {
+		positions[i] = uint32(i)
+	}
+
+	for i := uint32(0); i < n; i++ {
+		j, err := g.rnd.intn("passphrase capital", i, g.wordCount-i)
+		if err != nil {
+			wipePositions(positions)
+			return nil, errors.Wrapf(err, "generate random word #%v", i)
+		}
+
+		positions[i], positions[i+j] = positions[i+j], positions[i]
+	}
+
+	return positions[:n], nil
+}
+
+// generateBlock returns the inserted characters: the classes in a random
+// order, and every character drawn from its class.
+func (g *PassphraseGenerator) generateBlock() ([]byte, error) {
+	var classes []int
+	for i, c := range g.block {
+		for j := uint32(0); j < c.n; j++ {
+			classes = append(classes, i)
+		}
+	}
+
+	if len(g.block) > 1 {
+		for i := len(classes) - 1; i > 0; i-- {
+			j, err := g.rnd.intn("passphrase inserted class", uint32(i), uint32(i+1))
+			if err != nil {
+				return nil, errors.Wrapf(err, "shuffle inserted class #%v", i)
+			}
+
+			classes[i], classes[j] = classes[j], classes[i]
+		}
+	}
+
+	block := make([]byte, len(classes))
+	for i, class := range classes {
+		c, err := g.rnd.pick("passphrase inserted char", uint32(i), g.block[class].chars)
+		if err != nil {
+			wipe(block)
+			return nil, errors.Wrapf(err, "choose secure random inserted char #%v", i)
+		}
+
+		block[i] = c
+	}
+
+	for i := range classes {
+		classes[i] = 0
+	}
+
+	return block, nil
+}
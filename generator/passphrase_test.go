@@ -0,0 +1,216 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestGenerateWordsMatchesPassphrase(t *testing.T) {
+	g, err := NewPassphraseGenerator(6, WordlistEFFShort(), " ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.rnd = randSource{reader: testSource(t)}
+	pw, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same randomness picks the same words.
+	g.rnd = randSource{reader: testSource(t)}
+	words, err := g.GenerateWords(6, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(words, " "); got != string(pw) {
+		t.Errorf("GenerateWords = %q, want the words of the passphrase %q", got, pw)
+	}
+}
+
+func TestGenerateWordsUnique(t *testing.T) {
+	words := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+	g, err := NewPassphraseGenerator(1, words, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.rnd = randSource{reader: testSource(t)}
+
+	// The first of all 8 unique words must still be uniform.
+	firsts := make([]int, len(words))
+	for i := 0; i < 8000; i++ {
+		got, err := g.GenerateWords(uint32(len(words)), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		seen := make(map[string]bool)
+		for _, w := range got {
+			if seen[w] {
+				t.Fatalf("%q has %q more than once", got, w)
+			}
+
+			seen[w] = true
+		}
+
+		firsts[strings.Index("abcdefgh", got[0])]++
+	}
+
+	if x := chiSquare(firsts); x > chiSquareLimit(len(words)) {
+		t.Errorf("first words %v are not uniform (chi-square %.1f)", firsts, x)
+	}
+
+	if _, err := g.GenerateWords(uint32(len(words))+1, true); err == nil {
+		t.Error("GenerateWords chose more unique words than there are")
+	}
+
+	if got, err := g.GenerateWords(20, false); err != nil || len(got) != 20 {
+		t.Errorf("GenerateWords(20, false) = %v, %v, want 20 words", got, err)
+	}
+}
+
+func TestWordsEntropy(t *testing.T) {
+	g, err := NewPassphraseGenerator(1, WordlistEFFLong(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := g.WordsEntropy(5, false), 5*math.Log2(7776); math.Abs(got-want) > 1e-9 {
+		t.Errorf("5 words: %v bits, want %v", got, want)
+	}
+
+	want := math.Log2(7776) + math.Log2(7775) + math.Log2(7774)
+	if got := g.WordsEntropy(3, true); math.Abs(got-want) > 1e-9 {
+		t.Errorf("3 unique words: %v bits, want %v", got, want)
+	}
+}
+
+// The EFF long list has 7776 words, 12.92 bits each. The entropy is not
+// rounded down to whole bits. No word has a space, where "-" would drop
+// e.g. drop-down.
+func TestEFFLongEntropy(t *testing.T) {
+	for _, tc := range []struct {
+		words  uint32
+		casing PassphraseCasing
+		want   string
+	}{
+		{1, CasingLower, "12.92"},
+		{3, CasingLower, "38.77"},
+		{4, CasingLower, "51.70"},
+		{5, CasingLower, "64.62"},
+		{6, CasingLower, "77.55"},
+		{7, CasingLower, "90.47"},
+		{10, CasingLower, "129.25"},
+		{6, CasingTitle, "77.55"},
+		{6, CasingCapitalizeOne, "80.13"},
+	} {
+		g, err := NewPassphraseGenerator(tc.words, WordlistEFFLong(), " ", WithCasing(tc.casing))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := fmt.Sprintf("%.2f", g.Entropy()); got != tc.want {
+			t.Errorf("%v words, casing %v: got %v bits, want %v", tc.words, tc.casing, got, tc.want)
+		}
+	}
+
+	if got := fmt.Sprintf("%.2f", BitsPerWord(len(WordlistEFFLong()))); got != "12.92" {
+		t.Errorf("got %v bits per word, want 12.92", got)
+	}
+}
+
+func TestClassCountsGenerate(t *testing.T) {
+	var words []string
+	for a := 'a'; a <= 'z'; a++ {
+		for b := 'a'; b <= 'z'; b++ {
+			words = append(words, string([]rune{a, b, 'x'}))
+		}
+	}
+
+	g, err := NewPassphraseGenerator(4, words, " ", WithClassCounts(2, 3, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.rnd = randSource{reader: testSource(t)}
+
+	// capitals and blocks count how often each word was capitalized and had
+	// the inserted block after it.
+	capitals, blocks := make([]int, 4), make([]int, 4)
+	for i := 0; i < 4000; i++ {
+		b, err := g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var upper, digit, special int
+		for _, c := range string(b) {
+			switch {
+			case unicode.IsUpper(c):
+				upper++
+			case unicode.IsDigit(c):
+				digit++
+			case c != ' ' && !unicode.IsLower(c):
+				special++
+			}
+		}
+
+		if upper != 2 || digit != 3 || special != 1 {
+			t.Fatalf("%q has %v uppercase, %v digit and %v special characters, want 2, 3 and 1", b, upper, digit, special)
+		}
+
+		fields := strings.Fields(string(b))
+		if len(fields) != 4 {
+			t.Fatalf("%q doesn't have 4 words", b)
+		}
+
+		for j, f := range fields {
+			if unicode.IsUpper(rune(f[0])) {
+				capitals[j]++
+			}
+
+			if len(f) != 3 {
+				blocks[j]++
+			}
+
+			if !slices.Contains(words, strings.ToLower(f[:3])) {
+				t.Fatalf("%q: word %q isn't intact", b, f)
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		counts []int
+	}{
+		{"capitalized words", capitals},
+		{"block positions", blocks},
+	} {
+		if chi := chiSquare(tc.counts); chi > chiSquareLimit(len(tc.counts)) {
+			t.Errorf("%v are not uniform: %v, chi-square %v", tc.name, tc.counts, chi)
+		}
+	}
+}
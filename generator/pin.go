@@ -0,0 +1,67 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// MaxPINLength is the longest PIN PINGenerator generates.
+const MaxPINLength = 64
+
+// PINGenerator generates numeric PINs, every digit chosen uniformly and
+// independently. Unlike a Generator with only digits, it doesn't start from
+// a letter base, so its entropy is exactly log2(10) bits per digit.
+type PINGenerator struct {
+	length uint32
+	rnd    randSource
+}
+
+func NewPINGenerator(length uint32) (*PINGenerator, error) {
+	if length == 0 {
+		return nil, fmt.Errorf("length must be at least 1")
+	}
+
+	if length > MaxPINLength {
+		return nil, fmt.Errorf("length (%v) exceeds the maximum PIN length of %v", length, MaxPINLength)
+	}
+
+	return &PINGenerator{length: length}, nil
+}
+
+func (g *PINGenerator) Entropy() float64 {
+	return float64(g.length) * math.Log2(float64(len(digitCharset)))
+}
+
+func (g *PINGenerator) Generate() ([]byte, error) {
+	ret := make([]byte, g.length)
+
+	for i := uint32(0); i < g.length; i++ {
+		c, err := g.rnd.pick("pin digit", i, digitCharset)
+		if err != nil {
+			wipe(ret)
+			return nil, errors.Wrapf(err, "generate secure random digit #%v", i)
+		}
+
+		ret[i] = c
+	}
+
+	return ret, nil
+}
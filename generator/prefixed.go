@@ -0,0 +1,155 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// base62Alphabet is the alphabet of the body and the checksum of prefixed
+// tokens.
+var base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// PrefixedTokenChecksumLength is the length of the checksum of a prefixed
+// token, enough for any CRC32 in base62.
+const PrefixedTokenChecksumLength = 6
+
+// DefaultPrefixedTokenLength is the default body length of prefixed tokens,
+// the same as GitHub's, for 178 bits.
+const DefaultPrefixedTokenLength = 30
+
+// MaxTokenPrefixLength is the longest prefix of a prefixed token.
+const MaxTokenPrefixLength = 32
+
+// checkTokenPrefix checks that prefix is made of ASCII letters, digits, and
+// underscores, so that the tokens can be double-clicked and matched by
+// secret scanners.
+func checkTokenPrefix(prefix string) error {
+	if prefix == "" || len(prefix) > MaxTokenPrefixLength {
+		return fmt.Errorf("prefix must be between 1 and %v characters", MaxTokenPrefixLength)
+	}
+
+	for i := 0; i < len(prefix); i++ {
+		c := prefix[i]
+		if c != '_' && (c < '0' || c > '9') && (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') {
+			return fmt.Errorf("prefix %q may only contain ASCII letters, digits, and underscores", prefix)
+		}
+	}
+
+	return nil
+}
+
+// GeneratePrefixedToken returns a token of prefix, bodyLength base62
+// characters chosen uniformly, and the CRC32 of the body in base62, padded
+// to PrefixedTokenChecksumLength characters with leading zeros. e.g.
+// "ghp_" and 30 characters like GitHub's tokens. The checksum lets services
+// reject mistyped tokens without looking them up. See
+// PrefixedTokenEntropy.
+func GeneratePrefixedToken(prefix string, bodyLength int) ([]byte, error) {
+	err := checkTokenPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if bodyLength < 1 || bodyLength > MaxTokenLength {
+		return nil, fmt.Errorf("body length must be between 1 and %v", MaxTokenLength)
+	}
+
+	ret := make([]byte, len(prefix)+bodyLength+PrefixedTokenChecksumLength)
+	copy(ret, prefix)
+
+	body := ret[len(prefix) : len(prefix)+bodyLength]
+	for i := range body {
+		c, err := randSource{}.pick("prefixed token char", uint32(i), base62Alphabet)
+		if err != nil {
+			wipe(ret)
+			return nil, errors.Wrapf(err, "generate secure random token char #%v", i)
+		}
+
+		body[i] = c
+	}
+
+	appendBase62Checksum(ret[len(prefix)+bodyLength:len(prefix)+bodyLength], body)
+
+	return ret, nil
+}
+
+// appendBase62Checksum appends the CRC32 of body in base62 to dst, which
+// must have room for it.
+func appendBase62Checksum(dst []byte, body []byte) []byte {
+	sum := crc32.ChecksumIEEE(body)
+
+	var digits [PrefixedTokenChecksumLength]byte
+	for i := len(digits) - 1; i >= 0; i-- {
+		digits[i] = base62Alphabet[sum%62]
+		sum /= 62
+	}
+
+	return append(dst, digits[:]...)
+}
+
+// PrefixedTokenEntropy returns the entropy of a token from
+// GeneratePrefixedToken. Only the body counts: the prefix is fixed and the
+// checksum follows from the body.
+func PrefixedTokenEntropy(bodyLength int) float64 {
+	return float64(bodyLength) * math.Log2(float64(len(base62Alphabet)))
+}
+
+// ErrTokenChecksum is returned, wrapped, by VerifyPrefixedToken when the
+// checksum doesn't match the body, e.g. because of a typo.
+var ErrTokenChecksum = fmt.Errorf("checksum mismatch")
+
+// VerifyPrefixedToken checks that token was made by GeneratePrefixedToken
+// with prefix: that it starts with prefix, that the body and the checksum
+// are base62, and that the checksum matches the body. Any single changed
+// character is detected.
+func VerifyPrefixedToken(token []byte, prefix string) error {
+	err := checkTokenPrefix(prefix)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.HasPrefix(token, []byte(prefix)) {
+		return fmt.Errorf("the token doesn't start with %q", prefix)
+	}
+
+	rest := token[len(prefix):]
+	if len(rest) <= PrefixedTokenChecksumLength {
+		return fmt.Errorf("the token is too short for a body and a %v-character checksum", PrefixedTokenChecksumLength)
+	}
+
+	for i, c := range rest {
+		if bytes.IndexByte([]byte(base62Alphabet), c) == -1 {
+			return fmt.Errorf("character #%v after the prefix is not base62", i+1)
+		}
+	}
+
+	body := rest[:len(rest)-PrefixedTokenChecksumLength]
+
+	var want [PrefixedTokenChecksumLength]byte
+	appendBase62Checksum(want[:0], body)
+	if !bytes.Equal(want[:], rest[len(body):]) {
+		return ErrTokenChecksum
+	}
+
+	return nil
+}
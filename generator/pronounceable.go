@@ -0,0 +1,294 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MaxSyllablePatternLength is the longest syllable pattern, separators
+// included.
+const MaxSyllablePatternLength = 64
+
+// syllableSeparators are the characters a syllable pattern may contain
+// between its slots. They are copied to the password as they are.
+const syllableSeparators = "-._"
+
+const vowelLetters = "aeiou"
+
+// Phonemes are the letter groups the slots of a syllable pattern expand to:
+// C to one of Consonants, V to one of Vowels, O to one of Onsets, the
+// consonant clusters that start a syllable, and K to one of Codas, the ones
+// that end it. Consonants and vowels are single letters, onsets and codas
+// one or more consonants. A group that is listed more than once is chosen
+// that much more often.
+type Phonemes struct {
+	Consonants []string
+	Vowels     []string
+	Onsets     []string
+	Codas      []string
+}
+
+// SyllablePattern is a named syllable pattern with the phonemes it is made
+// of.
+type SyllablePattern struct {
+	Name        string
+	Description string
+	Pattern     string
+	Phonemes    Phonemes
+}
+
+var simplePhonemes = Phonemes{
+	Consonants: strings.Split("b d f g h k l m n p r s t v z", " "),
+	Vowels:     strings.Split(vowelLetters, ""),
+}
+
+// SyllablePatterns are the named patterns WithSyllablePattern knows of. The
+// first one is the default.
+var SyllablePatterns = []SyllablePattern{
+	{
+		Name:        "simple",
+		Description: "alternating consonants and vowels",
+		Pattern:     "CVCVC-CVCVC-CVCVC-CVCVC",
+		Phonemes:    simplePhonemes,
+	},
+	{
+		Name:        "germanic",
+		Description: "syllables with consonant clusters, weighted towards common letters",
+		Pattern:     "OVK-OVK-OVK-OVK-OVK-OVK",
+		Phonemes: Phonemes{
+			Vowels: strings.Split("a a e e e i i o u", " "),
+			Onsets: strings.Split("b d f g h k l m n p r r s s t t w z bl br dr fl fr gl gr kl kr pl pr schl schr schw sp st str tr zw", " "),
+			Codas:  strings.Split("d f g k l m n n r r s s t t ch ck ft ld lt nd ng nk nt rd rk rm rn rt sch st tz", " "),
+		},
+	},
+	{
+		Name:        "japanese-romaji",
+		Description: "open syllables like romanized Japanese",
+		Pattern:     "OVOV-OVOV-OVOV-OVOV-OVOV",
+		Phonemes: Phonemes{
+			Vowels: strings.Split(vowelLetters, ""),
+			Onsets: strings.Split("k s t n h m r g z d b p sh ch ts ky ny ry j", " "),
+		},
+	},
+}
+
+// PronounceableGenerator generates passwords that can be read out and
+// remembered more easily, made of syllables that follow a pattern.
+type PronounceableGenerator struct {
+	pattern  string
+	phonemes *Phonemes
+	slots    [][]string
+	rnd      randSource
+}
+
+type PronounceableOption func(*PronounceableGenerator)
+
+// WithSyllablePattern sets the pattern of the passwords, either the name of
+// one of SyllablePatterns, or a pattern of the slots C, V, O, and K, with the
+// separators - . and _ between them, e.g. "CVC-CVVC-CVC". A pattern that is
+// not a name expands to the phonemes of the first named pattern.
+func WithSyllablePattern(p string) PronounceableOption {
+	return func(g *PronounceableGenerator) {
+		g.pattern = p
+	}
+}
+
+// WithPhonemes replaces the phonemes the slots of the pattern expand to.
+func WithPhonemes(p Phonemes) PronounceableOption {
+	return func(g *PronounceableGenerator) {
+		g.phonemes = &p
+	}
+}
+
+func NewPronounceableGenerator(opts ...PronounceableOption) (*PronounceableGenerator, error) {
+	g := &PronounceableGenerator{pattern: SyllablePatterns[0].Name}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	pattern := g.pattern
+	phonemes := simplePhonemes
+	for _, sp := range SyllablePatterns {
+		if sp.Name == g.pattern {
+			pattern, phonemes = sp.Pattern, sp.Phonemes
+			break
+		}
+	}
+
+	if g.phonemes != nil {
+		phonemes = *g.phonemes
+	}
+
+	err := phonemes.validate()
+	if err != nil {
+		return nil, errors.Wrap(err, "validate phonemes")
+	}
+
+	g.slots, err = parseSyllablePattern(pattern, phonemes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse syllable pattern %q", pattern)
+	}
+
+	return g, nil
+}
+
+func (p *Phonemes) validate() error {
+	for _, set := range []struct {
+		name    string
+		units   []string
+		single  bool
+		vowel   bool
+		example string
+	}{
+		{"consonant", p.Consonants, true, false, "a single consonant"},
+		{"vowel", p.Vowels, true, true, "a single vowel"},
+		{"onset", p.Onsets, false, false, "one or more consonants"},
+		{"coda", p.Codas, false, false, "one or more consonants"},
+	} {
+		for _, u := range set.units {
+			ok := u != "" && (!set.single || len(u) == 1)
+			for _, c := range u {
+				if c < 'a' || c > 'z' || strings.ContainsRune(vowelLetters, c) != set.vowel {
+					ok = false
+				}
+			}
+
+			if !ok {
+				return fmt.Errorf("%v %q is not %v", set.name, u, set.example)
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseSyllablePattern expands every slot of the pattern to the phonemes it
+// chooses from, and every separator to itself.
+//
+// Slots next to each other must not both expand to consonants, or both to
+// vowels, if one of them has groups of several letters. Otherwise, "st" and
+// "r" could produce the same letters as "s" and "tr", and the entropy would be
+// overstated.
+func parseSyllablePattern(pattern string, p Phonemes) ([][]string, error) {
+	if len(pattern) > MaxSyllablePatternLength {
+		return nil, fmt.Errorf("the pattern is longer than %v characters", MaxSyllablePatternLength)
+	}
+
+	var slots [][]string
+	hasSlot := false
+	prev := byte(0)
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+
+		var units []string
+		switch c {
+		case 'C':
+			units = p.Consonants
+		case 'V':
+			units = p.Vowels
+		case 'O':
+			units = p.Onsets
+		case 'K':
+			units = p.Codas
+		default:
+			if !strings.ContainsRune(syllableSeparators, rune(c)) {
+				return nil, fmt.Errorf("unknown slot %q at position %v (use C, V, O, K, and the separators %v)", c, i+1, syllableSeparators)
+			}
+
+			slots = append(slots, []string{string(c)})
+			prev = 0
+
+			continue
+		}
+
+		if len(units) == 0 {
+			return nil, fmt.Errorf("slot %c at position %v has no phonemes to choose from", c, i+1)
+		}
+
+		// C and V are single letters, O and K are clusters.
+		if prev != 0 && (prev == 'V') == (c == 'V') && (prev == 'O' || prev == 'K' || c == 'O' || c == 'K') {
+			return nil, fmt.Errorf("slots %c and %c at positions %v and %v could run into each other, put a V or a separator between them", prev, c, i, i+1)
+		}
+
+		slots = append(slots, units)
+		hasSlot = true
+		prev = c
+	}
+
+	if !hasSlot {
+		return nil, fmt.Errorf("the pattern has no slots")
+	}
+
+	return slots, nil
+}
+
+// Entropy returns the min-entropy of the passwords. For a slot whose
+// phonemes are weighted, it counts the most likely phoneme, so that a weight
+// never overstates it.
+func (g *PronounceableGenerator) Entropy() float64 {
+	var bits float64
+	for _, units := range g.slots {
+		counts := make(map[string]int, len(units))
+		most := 0
+		for _, u := range units {
+			counts[u]++
+			most = max(most, counts[u])
+		}
+
+		bits += math.Log2(float64(len(units)) / float64(most))
+	}
+
+	return bits
+}
+
+func (g *PronounceableGenerator) Generate() ([]byte, error) {
+	// The buffer is big enough for the longest password, so that appending
+	// never leaves a copy of its start behind.
+	size := 0
+	for _, units := range g.slots {
+		longest := 0
+		for _, u := range units {
+			longest = max(longest, len(u))
+		}
+
+		size += longest
+	}
+
+	ret := make([]byte, 0, size)
+
+	for i, units := range g.slots {
+		if len(units) == 1 {
+			ret = append(ret, units[0]...)
+			continue
+		}
+
+		n, err := g.rnd.intn("syllable slot", uint32(i), uint32(len(units)))
+		if err != nil {
+			wipe(ret[:cap(ret)])
+			return nil, errors.Wrapf(err, "generate secure random phoneme #%v", i)
+		}
+
+		ret = append(ret, units[n]...)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,207 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSyllablePatternEntropy(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		want    float64
+	}{
+		// 4 x (3 x log2(15) + 2 x log2(5))
+		{"simple", 65.4581},
+		// 6 x log2(37/2 x 9/3 x 31/2), as the most likely onsets and codas
+		// are listed twice, and e three times.
+		{"germanic", 58.4916},
+		// 10 x log2(19 x 5)
+		{"japanese-romaji", 65.6985},
+		{"CV", 6.2288},
+		{"CVC-CVVC-CVC", 32.7291},
+		{"V.V_V", 6.9658},
+	} {
+		g, err := NewPronounceableGenerator(WithSyllablePattern(tc.pattern))
+		if err != nil {
+			t.Fatalf("%v: %v", tc.pattern, err)
+		}
+
+		if math.Abs(g.Entropy()-tc.want) > 1e-4 {
+			t.Errorf("%v: got %.4f bits, want %.4f", tc.pattern, g.Entropy(), tc.want)
+		}
+	}
+
+	g, err := NewPronounceableGenerator()
+	if err != nil || math.Abs(g.Entropy()-65.4581) > 1e-4 {
+		t.Errorf("default: got %v, %v", g, err)
+	}
+}
+
+// phonemeRegexp matches the passwords of a pattern of the default phonemes.
+func phonemeRegexp(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	for _, c := range pattern {
+		switch c {
+		case 'C':
+			sb.WriteString("[" + strings.Join(simplePhonemes.Consonants, "") + "]")
+		case 'V':
+			sb.WriteString("[" + vowelLetters + "]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return regexp.MustCompile("^" + sb.String() + "$")
+}
+
+func TestPronounceableGenerate(t *testing.T) {
+	for _, sp := range SyllablePatterns {
+		g, err := NewPronounceableGenerator(WithSyllablePattern(sp.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g.rnd = randSource{reader: testSource(t)}
+
+		for i := 0; i < 1000; i++ {
+			b, err := g.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			groups := strings.Split(string(b), "-")
+			if len(groups) != strings.Count(sp.Pattern, "-")+1 {
+				t.Fatalf("%v: %q has the wrong number of groups", sp.Name, b)
+			}
+
+			if sp.Name == "simple" && !phonemeRegexp(sp.Pattern).Match(b) {
+				t.Fatalf("%v: %q doesn't follow the pattern", sp.Name, b)
+			}
+		}
+	}
+
+	// Every pair of a consonant and a vowel is equally likely.
+	g, err := NewPronounceableGenerator(WithSyllablePattern("CV"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.rnd = randSource{reader: testSource(t)}
+
+	pairs := make(map[string]int)
+	for i := 0; i < 75*200; i++ {
+		b, err := g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pairs[string(b)]++
+	}
+
+	counts := make([]int, 0, len(pairs))
+	for _, n := range pairs {
+		counts = append(counts, n)
+	}
+
+	if len(counts) != 75 {
+		t.Errorf("got %v pairs, want 75", len(counts))
+	}
+
+	if x := chiSquare(counts); x > chiSquareLimit(75) {
+		t.Errorf("chi-square %.2f exceeds %.2f", x, chiSquareLimit(75))
+	}
+}
+
+func TestPronounceableWeights(t *testing.T) {
+	g, err := NewPronounceableGenerator(WithSyllablePattern("V"), WithPhonemes(Phonemes{Vowels: []string{"a", "a", "a", "e"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The min-entropy of the most likely vowel, log2(4/3).
+	if math.Abs(g.Entropy()-math.Log2(4.0/3)) > 1e-9 {
+		t.Errorf("got %v bits", g.Entropy())
+	}
+
+	g.rnd = randSource{reader: testSource(t)}
+
+	a := 0
+	const n = 20000
+	for i := 0; i < n; i++ {
+		b, err := g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(b) == "a" {
+			a++
+		}
+	}
+
+	// Within about 5 standard deviations of 3/4.
+	if math.Abs(float64(a)/n-0.75) > 0.016 {
+		t.Errorf("a was chosen %v times out of %v", a, n)
+	}
+}
+
+func TestPronounceableErrors(t *testing.T) {
+	for _, tc := range []struct {
+		pattern  string
+		phonemes *Phonemes
+		want     string
+	}{
+		{"", nil, "the pattern has no slots"},
+		{"---", nil, "the pattern has no slots"},
+		{"CVX", nil, "unknown slot 'X' at position 3"},
+		{"cv", nil, "unknown slot 'c' at position 1"},
+		{strings.Repeat("CV", 33), nil, "longer than 64 characters"},
+		{"CVO", nil, "slot O at position 3 has no phonemes to choose from"},
+		{"germanic-OK", nil, "unknown slot 'g'"},
+		{"OVKO", &SyllablePatterns[1].Phonemes, "slots K and O at positions 3 and 4 could run into each other"},
+		{"OC", &Phonemes{Consonants: []string{"b"}, Onsets: []string{"st"}}, "slots O and C at positions 1 and 2"},
+		{"CV", &Phonemes{Consonants: []string{"bl"}, Vowels: []string{"a"}}, "consonant \"bl\" is not a single consonant"},
+		{"CV", &Phonemes{Consonants: []string{"a"}, Vowels: []string{"a"}}, "consonant \"a\" is not a single consonant"},
+		{"CV", &Phonemes{Consonants: []string{"b"}, Vowels: []string{"B"}}, "vowel \"B\" is not a single vowel"},
+		{"OV", &Phonemes{Onsets: []string{""}, Vowels: []string{"a"}}, "onset \"\" is not one or more consonants"},
+		{"OV", &Phonemes{Onsets: []string{"sta"}, Vowels: []string{"a"}}, "onset \"sta\" is not one or more consonants"},
+	} {
+		opts := []PronounceableOption{WithSyllablePattern(tc.pattern)}
+		if tc.phonemes != nil {
+			opts = append(opts, WithPhonemes(*tc.phonemes))
+		}
+
+		_, err := NewPronounceableGenerator(opts...)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: got %v, want an error containing %q", tc.pattern, err, tc.want)
+		}
+	}
+
+	// Vowels are single letters, so they may follow each other, and so may
+	// consonants.
+	germanic := SyllablePatterns[1].Phonemes
+	germanic.Consonants = simplePhonemes.Consonants
+	for _, pattern := range []string{"VV", "CC", "OVV", "KVO"} {
+		_, err := NewPronounceableGenerator(WithSyllablePattern(pattern), WithPhonemes(germanic))
+		if err != nil {
+			t.Errorf("%q: %v", pattern, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+// ProblematicChars are the special characters that most often break a
+// password pasted into a config file, a shell command or an SQL string:
+// quotes, backslash, and the characters that start comments, variable
+// references or new statements.
+const ProblematicChars = "'\"`\\$#;&"
+
+// quoteSafeSpecial leaves out ProblematicChars as well as the other
+// separator and escape characters of YAML, .env, INI, JSON and SQL, such as
+// =, :, % and the brackets.
+const quoteSafeSpecial = "!*+-./?@^_~"
+
+// QuoteSafeCharset is DefaultCharset with only the special characters that
+// can be pasted into config files, JSON and SQL strings without quoting or
+// escaping.
+var QuoteSafeCharset = Charset{
+	Name:        "quote-safe",
+	Description: "The default letters and digits, and special characters inert in config files and SQL",
+
+	Letters:   letterCharset,
+	Uppercase: true,
+	Digits:    digitCharset,
+	Special:   quoteSafeSpecial,
+}
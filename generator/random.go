@@ -0,0 +1,321 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// TraceEvent describes a single consumption of randomness.
+type TraceEvent struct {
+	// Purpose is what the random value was drawn for, e.g. "base char".
+	Purpose string `json:"purpose"`
+	// Index is the position or ordinal the value was drawn for.
+	Index uint32 `json:"index"`
+	// Bytes is the number of random bytes read to produce the value.
+	Bytes int `json:"bytes"`
+	// Bound is the exclusive upper bound of the drawn value, or 0 if it
+	// doesn't fit in 32 bits.
+	Bound uint32 `json:"bound"`
+	// Choice is the drawn value, or 0 if the bound doesn't fit in 32 bits.
+	Choice uint32 `json:"choice"`
+}
+
+type Tracer func(TraceEvent)
+
+// ErrEntropyUnavailable is returned, wrapped, when the source of randomness
+// fails. The generator never falls back to another source. Every buffer it
+// has filled so far is wiped and no password is returned. Check for it with
+// errors.Is.
+var ErrEntropyUnavailable = fmt.Errorf("entropy unavailable")
+
+// entropyError is a failed read from the source of randomness.
+type entropyError struct {
+	err error
+}
+
+func (e entropyError) Error() string {
+	return ErrEntropyUnavailable.Error() + ": " + e.err.Error()
+}
+
+func (e entropyError) Unwrap() error {
+	return e.err
+}
+
+func (e entropyError) Is(target error) bool {
+	return target == ErrEntropyUnavailable
+}
+
+// randSource is the single path all randomness in the package is drawn
+// through, so that every draw can be traced and the source can be swapped.
+type randSource struct {
+	tracer Tracer
+	// reader is the source of random bytes, or nil for crypto/rand.
+	reader io.Reader
+	// buf buffers crypto/rand, or is nil for the shared randBuf.
+	buf *randBuffer
+}
+
+// read fills p with random bytes. On error, p is wiped.
+func (r randSource) read(p []byte) error {
+	var err error
+	switch {
+	case r.reader == nil && r.buf != nil:
+		err = r.buf.read(p)
+	case r.reader == nil:
+		err = randBuf.read(p)
+	default:
+		// Reading through a copy keeps p from escaping to the heap, so
+		// that the draws from crypto/rand don't allocate.
+		tmp := make([]byte, len(p))
+		_, err = io.ReadFull(r.reader, tmp)
+		copy(p, tmp)
+		wipe(tmp)
+		err = errors.Wrap(err, "random-read")
+	}
+
+	if err != nil {
+		wipe(p)
+		return entropyError{err: err}
+	}
+
+	return nil
+}
+
+func (r randSource) trace(purpose string, index uint32, bytes int, bound, choice uint32) {
+	if r.tracer == nil {
+		return
+	}
+
+	r.tracer(TraceEvent{
+		Purpose: purpose,
+		Index:   index,
+		Bytes:   bytes,
+		Bound:   bound,
+		Choice:  choice,
+	})
+}
+
+// intn returns a uniformly distributed integer in [0, n).
+func (r randSource) intn(purpose string, index uint32, n uint32) (uint32, error) {
+	v, bytes, err := r.uint32n(n)
+	if err != nil {
+		return 0, err
+	}
+
+	r.trace(purpose, index, bytes, n, v)
+
+	return v, nil
+}
+
+// bigIntn returns a uniformly distributed integer in [0, n) for bounds that
+// may not fit in 32 bits. Draws of n's bit length at or above n are rejected.
+func (r randSource) bigIntn(purpose string, index uint32, n *big.Int) (*big.Int, error) {
+	if n.Sign() <= 0 {
+		return nil, fmt.Errorf("bound must be greater than zero")
+	}
+
+	if n.IsUint64() && n.Uint64() <= math.MaxUint32 {
+		v, err := r.intn(purpose, index, uint32(n.Uint64()))
+		if err != nil {
+			return nil, err
+		}
+
+		return big.NewInt(int64(v)), nil
+	}
+
+	buf := make([]byte, (n.BitLen()+7)/8)
+	defer wipe(buf)
+
+	topBits := n.BitLen() % 8
+	v := new(big.Int)
+	for bytes := len(buf); ; bytes += len(buf) {
+		err := r.read(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		if topBits != 0 {
+			buf[0] &= 1<<topBits - 1
+		}
+
+		if v.SetBytes(buf).Cmp(n) < 0 {
+			r.trace(purpose, index, bytes, 0, 0)
+			return v, nil
+		}
+	}
+}
+
+// pick returns a uniformly chosen character from charset.
+func (r randSource) pick(purpose string, index uint32, charset string) (byte, error) {
+	pos, err := r.intn(purpose, index, uint32(len(charset)))
+	if err != nil {
+		return 0, err
+	}
+
+	return charset[pos], nil
+}
+
+// char returns a uniformly chosen character from charset. Bytes at or above
+// the largest multiple of len(charset) that fits in a byte are rejected, as
+// taking them modulo len(charset) would favor the first characters. Charsets
+// of more than 256 characters, which a byte can't pick from, are drawn with
+// intn instead.
+func (r randSource) char(purpose string, index uint32, charset []rune) (rune, error) {
+	if len(charset) == 0 {
+		return 0, fmt.Errorf("charset must not be empty")
+	}
+
+	if len(charset) > 256 {
+		pos, err := r.intn(purpose, index, uint32(len(charset)))
+		if err != nil {
+			return 0, err
+		}
+
+		return charset[pos], nil
+	}
+
+	limit := 256 - 256%len(charset)
+
+	var total int
+	for {
+		b, bytes, err := r.readByte()
+		if err != nil {
+			return 0, errors.Wrap(err, "get secure random byte")
+		}
+
+		total += bytes
+
+		if int(b) < limit {
+			pos := int(b) % len(charset)
+			r.trace(purpose, index, total, uint32(len(charset)), uint32(pos))
+
+			return charset[pos], nil
+		}
+	}
+}
+
+const randBufferSize = 4096
+
+// randBuffer reads from crypto/rand in blocks, so that a password doesn't take
+// a system call per character. Bytes are zeroed as soon as they are handed
+// out, so the buffer only ever holds randomness that has not been used yet.
+type randBuffer struct {
+	mu  sync.Mutex
+	buf [randBufferSize]byte
+	pos int
+	n   int
+}
+
+var randBuf randBuffer
+
+func (r *randBuffer) read(p []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for len(p) != 0 {
+		if r.pos == r.n {
+			r.pos, r.n = 0, 0
+
+			_, err := io.ReadFull(rand.Reader, r.buf[:])
+			if err != nil {
+				// Don't leave a partial read behind.
+				r.wipe()
+				return errors.Wrap(err, "random-read")
+			}
+
+			r.n = len(r.buf)
+		}
+
+		n := copy(p, r.buf[r.pos:r.n])
+		for i := r.pos; i < r.pos+n; i++ {
+			r.buf[i] = 0
+		}
+
+		r.pos += n
+		p = p[n:]
+	}
+
+	return nil
+}
+
+func (r *randBuffer) wipe() {
+	for i := range r.buf {
+		r.buf[i] = 0
+	}
+
+	r.pos, r.n = 0, 0
+}
+
+// DiscardBufferedRandomness wipes the random bytes read ahead of time, e.g.
+// before the process exits, so no future draws are left in memory. Later
+// draws read from crypto/rand again.
+func DiscardBufferedRandomness() {
+	randBuf.mu.Lock()
+	defer randBuf.mu.Unlock()
+
+	randBuf.wipe()
+}
+
+// readByte returns a single random byte, and the number of bytes read, which is
+// always 1.
+func (r randSource) readByte() (byte, int, error) {
+	var buf [1]byte
+	defer wipe(buf[:])
+
+	err := r.read(buf[:])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return buf[0], len(buf), nil
+}
+
+// uint32n returns a uniformly distributed integer in [0, n) and
+// the number of random bytes read to produce it. Draws at or above the
+// largest multiple of n that fits in 32 bits are rejected to avoid modulo
+// bias.
+func (r randSource) uint32n(n uint32) (uint32, int, error) {
+	if n == 0 {
+		return 0, 0, fmt.Errorf("bound must be greater than zero")
+	}
+
+	limit := (1 << 32) - (1<<32)%uint64(n)
+
+	var buf [4]byte
+	defer wipe(buf[:])
+
+	for bytes := len(buf); ; bytes += len(buf) {
+		err := r.read(buf[:])
+		if err != nil {
+			return 0, 0, err
+		}
+
+		v := binary.BigEndian.Uint32(buf[:])
+		if uint64(v) < limit {
+			return v % n, bytes, nil
+		}
+	}
+}
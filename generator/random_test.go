@@ -0,0 +1,331 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"testing/iotest"
+)
+
+// testSource returns a deterministic source seeded with the name of the
+// test, so that the statistical tests give the same result on every run.
+func testSource(t testing.TB) io.Reader {
+	src, err := NewDeterministicSource([]byte(t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return src
+}
+
+// chiSquare returns the chi-square statistic of counts against the uniform
+// distribution.
+func chiSquare(counts []int) float64 {
+	var total int
+	for _, c := range counts {
+		total += c
+	}
+
+	expected := float64(total) / float64(len(counts))
+
+	var x float64
+	for _, c := range counts {
+		d := float64(c) - expected
+		x += d * d / expected
+	}
+
+	return x
+}
+
+// chiSquareLimit returns the value the chi-square statistic of a uniform
+// distribution over k categories only exceeds with a probability of about
+// one in a million, using the Wilson-Hilferty approximation.
+func chiSquareLimit(k int) float64 {
+	const z = 4.75
+
+	df := float64(k - 1)
+
+	return df * math.Pow(1-2/(9*df)+z*math.Sqrt(2/(9*df)), 3)
+}
+
+func TestUint32nDistribution(t *testing.T) {
+	rnd := randSource{reader: testSource(t)}
+
+	for _, n := range []uint32{2, 3, 7, 24, 100, 1000} {
+		counts := make([]int, n)
+		for i := 0; i < 2000*int(n); i++ {
+			v, _, err := rnd.uint32n(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if v >= n {
+				t.Fatalf("uint32n(%v) returned %v", n, v)
+			}
+
+			counts[v]++
+		}
+
+		if x := chiSquare(counts); x > chiSquareLimit(int(n)) {
+			t.Errorf("uint32n(%v): chi-square statistic %.1f exceeds %.1f", n, x, chiSquareLimit(int(n)))
+		}
+	}
+}
+
+func TestUint32nRejectsBiasedDraws(t *testing.T) {
+	// 2^32 - 1 is the one value at or above the largest multiple of 3 that
+	// fits in 32 bits, so it must be drawn again.
+	rnd := randSource{reader: bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 5})}
+
+	v, n, err := rnd.uint32n(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != 2 || n != 8 {
+		t.Errorf("got %v after reading %v bytes, want 2 after 8", v, n)
+	}
+}
+
+func TestUint32nBounds(t *testing.T) {
+	rnd := randSource{reader: testSource(t)}
+
+	for i := 0; i < 100; i++ {
+		v, err := rnd.intn("test", 0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v != 0 {
+			t.Fatalf("intn(1) returned %v", v)
+		}
+	}
+
+	if _, err := rnd.intn("test", 0, 0); err == nil {
+		t.Error("intn(0) succeeded")
+	}
+}
+
+func BenchmarkUint32n(b *testing.B) {
+	var rnd randSource
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_, _, err := rnd.uint32n(24)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGenerate reports the allocations per password: the characters,
+// the positions and the result, however many draws they take.
+func BenchmarkGenerate(b *testing.B) {
+	g, err := NewGenerator(24, 2, 2, 2)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		pw, err := g.Generate()
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		wipe(pw)
+	}
+}
+
+func TestCharDistribution(t *testing.T) {
+	rnd := randSource{reader: testSource(t)}
+
+	for _, charset := range []string{
+		"abcdefghijkmnpqrstuvwxyz",
+		"0123456789",
+		"!#$%&*+-=?@^_",
+		emojiChars(),
+	} {
+		chars := []rune(charset)
+		index := make(map[rune]int, len(chars))
+		for i, c := range chars {
+			index[c] = i
+		}
+
+		counts := make([]int, len(chars))
+		for i := 0; i < 300000; i++ {
+			c, err := rnd.char("test", 0, chars)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pos, ok := index[c]
+			if !ok {
+				t.Fatalf("char returned %q, which is not in %q", c, charset)
+			}
+
+			counts[pos]++
+		}
+
+		if x := chiSquare(counts); x > chiSquareLimit(len(chars)) {
+			t.Errorf("%q: chi-square statistic %.1f exceeds %.1f", charset, x, chiSquareLimit(len(chars)))
+		}
+	}
+}
+
+func TestCharRejectsBiasedBytes(t *testing.T) {
+	// 240 is the largest multiple of 24 that fits in a byte, so 240 and 255
+	// must be drawn again rather than taken modulo 24.
+	rnd := randSource{reader: bytes.NewReader([]byte{240, 255, 25})}
+
+	c, err := rnd.char("test", 0, []rune("abcdefghijkmnpqrstuvwxyz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c != 'b' {
+		t.Errorf("got %q, want 'b'", c)
+	}
+}
+
+// setRandReader replaces crypto/rand's reader for the rest of the test.
+func setRandReader(t *testing.T, r io.Reader) {
+	old := rand.Reader
+	rand.Reader = r
+
+	t.Cleanup(func() {
+		rand.Reader = old
+	})
+}
+
+// failingReader yields n bytes of src and then fails. It keeps every
+// buffer it was handed, so that tests can check they were wiped.
+type failingReader struct {
+	src  io.Reader
+	n    int
+	bufs [][]byte
+}
+
+var errTestRead = errors.New("test read error")
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	f.bufs = append(f.bufs, p)
+
+	if f.n == 0 {
+		return 0, errTestRead
+	}
+
+	if len(p) > f.n {
+		p = p[:f.n]
+	}
+
+	n, err := f.src.Read(p)
+	f.n -= n
+
+	return n, err
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestRandBufferPartialReads(t *testing.T) {
+	want := make([]byte, 3*randBufferSize)
+	_, err := io.ReadFull(testSource(t), want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setRandReader(t, iotest.OneByteReader(testSource(t)))
+
+	var buf randBuffer
+	got := make([]byte, 0, len(want))
+	for size := 1; len(got) < len(want); size = size*3 + 1 {
+		p := make([]byte, min(size, len(want)-len(got)))
+		err := buf.read(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got = append(got, p...)
+
+		if !isZero(buf.buf[:buf.pos]) {
+			t.Fatal("bytes handed out were left in the buffer")
+		}
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Error("the buffered bytes differ from the stream")
+	}
+}
+
+func TestRandBufferReadError(t *testing.T) {
+	setRandReader(t, &failingReader{src: testSource(t), n: randBufferSize / 2})
+
+	var buf randBuffer
+	p := make([]byte, 16)
+
+	err := randSource{buf: &buf}.read(p)
+	if !errors.Is(err, ErrEntropyUnavailable) || !errors.Is(err, errTestRead) {
+		t.Fatalf("got error %v, want the read error wrapped with ErrEntropyUnavailable", err)
+	}
+
+	if !isZero(buf.buf[:]) || !isZero(p) {
+		t.Error("the partial read was left in memory")
+	}
+
+	setRandReader(t, testSource(t))
+
+	err = buf.read(p)
+	if err != nil {
+		t.Fatalf("reading after an error: %v", err)
+	}
+}
+
+func TestDiscardBufferedRandomness(t *testing.T) {
+	setRandReader(t, testSource(t))
+	DiscardBufferedRandomness()
+
+	var p [16]byte
+	err := randBuf.read(p[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if isZero(randBuf.buf[randBuf.pos:]) {
+		t.Fatal("nothing was read ahead")
+	}
+
+	DiscardBufferedRandomness()
+
+	if !isZero(randBuf.buf[:]) {
+		t.Error("the bytes read ahead were left in memory")
+	}
+}
@@ -0,0 +1,140 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// RecoveryCodeCharset is the default charset of recovery codes: uppercase
+// letters and digits without the look-alikes 0, 1, I, L and O.
+var RecoveryCodeCharset = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// MaxRecoveryCodeLength is the longest recovery code, hyphens left out.
+const MaxRecoveryCodeLength = 64
+
+// GenerateRecoveryCodes generates n distinct one-time recovery codes of
+// groups groups of groupLen characters from charset, joined by hyphens, e.g.
+// "7KQ2-MX9D". Like GenerateMany, they are only compared by their hashes,
+// and on error, they are wiped.
+func GenerateRecoveryCodes(n, groups, groupLen int, charset string) ([][]byte, error) {
+	if n < 1 || n > MaxBatchSize {
+		return nil, fmt.Errorf("code count must be between 1 and %v", MaxBatchSize)
+	}
+
+	if groups < 1 || groupLen < 1 || groups*groupLen > MaxRecoveryCodeLength {
+		return nil, fmt.Errorf("codes must have at least one group of at least one character, and at most %v characters", MaxRecoveryCodeLength)
+	}
+
+	err := checkRecoveryCodeCharset(charset)
+	if err != nil {
+		return nil, err
+	}
+
+	combos := new(big.Int).Exp(big.NewInt(int64(len(charset))), big.NewInt(int64(groups*groupLen)), nil)
+	if combos.Cmp(big.NewInt(int64(n))) < 0 {
+		return nil, fmt.Errorf("there are fewer than %v distinct codes of %v characters from %v symbols", n, groups*groupLen, len(charset))
+	}
+
+	ret := make([][]byte, 0, n)
+	seen := make(map[[sha256.Size]byte]struct{}, n)
+
+	fail := func(err error) ([][]byte, error) {
+		for _, b := range ret {
+			wipe(b)
+		}
+
+		clear(seen)
+
+		return nil, err
+	}
+
+	for attempts := 0; len(ret) < n; attempts++ {
+		if attempts >= maxBatchAttempts(n) {
+			return fail(errTooManyAttempts)
+		}
+
+		b, err := generateRecoveryCode(groups, groupLen, charset)
+		if err != nil {
+			return fail(errors.Wrapf(err, "generate code #%v", len(ret)+1))
+		}
+
+		sum := sha256.Sum256(b)
+		if _, ok := seen[sum]; ok {
+			wipe(b)
+			continue
+		}
+
+		seen[sum] = struct{}{}
+		ret = append(ret, b)
+	}
+
+	clear(seen)
+
+	return ret, nil
+}
+
+// RecoveryCodeEntropy returns the entropy of a single recovery code.
+func RecoveryCodeEntropy(groups, groupLen int, charset string) float64 {
+	return float64(groups*groupLen) * math.Log2(float64(len(charset)))
+}
+
+func generateRecoveryCode(groups, groupLen int, charset string) ([]byte, error) {
+	ret := make([]byte, 0, groups*(groupLen+1)-1)
+	for i := 0; i < groups*groupLen; i++ {
+		if i != 0 && i%groupLen == 0 {
+			ret = append(ret, '-')
+		}
+
+		c, err := randSource{}.pick("recovery code char", uint32(i), charset)
+		if err != nil {
+			wipe(ret)
+			return nil, errors.Wrapf(err, "generate secure random code char #%v", i)
+		}
+
+		ret = append(ret, c)
+	}
+
+	return ret, nil
+}
+
+func checkRecoveryCodeCharset(charset string) error {
+	if len(charset) < 2 {
+		return fmt.Errorf("the charset must have at least two characters")
+	}
+
+	var seen [0x80]bool
+	for i := 0; i < len(charset); i++ {
+		c := charset[i]
+		if c <= ' ' || c >= 0x7f || c == '-' {
+			return fmt.Errorf("the charset has %q, but only printable ASCII characters other than space and hyphen are allowed", c)
+		}
+
+		if seen[c] {
+			return fmt.Errorf("the charset has %q more than once", c)
+		}
+
+		seen[c] = true
+	}
+
+	return nil
+}
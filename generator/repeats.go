@@ -0,0 +1,226 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+// maxRedraws bounds how many times a single character is re-drawn to satisfy
+// the repeat limit. Construction rejects infeasible limits, so this is only a
+// safety net.
+const maxRedraws = 10000
+
+func (g *Generator) classCounts() (lower, upper, digit, special uint32) {
+	return g.length - g.uppercaseCount - g.digitCount - g.specialCount, g.uppercaseCount, g.digitCount, g.specialCount
+}
+
+func (g *Generator) checkMaxRepeatsFeasible() error {
+	if g.maxRepeats == 0 {
+		return nil
+	}
+
+	upper, digit, special := g.uppercaseCount, g.digitCount, g.specialCount
+	lower := g.reservedLowercase(upper, digit, special)
+
+	checkFn := func(class string, count uint32, charset []rune) error {
+		if uint64(count) > uint64(g.maxRepeats)*uint64(len(charset)) {
+			return fmt.Errorf("%v %v characters from %v possible characters cannot be generated with at most %v repeats per character", count, class, len(charset), g.maxRepeats)
+		}
+
+		return nil
+	}
+
+	err := checkFn("lowercase", lower, g.chars.letters)
+	if err == nil {
+		err = checkFn("uppercase", upper, g.chars.letters)
+	}
+
+	if err == nil {
+		err = checkFn("digit", digit, g.chars.digits)
+	}
+
+	if err == nil {
+		err = checkFn("special", special, g.chars.special)
+	}
+
+	if err != nil || g.countMode != CountsMinimum {
+		return err
+	}
+
+	// The free characters are drawn last, so there must be room for all the
+	// characters in the free pool.
+	for _, set := range g.countSets() {
+		if pool := g.freePool(g.comboCounts(set)); uint64(g.length) > uint64(g.maxRepeats)*uint64(len(pool)) {
+			return fmt.Errorf("%v characters from %v possible characters cannot be generated with at most %v repeats per character", g.length, len(pool), g.maxRepeats)
+		}
+	}
+
+	return nil
+}
+
+func (g *Generator) repeatLimitReached(pw []rune, c rune) bool {
+	return g.maxRepeats != 0 && countRune(pw, c) >= g.maxRepeats
+}
+
+func countRune(pw []rune, c rune) uint32 {
+	var n uint32
+	for _, other := range pw {
+		if other == c {
+			n++
+		}
+	}
+
+	return n
+}
+
+// drawChar draws a character from charset, re-drawing it while it would
+// exceed the repeat limit in pw.
+func (g *Generator) drawChar(pw []rune, purpose string, index uint32, charset []rune) (rune, error) {
+	for i := 0; i < maxRedraws; i++ {
+		c, err := g.rnd.char(purpose, index, charset)
+		if err != nil {
+			return 0, err
+		}
+
+		if !g.repeatLimitReached(pw, c) {
+			return c, nil
+		}
+	}
+
+	return 0, fmt.Errorf("bug: anti-deadlock code reached: exceeded the maximum amount of attempts drawing a character within the repeat limit")
+}
+
+// enforceMaxRepeats re-draws the base letters that appear more often than the
+// repeat limit allows. Uppercase, digit and special characters are already
+// drawn within the limit.
+func (g *Generator) enforceMaxRepeats(pw []rune) error {
+	if g.maxRepeats == 0 {
+		return nil
+	}
+
+	for i := range pw {
+		if !slices.Contains(g.chars.letters, pw[i]) || countRune(pw, pw[i]) <= g.maxRepeats {
+			continue
+		}
+
+		// Take the letter out before drawing so that it doesn't count
+		// against its own replacement.
+		pw[i] = 0
+
+		c, err := g.drawChar(pw, "repeat redraw", uint32(i), g.chars.letters)
+		if err != nil {
+			return errors.Wrapf(err, "redraw letter #%v", i)
+		}
+
+		pw[i] = c
+	}
+
+	return nil
+}
+
+// MaxRepeatsPenalty estimates how many bits of entropy the repeat limit costs,
+// by comparing the number of sequences of each character class that respect
+// the limit with the unrestricted number.
+func (g *Generator) MaxRepeatsPenalty() float64 {
+	if g.maxRepeats == 0 {
+		return 0
+	}
+
+	upper, digit, special := g.uppercaseCount, g.digitCount, g.specialCount
+	lower := g.reservedLowercase(upper, digit, special)
+
+	var penalty float64
+	for _, class := range []struct {
+		count uint32
+		size  int
+	}{
+		{g.length - upper - digit - special - lower, len(g.freePool(upper, digit, special))},
+		{lower, len(g.chars.letters)},
+		{upper, len(g.chars.letters)},
+		{digit, len(g.chars.digits)},
+		{special, len(g.chars.special)},
+	} {
+		if class.count == 0 {
+			continue
+		}
+
+		unrestricted := float64(class.count) * math.Log2(float64(class.size))
+		penalty += unrestricted - log2BigInt(countLimitedSequences(class.count, class.size, g.maxRepeats))
+	}
+
+	return penalty
+}
+
+// countLimitedSequences returns the number of sequences of the given length
+// over an alphabet of the given size in which no symbol appears more than
+// maxRepeats times.
+func countLimitedSequences(length uint32, alphabetSize int, maxRepeats uint32) *big.Int {
+	// ways[t] is the number of sequences of length t over the symbols
+	// considered so far.
+	ways := make([]*big.Int, length+1)
+	for t := range ways {
+		ways[t] = big.NewInt(0)
+	}
+	ways[0].SetInt64(1)
+
+	binomial := new(big.Int)
+	for i := 0; i < alphabetSize; i++ {
+		next := make([]*big.Int, length+1)
+		for t := uint32(0); t <= length; t++ {
+			next[t] = big.NewInt(0)
+			for j := uint32(0); j <= maxRepeats && j <= t; j++ {
+				// Choose which j of the t positions hold the new symbol.
+				binomial.Binomial(int64(t), int64(j))
+				next[t].Add(next[t], binomial.Mul(binomial, ways[t-j]))
+			}
+		}
+
+		ways = next
+	}
+
+	return ways[length]
+}
+
+func log2BigInt(x *big.Int) float64 {
+	if x.Sign() <= 0 {
+		return 0
+	}
+
+	shift := x.BitLen() - 64
+	if shift <= 0 {
+		f, _ := new(big.Float).SetInt(x).Float64()
+		return math.Log2(f)
+	}
+
+	mantissa, _ := new(big.Float).SetInt(new(big.Int).Rsh(x, uint(shift))).Float64()
+	return math.Log2(mantissa) + float64(shift)
+}
+
+func subtractBits(bits uint64, penalty float64) uint64 {
+	p := uint64(math.Ceil(penalty))
+	if p >= bits {
+		return 0
+	}
+
+	return bits - p
+}
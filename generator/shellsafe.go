@@ -0,0 +1,35 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+// shellSafeSpecial are the special characters that POSIX sh gives no
+// meaning to, inside single or double quotes or not quoted at all. This
+// leaves out the quotes, backslash, $, backtick, the glob and brace
+// characters, the tilde, and history expansion's !.
+const shellSafeSpecial = "%+,-./:=@_"
+
+// ShellSafeCharset is DefaultCharset with only the special characters that
+// need no escaping in shell scripts, cron jobs and command lines.
+var ShellSafeCharset = Charset{
+	Name:        "shell-safe",
+	Description: "The default letters and digits, and special characters inert in POSIX sh",
+
+	Letters:   letterCharset,
+	Uppercase: true,
+	Digits:    digitCharset,
+	Special:   shellSafeSpecial,
+}
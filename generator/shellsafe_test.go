@@ -0,0 +1,161 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// shellWords splits a simple command line into words the way POSIX sh
+// does. It reports false if the line needs more than word splitting and
+// quote removal: a parameter or command substitution, an escape, a glob,
+// an operator, a comment, a tilde, or bash's brace and history expansion.
+func shellWords(line string) ([]string, bool) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, false
+			}
+
+			word.WriteString(line[i+1 : i+1+end])
+			i += 1 + end
+			inWord = true
+		case c == '"':
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if strings.IndexByte("$`\\!", line[i]) >= 0 {
+					return nil, false
+				}
+
+				word.WriteByte(line[i])
+			}
+
+			if i == len(line) {
+				return nil, false
+			}
+
+			inWord = true
+		case strings.IndexByte("|&;<>()$`\\*?[]{}!\n", c) >= 0, (c == '#' || c == '~') && !inWord:
+			return nil, false
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, true
+}
+
+func TestShellWords(t *testing.T) {
+	for _, tc := range []struct {
+		line  string
+		words []string
+	}{
+		{`printf %s abc`, []string{"printf", "%s", "abc"}},
+		{`printf %s "a b"`, []string{"printf", "%s", "a b"}},
+		{`printf %s 'a"b'`, []string{"printf", "%s", `a"b`}},
+		{`printf %s a#b`, []string{"printf", "%s", "a#b"}},
+		{`printf %s "a$b"`, nil},
+		{`printf %s 'a'b''`, []string{"printf", "%s", "ab"}},
+		{`printf %s 'it's'`, nil},
+		{`printf %s a*`, nil},
+		{`printf %s ~a`, nil},
+		{`printf %s "a\"b"`, nil},
+	} {
+		words, ok := shellWords(tc.line)
+		if ok != (tc.words != nil) || !slices.Equal(words, tc.words) {
+			t.Errorf("%v: got %q, %v", tc.line, words, ok)
+		}
+	}
+}
+
+func TestShellSafeRoundTrip(t *testing.T) {
+	g, err := NewGenerator(32, 4, 4, 8, WithCharset(ShellSafeCharset), WithRandSource(testSource(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2000; i++ {
+		pw, err := g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, format := range []string{`printf %%s "%s"`, `printf %%s '%s'`, `printf %%s %s`} {
+			line := fmt.Sprintf(format, pw)
+
+			words, ok := shellWords(line)
+			if !ok || len(words) != 3 || words[2] != string(pw) {
+				t.Fatalf("%v is not passed to printf as is: got %q", line, words)
+			}
+		}
+	}
+}
+
+// A password may start or end with any of the special characters, so
+// they must be inert anywhere in a word.
+func TestShellSafeSpecials(t *testing.T) {
+	for _, c := range shellSafeSpecial {
+		for _, word := range []string{"%c", "%ca", "a%c", `"%c"`, `'%c'`} {
+			line := "printf %s " + fmt.Sprintf(word, c)
+
+			words, ok := shellWords(line)
+			if !ok || len(words) != 3 || words[2] != strings.Trim(fmt.Sprintf(word, c), `"'`) {
+				t.Errorf("%v is not passed to printf as is: got %q", line, words)
+			}
+		}
+	}
+}
+
+func TestShellSafeEntropy(t *testing.T) {
+	safe, err := NewGenerator(16, 2, 2, 2, WithCharset(ShellSafeCharset))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	def, err := NewGenerator(16, 2, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(safe.chars.special) != len(shellSafeSpecial) {
+		t.Errorf("the preset has %v special characters, want %v", len(safe.chars.special), len(shellSafeSpecial))
+	}
+
+	if safe.EntropyMax() >= def.EntropyMax() {
+		t.Errorf("the shell-safe entropy %v is not below the default %v", safe.EntropyMax(), def.EntropyMax())
+	}
+}
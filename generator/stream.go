@@ -0,0 +1,165 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"unicode/utf8"
+)
+
+const streamBufferSize = 4096
+
+// streamReader streams characters drawn uniformly from an alphabet, UTF-8
+// encoded. Random bytes are read in blocks and rejection-sampled one at a
+// time for alphabets of up to 256 characters. Larger ones are drawn with
+// intn.
+type streamReader struct {
+	alphabet []rune
+	// limit is the largest multiple of the alphabet size that fits in a
+	// byte. Bytes at or above it are rejected to avoid modulo bias.
+	limit int
+	rnd   randSource
+
+	buf    [streamBufferSize]byte
+	pos    int
+	n      int
+	index  uint32
+	closed bool
+
+	// pending holds the rest of a multi-byte character that didn't fit
+	// into the last Read.
+	pending  [utf8.UTFMax]byte
+	npending int
+}
+
+// alphabet returns the characters a password from g may use: the letters,
+// plus every class the generator may use.
+func (g *Generator) alphabet() []rune {
+	ret := slices.Clone(g.chars.letters)
+	if g.usesClass(classUpper) {
+		ret = append(ret, g.chars.upper...)
+	}
+
+	if g.usesClass(classDigit) {
+		ret = append(ret, g.chars.digits...)
+	}
+
+	if g.usesClass(classSpecial) {
+		ret = append(ret, g.chars.special...)
+	}
+
+	return ret
+}
+
+// Reader returns a stream of characters drawn uniformly from the generator's
+// alphabet, with no limit on the total length. It is meant for long key
+// material, so only the classes the generator may use are honored, not the
+// exact counts or the length. The max repeats limit does not apply either.
+// Close wipes the internal buffers.
+func (g *Generator) Reader() io.ReadCloser {
+	alphabet := g.alphabet()
+
+	return &streamReader{
+		alphabet: alphabet,
+		limit:    256 - 256%min(len(alphabet), 256),
+		rnd:      g.rnd,
+	}
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	if s.closed {
+		return 0, fmt.Errorf("read from closed stream")
+	}
+
+	i := copy(p, s.pending[:s.npending])
+	copy(s.pending[:], s.pending[i:s.npending])
+	s.npending -= i
+	wipe(s.pending[s.npending:])
+
+	for i < len(p) {
+		c, err := s.next()
+		if err != nil {
+			// Nothing drawn before the failure is handed out.
+			wipe(p[:i])
+			return 0, err
+		}
+
+		if utf8.RuneLen(c) <= len(p)-i {
+			i += utf8.EncodeRune(p[i:], c)
+			continue
+		}
+
+		n := utf8.EncodeRune(s.pending[:], c)
+		s.npending = n - copy(p[i:], s.pending[:n])
+		copy(s.pending[:], s.pending[n-s.npending:n])
+		wipe(s.pending[s.npending:])
+		i = len(p)
+	}
+
+	return len(p), nil
+}
+
+func (s *streamReader) next() (rune, error) {
+	if len(s.alphabet) > 256 {
+		choice, err := s.rnd.intn("stream char", s.index, uint32(len(s.alphabet)))
+		if err != nil {
+			return 0, err
+		}
+
+		s.index++
+
+		return s.alphabet[choice], nil
+	}
+
+	for bytes := 1; ; bytes++ {
+		if s.pos == s.n {
+			err := s.rnd.read(s.buf[:])
+			if err != nil {
+				return 0, err
+			}
+
+			s.pos, s.n = 0, len(s.buf)
+		}
+
+		b := int(s.buf[s.pos])
+		s.buf[s.pos] = 0
+		s.pos++
+
+		if b < s.limit {
+			choice := b % len(s.alphabet)
+			s.rnd.trace("stream char", s.index, bytes, uint32(len(s.alphabet)), uint32(choice))
+			s.index++
+
+			return s.alphabet[choice], nil
+		}
+	}
+}
+
+func (s *streamReader) Close() error {
+	for i := range s.buf {
+		s.buf[i] = 0
+	}
+
+	wipe(s.pending[:])
+
+	s.pos, s.n, s.npending = 0, 0, 0
+	s.closed = true
+
+	return nil
+}
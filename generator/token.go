@@ -0,0 +1,332 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MaxTokenBytes is the largest number of random bytes a token may encode.
+const MaxTokenBytes = 4096
+
+// MaxTokenLength is the longest token GenerateTokenOfLength generates.
+const MaxTokenLength = 8192
+
+// TokenEncoding is the text encoding of a random token.
+type TokenEncoding int
+
+const (
+	// TokenHex is lowercase hex.
+	TokenHex TokenEncoding = iota
+	// TokenBase64URL is URL-safe base64 without padding (RFC 4648).
+	TokenBase64URL
+	// TokenBase58 is the Bitcoin base58 alphabet, which leaves out the
+	// look-alikes 0, O, I and l.
+	TokenBase58
+	// TokenBase32 is RFC 4648 base32 without padding, as used for TOTP
+	// secrets.
+	TokenBase32
+	// TokenCrockford is Crockford base32, which leaves out the look-alikes
+	// I, L and O, and is read the same in any case. See FormatCrockford for
+	// hyphens and the check symbol.
+	TokenCrockford
+	// TokenBase64 is standard base64 with padding (RFC 4648), as most
+	// configuration files expect it.
+	TokenBase64
+)
+
+var hexAlphabet = "0123456789abcdef"
+var base64URLAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+var base32Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+var base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+var base64Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+var tokenEncodings = []struct {
+	name     string
+	alphabet string
+}{
+	TokenHex:       {"hex", hexAlphabet},
+	TokenBase64URL: {"base64url", base64URLAlphabet},
+	TokenBase58:    {"base58", base58Alphabet},
+	TokenBase32:    {"base32", base32Alphabet},
+	TokenCrockford: {"crockford", crockfordAlphabet},
+	TokenBase64:    {"base64", base64Alphabet},
+}
+
+func (e TokenEncoding) String() string {
+	if e < 0 || int(e) >= len(tokenEncodings) {
+		return fmt.Sprintf("TokenEncoding(%d)", int(e))
+	}
+
+	return tokenEncodings[e].name
+}
+
+// TokenEncodingNames returns the names of the token encodings.
+func TokenEncodingNames() []string {
+	names := make([]string, len(tokenEncodings))
+	for i, e := range tokenEncodings {
+		names[i] = e.name
+	}
+
+	return names
+}
+
+// TokenEncodingByName returns the token encoding with the given name.
+func TokenEncodingByName(name string) (TokenEncoding, error) {
+	for i, e := range tokenEncodings {
+		if e.name == name {
+			return TokenEncoding(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown encoding %q (available: %v)", name, strings.Join(TokenEncodingNames(), ", "))
+}
+
+// GenerateHex returns nBytes random bytes encoded as lowercase hex. The
+// entropy is 8 bits per byte, see TokenEntropy.
+func GenerateHex(nBytes int) ([]byte, error) {
+	return GenerateToken(TokenHex, nBytes)
+}
+
+// GenerateToken returns nBytes random bytes in the given encoding. The
+// entropy is 8 bits per byte, see TokenEntropy. Like any base58 encoder,
+// TokenBase58 writes leading zero bytes as a single character each, so its
+// tokens may be a character or so shorter than usual.
+func GenerateToken(enc TokenEncoding, nBytes int) ([]byte, error) {
+	raw, err := randomTokenBytes(nBytes)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(raw)
+
+	return encodeToken(enc, raw)
+}
+
+// GenerateTokenEncodings returns nBytes random bytes in every one of the
+// given encodings, in the same order. The bytes are read once, so that all
+// the encodings encode the same ones, and wiped once they are encoded. The
+// caller must wipe the encodings.
+func GenerateTokenEncodings(encs []TokenEncoding, nBytes int) ([][]byte, error) {
+	raw, err := randomTokenBytes(nBytes)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(raw)
+
+	ret := make([][]byte, len(encs))
+	for i, enc := range encs {
+		ret[i], err = encodeToken(enc, raw)
+		if err != nil {
+			for _, b := range ret {
+				wipe(b)
+			}
+
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+// encodeToken returns raw in the given encoding.
+func encodeToken(enc TokenEncoding, raw []byte) ([]byte, error) {
+	switch enc {
+	case TokenHex:
+		ret := make([]byte, hex.EncodedLen(len(raw)))
+		hex.Encode(ret, raw)
+		return ret, nil
+	case TokenBase64URL:
+		ret := make([]byte, base64.RawURLEncoding.EncodedLen(len(raw)))
+		base64.RawURLEncoding.Encode(ret, raw)
+		return ret, nil
+	case TokenBase58:
+		return encodeBase58(raw), nil
+	case TokenBase32:
+		enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+		ret := make([]byte, enc.EncodedLen(len(raw)))
+		enc.Encode(ret, raw)
+		return ret, nil
+	case TokenCrockford:
+		return encodeCrockford(raw), nil
+	case TokenBase64:
+		ret := make([]byte, base64.StdEncoding.EncodedLen(len(raw)))
+		base64.StdEncoding.Encode(ret, raw)
+		return ret, nil
+	default:
+		return nil, fmt.Errorf("unknown encoding %v", enc)
+	}
+}
+
+// GenerateTokenOfLength returns a token of exactly length characters in the
+// given encoding. This is the encoding of a uniformly random number with
+// length digits, so every character is chosen uniformly, see
+// TokenLengthEntropy.
+func GenerateTokenOfLength(enc TokenEncoding, length int) ([]byte, error) {
+	if enc < 0 || int(enc) >= len(tokenEncodings) {
+		return nil, fmt.Errorf("unknown encoding %v", enc)
+	}
+
+	if length < 1 || length > MaxTokenLength {
+		return nil, fmt.Errorf("length must be between 1 and %v", MaxTokenLength)
+	}
+
+	alphabet := tokenEncodings[enc].alphabet
+	ret := make([]byte, length)
+	for i := range ret {
+		c, err := randSource{}.pick("token char", uint32(i), alphabet)
+		if err != nil {
+			wipe(ret)
+			return nil, errors.Wrapf(err, "generate secure random token char #%v", i)
+		}
+
+		ret[i] = c
+	}
+
+	return ret, nil
+}
+
+// TokenEntropy returns the entropy of a token that encodes nBytes random
+// bytes, regardless of the encoding.
+func TokenEntropy(nBytes int) float64 {
+	return float64(nBytes) * 8
+}
+
+// TokenLengthEntropy returns the entropy of a token from
+// GenerateTokenOfLength.
+func TokenLengthEntropy(enc TokenEncoding, length int) float64 {
+	if enc < 0 || int(enc) >= len(tokenEncodings) {
+		return 0
+	}
+
+	return float64(length) * math.Log2(float64(len(tokenEncodings[enc].alphabet)))
+}
+
+// TokenLengthForBits returns the shortest length of a token from
+// GenerateTokenOfLength that carries at least the given bits of entropy. The
+// length is rounded up, also for encodings whose characters carry a
+// fractional number of bits, such as base58.
+func TokenLengthForBits(enc TokenEncoding, bits int) (int, error) {
+	if enc < 0 || int(enc) >= len(tokenEncodings) {
+		return 0, fmt.Errorf("unknown encoding %v", enc)
+	}
+
+	if bits < 1 {
+		return 0, fmt.Errorf("bits must be at least 1")
+	}
+
+	// Checked before any big.Int work, which would take as long as the bits
+	// are many.
+	if maxBits := TokenLengthEntropy(enc, MaxTokenLength); float64(bits) > maxBits {
+		return 0, fmt.Errorf("%v bits need more than the maximum of %v %v characters, which carry %.2f bits", bits, MaxTokenLength, enc, maxBits)
+	}
+
+	// The estimate may be off by one either way due to rounding, so it is
+	// checked exactly: the shortest length for which size^length >= 2^bits.
+	size := big.NewInt(int64(len(tokenEncodings[enc].alphabet)))
+	target := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	reaches := func(length int) bool {
+		return new(big.Int).Exp(size, big.NewInt(int64(length)), nil).Cmp(target) >= 0
+	}
+
+	length := max(int(math.Ceil(float64(bits)/math.Log2(float64(size.Int64())))), 1)
+	for length > 1 && reaches(length-1) {
+		length--
+	}
+
+	for !reaches(length) {
+		length++
+	}
+
+	if length > MaxTokenLength {
+		return 0, fmt.Errorf("%v bits need %v %v characters, more than the maximum of %v", bits, length, enc, MaxTokenLength)
+	}
+
+	return length, nil
+}
+
+// randomTokenBytes reads nBytes random bytes. The caller must wipe them once
+// they are encoded.
+func randomTokenBytes(nBytes int) ([]byte, error) {
+	if nBytes < 1 || nBytes > MaxTokenBytes {
+		return nil, fmt.Errorf("byte length must be between 1 and %v", MaxTokenBytes)
+	}
+
+	raw := make([]byte, nBytes)
+	err := randSource{}.read(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "read secure random bytes")
+	}
+
+	return raw, nil
+}
+
+// encodeBase58 encodes raw as a big-endian number in base58, with a leading
+// "1" for every leading zero byte. The digits are computed in a buffer of
+// its own that is wiped, rather than with math/big, whose temporaries can't
+// be.
+func encodeBase58(raw []byte) []byte {
+	zeros := 0
+	for zeros < len(raw) && raw[zeros] == 0 {
+		zeros++
+	}
+
+	// log(256) / log(58) is less than 1.38.
+	size := (len(raw)-zeros)*138/100 + 1
+	digits := make([]byte, size)
+	defer wipe(digits)
+
+	high := size - 1
+	for _, b := range raw[zeros:] {
+		carry := int(b)
+		j := size - 1
+		for ; j > high || carry != 0; j-- {
+			carry += 256 * int(digits[j])
+			digits[j] = byte(carry % 58)
+			carry /= 58
+
+			if j == 0 {
+				break
+			}
+		}
+
+		high = j
+	}
+
+	start := 0
+	for start < size && digits[start] == 0 {
+		start++
+	}
+
+	ret := make([]byte, zeros+size-start)
+	for i := 0; i < zeros; i++ {
+		ret[i] = base58Alphabet[0]
+	}
+
+	for i, d := range digits[start:] {
+		ret[zeros+i] = base58Alphabet[d]
+	}
+
+	return ret
+}
@@ -0,0 +1,150 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTokenLengthForBits(t *testing.T) {
+	for _, tc := range []struct {
+		enc  TokenEncoding
+		bits int
+		want int
+	}{
+		{TokenHex, 128, 32},
+		{TokenHex, 129, 33},
+		{TokenBase64URL, 128, 22},
+		{TokenBase64URL, 126, 21},
+		{TokenBase58, 128, 22},
+		{TokenBase58, 256, 44},
+		{TokenBase32, 128, 26},
+		{TokenBase32, 160, 32},
+		{TokenCrockford, 80, 16},
+		{TokenBase58, 1, 1},
+	} {
+		got, err := TokenLengthForBits(tc.enc, tc.bits)
+		if err != nil {
+			t.Errorf("%v, %v bits: %v", tc.enc, tc.bits, err)
+			continue
+		}
+
+		if got != tc.want {
+			t.Errorf("%v, %v bits: got %v characters, want %v", tc.enc, tc.bits, got, tc.want)
+		}
+	}
+}
+
+func TestTokenLengthForBitsRoundsUp(t *testing.T) {
+	for enc := range tokenEncodings {
+		perChar := math.Log2(float64(len(tokenEncodings[enc].alphabet)))
+		for bits := 1; bits <= 1024; bits++ {
+			n, err := TokenLengthForBits(TokenEncoding(enc), bits)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Allow for the rounding of the float estimate only.
+			if float64(n)*perChar < float64(bits)-1e-9 || float64(n-1)*perChar >= float64(bits)+1e-9 {
+				t.Fatalf("%v, %v bits: %v characters carry %.3f bits", TokenEncoding(enc), bits, n, float64(n)*perChar)
+			}
+		}
+	}
+}
+
+func TestTokenLengthForBitsLimits(t *testing.T) {
+	if _, err := TokenLengthForBits(TokenHex, 0); err == nil {
+		t.Error("0 bits accepted")
+	}
+
+	if _, err := TokenLengthForBits(TokenHex, 4*MaxTokenLength+1); err == nil {
+		t.Error("more than the maximum length accepted")
+	}
+
+	// Rejected at once rather than after raising the alphabet size to a
+	// power of that many bits.
+	for _, enc := range []TokenEncoding{TokenHex, TokenBase58, TokenBase64} {
+		start := time.Now()
+		if _, err := TokenLengthForBits(enc, 2000000000); err == nil {
+			t.Errorf("%v: 2000000000 bits accepted", enc)
+		}
+
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("%v: rejecting 2000000000 bits took %v", enc, d)
+		}
+	}
+
+	// The largest number of bits that fit is accepted.
+	for _, enc := range []TokenEncoding{TokenHex, TokenBase58, TokenBase64} {
+		bits := int(TokenLengthEntropy(enc, MaxTokenLength))
+		if n, err := TokenLengthForBits(enc, bits); err != nil || n > MaxTokenLength {
+			t.Errorf("%v: %v bits: got %v, %v", enc, bits, n, err)
+		}
+	}
+
+	if _, err := TokenLengthForBits(TokenEncoding(-1), 128); err == nil {
+		t.Error("unknown encoding accepted")
+	}
+}
+
+func TestGenerateTokenEncodings(t *testing.T) {
+	encs := []TokenEncoding{TokenHex, TokenBase64, TokenBase64URL, TokenBase58, TokenBase32, TokenCrockford}
+
+	for _, n := range []int{1, 3, 32, 33} {
+		got, err := GenerateTokenEncodings(encs, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := hex.DecodeString(string(got[0]))
+		if err != nil || len(raw) != n {
+			t.Fatalf("hex %q doesn't decode to %v bytes: %v", got[0], n, err)
+		}
+
+		// Every encoding encodes the same bytes.
+		for i, enc := range encs {
+			want, err := encodeToken(enc, raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got[i], want) {
+				t.Errorf("%v bytes: %v is %q, but %q for the bytes of the hex", n, enc, got[i], want)
+			}
+		}
+	}
+
+	if _, err := GenerateTokenEncodings([]TokenEncoding{TokenHex, TokenEncoding(99)}, 4); err == nil {
+		t.Error("GenerateTokenEncodings succeeded with an unknown encoding")
+	}
+}
+
+func TestGenerateTokenEncodingsEntropyUnavailable(t *testing.T) {
+	DiscardBufferedRandomness()
+	setRandReader(t, &failingReader{src: testSource(t)})
+	t.Cleanup(DiscardBufferedRandomness)
+
+	got, err := GenerateTokenEncodings([]TokenEncoding{TokenHex, TokenBase64}, 16)
+	if got != nil || !errors.Is(err, ErrEntropyUnavailable) {
+		t.Errorf("GenerateTokenEncodings = %q, %v, want nil and ErrEntropyUnavailable", got, err)
+	}
+}
@@ -0,0 +1,85 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+)
+
+// DefaultTOTPSecretBytes is the TOTP secret length RFC 4226 recommends, 160
+// bits, the output size of HMAC-SHA1.
+const DefaultTOTPSecretBytes = 20
+
+// MinTOTPSecretBytes is the minimum secret length of RFC 4226, 128 bits.
+const MinTOTPSecretBytes = 16
+
+// GenerateTOTPSecret returns a random TOTP secret of nBytes bytes, encoded in
+// base32 without padding, the way authenticator apps expect it.
+func GenerateTOTPSecret(nBytes int) ([]byte, error) {
+	if nBytes < MinTOTPSecretBytes {
+		return nil, fmt.Errorf("TOTP secrets must be at least %v bytes long", MinTOTPSecretBytes)
+	}
+
+	return GenerateToken(TokenBase32, nBytes)
+}
+
+// TOTPURI returns the otpauth:// URI that authenticator apps enroll secret
+// from, e.g. through a QR code, in the Key Uri Format of Google
+// Authenticator. The issuer may be empty, the account name may not. Both are
+// percent-encoded as UTF-8, so spaces and any other characters are fine. The
+// caller must wipe the URI, as it contains the secret.
+func TOTPURI(secret []byte, issuer, account string) ([]byte, error) {
+	if account == "" {
+		return nil, fmt.Errorf("the account name must not be empty")
+	}
+
+	ret := []byte("otpauth://totp/")
+	if issuer != "" {
+		ret = appendURIEscaped(ret, issuer)
+		ret = append(ret, ':')
+	}
+
+	ret = appendURIEscaped(ret, account)
+	ret = append(ret, "?secret="...)
+	ret = append(ret, secret...)
+
+	if issuer != "" {
+		ret = append(ret, "&issuer="...)
+		ret = appendURIEscaped(ret, issuer)
+	}
+
+	return ret, nil
+}
+
+// appendURIEscaped appends s with every byte but the unreserved characters
+// of RFC 3986 percent-encoded. This is stricter than net/url, which encodes
+// spaces in queries as "+", which some apps show literally.
+func appendURIEscaped(dst []byte, s string) []byte {
+	const hexDigits = "0123456789ABCDEF"
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			dst = append(dst, c)
+		default:
+			dst = append(dst, '%', hexDigits[c>>4], hexDigits[c&15])
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,88 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+)
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	for _, n := range []int{MinTOTPSecretBytes, 17, DefaultTOTPSecretBytes, 32} {
+		secret, err := GenerateTOTPSecret(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := enc.DecodeString(string(secret))
+		if err != nil {
+			t.Fatalf("%q is not unpadded base32: %v", secret, err)
+		}
+
+		if len(raw) != n {
+			t.Errorf("%q decodes to %v bytes, want %v", secret, len(raw), n)
+		}
+	}
+
+	if _, err := GenerateTOTPSecret(MinTOTPSecretBytes - 1); err == nil {
+		t.Errorf("a secret of %v bytes succeeded", MinTOTPSecretBytes-1)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+
+	for _, tc := range []struct {
+		issuer, account, want string
+	}{
+		{"", "alice", "otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP"},
+		{"ACME Corp", "alice@example.com", "otpauth://totp/ACME%20Corp:alice%40example.com?secret=JBSWY3DPEHPK3PXP&issuer=ACME%20Corp"},
+		{"Bücher GmbH", "jürgen", "otpauth://totp/B%C3%BCcher%20GmbH:j%C3%BCrgen?secret=JBSWY3DPEHPK3PXP&issuer=B%C3%BCcher%20GmbH"},
+		{"東京", "ユーザー", "otpauth://totp/%E6%9D%B1%E4%BA%AC:%E3%83%A6%E3%83%BC%E3%82%B6%E3%83%BC?secret=JBSWY3DPEHPK3PXP&issuer=%E6%9D%B1%E4%BA%AC"},
+		{"a:b&c", "d?e=f+g", "otpauth://totp/a%3Ab%26c:d%3Fe%3Df%2Bg?secret=JBSWY3DPEHPK3PXP&issuer=a%3Ab%26c"},
+	} {
+		uri, err := TOTPURI([]byte(secret), tc.issuer, tc.account)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(uri) != tc.want {
+			t.Errorf("issuer %q, account %q: got %v, want %v", tc.issuer, tc.account, uri, tc.want)
+		}
+
+		u, err := url.Parse(string(uri))
+		if err != nil {
+			t.Fatalf("%v: %v", uri, err)
+		}
+
+		label := "/" + tc.account
+		if tc.issuer != "" {
+			label = "/" + tc.issuer + ":" + tc.account
+		}
+
+		if u.Path != label || u.Query().Get("issuer") != tc.issuer || u.Query().Get("secret") != secret {
+			t.Errorf("%v parses to the label %q, issuer %q and secret %q", uri, u.Path, u.Query().Get("issuer"), u.Query().Get("secret"))
+		}
+	}
+
+	if _, err := TOTPURI([]byte(secret), "ACME", ""); err == nil {
+		t.Error("an empty account name succeeded")
+	}
+}
@@ -0,0 +1,191 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// cyrillicCharset has the sizes of asciiCharset, but only multi-byte
+// characters.
+var (
+	asciiCharset = Charset{
+		Name:      "ascii",
+		Letters:   letterCharset,
+		Uppercase: true,
+		Digits:    digitCharset,
+		Special:   "!#%&",
+	}
+	cyrillicCharset = Charset{
+		Name:      "cyrillic",
+		Letters:   "абвгдежзийклмнопрстуфхцч",
+		Uppercase: true,
+		Digits:    "٠١٢٣٤٥٦٧٨٩",
+		Special:   "€£¥§",
+		Unicode:   true,
+	}
+)
+
+func TestGenerateMultiByte(t *testing.T) {
+	for _, c := range []Charset{
+		cyrillicCharset,
+		{Name: "cjk", Letters: "日月火水木金土山川田", Digits: "〇一二三四五六七八九", Unicode: true},
+		{Name: "accented", Letters: "àáâäçèéêëìíîïñòóôöùúûü", Uppercase: true, Special: "¡¿", Unicode: true},
+	} {
+		var digit, special uint32
+		if c.Digits != "" {
+			digit = 3
+		}
+
+		if c.Special != "" {
+			special = 2
+		}
+
+		var upper uint32
+		if c.Uppercase {
+			upper = 4
+		}
+
+		g, err := NewGenerator(20, upper, digit, special, WithCharset(c), WithRandSource(testSource(t)))
+		if err != nil {
+			t.Fatalf("%v: %v", c.Name, err)
+		}
+
+		for i := 0; i < 1000; i++ {
+			pw, err := g.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !utf8.Valid(pw) {
+				t.Fatalf("%v: %q is not valid UTF-8", c.Name, pw)
+			}
+
+			if n := utf8.RuneCount(pw); n != 20 {
+				t.Fatalf("%v: %q has %v characters, want 20", c.Name, pw, n)
+			}
+
+			err = g.Validate(pw)
+			if err != nil {
+				t.Fatalf("%v: %q: %v", c.Name, pw, err)
+			}
+
+			var gotUpper uint32
+			for _, ch := range string(pw) {
+				if unicode.IsUpper(ch) {
+					gotUpper++
+				}
+			}
+
+			if gotUpper != upper {
+				t.Fatalf("%v: %q has %v uppercase letters, want %v", c.Name, pw, gotUpper, upper)
+			}
+		}
+
+		if err := g.GenerateInto(make([]byte, 20)); err == nil {
+			t.Errorf("%v: GenerateInto succeeded with multi-byte characters", c.Name)
+		}
+	}
+}
+
+// The characters are drawn by index, so classes of the same sizes give the
+// same passwords and the same entropy whatever their encoding.
+func TestGenerateMultiByteMatchesASCII(t *testing.T) {
+	var passwords [2][]rune
+	var entropy [2]uint64
+	for i, c := range []Charset{asciiCharset, cyrillicCharset} {
+		src, err := NewDeterministicSource([]byte("cpass"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g, err := NewGenerator(24, 3, 3, 3, WithCharset(c), WithRandSource(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pw, err := g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		passwords[i] = []rune(string(pw))
+		entropy[i] = g.EntropyMax()
+	}
+
+	if entropy[0] != entropy[1] {
+		t.Errorf("the entropy is %v with ASCII, but %v with Cyrillic", entropy[0], entropy[1])
+	}
+
+	classes := func(c Charset) []string {
+		return []string{c.Letters, strings.ToUpper(c.Letters), c.Digits, c.Special}
+	}
+
+	ascii, cyrillic := classes(asciiCharset), classes(cyrillicCharset)
+	for i, ch := range passwords[0] {
+		for class := range ascii {
+			if pos := strings.IndexRune(ascii[class], ch); pos != -1 {
+				if want := []rune(cyrillic[class])[pos]; passwords[1][i] != want {
+					t.Fatalf("character %v is %q with ASCII, so it should be %q with Cyrillic, got %q", i, ch, want, passwords[1][i])
+				}
+			}
+		}
+	}
+}
+
+func TestValidateMultiByte(t *testing.T) {
+	g, err := NewGenerator(4, 1, 1, 1, WithCharset(cyrillicCharset))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		pw  string
+		err string
+	}{
+		{"аБ٣€", ""},
+		{"аБ٣", "length is 3, expected 4"},
+		{"аБ3€", "character at position 2 is not in the"},
+		{"аб٣€", "has 0 uppercase"},
+		{"аБ٣\xe2\x82", "is not valid UTF-8"},
+	} {
+		err := g.Validate([]byte(tc.pw))
+		if tc.err == "" && err != nil || tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("%q: got error %v, want %q", tc.pw, err, tc.err)
+		}
+	}
+}
+
+func TestMaxBytesMultiByte(t *testing.T) {
+	// Four Cyrillic letters take up exactly 8 bytes.
+	g, err := NewGenerator(4, 0, 0, 0, WithCharset(cyrillicCharset), WithMaxBytes(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pw, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pw) > 8 {
+		t.Errorf("%q takes up %v bytes, more than 8", pw, len(pw))
+	}
+}
@@ -0,0 +1,104 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// UUIDEntropy is the entropy of a version 4 UUID: its 128 bits less the 4
+// version and 2 variant bits.
+const UUIDEntropy = 122
+
+// GenerateUUIDv4 returns a random RFC 4122 version 4 UUID in the canonical
+// hyphenated form, e.g. "0b5d3f5c-7f0e-4a4e-9c1d-2f3a4b5c6d7e".
+func GenerateUUIDv4() ([]byte, error) {
+	raw, err := randomTokenBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(raw)
+
+	// The version is in the high nibble of byte 6, and the variant in the
+	// two high bits of byte 8.
+	raw[6] = raw[6]&0x0f | 0x40
+	raw[8] = raw[8]&0x3f | 0x80
+
+	ret := make([]byte, 36)
+	hex.Encode(ret[0:8], raw[0:4])
+	ret[8] = '-'
+	hex.Encode(ret[9:13], raw[4:6])
+	ret[13] = '-'
+	hex.Encode(ret[14:18], raw[6:8])
+	ret[18] = '-'
+	hex.Encode(ret[19:23], raw[8:10])
+	ret[23] = '-'
+	hex.Encode(ret[24:], raw[10:])
+
+	return ret, nil
+}
+
+// GenerateUUIDsV4 generates n distinct version 4 UUIDs. Like GenerateMany,
+// it only compares their hashes, and wipes the UUIDs on error.
+func GenerateUUIDsV4(n int) ([][]byte, error) {
+	if n < 0 || n > MaxBatchSize {
+		return nil, fmt.Errorf("batch size must be between 0 and %v", MaxBatchSize)
+	}
+
+	ret := make([][]byte, 0, n)
+	seen := make(map[[sha256.Size]byte]struct{}, n)
+
+	fail := func(err error) ([][]byte, error) {
+		for _, b := range ret {
+			wipe(b)
+		}
+
+		clear(seen)
+
+		return nil, err
+	}
+
+	for attempts := 0; len(ret) < n; attempts++ {
+		if attempts >= maxBatchAttempts(n) {
+			return fail(errTooManyAttempts)
+		}
+
+		b, err := GenerateUUIDv4()
+		if err != nil {
+			return fail(errors.Wrapf(err, "generate UUID #%v", len(ret)+1))
+		}
+
+		// A collision is all but impossible with 122 random bits, but it
+		// costs nothing to rule out.
+		sum := sha256.Sum256(b)
+		if _, ok := seen[sum]; ok {
+			wipe(b)
+			continue
+		}
+
+		seen[sum] = struct{}{}
+		ret = append(ret, b)
+	}
+
+	clear(seen)
+
+	return ret, nil
+}
@@ -0,0 +1,93 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidFormat = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestGenerateUUIDv4(t *testing.T) {
+	// ones and zeros record which bits were set and cleared in any UUID.
+	var ones, zeros [16]byte
+
+	uuids, err := GenerateUUIDsV4(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(uuids) != 1000 {
+		t.Fatalf("got %v UUIDs, want 1000", len(uuids))
+	}
+
+	seen := make(map[string]struct{}, len(uuids))
+	for _, u := range uuids {
+		if !uuidFormat.Match(u) {
+			t.Fatalf("%q is not a version 4 UUID in the canonical form", u)
+		}
+
+		if _, ok := seen[string(u)]; ok {
+			t.Fatalf("%q was generated twice", u)
+		}
+
+		seen[string(u)] = struct{}{}
+
+		raw, err := hex.DecodeString(strings.ReplaceAll(string(u), "-", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if raw[6]>>4 != 4 {
+			t.Fatalf("%q: version is %v, want 4", u, raw[6]>>4)
+		}
+
+		if raw[8]>>6 != 0b10 {
+			t.Fatalf("%q: variant bits are %02b, want 10", u, raw[8]>>6)
+		}
+
+		for i, b := range raw {
+			ones[i] |= b
+			zeros[i] |= ^b
+		}
+	}
+
+	// Every bit but the version and variant must be random.
+	var random int
+	for i := range ones {
+		for bit := 0; bit < 8; bit++ {
+			if ones[i]&zeros[i]&(1<<bit) != 0 {
+				random++
+			}
+		}
+	}
+
+	if random != UUIDEntropy {
+		t.Errorf("%v bits varied, want %v", random, UUIDEntropy)
+	}
+}
+
+func TestGenerateUUIDsV4BatchSize(t *testing.T) {
+	for _, n := range []int{-1, MaxBatchSize + 1} {
+		if _, err := GenerateUUIDsV4(n); err == nil {
+			t.Errorf("a batch of %v succeeded", n)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+)
+
+// Validate checks that pw satisfies every constraint of the generator: the
+// length, the byte limit, the number of characters of each class, the minimum
+// number of classes, the charset, and the repeat limit.
+func (g *Generator) Validate(pw []byte) error {
+	if !utf8.Valid(pw) {
+		return fmt.Errorf("is not valid UTF-8")
+	}
+
+	if uint32(utf8.RuneCount(pw)) != g.length {
+		return fmt.Errorf("length is %v, expected %v", utf8.RuneCount(pw), g.length)
+	}
+
+	if g.maxBytes != 0 && uint32(len(pw)) > g.maxBytes {
+		return fmt.Errorf("takes up %v bytes, at most %v allowed", len(pw), g.maxBytes)
+	}
+
+	var lower, upper, digit, special uint32
+	i := 0
+	for _, c := range string(pw) {
+		switch {
+		case slices.Contains(g.chars.letters, c):
+			lower++
+		case slices.Contains(g.chars.upper, c):
+			upper++
+		case slices.Contains(g.chars.digits, c):
+			digit++
+		case slices.Contains(g.chars.special, c):
+			special++
+		default:
+			return fmt.Errorf("character at position %v is not in the %q charset", i, g.charset.Name)
+		}
+
+		i++
+	}
+
+	if !g.validCounts(lower, upper, digit, special) {
+		if g.countMode == CountsMinimum {
+			if len(g.classCombos) != 0 {
+				return fmt.Errorf("has %v lowercase, %v uppercase, %v digit and %v special characters, expected at least %v, %v, %v and %v plus one character each from enough other classes to reach %v classes", lower, upper, digit, special, g.minLowercase, g.uppercaseCount, g.digitCount, g.specialCount, g.minClasses)
+			}
+
+			return fmt.Errorf("has %v lowercase, %v uppercase, %v digit and %v special characters, expected at least %v, %v, %v and %v, and none of the classes with a count of 0", lower, upper, digit, special, g.minLowercase, g.uppercaseCount, g.digitCount, g.specialCount)
+		}
+
+		if len(g.classCombos) != 0 {
+			return fmt.Errorf("has %v uppercase, %v digit and %v special characters, expected %v, %v and %v plus one character each from enough other classes to reach %v classes", upper, digit, special, g.uppercaseCount, g.digitCount, g.specialCount, g.minClasses)
+		}
+
+		return fmt.Errorf("has %v uppercase, %v digit and %v special characters, expected %v, %v and %v", upper, digit, special, g.uppercaseCount, g.digitCount, g.specialCount)
+	}
+
+	if g.maxRepeats != 0 {
+		i := 0
+		for pos, c := range string(pw) {
+			// UTF-8 is self-synchronizing, so counting the encoded
+			// character counts exactly its occurrences.
+			if n := bytes.Count(pw, pw[pos:pos+utf8.RuneLen(c)]); uint32(n) > g.maxRepeats {
+				return fmt.Errorf("character at position %v appears %v times, at most %v allowed", i, n, g.maxRepeats)
+			}
+
+			i++
+		}
+	}
+
+	return nil
+}
+
+// validCounts reports whether a password with the given class counts can come
+// from g.
+func (g *Generator) validCounts(lower, upper, digit, special uint32) bool {
+	if g.countMode == CountsMinimum {
+		for _, set := range g.countSets() {
+			u, d, s := g.comboCounts(set)
+			if lower >= g.reservedLowercase(u, d, s) && atLeast(upper, u) && atLeast(digit, d) && atLeast(special, s) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if upper == g.uppercaseCount && digit == g.digitCount && special == g.specialCount {
+		return len(g.classCombos) == 0
+	}
+
+	for _, set := range g.classCombos {
+		u, d, s := g.comboCounts(set)
+		if upper == u && digit == d && special == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// atLeast reports whether n characters of a class meet a count of want with
+// CountsMinimum, where classes with a count of 0 are not drawn at all.
+func atLeast(n, want uint32) bool {
+	if want == 0 {
+		return n == 0
+	}
+
+	return n >= want
+}
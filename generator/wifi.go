@@ -0,0 +1,87 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The length bounds of a WPA2 or WPA3 passphrase, from IEEE 802.11i. A
+// 64-character key is a raw hex PSK rather than a passphrase.
+const (
+	MinWPALength = 8
+	MaxWPALength = 63
+)
+
+// WiFiCharset leaves out the special characters that router web interfaces
+// tend to mangle: quotes and backslashes, which it has none of anyway, and
+// the HTML metacharacters &, < and >.
+var WiFiCharset = Charset{
+	Name:        "wifi",
+	Description: "The default charset without the special characters router web interfaces mangle",
+
+	Letters:   letterCharset,
+	Uppercase: true,
+	Digits:    digitCharset,
+	Special: filterCharset(specialCharset, func(c rune) bool {
+		return !strings.ContainsRune("&<>", c)
+	}),
+}
+
+// WithWPA makes New reject lengths outside the WPA passphrase bounds.
+func WithWPA() Option {
+	return func(g *Generator) {
+		g.wpa = true
+	}
+}
+
+// WiFiQRPayload returns the payload of a QR code that joins a WPA network,
+// in the format of the ZXing project that phone cameras understand. The
+// caller must wipe it, as it contains the passphrase.
+func WiFiQRPayload(ssid string, passphrase []byte) []byte {
+	ret := make([]byte, 0, len("WIFI:T:WPA;S:;P:;;")+2*len(ssid)+2*len(passphrase))
+	ret = append(ret, "WIFI:T:WPA;S:"...)
+	ret = appendWiFiEscaped(ret, []byte(ssid))
+	ret = append(ret, ";P:"...)
+	ret = appendWiFiEscaped(ret, passphrase)
+	ret = append(ret, ";;"...)
+
+	return ret
+}
+
+// appendWiFiEscaped appends s with the characters that delimit the payload
+// fields escaped with a backslash.
+func appendWiFiEscaped(dst, s []byte) []byte {
+	for _, c := range s {
+		if strings.IndexByte(`\;,:"`, c) != -1 {
+			dst = append(dst, '\\')
+		}
+
+		dst = append(dst, c)
+	}
+
+	return dst
+}
+
+func (g *Generator) checkWPA() error {
+	if g.length < MinWPALength || g.length > MaxWPALength {
+		return fmt.Errorf("WPA passphrases must be %v to %v characters long, but length is %v", MinWPALength, MaxWPALength, g.length)
+	}
+
+	return nil
+}
@@ -0,0 +1,274 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/unicode/norm"
+)
+
+// The most common English words, most frequent first. Derived from the US TV
+// and film frequency list shipped with zxcvbn (MIT license).
+//
+//go:embed data/common_english.txt
+var commonEnglishData string
+
+const MaxCommonEnglishWords = 5000
+
+// RecommendedMinWordlistSize is the wordlist size below which passphrases get
+// less than 12 bits of entropy per word.
+const RecommendedMinWordlistSize = 4096
+
+type WordlistBuildOptions struct {
+	// MinLength and MaxLength bound the word length in characters. Zero means
+	// no bound.
+	MinLength int
+	MaxLength int
+
+	// AllowedChars lists the characters words may consist of. Words with any
+	// other character are dropped. Defaults to the lowercase English alphabet.
+	AllowedChars string
+
+	// DropCommon drops this many of the most common English words.
+	DropCommon int
+}
+
+// BuildWordlist tokenizes the text read from r into lowercase words, filters
+// them according to opts, and returns them deduplicated and sorted.
+func BuildWordlist(r io.Reader, opts WordlistBuildOptions) ([]string, error) {
+	if opts.MinLength < 0 || opts.MaxLength < 0 {
+		return nil, fmt.Errorf("word length bounds must not be negative")
+	}
+
+	if opts.MaxLength != 0 && opts.MinLength > opts.MaxLength {
+		return nil, fmt.Errorf("min length (%v) > max length (%v)", opts.MinLength, opts.MaxLength)
+	}
+
+	if opts.DropCommon < 0 || opts.DropCommon > MaxCommonEnglishWords {
+		return nil, fmt.Errorf("common word count must be between 0 and %v", MaxCommonEnglishWords)
+	}
+
+	allowedChars := opts.AllowedChars
+	if allowedChars == "" {
+		allowedChars = "abcdefghijklmnopqrstuvwxyz"
+	}
+
+	dropped := make(map[string]struct{}, opts.DropCommon)
+	for i, w := range strings.Fields(commonEnglishData) {
+		if i >= opts.DropCommon {
+			break
+		}
+
+		dropped[w] = struct{}{}
+	}
+
+	words := make(map[string]struct{})
+
+	addWordFn := func(w string) {
+		if w == "" {
+			return
+		}
+
+		n := utf8.RuneCountInString(w)
+		if n < opts.MinLength || (opts.MaxLength != 0 && n > opts.MaxLength) {
+			return
+		}
+
+		for _, c := range w {
+			if !strings.ContainsRune(allowedChars, c) {
+				return
+			}
+		}
+
+		if _, ok := dropped[w]; ok {
+			return
+		}
+
+		words[w] = struct{}{}
+	}
+
+	br := bufio.NewReader(r)
+
+	var token strings.Builder
+	for {
+		c, _, err := br.ReadRune()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return nil, errors.Wrap(err, "read rune")
+		}
+
+		if unicode.IsLetter(c) {
+			token.WriteRune(unicode.ToLower(c))
+			continue
+		}
+
+		addWordFn(token.String())
+		token.Reset()
+	}
+
+	addWordFn(token.String())
+
+	ret := make([]string, 0, len(words))
+	for w := range words {
+		ret = append(ret, w)
+	}
+
+	sort.Strings(ret)
+
+	return ret, nil
+}
+
+// MinWordlistSize is the smallest wordlist LoadWordlist accepts.
+const MinWordlistSize = 64
+
+// WeakWordlistSize is the wordlist size below which passphrases get less
+// than 10 bits of entropy per word, so that they need many more words to be
+// secure.
+const WeakWordlistSize = 1024
+
+// Wordlist is a list of distinct words for passphrases.
+type Wordlist struct {
+	words []string
+}
+
+// LoadWordlist reads a wordlist of one word per line. A leading byte order
+// mark and whitespace around the words, including the carriage returns of
+// CRLF line endings, are ignored. The words are normalized to NFC, so that
+// accented letters count as one character. Empty lines, words with
+// whitespace inside, duplicates, and lists of fewer than MinWordlistSize
+// words are rejected.
+//
+// Diceware lists like the EFF ones, with the dice rolls in front of every
+// word, load as well. Whether the list has rolls is decided by the first
+// line, and the rolls are dropped.
+func LoadWordlist(r io.Reader) (*Wordlist, error) {
+	var words []string
+	seen := make(map[string]int)
+	dice := 0
+
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+
+		w := strings.TrimSpace(text)
+		if line == 1 {
+			dice = diceRollLen(w)
+		}
+
+		if dice > 0 {
+			if diceRollLen(w) != dice {
+				return nil, fmt.Errorf("line %v: expected %v dice rolls and a word", line, dice)
+			}
+
+			w = strings.TrimSpace(w[dice:])
+		}
+
+		switch {
+		case w == "":
+			return nil, fmt.Errorf("line %v is empty", line)
+		case !utf8.ValidString(w):
+			return nil, fmt.Errorf("line %v is not valid UTF-8", line)
+		case strings.ContainsFunc(w, unicode.IsSpace):
+			return nil, fmt.Errorf("line %v has more than one word: %q", line, w)
+		}
+
+		w = norm.NFC.String(w)
+		if first, ok := seen[w]; ok {
+			return nil, fmt.Errorf("line %v repeats %q from line %v", line, w, first)
+		}
+
+		seen[w] = line
+		words = append(words, w)
+	}
+
+	err := sc.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "read wordlist")
+	}
+
+	if len(words) < MinWordlistSize {
+		return nil, fmt.Errorf("the wordlist has %v words, fewer than the minimum of %v", len(words), MinWordlistSize)
+	}
+
+	return &Wordlist{words: words}, nil
+}
+
+// diceRollLen returns the length of the dice rolls that start a diceware
+// line, or 0 if the line doesn't start with rolls and whitespace.
+func diceRollLen(line string) int {
+	n := strings.IndexFunc(line, func(r rune) bool { return r < '1' || r > '6' })
+	if n <= 0 || !unicode.IsSpace(rune(line[n])) {
+		return 0
+	}
+
+	return n
+}
+
+// Words returns the words in the order they were read.
+func (l *Wordlist) Words() []string {
+	return append([]string(nil), l.words...)
+}
+
+// Len returns the number of words.
+func (l *Wordlist) Len() int {
+	return len(l.words)
+}
+
+// BitsPerWord returns the entropy a uniformly chosen word from the list
+// carries.
+func (l *Wordlist) BitsPerWord() float64 {
+	return BitsPerWord(len(l.words))
+}
+
+// WriteWordlist writes words to w, one word per line.
+func WriteWordlist(w io.Writer, words []string) error {
+	bw := bufio.NewWriter(w)
+	for _, word := range words {
+		_, err := bw.WriteString(word + "\n")
+		if err != nil {
+			return errors.Wrap(err, "write word")
+		}
+	}
+
+	return errors.Wrap(bw.Flush(), "flush")
+}
+
+// BitsPerWord returns the entropy a uniformly chosen word from a list of the
+// given size carries.
+func BitsPerWord(wordlistSize int) float64 {
+	if wordlistSize <= 0 {
+		return 0
+	}
+
+	return math.Log2(float64(wordlistSize))
+}
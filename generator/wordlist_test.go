@@ -0,0 +1,98 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testWords returns n distinct words.
+func testWords(n int) []string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = fmt.Sprintf("word%v", i)
+	}
+
+	return words
+}
+
+func TestLoadWordlist(t *testing.T) {
+	words := testWords(MinWordlistSize)
+	lines := strings.Join(words, "\n") + "\n"
+
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"plain", lines, words},
+		{"no final newline", strings.TrimSuffix(lines, "\n"), words},
+		{"byte order mark", "\ufeff" + lines, words},
+		{"crlf", strings.Join(words, "\r\n") + "\r\n", words},
+		{"surrounding whitespace", " " + strings.Join(words, "\t\n  ") + " \n", words},
+		{"nfc", "cafe\u0301\n" + lines, append([]string{"caf\u00e9"}, words...)},
+		{"diceware", effShortData, effShortWords},
+		{"diceware crlf", strings.ReplaceAll(effLongData, "\n", "\r\n"), effLongWords},
+	} {
+		l, err := LoadWordlist(strings.NewReader(tc.input))
+		if err != nil {
+			t.Errorf("%v: %v", tc.name, err)
+			continue
+		}
+
+		if !slices.Equal(l.Words(), tc.want) {
+			t.Errorf("%v: got %q, want %q", tc.name, l.Words(), tc.want)
+		}
+
+		if l.Len() != len(tc.want) {
+			t.Errorf("%v: got length %v, want %v", tc.name, l.Len(), len(tc.want))
+		}
+	}
+}
+
+func TestLoadWordlistErrors(t *testing.T) {
+	lines := strings.Join(testWords(MinWordlistSize), "\n") + "\n"
+
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"too small", strings.Join(testWords(MinWordlistSize-1), "\n"), fmt.Sprintf("has %v words, fewer than the minimum of %v", MinWordlistSize-1, MinWordlistSize)},
+		{"empty", "", "has 0 words"},
+		{"duplicate", lines + "word3\n", fmt.Sprintf("line %v repeats \"word3\" from line 4", MinWordlistSize+1)},
+		{"duplicate after nfc", "caf\u00e9\ncafe\u0301\n" + lines, "line 2 repeats \"caf\u00e9\" from line 1"},
+		{"empty line", "word\n\n" + lines, "line 2 is empty"},
+		{"blank line", "word\n \r\n" + lines, "line 2 is empty"},
+		{"two words", "two words\n" + lines, "line 1 has more than one word"},
+		{"missing rolls", "1111\tword\n" + lines, "line 2: expected 4 dice rolls and a word"},
+		{"other rolls", "1111\tword\n11111\tdrow\n" + lines, "line 2: expected 4 dice rolls and a word"},
+		{"invalid utf-8", "w\xffrd\n" + lines, "line 1 is not valid UTF-8"},
+	} {
+		l, err := LoadWordlist(strings.NewReader(tc.input))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%v: got %v, want an error containing %q", tc.name, err, tc.want)
+		}
+
+		if l != nil {
+			t.Errorf("%v: got a wordlist with the error", tc.name)
+		}
+	}
+}
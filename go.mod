@@ -4,5 +4,9 @@ go 1.21.1
 
 require (
 	github.com/pkg/errors v0.9.1
+	golang.org/x/crypto v0.17.0
 	golang.org/x/exp v0.0.0-20231006140011-7918f672742d
+	golang.org/x/sys v0.15.0
+	golang.org/x/term v0.15.0
+	golang.org/x/text v0.14.0
 )
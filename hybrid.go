@@ -0,0 +1,96 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/AlexSSD7/cpass/generator"
+)
+
+const hybridReportPrefix = "Generated Password: "
+
+func runHybrid(args []string) {
+	fs := flag.NewFlagSet("hybrid", flag.ExitOnError)
+	pf := addPassphraseFlags(fs)
+	blockLength := fs.Uint("block-length", 4, "Length of the random character block")
+	blockUpper := fs.Uint("block-upper", 1, "Number of uppercase characters in the block")
+	blockDigits := fs.Uint("block-digits", 1, "Number of digits in the block")
+	blockSpecial := fs.Uint("block-special", 1, "Number of special characters in the block")
+	blockCharset := fs.String("block-charset", generator.DefaultCharset.Name, "Named charset preset to generate the block from")
+	blockSeparator := fs.String("block-separator", "-", "String to join the passphrase and the block with")
+	order := fs.String("order", "suffix", "Where to put the block: suffix (after the passphrase) or prefix (before it)")
+	count := fs.Int("count", 1, fmt.Sprintf("Number of passwords to generate (1-%v)", maxCount))
+	quiet := fs.Bool("q", false, "Quiet mode: write only the passwords to stdout, one per line, and everything else to stderr")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	if *count < 1 || *count > maxCount {
+		fmt.Fprintf(os.Stderr, "Error: count must be between 1 and %v\n", maxCount)
+		os.Exit(1)
+	}
+
+	if *quiet {
+		ui = os.Stderr
+	}
+
+	var hybridOrder generator.HybridOrder
+	switch *order {
+	case "suffix":
+		hybridOrder = generator.HybridSuffix
+	case "prefix":
+		hybridOrder = generator.HybridPrefix
+	default:
+		fmt.Fprintf(ui, "Error: unknown order %q, expected suffix or prefix\n", *order)
+		os.Exit(1)
+	}
+
+	charset, err := generator.CharsetByName(*blockCharset)
+	if err != nil {
+		fmt.Fprintf(ui, "Error: select block charset: %s\n", err)
+		os.Exit(1)
+	}
+
+	block, err := generator.NewGenerator(uint32(*blockLength), uint32(*blockUpper), uint32(*blockDigits), uint32(*blockSpecial), generator.WithCharset(charset))
+	if err != nil {
+		fmt.Fprintf(ui, "Error: create block generator instance: %s\n", err)
+		os.Exit(1)
+	}
+
+	g, err := generator.NewHybridGenerator(pf.newGenerator(fs), block, hybridOrder, *blockSeparator)
+	if err != nil {
+		fmt.Fprintf(ui, "Error: create hybrid generator instance: %s\n", err)
+		os.Exit(1)
+	}
+
+	entropy, err := g.Entropy()
+	if err != nil {
+		fmt.Fprintf(ui, "Error: get entropy: %s\n", err)
+		os.Exit(1)
+	}
+
+	writeGenerated(*count, *quiet, hybridReportPrefix, "password", g.Generate)
+
+	fmt.Fprintln(ui, passphraseEntropyString(entropy))
+	generator.DiscardBufferedRandomness()
+}
@@ -0,0 +1,66 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/AlexSSD7/cpass/generator"
+)
+
+func runIdentifier(args []string) {
+	fs := flag.NewFlagSet("identifier", flag.ExitOnError)
+	length := fs.Uint("length", 12, fmt.Sprintf("Identifier length (1-%v)", generator.MaxIdentifierLength))
+	count := fs.Int("count", 1, "Number of distinct identifiers to generate")
+	startWithLetter := fs.Bool("start-with-letter", false, "Require the identifier to start with a letter, for systems stricter than RFC 1123")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	if *count < 1 {
+		fmt.Fprint(os.Stderr, "Error: count must be at least 1\n")
+		os.Exit(1)
+	}
+
+	if *length > generator.MaxIdentifierLength {
+		fmt.Fprintf(os.Stderr, "Error: length (%v) exceeds the maximum DNS label length of %v\n", *length, generator.MaxIdentifierLength)
+		os.Exit(1)
+	}
+
+	g, err := generator.NewIdentifierGenerator(uint32(*length), *startWithLetter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: create identifier generator instance: %s\n", err)
+		os.Exit(1)
+	}
+
+	ids, err := g.GenerateUnique(*count)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: generate identifiers: %s\n", err)
+		os.Exit(1)
+	}
+
+	for _, id := range ids {
+		fmt.Println(string(id))
+	}
+
+	fmt.Fprintf(os.Stderr, "Entropy per identifier: %.2f bits (%v)\n", g.Entropy(), getRatingString(g.Entropy()))
+}
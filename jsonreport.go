@@ -0,0 +1,294 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// JSONSchemaVersion is the major version of the JSON output. Within a major
+// version, fields are only ever added, never removed, renamed, or changed in
+// type, which TestJSONSchemaIsAdditive checks against the fixture in
+// testdata. Anything else bumps it.
+const JSONSchemaVersion = 1
+
+// JSONReport is the object -format json writes for every password, on a
+// single line. All fields are stable unless noted otherwise.
+type JSONReport struct {
+	// SchemaVersion is JSONSchemaVersion.
+	SchemaVersion int `json:"schema_version"`
+	// Version is the same as SchemaVersion, kept for consumers from before
+	// it. Deprecated: use SchemaVersion.
+	Version int `json:"version"`
+	// Password is the generated password. It must stay the third field, see
+	// writeJSONReport.
+	Password JSONSecret `json:"password"`
+	// GeneratedAt is when the password was generated, in RFC 3339 in UTC.
+	GeneratedAt string `json:"generated_at"`
+	// Mode is what was generated: "password" for the character mode.
+	Mode string `json:"mode"`
+	// Length is the length of the password in characters.
+	Length uint32 `json:"length"`
+	// Bytes is the length of the UTF-8 encoded password.
+	Bytes int `json:"bytes"`
+	// UppercaseCount, DigitCount and SpecialCount are the class counts of
+	// the policy. They are the same as in Policy.
+	UppercaseCount uint32 `json:"uppercase_count"`
+	DigitCount     uint32 `json:"digit_count"`
+	SpecialCount   uint32 `json:"special_count"`
+	// Charset is the name of the charset, the same as in Policy.
+	Charset string `json:"charset"`
+	// Policy is the policy the password was generated with.
+	Policy JSONPolicy `json:"policy"`
+	// EntropyMin, EntropyMax and Entropy are the minimum, maximum, and
+	// realistic entropy in bits.
+	EntropyMin uint64  `json:"entropy_min"`
+	EntropyMax uint64  `json:"entropy_max"`
+	Entropy    float64 `json:"entropy"`
+	// Rating rates Entropy, e.g. "Good". The wording may change, so match it
+	// for display only and compare Entropy instead.
+	Rating string `json:"rating"`
+}
+
+// JSONPolicy is the policy of a JSONReport.
+type JSONPolicy struct {
+	Length         uint32 `json:"length"`
+	UppercaseCount uint32 `json:"uppercase_count"`
+	DigitCount     uint32 `json:"digit_count"`
+	SpecialCount   uint32 `json:"special_count"`
+	Charset        string `json:"charset"`
+	// Counts is how the class counts are met: "exact" or "minimum".
+	Counts string `json:"counts"`
+}
+
+// JSONSecret is a password in a JSON object. It marshals as null, so that
+// encoding/json, which would leave copies in its internal buffers, never
+// sees it. writeJSONReport writes it in place of the null.
+type JSONSecret []byte
+
+func (JSONSecret) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+func (s *JSONSecret) UnmarshalJSON(data []byte) error {
+	var v *string
+	err := json.Unmarshal(data, &v)
+	if v != nil {
+		*s = JSONSecret(*v)
+	}
+
+	return err
+}
+
+// newJSONReport returns the report of a password of params without the
+// password itself.
+func newJSONReport(params passwordParams, charset, counts string, entropy entropyReport) JSONReport {
+	return JSONReport{
+		SchemaVersion:  JSONSchemaVersion,
+		Version:        JSONSchemaVersion,
+		GeneratedAt:    time.Now().UTC().Format(time.RFC3339),
+		Mode:           "password",
+		Length:         params.length,
+		UppercaseCount: params.uppercaseCount,
+		DigitCount:     params.digitCount,
+		SpecialCount:   params.specialCount,
+		Charset:        charset,
+		Policy: JSONPolicy{
+			Length:         params.length,
+			UppercaseCount: params.uppercaseCount,
+			DigitCount:     params.digitCount,
+			SpecialCount:   params.specialCount,
+			Charset:        charset,
+			Counts:         counts,
+		},
+		EntropyMin: entropy.min,
+		EntropyMax: entropy.max,
+		Entropy:    entropy.avg,
+		Rating:     getRatingString(entropy.avg),
+	}
+}
+
+// writeJSONReport writes report with pw as a single-line JSON object. The
+// rest of the report is marshaled with encoding/json, and pw is written in
+// place of the null its field marshals as, in a buffer that is wiped
+// afterwards.
+func writeJSONReport(w io.Writer, pw []byte, report JSONReport) error {
+	report.Bytes = len(pw)
+
+	var meta bytes.Buffer
+	enc := json.NewEncoder(&meta)
+	enc.SetEscapeHTML(false)
+
+	err := enc.Encode(report)
+	if err != nil {
+		return errors.Wrap(err, "marshal report")
+	}
+
+	prefix := fmt.Appendf(nil, `{"schema_version":%d,"version":%d,"password":`, report.SchemaVersion, report.Version)
+	if !bytes.HasPrefix(meta.Bytes(), append(prefix, "null"...)) {
+		return fmt.Errorf("the password is not the third field of the report")
+	}
+
+	rest := meta.Bytes()[len(prefix)+len("null"):]
+
+	// Every escaped byte takes up at most 6 bytes, so that the buffer is
+	// never reallocated, which would leave a copy behind.
+	buf := make([]byte, 0, len(prefix)+6*len(pw)+2+len(rest))
+	defer func() {
+		wipe(buf[:cap(buf)])
+	}()
+
+	buf = append(buf, prefix...)
+	buf = appendJSONString(buf, pw)
+	buf = append(buf, rest...)
+
+	_, err = w.Write(buf)
+
+	return err
+}
+
+// JSONTokenReport is the object cpass token -emit writes for every token,
+// on a single line. All fields are stable unless noted otherwise.
+type JSONTokenReport struct {
+	// SchemaVersion is JSONSchemaVersion.
+	SchemaVersion int `json:"schema_version"`
+	// GeneratedAt is when the token was generated, in RFC 3339 in UTC.
+	GeneratedAt string `json:"generated_at"`
+	// Mode is "token".
+	Mode string `json:"mode"`
+	// Bytes is the number of random bytes the encodings encode.
+	Bytes int `json:"bytes"`
+	// Entropy is the entropy of the bytes in bits, the same for every
+	// encoding.
+	Entropy float64 `json:"entropy"`
+	// Rating rates Entropy, like JSONReport.Rating.
+	Rating string `json:"rating"`
+	// Encodings maps the names of the requested encodings to the bytes in
+	// them. It must stay the last field, see writeTokenJSONReport.
+	Encodings JSONSecrets `json:"encodings"`
+}
+
+// JSONSecrets maps names to secrets in a JSON object, in order. Like
+// JSONSecret, it marshals as null, and writeTokenJSONReport writes it in
+// place of the null.
+type JSONSecrets struct {
+	Names  []string
+	Values [][]byte
+}
+
+func (JSONSecrets) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+func (s *JSONSecrets) UnmarshalJSON(data []byte) error {
+	var m map[string]string
+	err := json.Unmarshal(data, &m)
+	if err != nil {
+		return err
+	}
+
+	s.Names, s.Values = nil, nil
+	for name, v := range m {
+		s.Names = append(s.Names, name)
+		s.Values = append(s.Values, []byte(v))
+	}
+
+	return nil
+}
+
+// writeTokenJSONReport writes report as a single-line JSON object, with its
+// encodings written in place of the null they marshal as, in a buffer that
+// is wiped afterwards.
+func writeTokenJSONReport(w io.Writer, report JSONTokenReport) error {
+	var meta bytes.Buffer
+	enc := json.NewEncoder(&meta)
+	enc.SetEscapeHTML(false)
+
+	err := enc.Encode(report)
+	if err != nil {
+		return errors.Wrap(err, "marshal report")
+	}
+
+	const suffix = `"encodings":null}` + "\n"
+	if !bytes.HasSuffix(meta.Bytes(), []byte(suffix)) {
+		return fmt.Errorf("the encodings are not the last field of the report")
+	}
+
+	head := meta.Bytes()[:meta.Len()-len(suffix)]
+
+	// The names are escaped as well, and every escaped byte takes up at
+	// most 6 bytes, so that the buffer is never reallocated.
+	size := len(head) + len(suffix)
+	for i, v := range report.Encodings.Values {
+		size += 6*(len(report.Encodings.Names[i])+len(v)) + 6
+	}
+
+	buf := make([]byte, 0, size)
+	defer func() {
+		wipe(buf[:cap(buf)])
+	}()
+
+	buf = append(buf, head...)
+	buf = append(buf, `"encodings":{`...)
+	for i, v := range report.Encodings.Values {
+		if i != 0 {
+			buf = append(buf, ',')
+		}
+
+		buf = appendJSONString(buf, []byte(report.Encodings.Names[i]))
+		buf = append(buf, ':')
+		buf = appendJSONString(buf, v)
+	}
+	buf = append(buf, "}}\n"...)
+
+	_, err = w.Write(buf)
+
+	return err
+}
+
+// appendJSONString appends s as a quoted JSON string. The caller makes sure
+// dst has room for it, so that it is not reallocated, leaving a copy behind.
+func appendJSONString(dst []byte, s []byte) []byte {
+	const hex = "0123456789abcdef"
+
+	dst = append(dst, '"')
+	for len(s) != 0 {
+		r, size := utf8.DecodeRune(s)
+
+		switch {
+		case r == '"' || r == '\\':
+			dst = append(dst, '\\', byte(r))
+		case r < 0x20:
+			dst = append(dst, '\\', 'u', '0', '0', hex[r>>4], hex[r&0xf])
+		case r == utf8.RuneError && size == 1:
+			dst = append(dst, `\ufffd`...)
+		default:
+			dst = append(dst, s[:size]...)
+		}
+
+		s = s[size:]
+	}
+
+	return append(dst, '"')
+}
@@ -0,0 +1,230 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlexSSD7/cpass/generator"
+)
+
+// jsonSchema is the fixture of the fields of a JSON report and their JSON
+// types, with the fields of nested objects as "object.field".
+type jsonSchema struct {
+	SchemaVersion int               `json:"schema_version"`
+	Fields        map[string]string `json:"fields"`
+}
+
+// jsonFieldTypes returns the JSON types of the fields of v, in the form of
+// jsonSchema.
+func jsonFieldTypes(prefix string, v map[string]any, types map[string]string) {
+	for name, field := range v {
+		switch field := field.(type) {
+		case string:
+			types[prefix+name] = "string"
+		case float64:
+			types[prefix+name] = "number"
+		case bool:
+			types[prefix+name] = "boolean"
+		case map[string]any:
+			types[prefix+name] = "object"
+			jsonFieldTypes(prefix+name+".", field, types)
+		default:
+			types[prefix+name] = "null"
+		}
+	}
+}
+
+func sampleJSONReport(t *testing.T, pw string) ([]byte, JSONReport) {
+	t.Helper()
+
+	report := newJSONReport(passwordParams{length: 12, uppercaseCount: 2, digitCount: 2, specialCount: 1}, "default", "exact", entropyReport{min: 50, avg: 60.5, max: 70})
+
+	var buf bytes.Buffer
+	err := writeJSONReport(&buf, []byte(pw), report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes(), report
+}
+
+// sampleTokenJSONReport returns the report of a token in every encoding.
+func sampleTokenJSONReport(t *testing.T) []byte {
+	t.Helper()
+
+	names := generator.TokenEncodingNames()
+	values := make([][]byte, len(names))
+	for i := range values {
+		values[i] = []byte("token-" + names[i])
+	}
+
+	var buf bytes.Buffer
+	err := writeTokenJSONReport(&buf, JSONTokenReport{
+		SchemaVersion: JSONSchemaVersion,
+		GeneratedAt:   time.Now().UTC().Format(time.RFC3339),
+		Mode:          "token",
+		Bytes:         32,
+		Entropy:       256,
+		Rating:        getRatingString(256),
+		Encodings:     JSONSecrets{Names: names, Values: values},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// TestJSONSchemaIsAdditive marshals the reports and checks their fields
+// against the fixtures: within a schema version, a field may be added to
+// both, but never removed, renamed, or given another type.
+func TestJSONSchemaIsAdditive(t *testing.T) {
+	password, _ := sampleJSONReport(t, "pw")
+
+	for _, tc := range []struct {
+		fixture string
+		out     []byte
+	}{
+		{"json_report_schema.json", password},
+		{"json_token_report_schema.json", sampleTokenJSONReport(t)},
+	} {
+		t.Run(tc.fixture, func(t *testing.T) {
+			checkJSONSchema(t, tc.fixture, tc.out)
+		})
+	}
+}
+
+func checkJSONSchema(t *testing.T, fixtureName string, out []byte) {
+	data, err := os.ReadFile(filepath.Join("testdata", fixtureName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fixture jsonSchema
+	err = json.Unmarshal(data, &fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fixture.SchemaVersion != JSONSchemaVersion {
+		t.Fatalf("the fixture is for schema version %v, but JSONSchemaVersion is %v: start a new fixture for the new major version", fixture.SchemaVersion, JSONSchemaVersion)
+	}
+
+	var v map[string]any
+	err = json.Unmarshal(out, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	jsonFieldTypes("", v, got)
+
+	for name, want := range fixture.Fields {
+		typ, ok := got[name]
+		switch {
+		case !ok:
+			t.Errorf("field %q was removed or renamed, which needs a new schema version", name)
+		case typ != want:
+			t.Errorf("field %q is a %v, but was a %v, which needs a new schema version", name, typ, want)
+		}
+	}
+
+	for name, typ := range got {
+		if _, ok := fixture.Fields[name]; !ok {
+			t.Errorf("new field %q (%v) is missing from the fixture", name, typ)
+		}
+	}
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	for _, pw := range []string{"abc", `q"u\o\te`, "<&>", "\x01\n\t", "пароль€", "null"} {
+		out, report := sampleJSONReport(t, pw)
+
+		if !bytes.HasSuffix(out, []byte("}\n")) || bytes.Count(out, []byte("\n")) != 1 {
+			t.Errorf("%q: report %q is not a single line", pw, out)
+		}
+
+		var got JSONReport
+		err := json.Unmarshal(out, &got)
+		if err != nil {
+			t.Fatalf("%q: %s in %s", pw, err, out)
+		}
+
+		if string(got.Password) != pw {
+			t.Errorf("password %q came back as %q", pw, got.Password)
+		}
+
+		if got.Bytes != len(pw) || got.Mode != "password" || got.SchemaVersion != JSONSchemaVersion || got.Version != JSONSchemaVersion {
+			t.Errorf("%q: unexpected report %+v", pw, got)
+		}
+
+		if got.Policy != report.Policy || got.Policy.Counts != "exact" || got.Policy.Length != 12 {
+			t.Errorf("%q: policy %+v, want %+v", pw, got.Policy, report.Policy)
+		}
+
+		if _, err := time.Parse(time.RFC3339, got.GeneratedAt); err != nil {
+			t.Errorf("%q: generated_at %q is not RFC 3339: %s", pw, got.GeneratedAt, err)
+		}
+
+		if got.Entropy != 60.5 || got.EntropyMin != 50 || got.EntropyMax != 70 || got.Rating != getRatingString(60.5) {
+			t.Errorf("%q: entropy fields %+v", pw, got)
+		}
+	}
+}
+
+func TestJSONSecretMarshalsAsNull(t *testing.T) {
+	out, err := json.Marshal(JSONReport{Password: JSONSecret("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(out, []byte("secret")) {
+		t.Errorf("encoding/json saw the password: %s", out)
+	}
+}
+
+func TestWriteTokenJSONReport(t *testing.T) {
+	names := []string{"hex", "base64"}
+	values := [][]byte{[]byte("00ff"), []byte("AP8=")}
+
+	var buf bytes.Buffer
+	err := writeTokenJSONReport(&buf, JSONTokenReport{SchemaVersion: JSONSchemaVersion, Mode: "token", Bytes: 2, Entropy: 16, Encodings: JSONSecrets{Names: names, Values: values}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"schema_version":1,"generated_at":"","mode":"token","bytes":2,"entropy":16,"rating":"","encodings":{"hex":"00ff","base64":"AP8="}}` + "\n"
+	if buf.String() != want {
+		t.Errorf("report is\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	var got JSONTokenReport
+	err = json.Unmarshal(buf.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Encodings.Names) != 2 {
+		t.Errorf("encodings %v, want 2", got.Encodings.Names)
+	}
+}
@@ -0,0 +1,105 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+)
+
+// keystrokeCount is how many keystrokes -extra-entropy collects. Each one is
+// stored as the key and the nanoseconds since the previous keystroke.
+const keystrokeCount = 64
+
+var errKeystrokesAborted = fmt.Errorf("keystroke input aborted")
+
+// readKeystrokeTimings asks for random keys to be typed on the terminal and
+// returns the keys together with the time between them, to be mixed into the
+// system randomness with generator.NewMixedSource. No entropy is credited for
+// them. The caller wipes the returned bytes.
+func readKeystrokeTimings(p *prompter) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+
+	fmt.Fprintf(ui, "Type random keys for a few seconds, they will be mixed into the system randomness.\n")
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, errors.Wrap(err, "make terminal raw")
+	}
+
+	restore := func() {
+		_ = term.Restore(fd, state)
+	}
+
+	// Raw mode turns Ctrl-C into a regular key press, but cpass can still be
+	// terminated from the outside, so restore the terminal then too.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-sigCh:
+			restore()
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	defer restore()
+
+	// The keys are never echoed, only counted.
+	buf := make([]byte, 0, keystrokeCount*9)
+	last := time.Now()
+	for n := 0; n < keystrokeCount; n++ {
+		stdoutScreen().clearLine()
+		fmt.Fprintf(ui, "Keystrokes: %v of %v%s > ", n, keystrokeCount, p.timeLeft())
+
+		key, err := p.readByte()
+		if err != nil {
+			wipe(buf)
+			return nil, errors.Wrap(err, "read key")
+		}
+
+		if key == 0x03 || key == 0x04 {
+			fmt.Fprint(ui, "\r\n")
+			wipe(buf)
+
+			return nil, errKeystrokesAborted
+		}
+
+		now := time.Now()
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(now.Sub(last).Nanoseconds()))
+		buf = append(buf, key)
+		last = now
+	}
+
+	stdoutScreen().clearLine()
+	fmt.Fprintf(ui, "Keystrokes: %v of %v\r\n", keystrokeCount, keystrokeCount)
+
+	return buf, nil
+}
@@ -17,135 +17,1528 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"runtime"
-	"strconv"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/AlexSSD7/cpass/clipboard"
 	"github.com/AlexSSD7/cpass/generator"
 	"github.com/pkg/errors"
 	"golang.org/x/exp/constraints"
+	"golang.org/x/term"
 )
 
 const Version = "v0.1.0"
 
-func askUint32(stdinReader *bufio.Reader, prompt string) (uint32, error) {
-	fmt.Printf("%s > ", prompt)
-	b, err := stdinReader.ReadBytes('\n')
-	if err != nil {
-		return 0, errors.Wrap(err, "read bytes")
-	}
+// ui receives everything cpass prints for the user to read: the banner,
+// prompts, reports, and errors. In quiet mode, it is stderr, so that stdout
+// carries nothing but the passwords.
+var ui io.Writer = os.Stdout
 
-	v, err := strconv.ParseUint(strings.ReplaceAll(string(b), "\n", ""), 10, 32)
-	if err != nil {
-		return 0, errors.Wrap(err, "parse uint")
+func isPowerOfTwo[T constraints.Unsigned](v T) bool {
+	return v > 0 && (v&(v-1)) == 0
+}
+
+func charsetPreview(charset string) string {
+	n := 0
+	for i := range charset {
+		if n == 5 {
+			return charset[:i]
+		}
+
+		n++
 	}
 
-	return uint32(v), nil
+	return charset
 }
 
-func askYesNo(stdinReader *bufio.Reader, prompt string) (bool, error) {
-	fmt.Printf("%s [y/n] > ", prompt)
-	b, err := stdinReader.ReadBytes('\n')
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "wordlist":
+			runWordlist(args[1:])
+			return
+		case "identifier":
+			runIdentifier(args[1:])
+			return
+		case "passphrase":
+			runPassphrase(args[1:])
+			return
+		case "words":
+			runWords(args[1:])
+			return
+		case "hybrid":
+			runHybrid(args[1:])
+			return
+		case "pin":
+			runPIN(args[1:])
+			return
+		case "pronounceable":
+			runPronounceable(args[1:])
+			return
+		case "token":
+			runToken(args[1:])
+			return
+		case "uuid":
+			runUUID(args[1:])
+			return
+		case "totp":
+			runTOTP(args[1:])
+			return
+		case "recovery-codes":
+			runRecoveryCodes(args[1:])
+			return
+		case "preset":
+			runPreset(args[1:])
+			return
+		case "charsets":
+			runCharsets(args[1:])
+			return
+		case "site":
+			runSite(args[1:])
+			return
+		case "derive":
+			runDerive(args[1:])
+			return
+		case "breachdb":
+			runBreachDB(args[1:])
+			return
+		case "selftest":
+			runSelftest(args[1:])
+			return
+		case "check":
+			runCheck(args[1:])
+			return
+		case "config":
+			runConfig(args[1:])
+			return
+		}
+	}
+
+	var charsetNames charsetList
+	flag.Var(&charsetNames, "charset", "Named charset preset to generate the password from; repeat to only use characters all of them allow (default \""+generator.DefaultCharset.Name+"\")")
+	charsetFile := flag.String("charset-file", "", "Read the charset to generate the password from from this file (see LoadCharset in the generator package for the format)")
+	letterChars := flag.String("letter-chars", "", "Replace the letters of the charset with these lowercase letters")
+	digitChars := flag.String("digit-chars", "", "Replace the digits of the charset with these characters")
+	unicodeChars := flag.Bool("unicode", false, "Allow non-ASCII characters such as accented letters, Cyrillic or CJK in -letter-chars, -digit-chars and -special-chars")
+	specialChars := flag.String("special-chars", "", "Replace the special characters of the charset with these, e.g. the few a site allows")
+	noShift := flag.Bool("no-shift", false, "Only use characters that can be typed without Shift on a US keyboard (same as -charset "+generator.NoShiftCharset.Name+")")
+	layoutPortable := flag.Bool("layout-portable", false, "Only use characters that are on the same key on QWERTY, QWERTZ and AZERTY keyboards (same as -charset "+generator.LayoutPortableCharset.Name+")")
+	shellSafe := flag.Bool("shell-safe", false, "Only use special characters that need no escaping in shell commands (same as -charset "+generator.ShellSafeCharset.Name+")")
+	quoteSafe := flag.Bool("quote-safe", false, "Only use special characters that need no quoting or escaping in config files, JSON and SQL (same as -charset "+generator.QuoteSafeCharset.Name+")")
+	bits := flag.Uint64("bits", 0, "Pick the shortest password length that reaches at least this many bits of minimum entropy instead of asking for it")
+	fullAlphabet := flag.Bool("full-alphabet", false, "Also use the letters l and o, which are left out by default as they are easily mistaken for 1 and 0")
+	space := flag.Bool("space", false, "Also use the space as a special character")
+	unambiguous := flag.Bool("unambiguous", false, "Leave out the characters that are easily misread as each other, such as 0 and O or 1, I and |")
+	exclude := flag.String("exclude", "", "Characters to leave out of the password, e.g. ones a backend rejects")
+	maxRepeats := flag.Uint("max-repeats", 0, "Allow any single character to appear at most this many times (0 for no limit)")
+	lengthFlag := flag.Uint("length", 0, "Password length, skips the prompt")
+	upperFlag := flag.Uint("upper", 0, "Number of uppercase characters, skips the prompt")
+	digitsFlag := flag.Uint("digits", 0, "Number of digit characters, skips the prompt")
+	specialFlag := flag.Uint("special", 0, "Number of special characters, skips the prompt")
+	maxBytes := flag.Uint("max-bytes", 0, "Limit the UTF-8 encoded password length to this many bytes, e.g. 72 for bcrypt (0 for no limit)")
+	counts := flag.String("counts", "exact", "How to meet the uppercase, digit, and special counts: exact, or minimum (the rest of the password may have more of the counted classes by chance)")
+	minLowercase := flag.Uint("min-lowercase", 0, "Make sure the password has at least this many lowercase letters")
+	minClasses := flag.Uint("min-classes", 0, "Include characters from at least this many of the lowercase, uppercase, digit, and special classes (0 for no requirement)")
+	policy := flag.String("policy", "", `Generate using this policy string instead of the equivalent flags, e.g. "length=16 upper=2 digits=3 special=1 charset=no-shift maxrepeat=2 classes>=3"`)
+	siteName := flag.String("site", "", "Generate using the policy stored for this site (see cpass site)")
+	trace := flag.Bool("trace", false, "Log every consumption of randomness to stderr as JSON lines (sensitive, for auditing only)")
+	formatTemplate := flag.String("format-template", "", `Print each password using this template instead of the default report. Verbs: %p password, %e entropy, %r rating, %l length, %n index, %% percent; escapes: \t, \n, \\`)
+	speak := flag.Bool("speak", false, "Read each generated password aloud character by character using the system text-to-speech engine")
+	speakRate := flag.Uint("speak-rate", 120, "Speech rate for -speak in words per minute")
+	mnemonic := flag.Bool("mnemonic", false, "Show a memorization aid after the password: a sentence of a random word per letter, with digits and symbols kept as they are")
+	big := flag.Bool("big", false, "Show each password in large block letters, e.g. to read it out across the room")
+	compare := flag.Bool("compare", false, "After each password, show how the entropy would change with nearby policies")
+	displayTTL := flag.Duration("display-ttl", 0, "Clear the password from the screen after this long, e.g. 30s (terminals only)")
+	minDistance := flag.Uint("min-distance", 0, "Require the password to differ from the previous one in at least this many positions")
+	minEditDistance := flag.Uint("min-edit-distance", 0, "Require at least this edit distance between the password and the previous one")
+	previousFile := flag.String("previous-file", "", "Read the previous password for -min-distance and -min-edit-distance from this file instead of a hidden prompt")
+	stepRevealFlag := flag.Bool("step-reveal", false, "Don't show the password at once, step through it one character at a time instead (terminals only)")
+	allowRecording := flag.Bool("allow-recording", false, "Don't ask for confirmation when the session appears to be recorded")
+	timeout := flag.Duration("timeout", 0, "End the session, wiping and clearing the passwords, when no input arrives for this long, e.g. 120s (0 for no timeout)")
+	noSandbox := flag.Bool("no-sandbox", false, "Don't restrict what cpass may do once it has started")
+	fresh := flag.Bool("fresh", false, "Don't offer the parameters remembered from the last run as defaults")
+	format := flag.String("format", "text", "Output format: text, or json for a JSON object per password on stdout with everything else on stderr")
+	count := flag.Uint("count", 1, "Generate this many passwords with the same parameters (at most 1000)")
+	quiet := flag.Bool("q", false, "Write only the password to stdout, everything else goes to stderr")
+	copyFlag := flag.Bool("copy", false, "Copy the password to the clipboard instead of showing it, and clear the clipboard again after -copy-timeout")
+	copyOSC52 := flag.Bool("copy-osc52", false, "Like -copy, but set the clipboard of the terminal with the OSC 52 escape sequence, e.g. over SSH")
+	hidden := flag.Bool("hidden", false, "Never show the password, show a masked placeholder and copy it to the clipboard instead (with -copy-osc52 if given)")
+	copyTimeout := flag.Duration("copy-timeout", 30*time.Second, "Clear the clipboard this long after -copy or -copy-osc52, unless something else was copied since")
+	copyRestore := flag.Bool("copy-restore", false, "When the clipboard is cleared, put back what it held before the password instead, if it still holds the password (the previous contents are kept in memory until then)")
+	copyOnly := flag.Bool("copy-only", false, "Like -copy (or -copy-osc52 if given), but never show the password, not even masked, and only confirm the copy")
+	outFile := flag.String("out", "", "Also write the password to this file with mode 0600, replacing it")
+	outOnly := flag.Bool("out-only", false, "With -out, never show the password, only confirm that it was written")
+	execCmd := flag.String("exec", "", "Also pass the password on stdin to this command, e.g. a password manager; it is split at spaces and run without a shell")
+	execOnly := flag.Bool("exec-only", false, "With -exec, never show the password, only confirm that it was passed on")
+	dice := flag.Bool("dice", false, "Ask for physical dice rolls and generate the password from them instead of the system random number generator")
+	extraEntropy := flag.Bool("extra-entropy", false, "Ask for random keystrokes and mix their timings into the system random number generator's output")
+	insecureSeed := flag.String("insecure-seed", "", "INSECURE, for test fixtures only: derive every password from this hex-encoded seed, so anyone who knows it knows the passwords (needs -i-know-this-is-insecure)")
+	insecureOK := flag.Bool("i-know-this-is-insecure", false, "Confirm that -insecure-seed makes the passwords predictable")
+	err := parseFlags(flag.CommandLine, args)
 	if err != nil {
-		return false, errors.Wrap(err, "read bytes")
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
 	}
 
-	return strings.EqualFold(string(b[0]), "y"), nil
-}
+	err = applyPolicy(flag.CommandLine, passwordPolicyKeys, *policy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -policy: %s\n", err)
+		os.Exit(2)
+	}
 
-func isPowerOfTwo[T constraints.Unsigned](v T) bool {
-	return v > 0 && (v&(v-1)) == 0
-}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unknown output format %q, expected text or json\n", *format)
+		os.Exit(2)
+	}
 
-func main() {
-	fmt.Printf("cpass %v %v/%v %v. Copyright (c) 2023 The cpass Authors. Distributed under GNU GPL v3, this program comes with ABSOLUTELY NO WARRANTY.\n", Version, runtime.GOOS, runtime.GOARCH, runtime.Version())
+	jsonOut := *format == "json"
 
-	stdinReader := bufio.NewReader(os.Stdin)
+	var countMode generator.CountMode
+	switch *counts {
+	case "exact":
+		countMode = generator.CountsExact
+	case "minimum":
+		countMode = generator.CountsMinimum
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown count mode %q, expected exact or minimum\n", *counts)
+		os.Exit(2)
+	}
 
-	var pwLen uint32
-	var err error
+	// Quiet, JSON and template output are read by programs, so stdout
+	// carries nothing else.
+	pwOut := os.Stdout
+	if *quiet || jsonOut || *formatTemplate != "" {
+		ui = os.Stderr
+	}
 
-	for {
-		pwLen, err = askUint32(stdinReader, "Password length")
+	fmt.Fprintf(ui, "cpass %v %v/%v %v. Copyright (c) 2023 The cpass Authors. Distributed under GNU GPL v3, this program comes with ABSOLUTELY NO WARRANTY.\n", Version, runtime.GOOS, runtime.GOARCH, runtime.Version())
+
+	var site *sitePolicy
+	if *siteName != "" {
+		var conflicting []string
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "charset", "charset-file", "letter-chars", "digit-chars", "special-chars", "no-shift", "layout-portable", "shell-safe", "quote-safe", "bits", "max-repeats", "min-classes", "length", "upper", "digits", "special", "policy":
+				conflicting = append(conflicting, "-"+f.Name)
+			}
+		})
+
+		if len(conflicting) != 0 {
+			fmt.Fprintf(ui, "Error: -site cannot be combined with %v, the site policy defines them\n", strings.Join(conflicting, ", "))
+			os.Exit(1)
+		}
+
+		site, err = loadSitePolicy(*siteName)
 		if err != nil {
-			fmt.Printf("Error: ask for password length: %s\n", err)
+			fmt.Fprintf(ui, "Error: load site policy: %s\n", err)
 			os.Exit(1)
 		}
 
-		if pwLen%10 == 0 || isPowerOfTwo(pwLen) {
-			fmt.Print("WARN: Detected a common base-ten (10, 20, etc) or power-of-two (16, 32, etc) password length. It's recommended to use something more random.\n")
-			yes, err := askYesNo(stdinReader, "Change password length?")
-			if err != nil {
-				fmt.Printf("Error: ask for yes/no: %s\n", err)
-				os.Exit(1)
+		charsetNames = charsetList{site.Charset}
+		*maxRepeats = uint(site.MaxRepeats)
+		*minClasses = uint(site.MinClasses)
+
+		fmt.Fprintf(ui, "Using the policy for %v: %v.\n", *siteName, site.describe())
+		if site.Notes != "" {
+			fmt.Fprintf(ui, "Notes: %v\n", site.Notes)
+		}
+	}
+
+	if *noShift {
+		charsetNames = append(charsetNames, generator.NoShiftCharset.Name)
+	}
+
+	if *layoutPortable {
+		charsetNames = append(charsetNames, generator.LayoutPortableCharset.Name)
+	}
+
+	if *shellSafe {
+		charsetNames = append(charsetNames, generator.ShellSafeCharset.Name)
+	}
+
+	if *quoteSafe {
+		charsetNames = append(charsetNames, generator.QuoteSafeCharset.Name)
+	}
+
+	if *charsetFile != "" && len(charsetNames) != 0 {
+		fmt.Fprint(ui, "Error: -charset-file cannot be combined with -charset or the flags that select a charset preset\n")
+		os.Exit(1)
+	}
+
+	if len(charsetNames) == 0 {
+		charsetNames = charsetList{generator.DefaultCharset.Name}
+	}
+
+	var fixed fixedParams
+	flag.Visit(func(f *flag.Flag) {
+		v := func(n *uint) *uint32 {
+			ret := uint32(*n)
+			return &ret
+		}
+
+		switch f.Name {
+		case "length":
+			fixed.length = v(lengthFlag)
+		case "upper":
+			fixed.uppercaseCount = v(upperFlag)
+		case "digits":
+			fixed.digitCount = v(digitsFlag)
+		case "special":
+			fixed.specialCount = v(specialFlag)
+		}
+	})
+
+	if fixed.length != nil && *bits != 0 {
+		fmt.Fprint(os.Stderr, "Error: -length and -bits cannot be used together\n")
+		os.Exit(1)
+	}
+
+	// Fail before prompting for the rest if the given values can't work.
+	if err := fixed.check(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	if *quiet || jsonOut {
+		var conflicting []string
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "format-template", "big", "step-reveal", "display-ttl":
+				conflicting = append(conflicting, "-"+f.Name)
+			}
+		})
+
+		mode := "-q"
+		if jsonOut {
+			mode = "-format json"
+		}
+
+		if len(conflicting) != 0 {
+			fmt.Fprintf(ui, "Error: %v cannot be combined with %v\n", mode, strings.Join(conflicting, ", "))
+			os.Exit(1)
+		}
+	}
+
+	if *count < 1 || *count > maxCount {
+		fmt.Fprintf(ui, "Error: -count must be between 1 and %v\n", maxCount)
+		os.Exit(1)
+	}
+
+	if *count > 1 {
+		var conflicting []string
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "big", "step-reveal", "display-ttl", "speak", "mnemonic":
+				conflicting = append(conflicting, "-"+f.Name)
 			}
+		})
 
-			if !yes {
-				fmt.Print("WARN: Going with unsafe password length.\n")
-				break
+		if len(conflicting) != 0 {
+			fmt.Fprintf(ui, "Error: -count cannot be combined with %v\n", strings.Join(conflicting, ", "))
+			os.Exit(1)
+		}
+	}
+
+	// The aid spells out the password, so it is only shown along with it.
+	if *mnemonic {
+		var conflicting []string
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "q", "format-template", "step-reveal", "display-ttl", "copy", "copy-osc52", "copy-only", "hidden", "out-only", "exec-only":
+				conflicting = append(conflicting, "-"+f.Name)
 			}
-		} else {
-			break
+		})
+
+		if jsonOut {
+			conflicting = append(conflicting, "-format json")
+		}
+
+		if len(conflicting) != 0 {
+			fmt.Fprintf(ui, "Error: -mnemonic cannot be combined with %v\n", strings.Join(conflicting, ", "))
+			os.Exit(1)
 		}
 	}
 
-	uppercaseCount, err := askUint32(stdinReader, "Number of uppercase characters to include (ABCDE)")
-	if err != nil {
-		fmt.Printf("Error: ask for uppercase character count: %s\n", err)
+	if *copyFlag && *copyOSC52 {
+		fmt.Fprint(ui, "Error: -copy and -copy-osc52 cannot be used together\n")
 		os.Exit(1)
 	}
 
-	digitCount, err := askUint32(stdinReader, "Number of digit characters to include (01234)")
-	if err != nil {
-		fmt.Printf("Error: ask for digit character count: %s\n", err)
+	copyMode := "-copy"
+	switch {
+	case *hidden:
+		copyMode = "-hidden"
+	case *copyOnly:
+		copyMode = "-copy-only"
+	case *copyOSC52:
+		copyMode = "-copy-osc52"
+	}
+
+	if *copyFlag || *copyOSC52 || *hidden || *copyOnly {
+		var conflicting []string
+		copyTimeoutSet := false
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "format-template", "big", "step-reveal":
+				conflicting = append(conflicting, "-"+f.Name)
+			case "copy-timeout":
+				copyTimeoutSet = true
+			}
+		})
+
+		// -display-ttl shows the password and clears it from the screen and
+		// the clipboard at once, except with -hidden, where it applies to
+		// revealing the password when copying it fails.
+		if copyTimeoutSet && *displayTTL != 0 && !*hidden {
+			fmt.Fprintf(ui, "Error: %v with -display-ttl clears the clipboard along with the screen, so it cannot be combined with -copy-timeout\n", copyMode)
+			os.Exit(1)
+		}
+
+		if jsonOut {
+			conflicting = append(conflicting, "-format json")
+		}
+
+		if *count > 1 {
+			conflicting = append(conflicting, "-count")
+		}
+
+		if len(conflicting) != 0 {
+			fmt.Fprintf(ui, "Error: %v cannot be combined with %v\n", copyMode, strings.Join(conflicting, ", "))
+			os.Exit(1)
+		}
+
+		if *copyTimeout <= 0 {
+			fmt.Fprint(ui, "Error: -copy-timeout must be positive\n")
+			os.Exit(1)
+		}
+	}
+
+	if *copyRestore {
+		switch {
+		case *copyOSC52:
+			fmt.Fprint(ui, "Error: -copy-restore cannot be combined with -copy-osc52, as the clipboard of the terminal can't be read\n")
+			os.Exit(1)
+		case !*copyFlag && !*copyOnly && !*hidden:
+			fmt.Fprint(ui, "Error: -copy-restore needs -copy, -copy-only or -hidden\n")
+			os.Exit(1)
+		}
+	}
+
+	if *outOnly && *outFile == "" {
+		fmt.Fprint(ui, "Error: -out-only needs -out, or the password would go nowhere\n")
 		os.Exit(1)
 	}
 
-	specialCount, err := askUint32(stdinReader, "Number of special characters to include (~!@#$)")
-	if err != nil {
-		fmt.Printf("Error: ask for special character count: %s\n", err)
+	if *execOnly && *execCmd == "" {
+		fmt.Fprint(ui, "Error: -exec-only needs -exec, or the password would go nowhere\n")
 		os.Exit(1)
 	}
 
-	g, err := generator.NewGenerator(pwLen, uppercaseCount, digitCount, specialCount)
-	if err != nil {
-		fmt.Printf("Error: create password generator instance: %s\n", err)
+	// An exclusive sink is the only place the password goes, so nothing
+	// that shows it can be combined with one.
+	exclusive := *copyOnly || *outOnly || *execOnly
+	if *outFile != "" || *execCmd != "" || exclusive {
+		var modes, conflicting []string
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "out", "out-only", "exec", "exec-only", "copy-only":
+				modes = append(modes, "-"+f.Name)
+			case "format-template":
+				conflicting = append(conflicting, "-"+f.Name)
+			case "big", "step-reveal", "display-ttl", "speak", "hidden":
+				if exclusive {
+					conflicting = append(conflicting, "-"+f.Name)
+				}
+			}
+		})
+
+		if jsonOut {
+			conflicting = append(conflicting, "-format json")
+		}
+
+		if *count > 1 {
+			conflicting = append(conflicting, "-count")
+		}
+
+		if len(conflicting) != 0 {
+			fmt.Fprintf(ui, "Error: %v cannot be combined with %v\n", strings.Join(modes, ", "), strings.Join(conflicting, ", "))
+			os.Exit(1)
+		}
+	}
+
+	if *big && *formatTemplate != "" {
+		fmt.Fprint(ui, "Error: -big and -format-template cannot be used together\n")
 		os.Exit(1)
 	}
 
-	b, err := g.Generate()
-	if err != nil {
-		fmt.Printf("Error: generate password: %s\n", err)
+	if *compare && *formatTemplate != "" {
+		fmt.Fprint(ui, "Error: -compare and -format-template cannot be used together\n")
 		os.Exit(1)
 	}
 
-	entropyMax := g.EntropyMax()
-	entropyMin, err := g.EntropyMin()
-	if err != nil {
-		fmt.Printf("Error: get min entropy: %s\n", err)
+	if *stepRevealFlag {
+		switch {
+		case *formatTemplate != "" || *big:
+			fmt.Fprint(ui, "Error: -step-reveal cannot be combined with -format-template or -big\n")
+			os.Exit(1)
+		case !term.IsTerminal(int(os.Stdin.Fd())) || !stdoutIsTerminal():
+			fmt.Fprint(ui, "Error: -step-reveal needs a terminal\n")
+			os.Exit(1)
+		}
+	}
+
+	if *displayTTL != 0 && *formatTemplate != "" {
+		fmt.Fprint(ui, "Error: -display-ttl and -format-template cannot be used together\n")
+		os.Exit(1)
+	}
+
+	if *displayTTL < 0 {
+		fmt.Fprint(ui, "Error: -display-ttl must not be negative\n")
+		os.Exit(1)
+	}
+
+	if *timeout < 0 {
+		fmt.Fprint(ui, "Error: -timeout must not be negative\n")
+		os.Exit(1)
+	}
+
+	if *displayTTL != 0 && !stdoutIsTerminal() {
+		fmt.Fprint(ui, "WARN: Ignoring -display-ttl since the output is not a terminal.\n")
+		*displayTTL = 0
+	}
+
+	var tmpl outputTemplate
+	if *formatTemplate != "" {
+		tmpl, err = parseOutputTemplate(*formatTemplate)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: parse format template: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var charset generator.Charset
+	if *charsetFile != "" {
+		charset, err = loadCharsetFile(*charsetFile)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: load charset file: %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(ui, "Loaded the %v charset from %v: %v.\n", charset.Name, *charsetFile, describeCharsetClasses(charset))
+	} else {
+		charset, err = generator.CharsetByName(charsetNames.String())
+		if err != nil {
+			fmt.Fprintf(ui, "Error: look up charset: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// The overrides are applied here rather than with the generator options,
+	// so that the prompts and reports show the characters actually used.
+	if *letterChars != "" || *digitChars != "" || *specialChars != "" {
+		charset.Unicode = charset.Unicode || *unicodeChars
+
+		for _, o := range []struct {
+			chars string
+			class *string
+		}{{*letterChars, &charset.Letters}, {*digitChars, &charset.Digits}, {*specialChars, &charset.Special}} {
+			if o.chars != "" {
+				*o.class = o.chars
+			}
+		}
+
+		charset.Name += "+custom"
+
+		err = charset.Validate()
+		if err != nil {
+			fmt.Fprintf(ui, "Error: override charset classes: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if charset.Unicode && (*big || *stepRevealFlag) {
+		fmt.Fprint(ui, "Error: -big and -step-reveal only have glyphs for ASCII characters, so they cannot be combined with a Unicode charset\n")
+		os.Exit(1)
+	}
+
+	// With every parameter given as a flag, stdin is never read.
+	interactive := site != nil || !fixed.complete(charset, *bits != 0)
+
+	if charset.Name != generator.DefaultCharset.Name {
+		fmt.Fprintf(ui, "Using the %v charset: %v possible characters (%.2f bits per character, %.2f with the default charset).\n", charset.Name, charset.Size(), math.Log2(float64(charset.Size())), math.Log2(float64(generator.DefaultCharset.Size())))
+	}
+
+	if slices.Contains(strings.Split(charset.Name, "+"), generator.PrintableASCIICharset.Name) {
+		fmt.Fprint(ui, "WARN: Some sites reject or mangle some printable ASCII characters, such as quotes, backslashes, and angle brackets. If the password is not accepted, try the default charset.\n")
+	}
+
+	if slices.Contains(strings.Split(charset.Name, "+"), generator.EmojiCharset.Name) {
+		fmt.Fprint(ui, "WARN: Many systems reject emoji in passwords, or normalize or count them differently, which can lock you out. Make sure that the password works everywhere you need it, including on devices without an emoji keyboard.\n")
+	}
+
+	if *space {
+		fmt.Fprintf(ui, "Also using the space: %v possible characters (%.2f bits per character). Take care not to lose it at the start or end of the password.\n", charset.Size()+1, math.Log2(float64(charset.Size()+1)))
+	}
+
+	genOpts := []generator.Option{generator.WithCharset(charset), generator.WithMaxCharRepeats(uint32(*maxRepeats)), generator.WithMinClasses(uint32(*minClasses)), generator.WithMinLowercase(uint32(*minLowercase)), generator.WithCountMode(countMode), generator.WithMaxBytes(uint32(*maxBytes))}
+	if *fullAlphabet {
+		genOpts = append(genOpts, generator.WithFullAlphabet())
+	}
+
+	if *space {
+		genOpts = append(genOpts, generator.WithSpace())
+	}
+
+	if *unambiguous {
+		genOpts = append(genOpts, generator.WithUnambiguous())
+	}
+
+	if *exclude != "" {
+		genOpts = append(genOpts, generator.WithExcludedChars(*exclude))
+	}
+
+	if *trace {
+		fmt.Fprint(os.Stderr, "WARN: Tracing is enabled. The trace reveals how every character of the password was chosen; treat it as sensitive as the password itself.\n")
+		genOpts = append(genOpts, generator.WithTracer(newStderrTracer()))
+	}
+
+	if *dice && (*insecureSeed != "" || *count > 1) {
+		fmt.Fprint(ui, "Error: -dice cannot be combined with -insecure-seed or -count\n")
 		os.Exit(1)
 	}
 
-	entropyAvg := (float64(g.EntropyMax()) + float64(entropyMin)) / 2
+	if *extraEntropy && (*insecureSeed != "" || *dice) {
+		fmt.Fprint(ui, "Error: -extra-entropy cannot be combined with -insecure-seed or -dice\n")
+		os.Exit(1)
+	}
+
+	if *extraEntropy && (!term.IsTerminal(int(os.Stdin.Fd())) || !stdoutIsTerminal()) {
+		fmt.Fprint(ui, "Error: -extra-entropy needs a terminal\n")
+		os.Exit(1)
+	}
+
+	if *insecureSeed != "" {
+		if !*insecureOK {
+			fmt.Fprint(os.Stderr, "Error: -insecure-seed makes every password predictable to anyone who knows the seed, pass -i-know-this-is-insecure if this is for test fixtures\n")
+			os.Exit(1)
+		}
+
+		seed, err := hex.DecodeString(*insecureSeed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: decode seed: %s\n", err)
+			os.Exit(1)
+		}
+
+		src, err := generator.NewDeterministicSource(seed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: create deterministic source: %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Fprint(os.Stderr, "WARN: INSECURE: The passwords are derived from -insecure-seed instead of the system random number generator. Anyone who knows the seed can reproduce them. Never use them as real passwords.\n")
+		genOpts = append(genOpts, generator.WithRandSource(src))
+	}
+
+	var spk *speaker
+	if *speak {
+		spk, err = findSpeaker(*speakRate)
+		if err != nil {
+			fmt.Fprintf(ui, "WARN: Cannot read passwords aloud: %s.\n", err)
+		}
+	}
+
+	// With -hidden, a missing clipboard is handled like a failed copy, so
+	// that the password can still be revealed once.
+	var cb clipboard.Clipboard
+	var cbErr error
+	if *copyOSC52 {
+		if !stdoutIsTerminal() {
+			fmt.Fprint(ui, "Error: -copy-osc52 needs stdout to be a terminal, so the escape sequence would not reach one (use -copy with a clipboard tool instead)\n")
+			os.Exit(1)
+		}
+
+		cb = clipboard.NewOSC52(os.Stdout, os.Getenv("TMUX") != "")
+	} else if *copyFlag || *hidden || *copyOnly {
+		cb, cbErr = clipboard.Find()
+		if cbErr != nil && !*hidden {
+			fmt.Fprintf(ui, "Error: find clipboard: %s\n", cbErr)
+			os.Exit(1)
+		}
+	}
+
+	var sinks []sink
+	if *copyOnly {
+		sinks = append(sinks, newClipboardSink(cb, true, *copyTimeout))
+	}
+
+	if *outFile != "" {
+		sinks = append(sinks, newFileSink(*outFile, *outOnly))
+	}
+
+	if *execCmd != "" {
+		s, err := newExecSink(*execCmd, *execOnly)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: %s\n", err)
+			os.Exit(1)
+		}
 
-	fmt.Printf(`
-Generated Password: %v
+		sinks = append(sinks, s)
+	}
 
-Entropy (min/realistic/max bits): %v/%v/%v (%v)
-`, string(b), entropyMin, entropyAvg, entropyMax, getRatingString(entropyAvg))
+	if cb != nil && !cb.MarksSensitive() {
+		fmt.Fprintf(ui, "WARN: %v cannot mark the password as sensitive, so clipboard managers may record it.\n", cb.Name())
+	}
 
-	// Clean up memory.
-	{
-		for i := 0; i < len(b); i++ {
-			b[i] = 0
+	var previous []byte
+	if *minDistance != 0 || *minEditDistance != 0 {
+		previous, err = readPreviousPassword(*previousFile)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: read previous password: %s\n", err)
+			os.Exit(1)
 		}
+	} else if *previousFile != "" {
+		fmt.Fprint(ui, "Error: -previous-file requires -min-distance or -min-edit-distance\n")
+		os.Exit(1)
+	}
+
+	p := newPrompter(os.Stdin)
+	if *timeout != 0 && interactive {
+		p = newTimeoutPrompter(os.Stdin, *timeout)
+	}
+
+	recording, err := detectRecording()
+	if err != nil {
+		fmt.Fprintf(ui, "Error: detect session recording: %s\n", err)
+		os.Exit(1)
+	}
 
-		_, _ = rand.Read(b)
+	// With an exclusive sink, nothing shows up on the terminal but the
+	// escape sequence of -copy-osc52.
+	if exclusive && !*copyOSC52 {
+		recording = ""
 	}
+
+	if recording != "" && !interactive {
+		fmt.Fprintf(os.Stderr, "WARN: This session appears to be recorded (%v), so the passwords may end up in the recording.\n", recording)
+	} else if recording != "" {
+		fmt.Fprintf(ui, "WARN: This session appears to be recorded (%v), so the passwords would end up in the recording.\n", recording)
+
+		// The password can go to the clipboard or a file instead, unless
+		// the run shows it in some other way anyway. The escape sequence of
+		// -copy-osc52 would end up in the recording too.
+		canFallBack := len(sinks) == 0 && cb == nil && !*hidden && !*copyOSC52 && *count <= 1 && tmpl == nil && !jsonOut
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "big", "step-reveal", "display-ttl", "speak", "mnemonic":
+				canFallBack = false
+			}
+		})
+
+		var choice string
+		switch {
+		case *allowRecording:
+			choice = "s"
+		case canFallBack:
+			choice, err = p.askString("Copy to the clipboard, write to a file, show anyway, or abort? [c/f/s/a]", "a")
+		default:
+			var ok bool
+			ok, err = p.askYesNo("Show passwords anyway?")
+			if ok {
+				choice = "s"
+			}
+		}
+
+		if errors.Is(err, errSessionTimeout) {
+			exitTimedOut(nil, false)
+		}
+
+		if err != nil && !errors.Is(err, io.EOF) {
+			fmt.Fprintf(ui, "Error: ask for recording fallback: %s\n", err)
+			os.Exit(1)
+		}
+
+		switch strings.ToLower(choice) {
+		case "s", "show":
+		case "c", "copy":
+			cb, err = clipboard.Find()
+			if err != nil {
+				fmt.Fprintf(ui, "Error: find clipboard: %s\n", err)
+				os.Exit(1)
+			}
+
+			if !cb.MarksSensitive() {
+				fmt.Fprintf(ui, "WARN: %v cannot mark the password as sensitive, so clipboard managers may record it.\n", cb.Name())
+			}
+
+			*copyOnly = true
+			sinks = append(sinks, newClipboardSink(cb, true, *copyTimeout))
+		case "f", "file":
+			path, err := p.askString("File to write the password to", "")
+			if errors.Is(err, errSessionTimeout) {
+				exitTimedOut(nil, false)
+			}
+
+			if err != nil || path == "" {
+				fmt.Fprint(ui, "Aborted.\n")
+				os.Exit(1)
+			}
+
+			sinks = append(sinks, newFileSink(path, true))
+		default:
+			fmt.Fprint(ui, "Aborted.\n")
+			os.Exit(1)
+		}
+	}
+
+	if *extraEntropy {
+		extra, err := readKeystrokeTimings(p)
+		if errors.Is(err, errSessionTimeout) {
+			exitTimedOut(nil, false)
+		}
+
+		if errors.Is(err, errKeystrokesAborted) {
+			fmt.Fprint(ui, "Aborted.\n")
+			os.Exit(1)
+		}
+
+		if err != nil {
+			fmt.Fprintf(ui, "Error: read keystrokes: %s\n", err)
+			os.Exit(1)
+		}
+
+		src, err := generator.NewMixedSource(extra)
+		wipe(extra)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: create mixed source: %s\n", err)
+			os.Exit(1)
+		}
+
+		genOpts = append(genOpts, generator.WithRandSource(src))
+	}
+
+	cfg, err := loadSettings()
+	if err != nil {
+		fmt.Fprintf(ui, "WARN: Failed to load the settings: %s\n", err)
+	}
+
+	var prev *passwordParams
+	if cfg.RememberLast && site == nil && !*fresh {
+		prev, err = loadLastParams()
+		if err != nil {
+			fmt.Fprintf(ui, "WARN: Failed to load the parameters of the last run: %s\n", err)
+		}
+
+		if prev != nil {
+			p.defaultLabel = "last: "
+		}
+	}
+
+	if !*noSandbox {
+		err = enableSandbox(newSandboxPolicy(cfg, spk, cb, *outFile, *execCmd != ""))
+		if err != nil {
+			fmt.Fprintf(ui, "Error: enable sandbox (run with -no-sandbox to skip it): %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var last *passwordParams
+	var sessionCount int
+
+	for {
+		var params passwordParams
+		if site != nil && sessionCount == 0 {
+			params = site.params()
+		} else {
+			var f fixedParams
+			if sessionCount == 0 {
+				f = fixed
+			}
+
+			params, err = askPasswordParams(p, charset, *bits == 0, prev, f)
+			if errors.Is(err, errSessionTimeout) {
+				exitTimedOut(nil, sessionCount != 0)
+			}
+
+			if err != nil {
+				fmt.Fprintf(ui, "Error: ask for password parameters: %s\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if *bits != 0 {
+			params.length, err = generator.LengthForEntropy(*bits, params.uppercaseCount, params.digitCount, params.specialCount, genOpts...)
+			if err != nil {
+				fmt.Fprintf(ui, "Error: find password length for %v bits: %s\n", *bits, err)
+				os.Exit(1)
+			}
+
+			fmt.Fprintf(ui, "Using password length %v to reach at least %v bits of minimum entropy.\n", params.length, *bits)
+		}
+
+		g, err := generator.NewGenerator(params.length, params.uppercaseCount, params.digitCount, params.specialCount, genOpts...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: create password generator instance: %s\n", err)
+			os.Exit(1)
+		}
+
+		if penalty := g.MaxRepeatsPenalty(); penalty != 0 {
+			fmt.Fprintf(ui, "Limiting every character to at most %v repeats costs about %.2f bits of entropy.\n", *maxRepeats, penalty)
+		}
+
+		entropyMax := g.EntropyMax()
+		entropyMin, err := g.EntropyMin()
+		if err != nil {
+			fmt.Fprintf(ui, "Error: get min entropy: %s\n", err)
+			os.Exit(1)
+		}
+
+		entropyAvg := (float64(g.EntropyMax()) + float64(entropyMin)) / 2
+		entropy := entropyReport{min: entropyMin, avg: entropyAvg, max: entropyMax}
+
+		if *dice {
+			rolls, err := readDiceRolls(p, rollsNeeded(float64(entropyMax)))
+			if errors.Is(err, errSessionTimeout) {
+				exitTimedOut(nil, sessionCount != 0)
+			}
+
+			if errors.Is(err, errDiceAborted) {
+				fmt.Fprint(ui, "Aborted.\n")
+				os.Exit(1)
+			}
+
+			if err != nil {
+				fmt.Fprintf(ui, "Error: read dice rolls: %s\n", err)
+				os.Exit(1)
+			}
+
+			g, err = generator.NewGenerator(params.length, params.uppercaseCount, params.digitCount, params.specialCount, append(genOpts[:len(genOpts):len(genOpts)], generator.WithRandSource(newDiceSource(rolls)))...)
+			wipe(rolls)
+
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: create password generator instance: %s\n", err)
+				os.Exit(1)
+			}
+		}
+
+		generate := func() ([]byte, error) {
+			return generateDistinct(g, previous, int(*minDistance), int(*minEditDistance))
+		}
+
+		var b []byte
+		// prevClipboard holds what -copy-restore puts back on the clipboard.
+		var prevClipboard []byte
+		if *count == 1 {
+			b, err = generate()
+			if err != nil {
+				fmt.Fprintf(ui, "Error: generate password: %s\n", err)
+				os.Exit(1)
+			}
+
+			if !*quiet && tmpl == nil && !jsonOut && bytes.ContainsAny(b, generator.ProblematicChars) {
+				fmt.Fprintf(ui, "WARN: The password contains some of %v, which often need quoting or escaping in config files, shell commands and SQL. -charset %v leaves them out.\n", generator.ProblematicChars, generator.QuoteSafeCharset.Name)
+			}
+
+			if len(sinks) != 0 {
+				fmt.Fprintln(ui)
+
+				if *copyOnly && *copyRestore {
+					prevClipboard = capturePrevious(cb)
+				}
+
+				err = deliver(ui, sinks, b, entropy)
+				if err != nil {
+					fmt.Fprintf(ui, "Error: deliver password: %s\n", err)
+					os.Exit(1)
+				}
+			}
+		}
+
+		if *count > 1 {
+			if tmpl == nil && !*quiet && !jsonOut {
+				fmt.Fprint(ui, "\nGenerated Passwords:\n\n")
+			}
+
+			pws, err := generateBatch(g, int(*count), previous, int(*minDistance), int(*minEditDistance))
+			if err != nil {
+				fmt.Fprintf(ui, "Error: generate passwords: %s\n", err)
+				os.Exit(1)
+			}
+
+			err = writeBatch(pws, func(i int, pw []byte) error {
+				switch {
+				case tmpl != nil:
+					return tmpl.render(os.Stdout, templateValues{
+						password: pw,
+						entropy:  entropyAvg,
+						rating:   getRatingString(entropyAvg),
+						index:    sessionCount + i + 1,
+					})
+				case jsonOut:
+					return writeJSONReport(pwOut, pw, newJSONReport(params, charset.Name, *counts, entropy))
+				case *quiet:
+					return writeLine(pwOut, pw)
+				default:
+					return writeLine(ui, pw)
+				}
+			})
+			if err != nil {
+				fmt.Fprintf(ui, "Error: write passwords: %s\n", err)
+				os.Exit(1)
+			}
+
+			if tmpl == nil && !jsonOut {
+				fmt.Fprintf(ui, "\n%v, each\n", entropy)
+			}
+
+			// The increment below counts one of them.
+			sessionCount += int(*count) - 1
+		} else if tmpl != nil {
+			err = tmpl.render(os.Stdout, templateValues{
+				password: b,
+				entropy:  entropyAvg,
+				rating:   getRatingString(entropyAvg),
+				index:    sessionCount + 1,
+			})
+			if err != nil {
+				fmt.Fprintf(ui, "Error: render format template: %s\n", err)
+				os.Exit(1)
+			}
+		} else if jsonOut {
+			err = writeJSONReport(pwOut, b, newJSONReport(params, charset.Name, *counts, entropy))
+			if err != nil {
+				fmt.Fprintf(ui, "Error: write JSON report: %s\n", err)
+				os.Exit(1)
+			}
+		} else if exclusiveSink(sinks) {
+			// The sinks have confirmed the delivery, and only the metadata
+			// is left to report.
+			fmt.Fprintf(ui, "\n%v\n", entropy)
+
+			if *copyOnly {
+				err = clearClipboardLater(cb, p, b, *copyTimeout, interactive, prevClipboard)
+				if errors.Is(err, errSessionTimeout) {
+					exitTimedOut(b, false)
+				}
+
+				if err != nil {
+					fmt.Fprintf(ui, "Error: clear clipboard: %s\n", err)
+					os.Exit(1)
+				}
+			}
+		} else if cb != nil && *displayTTL != 0 && !*hidden {
+			// The password is shown and copied, and one countdown clears it
+			// from both.
+			fmt.Fprintln(ui)
+			writeReport(ui, b, entropy)
+
+			if *copyRestore {
+				prevClipboard = capturePrevious(cb)
+			}
+
+			err = cb.Write(b)
+			if err != nil {
+				fmt.Fprintf(ui, "Error: copy password to the clipboard: %s\n", err)
+				os.Exit(1)
+			}
+
+			reason, err := waitForClear(p, *displayTTL, clearFromScreenAndClipboard)
+			if err != nil {
+				fmt.Fprintf(ui, "Error: wait to clear password: %s\n", err)
+				os.Exit(1)
+			}
+
+			clearLinesAbove(terminalRows(len(reportPrefix)+len(b)) + 3)
+			writeReport(ui, []byte("[cleared]"), entropy)
+
+			err = clearClipboard(cb, sha256.Sum256(b), prevClipboard)
+			if err != nil {
+				fmt.Fprintf(ui, "Error: clear clipboard: %s\n", err)
+				os.Exit(1)
+			}
+
+			exitIfDisplayEnded(reason, b)
+		} else if cb != nil || *hidden {
+			placeholder := []byte("[copied to the clipboard]")
+			if *hidden {
+				placeholder = bytes.Repeat([]byte("*"), utf8.RuneCount(b))
+			}
+
+			// Nothing sensitive is written, quiet or not.
+			if *quiet {
+				fmt.Fprintln(ui, entropy)
+			} else {
+				fmt.Fprintln(ui)
+				writeReport(ui, placeholder, entropy)
+			}
+
+			err = cbErr
+			if cb != nil {
+				if *copyRestore {
+					prevClipboard = capturePrevious(cb)
+				}
+
+				err = errors.Wrap(cb.Write(b), "write clipboard")
+			}
+
+			if err != nil && *hidden {
+				fmt.Fprintf(ui, "WARN: Failed to copy the password to the clipboard: %s\n", err)
+
+				reason, err := revealOnce(p, b, *displayTTL)
+				if errors.Is(err, errSessionTimeout) {
+					exitTimedOut(b, false)
+				}
+
+				if err != nil {
+					fmt.Fprintf(ui, "Error: reveal password: %s\n", err)
+					os.Exit(1)
+				}
+
+				exitIfDisplayEnded(reason, b)
+			} else if err != nil {
+				fmt.Fprintf(ui, "Error: copy password to the clipboard: %s\n", err)
+				os.Exit(1)
+			} else {
+				err = clearClipboardLater(cb, p, b, *copyTimeout, interactive, prevClipboard)
+				if errors.Is(err, errSessionTimeout) {
+					exitTimedOut(b, false)
+				}
+
+				if err != nil {
+					fmt.Fprintf(ui, "Error: clear clipboard: %s\n", err)
+					os.Exit(1)
+				}
+			}
+		} else if *quiet {
+			err = writeLine(pwOut, b)
+			if err != nil {
+				fmt.Fprintf(ui, "Error: write password: %s\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Fprintln(ui, entropy)
+		} else if *big {
+			fmt.Fprint(ui, "\nGenerated Password:\n\n")
+
+			reason, err := showBig(p, b, *displayTTL)
+			if err != nil {
+				fmt.Fprintf(ui, "Error: show big password: %s\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Fprintf(ui, "\n%v\n", entropy)
+
+			exitIfDisplayEnded(reason, b)
+		} else {
+			fmt.Fprintln(ui)
+
+			if *stepRevealFlag {
+				writeReport(ui, []byte("[hidden]"), entropy)
+
+				err = stepReveal(p, b)
+				if errors.Is(err, errSessionTimeout) {
+					exitTimedOut(b, true)
+				}
+
+				if err != nil {
+					fmt.Fprintf(ui, "Error: step through password: %s\n", err)
+					os.Exit(1)
+				}
+			} else {
+				writeReport(ui, b, entropy)
+			}
+
+			if *displayTTL != 0 && !*stepRevealFlag {
+				reason, err := waitForClear(p, *displayTTL, clearFromScreen)
+				if err != nil {
+					fmt.Fprintf(ui, "Error: wait to clear password: %s\n", err)
+					os.Exit(1)
+				}
+
+				// Clear everything from the password line down, which may
+				// have wrapped, and print the report again without it.
+				clearLinesAbove(terminalRows(len(reportPrefix)+len(b)) + 3)
+				writeReport(ui, []byte("[cleared]"), entropy)
+
+				exitIfDisplayEnded(reason, b)
+			}
+		}
+
+		if *maxBytes != 0 && tmpl == nil && !jsonOut && b != nil {
+			fmt.Fprintf(ui, "Length: %v characters, %v bytes (at most %v allowed)\n", utf8.RuneCount(b), len(b), *maxBytes)
+		}
+
+		if *mnemonic {
+			err = writeMnemonic(ui, b)
+			if err != nil {
+				fmt.Fprintf(ui, "Error: write mnemonic: %s\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if *compare {
+			fmt.Fprintln(ui)
+			err = writeComparisonTable(ui, policyComparisons(params, charset, genOpts))
+			if err != nil {
+				fmt.Fprintf(ui, "Error: write comparison table: %s\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if spk != nil {
+			err = speakPassword(p, spk, b)
+			if errors.Is(err, errSessionTimeout) {
+				exitTimedOut(b, true)
+			}
+
+			if err != nil {
+				fmt.Fprintf(ui, "Error: speak password: %s\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Clean up memory before moving on to the next password.
+		wipe(b)
+		wipe(prevClipboard)
+
+		if n := p.discardPending(); n != 0 {
+			fmt.Fprintf(ui, "WARN: Ignored %v extra value(s) from the last answer.\n", n)
+		}
+
+		sessionCount++
+		last = &params
+
+		// Programs reading the quiet, JSON, template or batch output
+		// expect a single run's worth of it.
+		machine := *quiet || jsonOut || tmpl != nil || *count > 1
+
+		another, err := askAnotherPassword(p, interactive && !machine)
+		if errors.Is(err, errSessionTimeout) {
+			exitTimedOut(nil, true)
+		}
+
+		if err != nil {
+			fmt.Fprintf(ui, "Error: ask for yes/no: %s\n", err)
+			os.Exit(1)
+		}
+
+		if !another {
+			break
+		}
+
+		prev = &params
+		p.defaultLabel = ""
+	}
+
+	wipe(previous)
+	generator.DiscardBufferedRandomness()
+
+	if last != nil && cfg.RememberLast {
+		err = sandboxError(saveLastParams(*last), "saving the parameters")
+		if err != nil {
+			fmt.Fprintf(ui, "WARN: Failed to remember the parameters for the next run: %s\n", err)
+		}
+	}
+
+	if sessionCount > 1 {
+		fmt.Fprintf(ui, "Generated %v passwords this session.\n", sessionCount)
+	}
+}
+
+// askAnotherPassword asks whether to go back to the top of the prompt flow
+// for another password. Without loop, e.g. when no prompt was shown or
+// stdout is read by a program, it returns false without asking or reading
+// any input. The end of the input means no.
+func askAnotherPassword(p *prompter, loop bool) (bool, error) {
+	if !loop {
+		return false, nil
+	}
+
+	fmt.Fprintln(ui)
+	another, err := p.askYesNo("Generate another with different settings?")
+	if errors.Is(err, io.EOF) {
+		return false, nil
+	}
+
+	return another, err
+}
+
+// charsetList collects repeated -charset flags, which select the intersection
+// of the named presets.
+type charsetList []string
+
+func (l *charsetList) String() string {
+	return strings.Join(*l, "+")
+}
+
+func (l *charsetList) Set(name string) error {
+	*l = append(*l, name)
+	return nil
+}
+
+// fixedParams holds the password parameters given as flags. The ones that are
+// nil are prompted for.
+type fixedParams struct {
+	length         *uint32
+	uppercaseCount *uint32
+	digitCount     *uint32
+	specialCount   *uint32
+}
+
+// check rejects a given length of 0 or below the sum of the given counts.
+func (f fixedParams) check() error {
+	if f.length == nil {
+		return nil
+	}
+
+	if *f.length == 0 {
+		return fmt.Errorf("-length must be at least 1")
+	}
+
+	var sum uint64
+	for _, n := range []*uint32{f.uppercaseCount, f.digitCount, f.specialCount} {
+		if n != nil {
+			sum += uint64(*n)
+		}
+	}
+
+	if sum > uint64(*f.length) {
+		return fmt.Errorf("the uppercase, digit, and special counts add up to %v, more than -length %v", sum, *f.length)
+	}
+
+	return nil
+}
+
+// complete reports whether no prompts are left, given that the charset
+// decides which counts are asked for and -bits replaces the length.
+func (f fixedParams) complete(charset generator.Charset, lengthFromBits bool) bool {
+	return (f.length != nil || lengthFromBits) &&
+		(f.uppercaseCount != nil || !charset.Uppercase) &&
+		(f.digitCount != nil || charset.Digits == "") &&
+		(f.specialCount != nil || charset.Special == "")
+}
+
+func askPasswordParams(p *prompter, charset generator.Charset, askLength bool, prev *passwordParams, fixed fixedParams) (passwordParams, error) {
+	var params passwordParams
+	var err error
+
+	if fixed.length != nil {
+		params.length = *fixed.length
+	} else if askLength {
+		params.length, err = p.askPasswordLength(prevField(prev, func(p *passwordParams) uint32 { return p.length }))
+		if err != nil {
+			return params, errors.Wrap(err, "ask for password length")
+		}
+	}
+
+	if fixed.uppercaseCount != nil {
+		params.uppercaseCount = *fixed.uppercaseCount
+	} else if charset.Uppercase {
+		params.uppercaseCount, err = p.askUint32(fmt.Sprintf("Number of uppercase characters to include (%s)", strings.ToUpper(charsetPreview(charset.Letters))), prevField(prev, func(p *passwordParams) uint32 { return p.uppercaseCount }))
+		if err != nil {
+			return params, errors.Wrap(err, "ask for uppercase character count")
+		}
+	}
+
+	if fixed.digitCount != nil {
+		params.digitCount = *fixed.digitCount
+	} else if charset.Digits != "" {
+		params.digitCount, err = p.askUint32(fmt.Sprintf("Number of digit characters to include (%s)", charsetPreview(charset.Digits)), prevField(prev, func(p *passwordParams) uint32 { return p.digitCount }))
+		if err != nil {
+			return params, errors.Wrap(err, "ask for digit character count")
+		}
+	}
+
+	if fixed.specialCount != nil {
+		params.specialCount = *fixed.specialCount
+	} else if charset.Special != "" {
+		params.specialCount, err = p.askUint32(fmt.Sprintf("Number of special characters to include (%s)", charsetPreview(charset.Special)), prevField(prev, func(p *passwordParams) uint32 { return p.specialCount }))
+		if err != nil {
+			return params, errors.Wrap(err, "ask for special character count")
+		}
+	}
+
+	return params, nil
+}
+
+type passwordParams struct {
+	length         uint32
+	uppercaseCount uint32
+	digitCount     uint32
+	specialCount   uint32
+}
+
+// reportPrefix is printed before the password by writeReport.
+const reportPrefix = "Generated Password: "
+
+// maxCount bounds -count.
+const maxCount = 1000
+
+type entropyReport struct {
+	min uint64
+	avg float64
+	max uint64
+}
+
+func (e entropyReport) String() string {
+	return fmt.Sprintf("Entropy (min/realistic/max bits): %v/%v/%v (%v)", e.min, e.avg, e.max, getRatingString(e.avg))
+}
+
+// writeReport prints the password and its entropy. The password is written
+// as-is rather than through a format string, so that no copies of it are
+// left behind once the caller wipes it.
+func writeReport(w io.Writer, pw []byte, entropy entropyReport) {
+	fmt.Fprint(w, reportPrefix)
+	_, _ = w.Write(pw)
+	fmt.Fprintf(w, "\n\n%v\n", entropy)
+}
+
+// writeLine writes pw followed by a newline, without copying pw.
+func writeLine(w io.Writer, pw []byte) error {
+	_, err := w.Write(pw)
+	if err == nil {
+		_, err = w.Write([]byte{'\n'})
+	}
+
+	return err
+}
+
+// writeGenerated writes count values from generate, one per line, wiping
+// each of them right after. In quiet mode, they go to stdout, otherwise a
+// single value is written as a report with the given prefix. what names the
+// values in errors, which exit the program.
+func writeGenerated(count int, quiet bool, prefix, what string, generate func() ([]byte, error)) {
+	out := ui
+	if quiet {
+		out = os.Stdout
+	} else {
+		fmt.Fprintln(ui)
+	}
+
+	for i := 0; i < count; i++ {
+		b, err := generate()
+		if err != nil {
+			fmt.Fprintf(ui, "Error: generate %v: %s\n", what, err)
+			os.Exit(1)
+		}
+
+		if !quiet && count == 1 {
+			fmt.Fprint(ui, prefix)
+		}
+
+		err = writeLine(out, b)
+		wipe(b)
+
+		if err != nil {
+			fmt.Fprintf(ui, "Error: write %v: %s\n", what, err)
+			os.Exit(1)
+		}
+	}
+
+	if !quiet {
+		fmt.Fprintln(ui)
+	}
+}
+
+// writeBatch hands each of pws to write, wiping it right after, and wipes
+// the ones left over if write fails.
+func writeBatch(pws [][]byte, write func(i int, pw []byte) error) error {
+	defer wipeAll(pws)
+
+	for i, pw := range pws {
+		err := write(i, pw)
+		wipe(pw)
+
+		if err != nil {
+			return errors.Wrapf(err, "write password #%v", i+1)
+		}
+	}
+
+	return nil
+}
+
+// exitIfDisplayEnded exits once a cleared password display has expired or
+// was interrupted, since the user is likely no longer at the terminal.
+func exitIfDisplayEnded(reason clearReason, pw []byte) {
+	switch reason {
+	case clearExpired:
+		wipe(pw)
+		fmt.Fprintln(ui, "The password display expired.")
+		os.Exit(0)
+	case clearInterrupted:
+		wipe(pw)
+		os.Exit(130)
+	case clearTimedOut:
+		exitTimedOut(pw, true)
+	}
+}
+
+func speakPassword(p *prompter, spk *speaker, pw []byte) error {
+	for {
+		err := spk.speak(pw)
+		if err != nil {
+			fmt.Fprintf(ui, "WARN: Failed to read the password aloud: %s\n", err)
+		}
+
+		again, err := p.askYesNo("Hear the password again?")
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return errors.Wrap(err, "ask for yes/no")
+		}
+
+		if !again {
+			return nil
+		}
+	}
+}
+
+func prevField(prev *passwordParams, field func(*passwordParams) uint32) *uint32 {
+	if prev == nil {
+		return nil
+	}
+
+	v := field(prev)
+	return &v
+}
+
+type traceLine struct {
+	Sensitive bool `json:"sensitive"`
+	generator.TraceEvent
+}
+
+func newStderrTracer() generator.Tracer {
+	enc := json.NewEncoder(os.Stderr)
+	return func(ev generator.TraceEvent) {
+		_ = enc.Encode(traceLine{
+			Sensitive:  true,
+			TraceEvent: ev,
+		})
+	}
+}
+
+func wipe(b []byte) {
+	for i := 0; i < len(b); i++ {
+		b[i] = 0
+	}
+
+	_, _ = rand.Read(b)
 }
 
 func getRatingString(entropyBits float64) string {
@@ -0,0 +1,144 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// runMainEnv makes the test binary run main with its arguments instead of
+// the tests, so that runCpass can run cpass end to end.
+const runMainEnv = "CPASS_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// runCpass runs cpass with args and stdin in a child process, with a
+// config directory of its own, and returns its stdout and stderr.
+func runCpass(t *testing.T, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+
+	return runCpassIn(t, t.TempDir(), stdin, args...)
+}
+
+// runCpassIn is runCpass with the config directory dir, which runs can
+// share.
+func runCpassIn(t *testing.T, dir, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(exe, args...)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1", "CPASS_CONFIG_DIR="+dir)
+	cmd.Stdin = strings.NewReader(stdin)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+
+	return stdout.String(), stderr.String(), err
+}
+
+// sessionInput answers the prompts for two passwords, with a yes to
+// generating another in between.
+const sessionInput = "17\n2\n3\n2\ny\n17\n2\n3\n2\nn\n"
+
+func TestSessionLoop(t *testing.T) {
+	stdout, stderr, err := runCpass(t, sessionInput, "-fresh", "-no-sandbox")
+	if err != nil {
+		t.Fatalf("cpass: %v\n%s", err, stderr)
+	}
+
+	if n := strings.Count(stdout, "Generated Password: "); n != 2 {
+		t.Errorf("got %v passwords, want 2:\n%s", n, stdout)
+	}
+
+	if !strings.Contains(stdout, "Generated 2 passwords this session.") {
+		t.Errorf("no session summary:\n%s", stdout)
+	}
+}
+
+func TestSessionLoopNeverRunsForMachineOutput(t *testing.T) {
+	for _, args := range [][]string{
+		{"-q"},
+		{"-format", "json"},
+		{"-format-template", "%p\\n"},
+	} {
+		stdout, stderr, err := runCpass(t, sessionInput, append([]string{"-fresh", "-no-sandbox"}, args...)...)
+		if err != nil {
+			t.Fatalf("%v: cpass: %v\n%s", args, err, stderr)
+		}
+
+		if n := strings.Count(stdout, "\n"); n != 1 {
+			t.Errorf("%v: got %v lines on stdout, want 1:\n%s", args, n, stdout)
+		}
+
+		if strings.Contains(stderr, "Generate another") || strings.Contains(stderr, "this session") {
+			t.Errorf("%v: entered the session loop:\n%s", args, stderr)
+		}
+	}
+}
+
+func TestJSONOutputWithRememberedDefaults(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, args := range [][]string{
+		{"config", "remember-last", "on"},
+		{"-no-sandbox"},
+	} {
+		_, stderr, err := runCpassIn(t, dir, "17\n2\n3\n2\n", args...)
+		if err != nil {
+			t.Fatalf("%v: cpass: %v\n%s", args, err, stderr)
+		}
+	}
+
+	// Empty answers take the remembered defaults.
+	stdout, stderr, err := runCpassIn(t, dir, "\n\n\n\ny\n\n\n\n\n", "-format", "json", "-no-sandbox")
+	if err != nil {
+		t.Fatalf("cpass: %v\n%s", err, stderr)
+	}
+
+	if !strings.Contains(stderr, "last: ") {
+		t.Errorf("the remembered defaults were not offered:\n%s", stderr)
+	}
+
+	var report JSONReport
+	err = json.Unmarshal([]byte(stdout), &report)
+	if err != nil {
+		t.Fatalf("stdout is not a single JSON report: %v\n%s", err, stdout)
+	}
+
+	if report.Length != 17 || report.UppercaseCount != 2 || report.DigitCount != 3 || report.SpecialCount != 2 {
+		t.Errorf("got %+v, want the remembered parameters", report)
+	}
+}
@@ -0,0 +1,42 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/AlexSSD7/cpass/generator"
+	"github.com/pkg/errors"
+)
+
+// writeMnemonic writes a memorization aid for pw, which is wiped as soon as
+// it is written.
+func writeMnemonic(w io.Writer, pw []byte) error {
+	aid, err := generator.Mnemonic(pw)
+	if err != nil {
+		return errors.Wrap(err, "build mnemonic")
+	}
+	defer wipe(aid)
+
+	_, err = fmt.Fprint(w, "\nMemorization aid (keep it as secret as the password): ")
+	if err != nil {
+		return err
+	}
+
+	return writeLine(w, aid)
+}
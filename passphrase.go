@@ -0,0 +1,500 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlexSSD7/cpass/generator"
+	"github.com/pkg/errors"
+)
+
+const passphraseReportPrefix = "Generated Passphrase: "
+
+// defaultPassphraseWords is the word count offered at the prompt.
+const defaultPassphraseWords = 6
+
+// passphraseFlags are the flags that configure a passphrase, shared by the
+// passphrase and hybrid modes.
+type passphraseFlags struct {
+	words        *uint
+	separator    *string
+	wordlistFile *string
+	list         *string
+	lang         *string
+	casing       *string
+	insert       *string
+	upper        *uint
+	digits       *uint
+	special      *uint
+	length       *uint
+	maxLength    *uint
+	minWordLen   *int
+	maxWordLen   *int
+	insertAt     *string
+	mutations    *uint
+	capital      *string
+}
+
+func addPassphraseFlags(fs *flag.FlagSet) *passphraseFlags {
+	return &passphraseFlags{
+		words:        fs.Uint("words", 0, fmt.Sprintf("Number of words (1-%v), skips the prompt", generator.MaxPassphraseWords)),
+		separator:    fs.String("separator", "-", "String to join the words with"),
+		wordlistFile: fs.String("wordlist", "", "Choose the words from this file of one word per line instead of an embedded wordlist"),
+		list:         fs.String("list", generator.DefaultWordlistName, "Embedded wordlist to choose the words from: "+strings.Join(generator.WordlistNames(), ", ")),
+		lang:         fs.String("wordlist-lang", "", "Choose the words from the embedded wordlist for this language: "+strings.Join(generator.WordlistLanguages(), ", ")),
+		casing:       fs.String("case", "lower", "How to capitalize the words: lower, capitalize-one (a random word), or title (every word)"),
+		insert:       fs.String("insert", "none", "Insert a random character: none, digit, or special"),
+		upper:        fs.Uint("upper", 0, "Capitalize this many random words, for policies that require uppercase characters"),
+		digits:       fs.Uint("digits", 0, "Insert this many random digits after a random word"),
+		special:      fs.Uint("special", 0, "Insert this many random special characters after a random word, together with the digits"),
+		length:       fs.Uint("length", 0, "Make every passphrase exactly this many characters long; without -words, the word count with the most entropy is picked"),
+		maxLength:    fs.Uint("max-length", 0, "Make no passphrase longer than this many characters; without -words, the word count with the most entropy is picked"),
+		minWordLen:   fs.Int("min-word-len", 0, "Only use words of at least this many characters (0 for no limit)"),
+		maxWordLen:   fs.Int("max-word-len", 0, "Only use words of at most this many characters (0 for no limit)"),
+		insertAt:     fs.String("insert-at", "end", "Where to insert the character: end (after the last word) or random (after a random word)"),
+		mutations:    fs.Uint("mutations", 0, "Apply this many random mutations to every passphrase: a letter turned uppercase, a letter swapped for a digit, or a special character inserted"),
+		capital:      fs.String("random-capital", "none", "Uppercase a random letter: none, phrase (one in the passphrase), or word (one in every word)"),
+	}
+}
+
+func runPassphrase(args []string) {
+	fs := flag.NewFlagSet("passphrase", flag.ExitOnError)
+	pf := addPassphraseFlags(fs)
+	count := fs.Int("count", 1, fmt.Sprintf("Number of passphrases to generate (1-%v)", maxCount))
+	quiet := fs.Bool("q", false, "Quiet mode: write only the passphrases to stdout, one per line, and everything else to stderr")
+	grammar := fs.String("grammar", "", "Make grammatical passphrases of one word per part of speech in this comma-separated template, e.g. "+generator.DefaultGrammarTemplate+" (parts: adjective, noun, verb, adverb)")
+	policy := fs.String("policy", "", `Generate using this policy string instead of the equivalent flags, e.g. "grammar=`+generator.DefaultGrammarTemplate+` separator=space case=title" (keys: words, grammar, separator, case)`)
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	err = applyPolicy(fs, passphrasePolicyKeys, *policy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -policy: %s\n", err)
+		os.Exit(2)
+	}
+
+	if *count < 1 || *count > maxCount {
+		fmt.Fprintf(os.Stderr, "Error: count must be between 1 and %v\n", maxCount)
+		os.Exit(1)
+	}
+
+	if *quiet {
+		ui = os.Stderr
+	}
+
+	if *grammar != "" {
+		g := newGrammarGenerator(fs, *grammar, *pf.separator, *pf.casing)
+
+		writeGenerated(*count, *quiet, passphraseReportPrefix, "passphrase", g.Generate)
+
+		fmt.Fprintln(ui, passphraseEntropyString(g.Entropy()))
+		generator.DiscardBufferedRandomness()
+
+		return
+	}
+
+	g := pf.newGenerator(fs)
+
+	writeGenerated(*count, *quiet, passphraseReportPrefix, "passphrase", g.Generate)
+
+	fmt.Fprintln(ui, passphraseEntropyString(g.Entropy()))
+	generator.DiscardBufferedRandomness()
+}
+
+// newGenerator asks for the options that weren't set on fs and returns the
+// passphrase generator for them. Errors are reported and exit the program.
+func (pf *passphraseFlags) newGenerator(fs *flag.FlagSet) *generator.PassphraseGenerator {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	if *pf.length != 0 && *pf.maxLength != 0 {
+		fmt.Fprint(ui, "Error: -length cannot be combined with -max-length\n")
+		os.Exit(1)
+	}
+
+	totalLength, exact := int(*pf.maxLength), false
+	if *pf.length != 0 {
+		totalLength, exact = int(*pf.length), true
+	}
+
+	// Without a word count, the options that weren't given are asked for.
+	// With a length limit, the word count is picked instead.
+	wordCount := uint32(*pf.words)
+	if *pf.words == 0 {
+		err := askPassphraseOptions(newPrompter(os.Stdin), set, totalLength == 0, &wordCount, pf.maxWordLen, pf.separator, pf.casing, pf.insert, pf.insertAt)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: ask for passphrase options: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	opts, err := passphraseOptions(*pf.casing, *pf.insert, *pf.insertAt)
+	if err != nil {
+		fmt.Fprintf(ui, "Error: parse passphrase options: %s\n", err)
+		os.Exit(1)
+	}
+
+	opts = append(opts, generator.WithWordLength(*pf.minWordLen, *pf.maxWordLen))
+
+	if set["upper"] || set["digits"] || set["special"] {
+		opts = append(opts, generator.WithClassCounts(uint32(*pf.upper), uint32(*pf.digits), uint32(*pf.special)))
+	}
+
+	if *pf.mutations != 0 {
+		opts = append(opts, generator.WithMutations(uint32(*pf.mutations)))
+	}
+
+	capital, err := parseRandomCapital(*pf.capital)
+	if err != nil {
+		fmt.Fprintf(ui, "Error: parse passphrase options: %s\n", err)
+		os.Exit(1)
+	}
+
+	if capital != generator.RandomCapitalNone {
+		opts = append(opts, generator.WithRandomCapital(capital))
+	}
+
+	wordlist := selectWordlist(set, *pf.wordlistFile, *pf.list, *pf.lang)
+
+	if totalLength != 0 {
+		opts = append(opts, generator.WithTotalLength(totalLength, exact))
+	}
+
+	var g *generator.PassphraseGenerator
+	if wordCount == 0 && totalLength != 0 {
+		g, err = mostEntropicPassphraseGenerator(wordlist, *pf.separator, opts)
+	} else {
+		g, err = generator.NewPassphraseGenerator(wordCount, wordlist, *pf.separator, opts...)
+	}
+
+	if err != nil {
+		fmt.Fprintf(ui, "Error: create passphrase generator instance: %s\n", err)
+		os.Exit(1)
+	}
+
+	if *pf.minWordLen != 0 || *pf.maxWordLen != 0 {
+		fmt.Fprintf(ui, "Using %v of the %v words of the wordlist (%.2f bits per word).\n", g.WordlistSize(), len(wordlist), generator.BitsPerWord(g.WordlistSize()))
+	}
+
+	if *pf.mutations != 0 {
+		added := g.MutationEntropy()
+		fmt.Fprintf(ui, "%v random mutations add at least %.2f bits, from %.2f to %.2f bits.\n", *pf.mutations, added, g.Entropy()-added, g.Entropy())
+	}
+
+	if capital != generator.RandomCapitalNone {
+		added := g.RandomCapitalEntropy()
+		fmt.Fprintf(ui, "The random capital adds %.2f bits, from %.2f to %.2f bits.\n", added, g.Entropy()-added, g.Entropy())
+	}
+
+	if *pf.separator == "" {
+		fmt.Fprint(ui, "WARN: Without a separator, different words may join into the same passphrase, so the entropy is an upper bound.\n")
+	}
+
+	return g
+}
+
+// selectWordlist returns the wordlist of the -wordlist, -wordlist-lang or
+// -list flag, which set tells apart. Errors are reported and exit the
+// program.
+func selectWordlist(set map[string]bool, wordlistFile, list, lang string) []string {
+	var wordlist []string
+	var err error
+	if wordlistFile != "" {
+		if set["list"] || set["wordlist-lang"] {
+			fmt.Fprint(ui, "Error: -wordlist cannot be combined with -list or -wordlist-lang\n")
+			os.Exit(1)
+		}
+
+		wordlist, err = loadWordlistFile(wordlistFile)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: load wordlist: %s\n", err)
+			os.Exit(1)
+		}
+	} else if lang != "" {
+		if set["list"] {
+			fmt.Fprint(ui, "Error: -wordlist-lang cannot be combined with -list\n")
+			os.Exit(1)
+		}
+
+		wordlist, err = generator.WordlistByLanguage(lang)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: select wordlist: %s\n", err)
+			os.Exit(1)
+		}
+	} else {
+		wordlist, err = generator.WordlistByName(list)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: select wordlist: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	return wordlist
+}
+
+// askPassphraseOptions asks for the word count and the options that weren't
+// set with flags.
+func askPassphraseOptions(p *prompter, set map[string]bool, askWordCount bool, wordCount *uint32, maxWordLen *int, separator, casing, insert, insertAt *string) error {
+	var err error
+	if askWordCount {
+		def := uint32(defaultPassphraseWords)
+
+		*wordCount, err = p.askUint32("Number of words", &def)
+		if err != nil {
+			return errors.Wrap(err, "ask for word count")
+		}
+	}
+
+	if !set["max-word-len"] {
+		var noLimit uint32
+		maxLen, err := p.askUint32("Maximum word length, 0 for no limit", &noLimit)
+		if err != nil {
+			return errors.Wrap(err, "ask for maximum word length")
+		}
+
+		*maxWordLen = int(maxLen)
+	}
+
+	if !set["separator"] {
+		answer, err := p.askString(`Separator ("none" for no separator, "space" for a space)`, *separator)
+		if err != nil {
+			return errors.Wrap(err, "ask for separator")
+		}
+
+		switch answer {
+		case "none":
+			*separator = ""
+		case "space":
+			*separator = " "
+		default:
+			*separator = answer
+		}
+	}
+
+	// The class counts and the mutations replace the casing and the
+	// inserted character, and a random capital the casing.
+	replaced := set["upper"] || set["digits"] || set["special"] || set["mutations"]
+
+	if !set["case"] && !replaced && !set["random-capital"] {
+		*casing, err = p.askString("Capitalization (lower, capitalize-one, title)", *casing)
+		if err != nil {
+			return errors.Wrap(err, "ask for capitalization")
+		}
+	}
+
+	if !set["insert"] && !replaced {
+		*insert, err = p.askString("Insert a random character (none, digit, special)", *insert)
+		if err != nil {
+			return errors.Wrap(err, "ask for inserted character")
+		}
+	}
+
+	if !set["insert-at"] && *insert != "none" {
+		*insertAt, err = p.askString("Insert it after the last word or a random one (end, random)", *insertAt)
+		if err != nil {
+			return errors.Wrap(err, "ask for inserted character position")
+		}
+	}
+
+	return nil
+}
+
+// mostEntropicPassphraseGenerator returns the generator for the word count
+// with the most entropy within the length limit in opts.
+func mostEntropicPassphraseGenerator(wordlist []string, separator string, opts []generator.PassphraseOption) (*generator.PassphraseGenerator, error) {
+	var best *generator.PassphraseGenerator
+	var lastErr error
+	for n := uint32(1); n <= generator.MaxPassphraseWords; n++ {
+		g, err := generator.NewPassphraseGenerator(n, wordlist, separator, opts...)
+		if err != nil {
+			lastErr = err
+
+			// Longer passphrases only fit less.
+			if best != nil {
+				break
+			}
+
+			continue
+		}
+
+		if best == nil || g.Entropy() > best.Entropy() {
+			best = g
+		}
+	}
+
+	if best == nil {
+		return nil, lastErr
+	}
+
+	unit := "words"
+	if best.WordCount() == 1 {
+		unit = "word"
+	}
+
+	fmt.Fprintf(ui, "Using %v %v, the most entropy within the length limit.\n", best.WordCount(), unit)
+
+	return best, nil
+}
+
+// newGrammarGenerator returns the generator of grammar passphrases for
+// template and reports how the entropy comes about. Errors are reported and
+// exit the program.
+func newGrammarGenerator(fs *flag.FlagSet, template, separator, casing string) *generator.GrammarGenerator {
+	var conflict string
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "words", "wordlist", "list", "wordlist-lang", "insert", "insert-at", "upper", "digits", "special", "length", "max-length", "min-word-len", "max-word-len", "mutations", "random-capital":
+			conflict = f.Name
+		}
+	})
+
+	if conflict != "" {
+		fmt.Fprintf(ui, "Error: -grammar cannot be combined with -%v\n", conflict)
+		os.Exit(1)
+	}
+
+	t, err := generator.ParseGrammarTemplate(template)
+	if err != nil {
+		fmt.Fprintf(ui, "Error: parse grammar template: %s\n", err)
+		os.Exit(1)
+	}
+
+	c, err := parseCasing(casing)
+	if err != nil {
+		fmt.Fprintf(ui, "Error: parse passphrase options: %s\n", err)
+		os.Exit(1)
+	}
+
+	g, err := generator.NewGrammarGenerator(t, separator, c)
+	if err != nil {
+		fmt.Fprintf(ui, "Error: create grammar passphrase generator instance: %s\n", err)
+		os.Exit(1)
+	}
+
+	sizes := make([]string, len(g.SlotSizes()))
+	for i, n := range g.SlotSizes() {
+		sizes[i] = fmt.Sprint(n)
+	}
+
+	diceware := float64(len(t)) * generator.BitsPerWord(len(generator.WordlistEFFLong()))
+	fmt.Fprintf(ui, "Template %v: %v words to choose from per slot. The part-of-speech lists are short, so %v words carry %.2f bits, compared to %.2f for %v words of the EFF long wordlist.\n", t, strings.Join(sizes, " x "), len(t), g.Entropy(), diceware, len(t))
+
+	if separator == "" {
+		fmt.Fprint(ui, "WARN: Without a separator, different words may join into the same passphrase, so the entropy is an upper bound.\n")
+	}
+
+	return g
+}
+
+// parseCasing parses the -case flag.
+func parseCasing(casing string) (generator.PassphraseCasing, error) {
+	switch casing {
+	case "lower":
+		return generator.CasingLower, nil
+	case "capitalize-one":
+		return generator.CasingCapitalizeOne, nil
+	case "title":
+		return generator.CasingTitle, nil
+	default:
+		return 0, fmt.Errorf("unknown capitalization %q, expected lower, capitalize-one, or title", casing)
+	}
+}
+
+// parseRandomCapital parses the -random-capital flag.
+func parseRandomCapital(capital string) (generator.RandomCapital, error) {
+	switch capital {
+	case "none":
+		return generator.RandomCapitalNone, nil
+	case "phrase":
+		return generator.RandomCapitalPhrase, nil
+	case "word":
+		return generator.RandomCapitalWord, nil
+	default:
+		return 0, fmt.Errorf("unknown random capital %q, expected none, phrase, or word", capital)
+	}
+}
+
+func passphraseOptions(casing, insert, insertAt string) ([]generator.PassphraseOption, error) {
+	var opts []generator.PassphraseOption
+
+	c, err := parseCasing(casing)
+	if err != nil {
+		return nil, err
+	}
+
+	if c != generator.CasingLower {
+		opts = append(opts, generator.WithCasing(c))
+	}
+
+	var anywhere bool
+	switch insertAt {
+	case "end":
+	case "random":
+		anywhere = true
+	default:
+		return nil, fmt.Errorf("unknown insert position %q, expected end or random", insertAt)
+	}
+
+	switch insert {
+	case "none":
+	case "digit":
+		opts = append(opts, generator.WithInsertedChar(generator.DefaultCharset.Digits, anywhere))
+	case "special":
+		opts = append(opts, generator.WithInsertedChar(generator.DefaultCharset.Special, anywhere))
+	default:
+		return nil, fmt.Errorf("unknown inserted character %q, expected none, digit, or special", insert)
+	}
+
+	return opts, nil
+}
+
+// loadWordlistFile loads a custom wordlist and reports how much entropy each
+// of its words carries.
+func loadWordlistFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "open wordlist file")
+	}
+	defer f.Close()
+
+	l, err := generator.LoadWordlist(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.Len() < generator.WeakWordlistSize {
+		fmt.Fprintf(ui, "WARN: The wordlist only has %v words, so each word carries just %.2f bits, compared to %.2f for the EFF long wordlist. Passphrases from it need far more words to be secure.\n", l.Len(), l.BitsPerWord(), generator.BitsPerWord(len(generator.WordlistEFFLong())))
+	}
+
+	fmt.Fprintf(ui, "Wordlist has %v words (%.2f bits per word).\n", l.Len(), l.BitsPerWord())
+
+	return l.Words(), nil
+}
+
+func passphraseEntropyString(bits float64) string {
+	return fmt.Sprintf("Entropy: %.2f bits (%v)", bits, getRatingString(bits))
+}
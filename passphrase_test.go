@@ -0,0 +1,69 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/AlexSSD7/cpass/generator"
+)
+
+func TestPassphraseLanguage(t *testing.T) {
+	for _, tc := range []struct {
+		lang  string
+		words []string
+	}{
+		{"de", generator.WordlistGermanDiceware()},
+		{"ES", generator.WordlistSpanishBIP39()},
+		{"fr", generator.WordlistFrenchBIP39()},
+	} {
+		stdout, stderr, err := runCpass(t, "", "passphrase", "-q", "-count", "20", "-words", "4", "-separator", " ", "-wordlist-lang", tc.lang)
+		if err != nil {
+			t.Fatalf("%v: cpass: %v\n%s", tc.lang, err, stderr)
+		}
+
+		lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+		if len(lines) != 20 {
+			t.Fatalf("%v: got %v passphrases, want 20:\n%s", tc.lang, len(lines), stdout)
+		}
+
+		for _, line := range lines {
+			words := strings.Split(line, " ")
+			if len(words) != 4 {
+				t.Fatalf("%v: %q doesn't have 4 words", tc.lang, line)
+			}
+
+			for _, w := range words {
+				if !slices.Contains(tc.words, w) {
+					t.Fatalf("%v: %q isn't from the wordlist", tc.lang, w)
+				}
+			}
+		}
+	}
+
+	for _, args := range [][]string{
+		{"-wordlist-lang", "xx"},
+		{"-wordlist-lang", "de", "-list", "eff-short"},
+	} {
+		_, _, err := runCpass(t, "", append([]string{"passphrase", "-q", "-words", "4"}, args...)...)
+		if err == nil {
+			t.Errorf("%q: succeeded", args)
+		}
+	}
+}
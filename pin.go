@@ -0,0 +1,74 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/AlexSSD7/cpass/generator"
+)
+
+const pinReportPrefix = "Generated PIN: "
+
+// defaultPINLength is the PIN length offered at the prompt.
+const defaultPINLength = 6
+
+func runPIN(args []string) {
+	fs := flag.NewFlagSet("pin", flag.ExitOnError)
+	length := fs.Uint("length", 0, fmt.Sprintf("Number of digits (1-%v), skips the prompt", generator.MaxPINLength))
+	count := fs.Int("count", 1, fmt.Sprintf("Number of PINs to generate (1-%v)", maxCount))
+	quiet := fs.Bool("q", false, "Quiet mode: write only the PINs to stdout, one per line, and everything else to stderr")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	if *count < 1 || *count > maxCount {
+		fmt.Fprintf(os.Stderr, "Error: count must be between 1 and %v\n", maxCount)
+		os.Exit(1)
+	}
+
+	if *quiet {
+		ui = os.Stderr
+	}
+
+	pinLength := uint32(*length)
+	if pinLength == 0 {
+		def := uint32(defaultPINLength)
+
+		pinLength, err = newPrompter(os.Stdin).askUint32("PIN length", &def)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: ask for PIN length: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	g, err := generator.NewPINGenerator(pinLength)
+	if err != nil {
+		fmt.Fprintf(ui, "Error: create PIN generator instance: %s\n", err)
+		os.Exit(1)
+	}
+
+	writeGenerated(*count, *quiet, pinReportPrefix, "PIN", g.Generate)
+
+	fmt.Fprintln(ui, passphraseEntropyString(g.Entropy()))
+	generator.DiscardBufferedRandomness()
+}
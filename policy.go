@@ -0,0 +1,131 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// policyKey is a key of policy strings and the flag it stands for.
+type policyKey struct {
+	key string
+	// op is "=", or ">=" for keys that set a minimum.
+	op   string
+	flag string
+	// values are the values that stand for ones a field can't hold, such as
+	// spaces.
+	values map[string]string
+}
+
+// passwordPolicyKeys are the keys of policy strings for character passwords.
+var passwordPolicyKeys = []policyKey{
+	{"length", "=", "length", nil},
+	{"upper", "=", "upper", nil},
+	{"digits", "=", "digits", nil},
+	{"special", "=", "special", nil},
+	{"charset", "=", "charset", nil},
+	{"maxrepeat", "=", "max-repeats", nil},
+	{"classes", ">=", "min-classes", nil},
+}
+
+// passphrasePolicyKeys are the keys of policy strings for passphrases.
+var passphrasePolicyKeys = []policyKey{
+	{"words", "=", "words", nil},
+	{"grammar", "=", "grammar", nil},
+	{"separator", "=", "separator", map[string]string{"space": " ", "none": ""}},
+	{"case", "=", "case", nil},
+}
+
+// applyPolicy sets the flags of fs that the policy string s stands for. A
+// policy string is a list of fields separated by spaces, each a key, an
+// operator and a value, e.g. "length=16 upper=2 maxrepeat=2". Every key
+// stands for a flag, see keys, and is set as if the flag was given, so the
+// values are checked the same way. A flag can't be given both on the command
+// line and in the policy.
+func applyPolicy(fs *flag.FlagSet, keys []policyKey, s string) error {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	seen := make(map[string]bool)
+	for _, field := range strings.Fields(s) {
+		k, value, err := parsePolicyField(keys, field)
+		if err != nil {
+			return err
+		}
+
+		if seen[k.key] {
+			return fmt.Errorf("%v is given more than once", k.key)
+		}
+
+		seen[k.key] = true
+
+		if given[k.flag] {
+			return fmt.Errorf("%v cannot be combined with -%v, the policy defines it", field, k.flag)
+		}
+
+		err = fs.Set(k.flag, value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for %v: %s", value, k.key, err)
+		}
+	}
+
+	return nil
+}
+
+// parsePolicyField returns the key of a field of a policy string and its
+// value.
+func parsePolicyField(keys []policyKey, field string) (policyKey, string, error) {
+	for _, k := range keys {
+		value, ok := strings.CutPrefix(field, k.key+k.op)
+		if !ok {
+			continue
+		}
+
+		if value == "" {
+			return policyKey{}, "", fmt.Errorf("%v has no value", field)
+		}
+
+		if v, ok := k.values[value]; ok {
+			value = v
+		}
+
+		return k, value, nil
+	}
+
+	name, _, ok := strings.Cut(field, "=")
+	if !ok {
+		return policyKey{}, "", fmt.Errorf("expected key=value, got %q", field)
+	}
+
+	name = strings.TrimRight(name, "<>")
+	for _, k := range keys {
+		if k.key == name {
+			return policyKey{}, "", fmt.Errorf("%v needs %v, e.g. %v%v3", field, k.op, k.key, k.op)
+		}
+	}
+
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.key + k.op
+	}
+
+	return policyKey{}, "", fmt.Errorf("unknown policy key %q (available: %v)", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,139 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// policyFlags returns a flag set with the flags passwordPolicyKeys stand for.
+func policyFlags(t *testing.T, args ...string) (*flag.FlagSet, *charsetList) {
+	t.Helper()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var charsetNames charsetList
+	fs.Var(&charsetNames, "charset", "")
+	for _, name := range []string{"length", "upper", "digits", "special", "max-repeats", "min-classes"} {
+		fs.Uint(name, 0, "")
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fs, &charsetNames
+}
+
+func TestApplyPolicy(t *testing.T) {
+	fs, charsetNames := policyFlags(t, "-length", "16")
+
+	err := applyPolicy(fs, passwordPolicyKeys, " upper=2  digits=3 special=1\tcharset=no-shift maxrepeat=2 classes>=3 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"length": "16", "upper": "2", "digits": "3", "special": "1", "max-repeats": "2", "min-classes": "3"} {
+		got := fs.Lookup(name).Value.String()
+		if got != want {
+			t.Errorf("-%v: got %v, want %v", name, got, want)
+		}
+	}
+
+	if !slices.Equal(*charsetNames, charsetList{"no-shift"}) {
+		t.Errorf("-charset: got %q, want no-shift", *charsetNames)
+	}
+
+	// The policy counts as given, e.g. for skipping the prompts.
+	var set []string
+	fs.Visit(func(f *flag.Flag) {
+		set = append(set, f.Name)
+	})
+
+	want := []string{"charset", "digits", "length", "max-repeats", "min-classes", "special", "upper"}
+	if !slices.Equal(set, want) {
+		t.Errorf("set flags: got %q, want %q", set, want)
+	}
+
+	err = applyPolicy(fs, passwordPolicyKeys, "")
+	if err != nil {
+		t.Errorf("empty policy: %v", err)
+	}
+}
+
+func TestApplyPolicyErrors(t *testing.T) {
+	for _, tc := range []struct {
+		args   []string
+		policy string
+		want   string
+	}{
+		{nil, "maxrepeat=x", `invalid value "x" for maxrepeat`},
+		{nil, "maxrepeat=", "maxrepeat= has no value"},
+		{nil, "maxrepeats=2", `unknown policy key "maxrepeats"`},
+		{nil, "maxrepeat>=2", "maxrepeat>=2 needs =, e.g. maxrepeat=3"},
+		{nil, "classes=3", "classes=3 needs >=, e.g. classes>=3"},
+		{nil, "classes<=3", "classes<=3 needs >=, e.g. classes>=3"},
+		{nil, "classes>=", "classes>= has no value"},
+		{nil, "length", `expected key=value, got "length"`},
+		{nil, "length=12 length=14", "length is given more than once"},
+		{[]string{"-max-repeats", "1"}, "maxrepeat=2", "maxrepeat=2 cannot be combined with -max-repeats"},
+	} {
+		fs, _ := policyFlags(t, tc.args...)
+
+		err := applyPolicy(fs, passwordPolicyKeys, tc.policy)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: got %v, want an error containing %q", tc.policy, err, tc.want)
+		}
+	}
+}
+
+func TestApplyPassphrasePolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy    string
+		separator string
+	}{
+		{"separator=space", " "},
+		{"separator=none", ""},
+		{"separator=_", "_"},
+		{"grammar=adj,noun", "-"},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		pf := addPassphraseFlags(fs)
+		grammar := fs.String("grammar", "", "")
+
+		err := applyPolicy(fs, passphrasePolicyKeys, tc.policy+" case=title")
+		if err != nil {
+			t.Errorf("%q: %v", tc.policy, err)
+			continue
+		}
+
+		if *pf.separator != tc.separator {
+			t.Errorf("%q: got separator %q, want %q", tc.policy, *pf.separator, tc.separator)
+		}
+
+		if *pf.casing != "title" {
+			t.Errorf("%q: got case %q, want title", tc.policy, *pf.casing)
+		}
+
+		if strings.HasPrefix(tc.policy, "grammar=") && *grammar != "adj,noun" {
+			t.Errorf("%q: got grammar %q", tc.policy, *grammar)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlexSSD7/cpass/generator"
+)
+
+// passwordPresets are the password formats of cpass preset. The ones that
+// are plain character policies have a site policy too, which cpass site edit
+// can start from.
+var passwordPresets = []struct {
+	name        string
+	description string
+	run         func(args []string)
+	policy      *sitePolicy
+}{
+	{"apple", "groups of six letters with one uppercase letter and one digit, like Safari's, e.g. mupric-gexwe7-zyHnod", runApplePreset, nil},
+	{"wifi", "a WPA2/WPA3 passphrase without the characters router interfaces choke on, optionally as a QR code payload", runWiFiPreset, &wifiPresetPolicy},
+}
+
+var wifiPresetPolicy = sitePolicy{
+	Length:         20,
+	UppercaseCount: 3,
+	DigitCount:     3,
+	SpecialCount:   2,
+	Charset:        generator.WiFiCharset.Name,
+}
+
+func presetNames() []string {
+	names := make([]string, len(passwordPresets))
+	for i, p := range passwordPresets {
+		names[i] = p.name
+	}
+
+	return names
+}
+
+// runPreset runs the preset named by the first argument, or asks for it.
+func runPreset(args []string) {
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	} else {
+		fmt.Fprint(ui, "Presets:\n")
+		for _, p := range passwordPresets {
+			fmt.Fprintf(ui, "  %v: %v\n", p.name, p.description)
+		}
+
+		var err error
+		name, err = newPrompter(os.Stdin).askString("Preset", passwordPresets[0].name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: ask for preset: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	for _, p := range passwordPresets {
+		if p.name == name {
+			p.run(args)
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: unknown preset %q (available: %v)\n", name, strings.Join(presetNames(), ", "))
+	os.Exit(2)
+}
+
+// presetPolicy returns a copy of the site policy of the named preset.
+func presetPolicy(name string) (sitePolicy, error) {
+	var names []string
+	for _, p := range passwordPresets {
+		if p.policy == nil {
+			continue
+		}
+
+		if p.name == name {
+			return *p.policy, nil
+		}
+
+		names = append(names, p.name)
+	}
+
+	return sitePolicy{}, fmt.Errorf("no preset %q with a character policy (available: %v)", name, strings.Join(names, ", "))
+}
+
+func runApplePreset(args []string) {
+	fs := flag.NewFlagSet("preset apple", flag.ExitOnError)
+	groups := fs.Uint("groups", 3, fmt.Sprintf("Number of groups (1-%v)", generator.MaxChunkGroups))
+	count := fs.Int("count", 1, fmt.Sprintf("Number of passwords to generate (1-%v)", maxCount))
+	quiet := fs.Bool("q", false, "Quiet mode: write only the passwords to stdout, one per line, and everything else to stderr")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	if *count < 1 || *count > maxCount {
+		fmt.Fprintf(os.Stderr, "Error: count must be between 1 and %v\n", maxCount)
+		os.Exit(1)
+	}
+
+	if *quiet {
+		ui = os.Stderr
+	}
+
+	g, err := generator.NewAppleStyleGenerator(uint32(*groups))
+	if err != nil {
+		fmt.Fprintf(ui, "Error: create generator instance: %s\n", err)
+		os.Exit(1)
+	}
+
+	writeGenerated(*count, *quiet, reportPrefix, "password", g.Generate)
+
+	fmt.Fprintln(ui, passphraseEntropyString(g.Entropy()))
+	generator.DiscardBufferedRandomness()
+}
+
+func runWiFiPreset(args []string) {
+	fs := flag.NewFlagSet("preset wifi", flag.ExitOnError)
+	length := fs.Uint("length", uint(wifiPresetPolicy.Length), fmt.Sprintf("Passphrase length (%v-%v)", generator.MinWPALength, generator.MaxWPALength))
+	upper := fs.Uint("upper", uint(wifiPresetPolicy.UppercaseCount), "Number of uppercase characters")
+	digits := fs.Uint("digits", uint(wifiPresetPolicy.DigitCount), "Number of digits")
+	special := fs.Uint("special", uint(wifiPresetPolicy.SpecialCount), "Number of special characters")
+	ssid := fs.String("ssid", "", "Write the WIFI: QR code payload for this network name instead of the bare passphrase, e.g. for qrencode")
+	count := fs.Int("count", 1, fmt.Sprintf("Number of passphrases to generate (1-%v)", maxCount))
+	quiet := fs.Bool("q", false, "Quiet mode: write only the passphrases to stdout, one per line, and everything else to stderr")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	if *count < 1 || *count > maxCount {
+		fmt.Fprintf(os.Stderr, "Error: count must be between 1 and %v\n", maxCount)
+		os.Exit(1)
+	}
+
+	if *quiet {
+		ui = os.Stderr
+	}
+
+	g, err := generator.NewGenerator(uint32(*length), uint32(*upper), uint32(*digits), uint32(*special), generator.WithCharset(generator.WiFiCharset), generator.WithWPA())
+	if err != nil {
+		fmt.Fprintf(ui, "Error: create generator instance: %s\n", err)
+		os.Exit(1)
+	}
+
+	entropy, err := g.EntropyMin()
+	if err != nil {
+		fmt.Fprintf(ui, "Error: get min entropy: %s\n", err)
+		os.Exit(1)
+	}
+
+	generate := g.Generate
+	prefix := passphraseReportPrefix
+	if *ssid != "" {
+		generate = func() ([]byte, error) {
+			b, err := g.Generate()
+			if err != nil {
+				return nil, err
+			}
+			defer wipe(b)
+
+			return generator.WiFiQRPayload(*ssid, b), nil
+		}
+		prefix = "QR Code Payload: "
+	}
+
+	writeGenerated(*count, *quiet, prefix, "passphrase", generate)
+
+	fmt.Fprintln(ui, passphraseEntropyString(float64(entropy)))
+	generator.DiscardBufferedRandomness()
+}
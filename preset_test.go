@@ -0,0 +1,66 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPresetPolicies(t *testing.T) {
+	for _, p := range passwordPresets {
+		if p.policy == nil {
+			continue
+		}
+
+		err := p.policy.validate()
+		if err != nil {
+			t.Errorf("%v: %v", p.name, err)
+		}
+	}
+
+	_, err := presetPolicy("apple")
+	if err == nil || !strings.Contains(err.Error(), "available: wifi") {
+		t.Errorf("apple: got %v", err)
+	}
+}
+
+func TestSiteEditFromPreset(t *testing.T) {
+	dir := t.TempDir()
+
+	_, stderr, err := runCpassIn(t, dir, "", "site", "add", "router", "-length", "12", "-notes", "home")
+	if err != nil {
+		t.Fatalf("cpass: %v\n%s", err, stderr)
+	}
+
+	// Taking every default keeps the preset, and the notes of the
+	// stored policy.
+	stdout, stderr, err := runCpassIn(t, dir, strings.Repeat("\n", 8), "site", "edit", "router", "-from-preset", "wifi")
+	if err != nil {
+		t.Fatalf("cpass: %v\n%s", err, stderr)
+	}
+
+	want := "Stored the policy for router: length 20, 3 uppercase, 3 digits, 2 special, wifi charset."
+	if !strings.Contains(stdout, want) {
+		t.Errorf("got %q, want it to contain %q", stdout, want)
+	}
+
+	stdout, stderr, err = runCpassIn(t, dir, "", "site", "show", "router")
+	if err != nil || !strings.Contains(stdout, "home") {
+		t.Errorf("cpass: %v, got %q\n%s", err, stdout, stderr)
+	}
+}
@@ -0,0 +1,142 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/AlexSSD7/cpass/generator"
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+)
+
+// maxDistanceAttempts bounds how often a password is regenerated to get far
+// enough from the previous one. Random passwords almost always pass the first
+// time, so running out means the thresholds cannot be met.
+const maxDistanceAttempts = 100
+
+// readPreviousPassword reads the previous password from path, or with a hidden
+// prompt if path is empty. It never comes from a flag, so it does not end up
+// in the shell history or the process list. The caller must wipe the
+// returned slice.
+func readPreviousPassword(path string) ([]byte, error) {
+	var pw []byte
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "read previous password file")
+		}
+
+		pw = bytes.TrimRight(b, "\r\n")
+	} else {
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			return nil, fmt.Errorf("stdin is not a terminal, use -previous-file")
+		}
+
+		fmt.Fprint(ui, "Previous password (hidden) > ")
+
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(ui)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "read password")
+		}
+
+		pw = b
+	}
+
+	if len(pw) == 0 {
+		return nil, fmt.Errorf("the previous password is empty")
+	}
+
+	return pw, nil
+}
+
+// generateDistinct generates passwords until one is at least the given
+// distances away from previous. A nil previous accepts the first password.
+func generateDistinct(g *generator.Generator, previous []byte, minHamming, minEdit int) ([]byte, error) {
+	for i := 0; i < maxDistanceAttempts; i++ {
+		b, err := g.Generate()
+		if err != nil {
+			return nil, err
+		}
+
+		if previous == nil || generator.CheckDistance(b, previous, minHamming, minEdit) == nil {
+			return b, nil
+		}
+
+		wipe(b)
+	}
+
+	return nil, fmt.Errorf("no password differed enough from the previous one in %v attempts", maxDistanceAttempts)
+}
+
+// generateBatch generates count distinct passwords that are each at least the
+// given distances away from previous. Passwords that are too close are
+// replaced, and the replacements are kept distinct from the rest too.
+func generateBatch(g *generator.Generator, count int, previous []byte, minHamming, minEdit int) ([][]byte, error) {
+	pws, err := g.GenerateManyParallel(context.Background(), count, runtime.NumCPU())
+	if err != nil || previous == nil {
+		return pws, err
+	}
+
+	acceptable := func(i int) bool {
+		if generator.CheckDistance(pws[i], previous, minHamming, minEdit) != nil {
+			return false
+		}
+
+		for j := range pws {
+			if j != i && bytes.Equal(pws[i], pws[j]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for i := range pws {
+		for attempts := 0; !acceptable(i); attempts++ {
+			if attempts >= maxDistanceAttempts {
+				wipeAll(pws)
+				return nil, fmt.Errorf("no password differed enough from the previous one in %v attempts", maxDistanceAttempts)
+			}
+
+			wipe(pws[i])
+
+			pws[i], err = g.Generate()
+			if err != nil {
+				pws[i] = nil
+				wipeAll(pws)
+
+				return nil, err
+			}
+		}
+	}
+
+	return pws, nil
+}
+
+func wipeAll(pws [][]byte) {
+	for _, pw := range pws {
+		wipe(pw)
+	}
+}
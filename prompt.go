@@ -0,0 +1,261 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// prompter asks the user questions on a line-based input. A numeric answer
+// may carry several whitespace-separated values, in which case the extra
+// values are used to answer the following numeric prompts.
+type prompter struct {
+	r       *bufio.Reader
+	pending []string
+	// in delivers the input bytes, see input. line holds the bytes of the
+	// line being read.
+	in   chan inputByte
+	line []byte
+	// defaultLabel is shown before default values, e.g. "last: " when the
+	// defaults come from the previous run.
+	defaultLabel string
+	// timeout is set when the session times out without input.
+	timeout *timeoutReader
+}
+
+func newPrompter(r io.Reader) *prompter {
+	return &prompter{
+		r: bufio.NewReader(r),
+	}
+}
+
+// newTimeoutPrompter returns a prompter whose reads fail with
+// errSessionTimeout once no input has arrived for the timeout.
+func newTimeoutPrompter(r io.Reader, timeout time.Duration) *prompter {
+	t := newTimeoutReader(r, timeout)
+
+	p := newPrompter(t)
+	p.timeout = t
+
+	return p
+}
+
+// timeLeft returns a note on the time left until the session times out for
+// the prompts, or an empty string if there is plenty left.
+func (p *prompter) timeLeft() string {
+	if p.timeout == nil {
+		return ""
+	}
+
+	left := p.timeout.timeLeft()
+	if left >= timeLeftShown {
+		return ""
+	}
+
+	return fmt.Sprintf(" (%v left)", max(left.Round(time.Second), 0))
+}
+
+// discardPending drops any values left over from a multi-value answer and
+// returns how many there were.
+func (p *prompter) discardPending() int {
+	n := len(p.pending)
+	p.pending = nil
+	return n
+}
+
+type inputByte struct {
+	b   byte
+	err error
+}
+
+// input returns the channel every read from p goes through. A single
+// goroutine reads the input for the lifetime of p, so that a wait for input
+// can be given up, e.g. when a countdown expires, without a read left behind
+// that would take the answer to the next prompt.
+func (p *prompter) input() <-chan inputByte {
+	if p.in == nil {
+		p.in = make(chan inputByte)
+
+		go func(r *bufio.Reader, in chan<- inputByte) {
+			for {
+				b, err := r.ReadByte()
+				in <- inputByte{b, err}
+			}
+		}(p.r, p.in)
+	}
+
+	return p.in
+}
+
+// readByte reads a single byte, e.g. a key in raw mode.
+func (p *prompter) readByte() (byte, error) {
+	c := <-p.input()
+	return c.b, c.err
+}
+
+func (p *prompter) readLine() (string, error) {
+	for {
+		line, done, err := p.feed(<-p.input())
+		if done {
+			return line, err
+		}
+	}
+}
+
+// feed adds c to the line being read, and returns the line once it is
+// complete. A line cut short by the end of the input is complete too.
+func (p *prompter) feed(c inputByte) (line string, done bool, err error) {
+	switch {
+	case c.err != nil && (!errors.Is(c.err, io.EOF) || len(p.line) == 0):
+		return "", true, errors.Wrap(c.err, "read bytes")
+	case c.err == nil && c.b != '\n':
+		p.line = append(p.line, c.b)
+		return "", false, nil
+	}
+
+	line = strings.TrimSpace(string(p.line))
+	clear(p.line)
+	p.line = p.line[:0]
+
+	return line, true, nil
+}
+
+// askUint32 prompts for an unsigned integer. If def is not nil, it is shown
+// in the prompt and returned when the answer is left empty.
+func (p *prompter) askUint32(prompt string, def *uint32) (uint32, error) {
+	if def != nil {
+		fmt.Fprintf(ui, "%s [%s%v]%s > ", prompt, p.defaultLabel, *def, p.timeLeft())
+	} else {
+		fmt.Fprintf(ui, "%s%s > ", prompt, p.timeLeft())
+	}
+
+	if len(p.pending) != 0 {
+		answer := p.pending[0]
+		p.pending = p.pending[1:]
+
+		// Values left over from an earlier answer were already validated.
+		fmt.Fprintln(ui, answer)
+		v, _ := strconv.ParseUint(answer, 10, 32)
+		return uint32(v), nil
+	}
+
+	for {
+		answer, err := p.readLine()
+		if err != nil {
+			return 0, err
+		}
+
+		if answer == "" && def != nil {
+			return *def, nil
+		}
+
+		fields := strings.Fields(answer)
+		if len(fields) <= 1 {
+			v, err := strconv.ParseUint(answer, 10, 32)
+			if err != nil {
+				return 0, errors.Wrap(err, "parse uint")
+			}
+
+			return uint32(v), nil
+		}
+
+		values := make([]uint32, len(fields))
+		for i, f := range fields {
+			v, err := strconv.ParseUint(f, 10, 32)
+			if err != nil {
+				values = nil
+				break
+			}
+
+			values[i] = uint32(v)
+		}
+
+		if values == nil {
+			fmt.Fprintf(ui, "Could not parse %q as a list of numbers. Hint: separate values with spaces, e.g. \"17 2 3 2\", or answer one prompt at a time.\n", answer)
+			fmt.Fprintf(ui, "%s%s > ", prompt, p.timeLeft())
+			continue
+		}
+
+		p.pending = fields[1:]
+		return values[0], nil
+	}
+}
+
+// askString prompts for a line of text. def is shown in the prompt, if not
+// empty, and returned when the answer is left empty.
+func (p *prompter) askString(prompt, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(ui, "%s [%s]%s > ", prompt, def, p.timeLeft())
+	} else {
+		fmt.Fprintf(ui, "%s%s > ", prompt, p.timeLeft())
+	}
+
+	answer, err := p.readLine()
+	if err != nil {
+		return "", err
+	}
+
+	if answer == "" {
+		return def, nil
+	}
+
+	return answer, nil
+}
+
+func (p *prompter) askYesNo(prompt string) (bool, error) {
+	fmt.Fprintf(ui, "%s [y/n]%s > ", prompt, p.timeLeft())
+	answer, err := p.readLine()
+	if err != nil {
+		return false, err
+	}
+
+	return strings.HasPrefix(strings.ToLower(answer), "y"), nil
+}
+
+func (p *prompter) askPasswordLength(def *uint32) (uint32, error) {
+	for {
+		pwLen, err := p.askUint32("Password length", def)
+		if err != nil {
+			return 0, err
+		}
+
+		if pwLen%10 != 0 && !isPowerOfTwo(pwLen) {
+			return pwLen, nil
+		}
+
+		fmt.Fprint(ui, "WARN: Detected a common base-ten (10, 20, etc) or power-of-two (16, 32, etc) password length. It's recommended to use something more random.\n")
+		yes, err := p.askYesNo("Change password length?")
+		if err != nil {
+			return 0, errors.Wrap(err, "ask for yes/no")
+		}
+
+		if !yes {
+			fmt.Fprint(ui, "WARN: Going with unsafe password length.\n")
+			return pwLen, nil
+		}
+
+		// The remaining values were meant for the old length.
+		p.discardPending()
+	}
+}
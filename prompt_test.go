@@ -0,0 +1,97 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPrompterReadsPartialLineAfterGivingUp(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+
+	p := newPrompter(r)
+
+	go func() {
+		_, _ = io.WriteString(w, "ab")
+	}()
+
+	// A wait that is given up after part of a line was typed keeps it.
+	for i := 0; i < 2; i++ {
+		_, done, err := p.feed(<-p.input())
+		if done || err != nil {
+			t.Fatalf("feed = %v, %v", done, err)
+		}
+	}
+
+	go func() {
+		_, _ = io.WriteString(w, "c\n")
+	}()
+
+	line, err := p.readLine()
+	if err != nil || line != "abc" {
+		t.Fatalf("readLine = %q, %v, want abc", line, err)
+	}
+}
+
+func TestAskAnotherPasswordWithoutLoop(t *testing.T) {
+	var out bytes.Buffer
+	ui = &out
+	defer func() { ui = os.Stdout }()
+
+	p := newPrompter(strings.NewReader("y\n17\n"))
+
+	another, err := askAnotherPassword(p, false)
+	if err != nil || another {
+		t.Fatalf("askAnotherPassword = %v, %v, want false", another, err)
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("asked %q without a loop", out.String())
+	}
+
+	// The answers are left for whatever reads next.
+	line, err := p.readLine()
+	if err != nil || line != "y" {
+		t.Errorf("readLine = %q, %v, want y", line, err)
+	}
+}
+
+func TestAskAnotherPassword(t *testing.T) {
+	ui = io.Discard
+	defer func() { ui = os.Stdout }()
+
+	for _, tc := range []struct {
+		input string
+		want  bool
+	}{
+		{"y\n", true},
+		{"yes\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	} {
+		another, err := askAnotherPassword(newPrompter(strings.NewReader(tc.input)), true)
+		if err != nil || another != tc.want {
+			t.Errorf("%q: askAnotherPassword = %v, %v, want %v", tc.input, another, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlexSSD7/cpass/generator"
+)
+
+func runPronounceable(args []string) {
+	names := make([]string, len(generator.SyllablePatterns))
+	for i, sp := range generator.SyllablePatterns {
+		names[i] = sp.Name
+	}
+
+	fs := flag.NewFlagSet("pronounceable", flag.ExitOnError)
+	pattern := fs.String("pattern", generator.SyllablePatterns[0].Name, "Syllable pattern: "+strings.Join(names, ", ")+", or slots of C (consonant), V (vowel), O (onset cluster), and K (coda cluster) with - . _ between them, e.g. CVC-CVVC-CVC")
+	count := fs.Int("count", 1, fmt.Sprintf("Number of passwords to generate (1-%v)", maxCount))
+	quiet := fs.Bool("q", false, "Quiet mode: write only the passwords to stdout, one per line, and everything else to stderr")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	if *count < 1 || *count > maxCount {
+		fmt.Fprintf(os.Stderr, "Error: count must be between 1 and %v\n", maxCount)
+		os.Exit(1)
+	}
+
+	if *quiet {
+		ui = os.Stderr
+	}
+
+	g, err := generator.NewPronounceableGenerator(generator.WithSyllablePattern(*pattern))
+	if err != nil {
+		fmt.Fprintf(ui, "Error: create pronounceable generator instance: %s\n", err)
+		os.Exit(1)
+	}
+
+	writeGenerated(*count, *quiet, reportPrefix, "password", g.Generate)
+
+	fmt.Fprintln(ui, passphraseEntropyString(g.Entropy()))
+	generator.DiscardBufferedRandomness()
+}
@@ -0,0 +1,153 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// recordingEnvMarkers are environment variables that terminal recorders set
+// in the sessions they record.
+var recordingEnvMarkers = []string{
+	"SCRIPT",            // script(1) on BSD and macOS
+	"ASCIINEMA_REC",     // asciinema 2
+	"ASCIINEMA_SESSION", // asciinema 3
+}
+
+// recordingPipeReaders are programs that commonly read cpass's output from a
+// pipe to store it somewhere.
+var recordingPipeReaders = []string{"tee", "script", "asciinema", "ttyrec", "logger"}
+
+// recordingMarkersFile lists extra environment variables, one per line, that
+// indicate a recorded session, e.g. ones set by corporate shells.
+const recordingMarkersFile = "recording-markers.txt"
+
+// detectRecording returns why the session appears to be recorded, or an empty
+// string if there is no indication of it.
+func detectRecording() (string, error) {
+	markers, err := loadRecordingMarkers()
+	if err != nil {
+		return "", errors.Wrap(err, "load recording markers")
+	}
+
+	for _, name := range markers {
+		if _, ok := os.LookupEnv(name); ok {
+			return fmt.Sprintf("the %v environment variable is set", name), nil
+		}
+	}
+
+	if reader := stdoutPipeReader(); reader != "" {
+		for _, name := range recordingPipeReaders {
+			if reader == name {
+				return fmt.Sprintf("the output is piped into %v", reader), nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+func loadRecordingMarkers() ([]string, error) {
+	markers := append([]string(nil), recordingEnvMarkers...)
+
+	dir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Join(dir, recordingMarkersFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return markers, nil
+		}
+
+		return nil, errors.Wrap(err, "open markers file")
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			markers = append(markers, line)
+		}
+	}
+
+	return markers, errors.Wrap(sc.Err(), "read markers file")
+}
+
+// stdoutPipeReader returns the name of another process that has the read end
+// of the pipe stdout is connected to. It only works where /proc exposes file
+// descriptors, i.e. on Linux, and returns an empty string otherwise.
+func stdoutPipeReader() string {
+	stat, err := os.Stdout.Stat()
+	if err != nil || stat.Mode()&os.ModeNamedPipe == 0 {
+		return ""
+	}
+
+	pipe, err := os.Readlink("/proc/self/fd/1")
+	if err != nil || !strings.HasPrefix(pipe, "pipe:") {
+		return ""
+	}
+
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return ""
+	}
+
+	self := strconv.Itoa(os.Getpid())
+	for _, proc := range procs {
+		pid := proc.Name()
+		if pid == self || strings.Trim(pid, "0123456789") != "" {
+			continue
+		}
+
+		fds, err := os.ReadDir(filepath.Join("/proc", pid, "fd"))
+		if err != nil {
+			continue
+		}
+
+		for _, fd := range fds {
+			if fd.Name() == "1" || fd.Name() == "2" {
+				// The process writes into the same pipe rather than
+				// reading from it, e.g. a sibling in the pipeline.
+				continue
+			}
+
+			target, err := os.Readlink(filepath.Join("/proc", pid, "fd", fd.Name()))
+			if err != nil || target != pipe {
+				continue
+			}
+
+			comm, err := os.ReadFile(filepath.Join("/proc", pid, "comm"))
+			if err != nil {
+				return ""
+			}
+
+			return strings.TrimSpace(string(comm))
+		}
+	}
+
+	return ""
+}
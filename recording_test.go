@@ -0,0 +1,58 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordingFallback(t *testing.T) {
+	t.Setenv("SCRIPT", "1")
+
+	path := filepath.Join(t.TempDir(), "pw")
+	stdout, stderr, err := runCpass(t, "f\n"+path+"\n17\n2\n3\n2\nn\n", "-fresh", "-no-sandbox")
+	if err != nil {
+		t.Fatalf("cpass: %v\n%s", err, stderr)
+	}
+
+	pw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pw = []byte(strings.TrimSuffix(string(pw), "\n"))
+	if len(pw) != 17 || strings.Contains(stdout, string(pw)) {
+		t.Errorf("got %q in the file, and the output %q", pw, stdout)
+	}
+
+	// Anything but a choice aborts, and so does the default.
+	for _, answer := range []string{"\n", "x\n", ""} {
+		_, _, err = runCpass(t, answer+"17\n2\n3\n2\nn\n", "-fresh", "-no-sandbox")
+		if err == nil {
+			t.Errorf("%q: not aborted", answer)
+		}
+	}
+
+	// With -big, the password can only be shown.
+	stdout, _, err = runCpass(t, "y\n17\n2\n3\n2\nn\n", "-fresh", "-no-sandbox", "-big")
+	if err != nil || !strings.Contains(stdout, "Show passwords anyway? [y/n]") {
+		t.Errorf("-big: %v, got %q", err, stdout)
+	}
+}
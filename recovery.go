@@ -0,0 +1,119 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/AlexSSD7/cpass/generator"
+	"github.com/pkg/errors"
+)
+
+func runRecoveryCodes(args []string) {
+	fs := flag.NewFlagSet("recovery-codes", flag.ExitOnError)
+	count := fs.Int("count", 10, "Number of codes")
+	groups := fs.Int("groups", 2, "Number of hyphen-separated groups per code")
+	groupLen := fs.Int("group-len", 4, "Number of characters per group")
+	charset := fs.String("charset", generator.RecoveryCodeCharset, "Characters to draw the codes from")
+	out := fs.String("out", "", "Write the codes to this file, readable only by you, instead of the terminal")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	codes, err := generator.GenerateRecoveryCodes(*count, *groups, *groupLen, *charset)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: generate recovery codes: %s\n", err)
+		os.Exit(1)
+	}
+
+	block := recoveryCodeBlock(codes)
+	wipeAll(codes)
+
+	err = writeRecoveryCodes(block, *out)
+	wipe(block)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: write recovery codes: %s\n", err)
+		os.Exit(1)
+	}
+
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %v recovery codes to %v.\n", len(codes), *out)
+	}
+
+	bits := generator.RecoveryCodeEntropy(*groups, *groupLen, *charset)
+	fmt.Fprintf(os.Stderr, "Entropy per code: %.2f bits (%v)\n", bits, getRatingString(bits))
+	generator.DiscardBufferedRandomness()
+}
+
+// recoveryCodeBlock numbers the codes one per line, aligned for printing.
+// The caller must wipe the block.
+func recoveryCodeBlock(codes [][]byte) []byte {
+	width := len(strconv.Itoa(len(codes)))
+
+	// Allocated at once, so that append leaves no unwiped copies behind.
+	size := 0
+	for _, code := range codes {
+		size += width + len(". ") + len(code) + 1
+	}
+
+	block := make([]byte, 0, size)
+	for i, code := range codes {
+		num := strconv.Itoa(i + 1)
+		for j := len(num); j < width; j++ {
+			block = append(block, ' ')
+		}
+
+		block = append(block, num...)
+		block = append(block, ". "...)
+		block = append(block, code...)
+		block = append(block, '\n')
+	}
+
+	return block
+}
+
+func writeRecoveryCodes(block []byte, path string) error {
+	if path == "" {
+		_, err := os.Stdout.Write(block)
+		return errors.Wrap(err, "write to stdout")
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return errors.Wrap(err, "open codes file")
+	}
+
+	// An existing file keeps its mode, which may be more permissive.
+	err = f.Chmod(0o600)
+	if err == nil {
+		_, err = f.Write(block)
+	}
+
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return errors.Wrap(err, "write codes file")
+}
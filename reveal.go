@@ -0,0 +1,162 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+)
+
+// crlfWriter translates "\n" to "\r\n", which a terminal in raw mode needs to
+// return to the start of the line.
+type crlfWriter struct {
+	w io.Writer
+}
+
+func (cw crlfWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) != 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i == -1 {
+			m, err := cw.w.Write(p)
+			return n + m, err
+		}
+
+		m, err := cw.w.Write(p[:i])
+		n += m
+		if err != nil {
+			return n, err
+		}
+
+		_, err = io.WriteString(cw.w, "\r\n")
+		if err != nil {
+			return n, err
+		}
+
+		n++
+		p = p[i+1:]
+	}
+
+	return n, nil
+}
+
+// stepReveal shows the password one character at a time on the alternate
+// screen: space reveals the next character, b steps back, and q (or Ctrl-C)
+// finishes. At most one character is visible at any moment, and the normal
+// screen comes back untouched once it is done where the terminal has an
+// alternate screen. Elsewhere the screen is cleared instead.
+func stepReveal(p *prompter, pw []byte) error {
+	fd := int(os.Stdin.Fd())
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return errors.Wrap(err, "make terminal raw")
+	}
+
+	scr := stdoutScreen()
+	restore := func() {
+		scr.clearScreen()
+		scr.leaveAltScreen()
+		_ = term.Restore(fd, state)
+	}
+
+	// Raw mode turns Ctrl-C into a regular key press, but cpass can still be
+	// terminated from the outside, so restore the terminal then too.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-sigCh:
+			restore()
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	defer restore()
+
+	scr.enterAltScreen()
+
+	w := crlfWriter{w: os.Stdout}
+	pos := -1
+	for {
+		scr.clearScreen()
+
+		err = drawRevealStep(w, pw, pos)
+		if err != nil {
+			return err
+		}
+
+		key, err := p.readByte()
+		if err != nil {
+			return errors.Wrap(err, "read key")
+		}
+
+		switch key {
+		case ' ':
+			pos = min(pos+1, len(pw))
+		case 'b':
+			pos = max(pos-1, -1)
+		case 'q', 0x03, 0x04:
+			return nil
+		}
+	}
+}
+
+func drawRevealStep(w io.Writer, pw []byte, pos int) error {
+	switch {
+	case pos < 0:
+		fmt.Fprintf(w, "The password has %v characters. Press space to reveal the first one.\n", len(pw))
+	case pos == len(pw):
+		fmt.Fprintf(w, "All %v characters have been shown.\n", len(pw))
+	default:
+		fmt.Fprintf(w, "Character %v of %v:\n\n", pos+1, len(pw))
+
+		_, err := renderBig(w, pw[pos:pos+1], terminalWidth())
+		if err != nil {
+			return err
+		}
+
+		var name [32]byte
+		spoken := appendSpokenChar(name[:0], rune(pw[pos]))
+		// Write the name directly, since fmt would keep a copy in its
+		// pooled buffers.
+		spoken = append(spoken, '\n')
+		_, err = w.Write(spoken)
+		wipe(name[:])
+
+		if err != nil {
+			return errors.Wrap(err, "write character name")
+		}
+	}
+
+	_, err := fmt.Fprint(w, "\n[space] next  [b] back  [q] done\n")
+
+	return errors.Wrap(err, "write key help")
+}
@@ -0,0 +1,86 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/AlexSSD7/cpass/clipboard"
+	"github.com/pkg/errors"
+)
+
+// sandboxPolicy describes what cpass still needs to do once the sandbox is
+// enabled, on top of terminal I/O and randomness.
+type sandboxPolicy struct {
+	// configDir is the directory the last parameters are saved to, or empty
+	// if no files are written.
+	configDir string
+	// outFile is the file -out writes the passwords to, or empty.
+	outFile string
+	// programs are the features that run other programs, e.g. -speak, which
+	// the sandbox has to allow.
+	programs []string
+}
+
+// exec reports whether the policy allows running other programs.
+func (p sandboxPolicy) exec() bool {
+	return len(p.programs) != 0
+}
+
+// sandboxEnabled is set once enableSandbox has restricted the process.
+var sandboxEnabled bool
+
+// sandboxError explains permission errors that come from the sandbox rather
+// than from the file system.
+func sandboxError(err error, action string) error {
+	if err != nil && sandboxEnabled && errors.Is(err, syscall.EPERM) {
+		return fmt.Errorf("%v is not allowed by the sandbox, run cpass with -no-sandbox if it is needed: %s", action, err)
+	}
+
+	return err
+}
+
+// newSandboxPolicy derives the policy from the features in use. Files are
+// only written when the last parameters are remembered and for -out, and
+// programs are only run for speech, the clipboard tools, and -exec.
+func newSandboxPolicy(cfg settings, spk *speaker, cb clipboard.Clipboard, outFile string, runsCommand bool) sandboxPolicy {
+	var policy sandboxPolicy
+
+	if cfg.RememberLast {
+		dir, err := configDir()
+		if err == nil {
+			policy.configDir = dir
+		}
+	}
+
+	policy.outFile = outFile
+
+	if spk != nil {
+		policy.programs = append(policy.programs, "-speak")
+	}
+
+	if clipboard.RunsPrograms(cb) {
+		policy.programs = append(policy.programs, cb.Name())
+	}
+
+	if runsCommand {
+		policy.programs = append(policy.programs, "-exec")
+	}
+
+	return policy
+}
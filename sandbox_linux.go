@@ -0,0 +1,209 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+//go:build linux && (amd64 || arm64)
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	seccompSetModeFilter   = 1
+	seccompFilterFlagTsync = 1
+
+	seccompRetKillProcess = 0x80000000
+	seccompRetErrno       = 0x00050000
+	seccompRetAllow       = 0x7fff0000
+
+	// Offsets into struct seccomp_data. The arguments are 64 bits wide, and
+	// the lower half comes first on both supported architectures.
+	seccompDataNr   = 0
+	seccompDataArch = 4
+	seccompDataArg1 = 24
+)
+
+// sandboxSyscalls are the system calls the Go runtime, the terminal, and
+// the generator need once cpass has started.
+var sandboxSyscalls = []uintptr{
+	unix.SYS_READ,
+	unix.SYS_WRITE,
+	unix.SYS_READV,
+	unix.SYS_WRITEV,
+	unix.SYS_PREAD64,
+	unix.SYS_PWRITE64,
+	unix.SYS_CLOSE,
+	unix.SYS_FSTAT,
+	unix.SYS_LSEEK,
+	unix.SYS_FCNTL,
+	unix.SYS_PIPE2,
+	unix.SYS_EVENTFD2,
+	unix.SYS_EPOLL_CREATE1,
+	unix.SYS_EPOLL_CTL,
+	unix.SYS_EPOLL_PWAIT,
+	unix.SYS_PPOLL,
+	unix.SYS_PSELECT6,
+	unix.SYS_MMAP,
+	unix.SYS_MUNMAP,
+	unix.SYS_MPROTECT,
+	unix.SYS_MADVISE,
+	unix.SYS_BRK,
+	unix.SYS_RT_SIGACTION,
+	unix.SYS_RT_SIGPROCMASK,
+	unix.SYS_RT_SIGRETURN,
+	unix.SYS_SIGALTSTACK,
+	unix.SYS_CLONE,
+	unix.SYS_FUTEX,
+	unix.SYS_NANOSLEEP,
+	unix.SYS_CLOCK_NANOSLEEP,
+	unix.SYS_CLOCK_GETTIME,
+	unix.SYS_GETTIMEOFDAY,
+	unix.SYS_SCHED_YIELD,
+	unix.SYS_SCHED_GETAFFINITY,
+	unix.SYS_GETPID,
+	unix.SYS_GETTID,
+	unix.SYS_TGKILL,
+	unix.SYS_GETRANDOM,
+	unix.SYS_GETRLIMIT,
+	unix.SYS_PRLIMIT64,
+	unix.SYS_UNAME,
+	unix.SYS_RESTART_SYSCALL,
+	unix.SYS_EXIT,
+	unix.SYS_EXIT_GROUP,
+}
+
+// sandboxFileSyscalls are added when files are written, see writeFileAtomic.
+var sandboxFileSyscalls = []uintptr{
+	unix.SYS_OPENAT,
+	unix.SYS_MKDIRAT,
+	unix.SYS_RENAMEAT,
+	unix.SYS_RENAMEAT2,
+	unix.SYS_UNLINKAT,
+	unix.SYS_FCHMOD,
+	unix.SYS_FCHMODAT,
+	unix.SYS_FSYNC,
+	unix.SYS_FDATASYNC,
+}
+
+// sandboxIoctls are the only ioctl requests allowed: reading and setting the
+// terminal mode and size. Most notably, TIOCSTI, which injects input into the
+// terminal, is not.
+var sandboxIoctls = []uint32{
+	unix.TCGETS,
+	unix.TCSETS,
+	unix.TCSETSW,
+	unix.TIOCGWINSZ,
+}
+
+// enableSandbox installs a seccomp filter that allows only the system calls
+// the policy needs. Other system calls fail with EPERM. When the policy runs
+// other programs, no filter is installed, and a warning says so.
+func enableSandbox(policy sandboxPolicy) error {
+	if policy.exec() {
+		// The filter would be inherited by the programs, e.g. the speech
+		// engine or xclip, which need far more than cpass itself.
+		verb := "runs"
+		if len(policy.programs) > 1 {
+			verb = "run"
+		}
+
+		fmt.Fprintf(ui, "WARN: The sandbox is off, since %v %v other programs, which it would restrict as well.\n", strings.Join(policy.programs, " and "), verb)
+		return nil
+	}
+
+	syscalls := append(append([]uintptr{}, sandboxSyscalls...), sandboxArchSyscalls...)
+	if policy.configDir != "" || policy.outFile != "" {
+		syscalls = append(syscalls, sandboxFileSyscalls...)
+	}
+
+	filter := seccompFilter(syscalls)
+
+	// PR_SET_NO_NEW_PRIVS applies to the calling thread only, and seccomp
+	// copies it to the other threads when synchronizing them.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0)
+	if err != nil {
+		return errors.Wrap(err, "set no_new_privs")
+	}
+
+	prog := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+
+	tid, _, errno := unix.Syscall(unix.SYS_SECCOMP, seccompSetModeFilter, seccompFilterFlagTsync, uintptr(unsafe.Pointer(&prog)))
+	if errno != 0 {
+		return errors.Wrap(errno, "install seccomp filter")
+	}
+
+	if tid != 0 {
+		return fmt.Errorf("install seccomp filter: thread %v cannot be synchronized", tid)
+	}
+
+	sandboxEnabled = true
+
+	return nil
+}
+
+// seccompFilter builds a BPF program that allows the given system calls and
+// the ioctl requests in sandboxIoctls, and fails everything else with EPERM.
+// Jumps in BPF can only go forward, so the ioctl checks come last.
+func seccompFilter(syscalls []uintptr) []unix.SockFilter {
+	prog := []unix.SockFilter{
+		bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArch),
+		bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, sandboxArch, 1, 0),
+		bpfStmt(unix.BPF_RET|unix.BPF_K, seccompRetKillProcess),
+		bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataNr),
+		// Skip the checks below, the deny, and the allow.
+		bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, unix.SYS_IOCTL, uint8(len(syscalls)+2), 0),
+	}
+
+	for i, nr := range syscalls {
+		prog = append(prog, bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, uint32(nr), uint8(len(syscalls)-i), 0))
+	}
+
+	prog = append(prog,
+		bpfStmt(unix.BPF_RET|unix.BPF_K, seccompRetErrno|uint32(unix.EPERM)),
+		bpfStmt(unix.BPF_RET|unix.BPF_K, seccompRetAllow),
+		bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArg1),
+	)
+
+	for i, req := range sandboxIoctls {
+		prog = append(prog, bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, req, uint8(len(sandboxIoctls)-i), 0))
+	}
+
+	return append(prog,
+		bpfStmt(unix.BPF_RET|unix.BPF_K, seccompRetErrno|uint32(unix.EPERM)),
+		bpfStmt(unix.BPF_RET|unix.BPF_K, seccompRetAllow),
+	)
+}
+
+func bpfStmt(code uint16, k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: code, K: k}
+}
+
+func bpfJump(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+	return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
@@ -0,0 +1,31 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+//go:build linux && amd64
+
+package main
+
+import "golang.org/x/sys/unix"
+
+const sandboxArch = unix.AUDIT_ARCH_X86_64
+
+var sandboxArchSyscalls = []uintptr{
+	unix.SYS_EPOLL_WAIT,
+	unix.SYS_POLL,
+	unix.SYS_SELECT,
+	unix.SYS_ARCH_PRCTL,
+	unix.SYS_NEWFSTATAT,
+}
@@ -0,0 +1,111 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+//go:build linux && (amd64 || arm64)
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// sandboxChildEnv makes TestSandboxDeniesFiles enable the sandbox in a
+// child process, as it can't be turned off again.
+const sandboxChildEnv = "CPASS_TEST_SANDBOX_CHILD"
+
+var generationArgs = []string{"-length", "20", "-upper", "2", "-digits", "2", "-special", "2", "-q"}
+
+func TestGenerationUnderSandbox(t *testing.T) {
+	stdout, stderr, err := runCpass(t, "", generationArgs...)
+	if err != nil {
+		t.Fatalf("cpass: %v\n%s", err, stderr)
+	}
+
+	if strings.Contains(stderr, "sandbox") {
+		t.Errorf("the sandbox was not enabled:\n%s", stderr)
+	}
+
+	if pw := strings.TrimSuffix(stdout, "\n"); utf8.RuneCountInString(pw) != 20 {
+		t.Errorf("got %q, want a password of 20 characters", stdout)
+	}
+}
+
+func TestGenerationUnderSandboxSavesLastParams(t *testing.T) {
+	dir := t.TempDir()
+
+	_, stderr, err := runCpassIn(t, dir, "", "config", "remember-last", "on")
+	if err != nil {
+		t.Fatalf("cpass config: %v\n%s", err, stderr)
+	}
+
+	_, stderr, err = runCpassIn(t, dir, "", generationArgs...)
+	if err != nil {
+		t.Fatalf("cpass: %v\n%s", err, stderr)
+	}
+
+	if strings.Contains(stderr, "WARN") {
+		t.Errorf("unexpected warning:\n%s", stderr)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, lastParamsFile)); err != nil {
+		t.Errorf("the last parameters were not saved: %v", err)
+	}
+}
+
+func TestSandboxWarnsWhenOff(t *testing.T) {
+	_, stderr, err := runCpass(t, "", append(generationArgs, "-exec", "true")...)
+	if err != nil {
+		t.Fatalf("cpass: %v\n%s", err, stderr)
+	}
+
+	if !strings.Contains(stderr, "WARN: The sandbox is off, since -exec runs other programs") {
+		t.Errorf("no warning that the sandbox is off:\n%s", stderr)
+	}
+}
+
+func TestSandboxDeniesFiles(t *testing.T) {
+	if os.Getenv(sandboxChildEnv) == "1" {
+		err := enableSandbox(sandboxPolicy{})
+		if err != nil {
+			t.Fatalf("enable sandbox: %v", err)
+		}
+
+		f, err := os.Open(os.Args[0])
+		if err == nil {
+			f.Close()
+			t.Fatal("opened a file under the sandbox")
+		}
+
+		if err := sandboxError(err, "opening a file"); !strings.Contains(err.Error(), "not allowed by the sandbox") {
+			t.Fatalf("got %v, want a sandbox error", err)
+		}
+
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSandboxDeniesFiles$")
+	cmd.Env = append(os.Environ(), sandboxChildEnv+"=1")
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("child: %v\n%s", err, out)
+	}
+}
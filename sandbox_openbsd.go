@@ -0,0 +1,74 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+//go:build openbsd
+
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// enableSandbox pledges the promises the policy needs and unveils only the
+// config directory and the -out file.
+func enableSandbox(policy sandboxPolicy) error {
+	promises := []string{"stdio", "tty"}
+	if policy.configDir != "" || policy.outFile != "" {
+		promises = append(promises, "rpath", "wpath", "cpath")
+	}
+
+	// The -out file is changed to mode 0600 if it exists.
+	if policy.outFile != "" {
+		promises = append(promises, "fattr")
+	}
+
+	// The speech engine can live anywhere in PATH and needs its libraries,
+	// so nothing is unveiled when it is used.
+	if policy.exec() {
+		promises = append(promises, "proc", "exec")
+	} else {
+		if policy.configDir != "" {
+			err := unix.Unveil(policy.configDir, "rwc")
+			if err != nil {
+				return errors.Wrap(err, "unveil config dir")
+			}
+		}
+
+		if policy.outFile != "" {
+			err := unix.Unveil(policy.outFile, "rwc")
+			if err != nil {
+				return errors.Wrap(err, "unveil -out file")
+			}
+		}
+
+		err := unix.UnveilBlock()
+		if err != nil {
+			return errors.Wrap(err, "block unveil")
+		}
+	}
+
+	err := unix.PledgePromises(strings.Join(promises, " "))
+	if err != nil {
+		return errors.Wrap(err, "pledge")
+	}
+
+	sandboxEnabled = true
+
+	return nil
+}
@@ -0,0 +1,157 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestANSIScreen(t *testing.T) {
+	var out bytes.Buffer
+	ui = &out
+	defer func() { ui = os.Stdout }()
+
+	for _, tc := range []struct {
+		name string
+		fn   func(screen)
+		want string
+	}{
+		{"clear lines above", func(s screen) { s.clearLinesAbove(3) }, "\r\x1b[3A\x1b[J"},
+		{"clear line", screen.clearLine, "\r\x1b[K"},
+		{"clear screen", screen.clearScreen, "\x1b[H\x1b[2J"},
+		{"enter alt screen", screen.enterAltScreen, "\x1b[?1049h"},
+		{"leave alt screen", screen.leaveAltScreen, "\x1b[?1049l"},
+	} {
+		out.Reset()
+		tc.fn(ansiScreen{})
+
+		if out.String() != tc.want {
+			t.Errorf("%v: got %q, want %q", tc.name, out.String(), tc.want)
+		}
+	}
+}
+
+// Output that is not a terminal gets the escape sequences on every
+// platform, so they stay intact e.g. through a pipe into a recording.
+func TestStdoutScreenNotTerminal(t *testing.T) {
+	if stdoutIsTerminal() {
+		t.Skip("stdout is a terminal")
+	}
+
+	if _, ok := newScreen().(ansiScreen); !ok {
+		t.Errorf("got %T, want ansiScreen", newScreen())
+	}
+
+	var out bytes.Buffer
+	ui = &out
+	defer func() { ui = os.Stdout }()
+
+	clearLinesAbove(2)
+	if out.String() != "\r\x1b[2A\x1b[J" {
+		t.Errorf("got %q", out.String())
+	}
+}
+
+func TestCRLFWriter(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"abc", "abc"},
+		{"a\nb\n", "a\r\nb\r\n"},
+		{"\n\n", "\r\n\r\n"},
+	} {
+		var out bytes.Buffer
+		n, err := crlfWriter{&out}.Write([]byte(tc.in))
+		if err != nil || n != len(tc.in) {
+			t.Errorf("%q: got %v, %v, want %v, nil", tc.in, n, err, len(tc.in))
+		}
+
+		if out.String() != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.in, out.String(), tc.want)
+		}
+	}
+}
+
+func TestDrawRevealStep(t *testing.T) {
+	pw := []byte("aZ7")
+
+	for _, tc := range []struct {
+		pos  int
+		want []string
+	}{
+		{-1, []string{"The password has 3 characters. Press space", "[space] next"}},
+		{1, []string{"Character 2 of 3:", "^^", "capital Zulu\n", "[space] next"}},
+		{3, []string{"All 3 characters have been shown.", "[space] next"}},
+	} {
+		var out bytes.Buffer
+		err := drawRevealStep(&out, pw, tc.pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, want := range tc.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("step %v: %q is missing from:\n%s", tc.pos, want, out.String())
+			}
+		}
+
+		// Only the current character is named, and the step is drawn
+		// without escape sequences, which the caller sends through the
+		// screen.
+		for _, other := range []string{"Alfa", "seven", "\x1b"} {
+			if strings.Contains(out.String(), other) {
+				t.Errorf("step %v: %q in:\n%s", tc.pos, other, out.String())
+			}
+		}
+	}
+}
+
+func TestWaitForClear(t *testing.T) {
+	var out bytes.Buffer
+	ui = &out
+	defer func() { ui = os.Stdout }()
+
+	reason, err := waitForClear(newPrompter(strings.NewReader("\n")), 30*time.Second, clearFromScreenAndClipboard)
+	if err != nil || reason != clearEnter {
+		t.Errorf("enter: got %v, %v", reason, err)
+	}
+
+	want := "Press Enter to clear the password from the screen and the clipboard (clearing in 30s) > "
+	if !strings.HasSuffix(out.String(), want) {
+		t.Errorf("enter: got %q, want it to end in %q", out.String(), want)
+	}
+
+	// Without input, the single countdown runs out.
+	r, w := io.Pipe()
+	defer w.Close()
+
+	out.Reset()
+	reason, err = waitForClear(newPrompter(r), time.Second, clearFromScreen)
+	if err != nil || reason != clearExpired {
+		t.Errorf("expiry: got %v, %v", reason, err)
+	}
+
+	if !strings.Contains(out.String(), "Press Enter to clear the password from the screen (clearing in 1s) > ") {
+		t.Errorf("expiry: got %q", out.String())
+	}
+}
@@ -0,0 +1,106 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	kernel32                       = windows.NewLazySystemDLL("kernel32.dll")
+	procFillConsoleOutputCharacter = kernel32.NewProc("FillConsoleOutputCharacterW")
+	procFillConsoleOutputAttribute = kernel32.NewProc("FillConsoleOutputAttribute")
+)
+
+// newScreen returns the screen for stdout. Windows 10 and later consoles,
+// ConPTY and Windows Terminal understand ANSI escape sequences once virtual
+// terminal processing is enabled. Legacy consoles that refuse it fall back to
+// the console API, which has no alternate screen.
+func newScreen() screen {
+	h := windows.Handle(os.Stdout.Fd())
+
+	var mode uint32
+	err := windows.GetConsoleMode(h, &mode)
+	if err != nil {
+		// Not a console, e.g. a pipe or a mintty pty, so pass the escape
+		// sequences through.
+		return ansiScreen{}
+	}
+
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 || windows.SetConsoleMode(h, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil {
+		return ansiScreen{}
+	}
+
+	return consoleScreen{h: h}
+}
+
+type consoleScreen struct {
+	h windows.Handle
+}
+
+func (s consoleScreen) info() (windows.ConsoleScreenBufferInfo, bool) {
+	var info windows.ConsoleScreenBufferInfo
+	err := windows.GetConsoleScreenBufferInfo(s.h, &info)
+
+	return info, err == nil
+}
+
+// fill blanks n cells starting at pos and moves the cursor there.
+func (s consoleScreen) fill(info windows.ConsoleScreenBufferInfo, pos windows.Coord, n int) {
+	var written uint32
+	// COORD is passed by value, packed into a single 32-bit argument.
+	coord := uintptr(uint16(pos.X)) | uintptr(uint16(pos.Y))<<16
+	_, _, _ = procFillConsoleOutputCharacter.Call(uintptr(s.h), ' ', uintptr(n), coord, uintptr(unsafe.Pointer(&written)))
+	_, _, _ = procFillConsoleOutputAttribute.Call(uintptr(s.h), uintptr(info.Attributes), uintptr(n), coord, uintptr(unsafe.Pointer(&written)))
+	_ = windows.SetConsoleCursorPosition(s.h, pos)
+}
+
+func (s consoleScreen) clearLinesAbove(n int) {
+	info, ok := s.info()
+	if !ok {
+		return
+	}
+
+	pos := windows.Coord{X: 0, Y: max(0, info.CursorPosition.Y-int16(n))}
+	s.fill(info, pos, int(info.Size.X)*int(info.Size.Y-pos.Y))
+}
+
+func (s consoleScreen) clearLine() {
+	info, ok := s.info()
+	if !ok {
+		return
+	}
+
+	s.fill(info, windows.Coord{X: 0, Y: info.CursorPosition.Y}, int(info.Size.X))
+}
+
+func (s consoleScreen) clearScreen() {
+	info, ok := s.info()
+	if !ok {
+		return
+	}
+
+	s.fill(info, windows.Coord{}, int(info.Size.X)*int(info.Size.Y))
+}
+
+func (consoleScreen) enterAltScreen() {}
+func (consoleScreen) leaveAltScreen() {}
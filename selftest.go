@@ -0,0 +1,119 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/AlexSSD7/cpass/generator"
+)
+
+var selftestPolicies = []struct {
+	charset    generator.Charset
+	params     passwordParams
+	minClasses uint32
+	// mixed draws from system randomness mixed with all-zero extra
+	// entropy, which must not skew the distribution.
+	mixed bool
+}{
+	{generator.DefaultCharset, passwordParams{length: 13, uppercaseCount: 2, digitCount: 2, specialCount: 1}, 0, false},
+	{generator.DefaultCharset, passwordParams{length: 20}, 0, false},
+	{generator.DefaultCharset, passwordParams{length: 16, uppercaseCount: 4, digitCount: 4, specialCount: 4}, 0, false},
+	{generator.NoShiftCharset, passwordParams{length: 14, digitCount: 3, specialCount: 3}, 0, false},
+	{generator.LayoutPortableCharset, passwordParams{length: 16, uppercaseCount: 4}, 0, false},
+	{generator.DefaultCharset, passwordParams{length: 12, digitCount: 1}, 3, false},
+	{generator.DefaultCharset, passwordParams{length: 13, uppercaseCount: 2, digitCount: 2, specialCount: 1}, 0, true},
+}
+
+// runSelftest checks the analytical per-position entropy of a few policies
+// against an empirical estimate from generated passwords, which catches
+// biased character draws.
+func runSelftest(args []string) {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	samples := fs.Int("samples", 50000, "Number of passwords to generate per policy")
+	tolerance := fs.Float64("tolerance", 0.01, "Allowed difference in bits beyond the confidence bounds")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "Policy\tExpected bits\tEstimated bits\tResult\n")
+
+	failed := 0
+	for _, policy := range selftestPolicies {
+		p := policy.params
+
+		opts := []generator.Option{generator.WithUppercase(p.uppercaseCount), generator.WithDigits(p.digitCount), generator.WithSpecial(p.specialCount), generator.WithCharset(policy.charset), generator.WithMinClasses(policy.minClasses)}
+		if policy.mixed {
+			src, err := generator.NewMixedSource(make([]byte, keystrokeCount*9))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: create mixed source: %s\n", err)
+				os.Exit(1)
+			}
+
+			opts = append(opts, generator.WithRandSource(src))
+		}
+
+		g, err := generator.New(p.length, opts...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: create password generator instance: %s\n", err)
+			os.Exit(1)
+		}
+
+		est, err := g.EntropyEmpirical(*samples)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: estimate entropy: %s\n", err)
+			os.Exit(1)
+		}
+
+		expected := g.PositionEntropy()
+
+		result := "ok"
+		if expected < est.Lower-*tolerance || expected > est.Upper+*tolerance {
+			result = "MISMATCH"
+			failed++
+		}
+
+		label := fmt.Sprintf("%v %v/%v/%v/%v", policy.charset.Name, p.length, p.uppercaseCount, p.digitCount, p.specialCount)
+		if policy.minClasses != 0 {
+			label += fmt.Sprintf(" classes>=%v", policy.minClasses)
+		}
+
+		if policy.mixed {
+			label += " mixed"
+		}
+
+		fmt.Fprintf(tw, "%v\t%.3f\t%.3f (%.3f-%.3f)\t%v\n", label, expected, est.Bits, est.Lower, est.Upper, result)
+	}
+
+	err = tw.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: write results: %s\n", err)
+		os.Exit(1)
+	}
+
+	if failed != 0 {
+		fmt.Fprintf(os.Stderr, "Error: the generated passwords don't match the expected entropy for %v of %v policies\n", failed, len(selftestPolicies))
+		os.Exit(1)
+	}
+}
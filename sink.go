@@ -0,0 +1,151 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AlexSSD7/cpass/clipboard"
+	"github.com/pkg/errors"
+)
+
+// sink is a destination for the password other than the terminal: the
+// clipboard, a file, or the input of a command. An exclusive sink keeps the
+// password off the terminal entirely, not even masked, and the report only
+// confirms the delivery.
+type sink struct {
+	// flag is the flag that configured the sink, for errors.
+	flag      string
+	exclusive bool
+	// deliver hands the password over without keeping a copy of it.
+	deliver func(pw []byte) error
+	// delivered describes what happened to the password, e.g. "copied".
+	delivered string
+	// clearAfter is how long until the password is cleared from the sink
+	// again, or 0 if it stays.
+	clearAfter time.Duration
+}
+
+func newClipboardSink(cb clipboard.Clipboard, exclusive bool, clearAfter time.Duration) sink {
+	return sink{
+		flag:      "-copy-only",
+		exclusive: exclusive,
+		deliver: func(pw []byte) error {
+			return errors.Wrap(cb.Write(pw), "write clipboard")
+		},
+		delivered:  "copied",
+		clearAfter: clearAfter,
+	}
+}
+
+// newFileSink writes the password to path, followed by a newline, replacing
+// the file. The file is created with mode 0600, and an existing file is
+// changed to it.
+func newFileSink(path string, exclusive bool) sink {
+	return sink{
+		flag:      "-out",
+		exclusive: exclusive,
+		deliver: func(pw []byte) error {
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+			if err != nil {
+				return errors.Wrap(sandboxError(err, "writing -out"), "open password file")
+			}
+
+			err = f.Chmod(0o600)
+			if err == nil {
+				err = writeLine(f, pw)
+			}
+
+			closeErr := f.Close()
+			if err == nil {
+				err = closeErr
+			}
+
+			return errors.Wrap(err, "write password file")
+		},
+		delivered: "written to " + path,
+	}
+}
+
+// newExecSink runs command with the password and a newline on stdin, e.g. to
+// store it in a password manager. The command is split at spaces, without a
+// shell, so the password can't end up in its arguments. Its output goes to
+// stderr.
+func newExecSink(command string, exclusive bool) (sink, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return sink{}, fmt.Errorf("-exec needs a command")
+	}
+
+	return sink{
+		flag:      "-exec",
+		exclusive: exclusive,
+		deliver: func(pw []byte) error {
+			cmd := exec.Command(args[0], args[1:]...)
+			cmd.Stdin = io.MultiReader(bytes.NewReader(pw), strings.NewReader("\n"))
+			cmd.Stdout = os.Stderr
+			cmd.Stderr = os.Stderr
+
+			return errors.Wrapf(sandboxError(cmd.Run(), "running -exec"), "run %v", args[0])
+		},
+		delivered: "passed to " + args[0],
+	}, nil
+}
+
+// exclusiveSink reports whether any of sinks keeps the password off the
+// terminal.
+func exclusiveSink(sinks []sink) bool {
+	for _, s := range sinks {
+		if s.exclusive {
+			return true
+		}
+	}
+
+	return false
+}
+
+// confirmDelivery writes e.g. "Password copied (20 chars, 101 bits,
+// auto-clear in 45s)." to w.
+func confirmDelivery(w io.Writer, s sink, pw []byte, entropy entropyReport) {
+	details := fmt.Sprintf("%v chars, %v bits", utf8.RuneCount(pw), entropy.min)
+	if s.clearAfter != 0 {
+		details += fmt.Sprintf(", auto-clear in %v", s.clearAfter)
+	}
+
+	fmt.Fprintf(w, "Password %v (%v).\n", s.delivered, details)
+}
+
+// deliver hands pw to every sink in turn and confirms each delivery on w.
+func deliver(w io.Writer, sinks []sink, pw []byte, entropy entropyReport) error {
+	for _, s := range sinks {
+		err := s.deliver(pw)
+		if err != nil {
+			return errors.Wrap(err, s.flag)
+		}
+
+		confirmDelivery(w, s, pw, entropy)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pw.txt")
+
+	// An existing file is replaced and loses its permissive mode.
+	err := os.WriteFile(path, []byte("previous contents\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = newFileSink(path, true).deliver([]byte("aB3$efgh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "aB3$efgh\n" {
+		t.Errorf("got %q, want %q", got, "aB3$efgh\n")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("got mode %o, want 600", mode)
+	}
+}
+
+func TestExecSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pw.txt")
+
+	s, err := newExecSink("tee "+path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.deliver([]byte("aB3$ efgh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "aB3$ efgh\n" {
+		t.Errorf("got %q on stdin, want %q", got, "aB3$ efgh\n")
+	}
+
+	s, err = newExecSink("false", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.deliver([]byte("x")); err == nil {
+		t.Error("a failing command succeeded")
+	}
+
+	if _, err := newExecSink("  ", false); err == nil {
+		t.Error("an empty command succeeded")
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	var delivered []string
+	record := func(name string) func([]byte) error {
+		return func(pw []byte) error {
+			delivered = append(delivered, name+" "+string(pw))
+			return nil
+		}
+	}
+
+	sinks := []sink{
+		{flag: "-copy-only", exclusive: true, deliver: record("clipboard"), delivered: "copied", clearAfter: 45 * time.Second},
+		{flag: "-out", deliver: record("file"), delivered: "written to pw.txt"},
+	}
+
+	if !exclusiveSink(sinks) || exclusiveSink(sinks[1:]) {
+		t.Error("exclusiveSink does not report the exclusive sink")
+	}
+
+	var out bytes.Buffer
+	err := deliver(&out, sinks, []byte("äB3$"), entropyReport{min: 101})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(delivered, ", ") != "clipboard äB3$, file äB3$" {
+		t.Errorf("delivered %q", delivered)
+	}
+
+	want := "Password copied (4 chars, 101 bits, auto-clear in 45s).\nPassword written to pw.txt (4 chars, 101 bits).\n"
+	if out.String() != want {
+		t.Errorf("got confirmations %q, want %q", out.String(), want)
+	}
+
+	if strings.Contains(out.String(), "äB3$") {
+		t.Error("the confirmation shows the password")
+	}
+}
@@ -0,0 +1,426 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/AlexSSD7/cpass/generator"
+	"github.com/pkg/errors"
+)
+
+// sitePolicy is the password policy remembered for a site. It never holds a
+// secret, only the parameters to generate one with.
+type sitePolicy struct {
+	Length         uint32 `json:"length"`
+	UppercaseCount uint32 `json:"uppercase_count"`
+	DigitCount     uint32 `json:"digit_count"`
+	SpecialCount   uint32 `json:"special_count"`
+	Charset        string `json:"charset"`
+	MaxRepeats     uint32 `json:"max_repeats,omitempty"`
+	MinClasses     uint32 `json:"min_classes,omitempty"`
+	Notes          string `json:"notes,omitempty"`
+}
+
+type siteStore struct {
+	Sites map[string]sitePolicy `json:"sites"`
+}
+
+func (s *sitePolicy) params() passwordParams {
+	return passwordParams{
+		length:         s.Length,
+		uppercaseCount: s.UppercaseCount,
+		digitCount:     s.DigitCount,
+		specialCount:   s.SpecialCount,
+	}
+}
+
+func (s *sitePolicy) describe() string {
+	ret := fmt.Sprintf("length %v, %v uppercase, %v digits, %v special, %v charset", s.Length, s.UppercaseCount, s.DigitCount, s.SpecialCount, s.Charset)
+	if s.MaxRepeats != 0 {
+		ret += fmt.Sprintf(", at most %v repeats", s.MaxRepeats)
+	}
+
+	if s.MinClasses != 0 {
+		ret += fmt.Sprintf(", at least %v classes", s.MinClasses)
+	}
+
+	return ret
+}
+
+func (s *sitePolicy) validate() error {
+	charset, err := generator.CharsetByName(s.Charset)
+	if err != nil {
+		return errors.Wrap(err, "look up charset")
+	}
+
+	_, err = generator.New(s.Length, generator.WithUppercase(s.UppercaseCount), generator.WithDigits(s.DigitCount), generator.WithSpecial(s.SpecialCount), generator.WithCharset(charset), generator.WithMaxCharRepeats(s.MaxRepeats), generator.WithMinClasses(s.MinClasses))
+	if err != nil {
+		return errors.Wrap(err, "create password generator instance")
+	}
+
+	return nil
+}
+
+func siteStorePath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "sites.json"), nil
+}
+
+func readSiteStore() (*siteStore, error) {
+	path, err := siteStorePath()
+	if err != nil {
+		return nil, errors.Wrap(err, "get site store path")
+	}
+
+	store := &siteStore{Sites: make(map[string]sitePolicy)}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+
+		return nil, errors.Wrap(err, "open site store")
+	}
+	defer f.Close()
+
+	err = decodeSiteStore(f, store)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decode site store %v", path)
+	}
+
+	return store, nil
+}
+
+func decodeSiteStore(r io.Reader, store *siteStore) error {
+	err := json.NewDecoder(r).Decode(store)
+	if err != nil {
+		return err
+	}
+
+	if store.Sites == nil {
+		store.Sites = make(map[string]sitePolicy)
+	}
+
+	return nil
+}
+
+func writeSiteStore(store *siteStore) error {
+	path, err := siteStorePath()
+	if err != nil {
+		return errors.Wrap(err, "get site store path")
+	}
+
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal site store")
+	}
+
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+func loadSitePolicy(name string) (*sitePolicy, error) {
+	store, err := readSiteStore()
+	if err != nil {
+		return nil, err
+	}
+
+	policy, ok := store.Sites[name]
+	if !ok {
+		return nil, fmt.Errorf("no policy stored for site %q (see cpass site list)", name)
+	}
+
+	return &policy, nil
+}
+
+const siteUsage = `Usage:
+  cpass site add <name> -length n [-upper n] [-digits n] [-special n] [-charset name] [-max-repeats n] [-min-classes n] [-notes text] [-force]
+  cpass site edit <name> [-from-preset name] [-dry-run]
+  cpass site list
+  cpass site show <name>
+  cpass site rm <name>
+  cpass site export [-out path]
+  cpass site import <path> [-force]
+`
+
+func runSite(args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, siteUsage)
+		os.Exit(2)
+	}
+
+	var err error
+
+	switch args[0] {
+	case "add":
+		err = runSiteAdd(args[1:])
+	case "edit":
+		err = runSiteEdit(args[1:])
+	case "list":
+		err = runSiteList()
+	case "show":
+		err = runSiteShow(args[1:])
+	case "rm":
+		err = runSiteRemove(args[1:])
+	case "export":
+		err = runSiteExport(args[1:])
+	case "import":
+		err = runSiteImport(args[1:])
+	default:
+		fmt.Fprint(os.Stderr, siteUsage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: site %v: %s\n", args[0], err)
+		os.Exit(1)
+	}
+}
+
+func singleName(fs *flag.FlagSet, args []string) (string, error) {
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return "", errors.Wrap(err, "parse flags")
+	}
+
+	if len(positional) != 1 {
+		return "", fmt.Errorf("expected exactly one site name")
+	}
+
+	return positional[0], nil
+}
+
+func runSiteAdd(args []string) error {
+	fs := flag.NewFlagSet("site add", flag.ExitOnError)
+	length := fs.Uint("length", 0, "Password length")
+	upper := fs.Uint("upper", 0, "Number of uppercase characters")
+	digits := fs.Uint("digits", 0, "Number of digit characters")
+	special := fs.Uint("special", 0, "Number of special characters")
+	charset := fs.String("charset", generator.DefaultCharset.Name, "Named charset preset")
+	maxRepeats := fs.Uint("max-repeats", 0, "Maximum repeats of any single character (0 for no limit)")
+	minClasses := fs.Uint("min-classes", 0, "Minimum number of character classes (0 for no requirement)")
+	notes := fs.String("notes", "", "Free-text notes about the site's rules")
+	force := fs.Bool("force", false, "Replace an existing policy with the same name")
+
+	name, err := singleName(fs, args)
+	if err != nil {
+		return err
+	}
+
+	policy := sitePolicy{
+		Length:         uint32(*length),
+		UppercaseCount: uint32(*upper),
+		DigitCount:     uint32(*digits),
+		SpecialCount:   uint32(*special),
+		Charset:        *charset,
+		MaxRepeats:     uint32(*maxRepeats),
+		MinClasses:     uint32(*minClasses),
+		Notes:          *notes,
+	}
+
+	err = policy.validate()
+	if err != nil {
+		return errors.Wrap(err, "validate policy")
+	}
+
+	store, err := readSiteStore()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := store.Sites[name]; ok && !*force {
+		return fmt.Errorf("a policy for %q already exists, use -force to replace it", name)
+	}
+
+	store.Sites[name] = policy
+
+	err = writeSiteStore(store)
+	if err != nil {
+		return errors.Wrap(err, "write site store")
+	}
+
+	fmt.Printf("Stored the policy for %v: %v.\n", name, policy.describe())
+
+	return nil
+}
+
+func sortedSiteNames(store *siteStore) []string {
+	names := make([]string, 0, len(store.Sites))
+	for name := range store.Sites {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func runSiteList() error {
+	store, err := readSiteStore()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range sortedSiteNames(store) {
+		policy := store.Sites[name]
+		fmt.Printf("%v\t%v\n", name, policy.describe())
+	}
+
+	return nil
+}
+
+func runSiteShow(args []string) error {
+	name, err := singleName(flag.NewFlagSet("site show", flag.ExitOnError), args)
+	if err != nil {
+		return err
+	}
+
+	policy, err := loadSitePolicy(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Site: %v\nPolicy: %v\n", name, policy.describe())
+	if policy.Notes != "" {
+		fmt.Printf("Notes: %v\n", policy.Notes)
+	}
+
+	return nil
+}
+
+func runSiteRemove(args []string) error {
+	name, err := singleName(flag.NewFlagSet("site rm", flag.ExitOnError), args)
+	if err != nil {
+		return err
+	}
+
+	store, err := readSiteStore()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := store.Sites[name]; !ok {
+		return fmt.Errorf("no policy stored for site %q", name)
+	}
+
+	delete(store.Sites, name)
+
+	return errors.Wrap(writeSiteStore(store), "write site store")
+}
+
+func runSiteExport(args []string) error {
+	fs := flag.NewFlagSet("site export", flag.ExitOnError)
+	out := fs.String("out", "", "Write the exported policies to this file instead of stdout")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		return errors.Wrap(err, "parse flags")
+	}
+
+	store, err := readSiteStore()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal site store")
+	}
+
+	data = append(data, '\n')
+
+	if *out == "" {
+		_, err = os.Stdout.Write(data)
+		return errors.Wrap(err, "write to stdout")
+	}
+
+	return errors.Wrap(writeFileAtomic(*out, data), "write export file")
+}
+
+func runSiteImport(args []string) error {
+	fs := flag.NewFlagSet("site import", flag.ExitOnError)
+	force := fs.Bool("force", false, "Replace existing policies with the same names")
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return errors.Wrap(err, "parse flags")
+	}
+
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one file to import")
+	}
+
+	f, err := os.Open(positional[0])
+	if err != nil {
+		return errors.Wrap(err, "open import file")
+	}
+	defer f.Close()
+
+	imported := &siteStore{}
+	err = decodeSiteStore(f, imported)
+	if err != nil {
+		return errors.Wrap(err, "decode import file")
+	}
+
+	store, err := readSiteStore()
+	if err != nil {
+		return err
+	}
+
+	var conflicts []string
+	for _, name := range sortedSiteNames(imported) {
+		policy := imported.Sites[name]
+
+		err = policy.validate()
+		if err != nil {
+			return errors.Wrapf(err, "validate policy for %q", name)
+		}
+
+		if _, ok := store.Sites[name]; ok && !*force {
+			conflicts = append(conflicts, name)
+		}
+	}
+
+	if len(conflicts) != 0 {
+		return fmt.Errorf("policies for %v already exist, use -force to replace them", strings.Join(conflicts, ", "))
+	}
+
+	for name, policy := range imported.Sites {
+		store.Sites[name] = policy
+	}
+
+	err = writeSiteStore(store)
+	if err != nil {
+		return errors.Wrap(err, "write site store")
+	}
+
+	fmt.Printf("Imported %v site policies.\n", len(imported.Sites))
+
+	return nil
+}
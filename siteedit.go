@@ -0,0 +1,215 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/AlexSSD7/cpass/generator"
+	"github.com/pkg/errors"
+)
+
+// siteStoreModTime returns the modification time of the site store, or the
+// zero time if it doesn't exist yet.
+func siteStoreModTime() (time.Time, error) {
+	path, err := siteStorePath()
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "get site store path")
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, errors.Wrap(err, "stat site store")
+	}
+
+	return fi.ModTime(), nil
+}
+
+// runSiteEdit walks through the options of a site policy, with the stored
+// values or those of a preset as defaults, and stores the result.
+func runSiteEdit(args []string) error {
+	fs := flag.NewFlagSet("site edit", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Print the site store that would be written instead of saving it")
+	fromPreset := fs.String("from-preset", "", "Start from the policy of this password preset instead of the stored one")
+
+	name, err := singleName(fs, args)
+	if err != nil {
+		return err
+	}
+
+	modTime, err := siteStoreModTime()
+	if err != nil {
+		return err
+	}
+
+	store, err := readSiteStore()
+	if err != nil {
+		return err
+	}
+
+	policy, exists := store.Sites[name]
+	if *fromPreset != "" {
+		notes := policy.Notes
+		policy, err = presetPolicy(*fromPreset)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Starting the policy for %v from the %v preset: %v.\n", name, *fromPreset, policy.describe())
+		policy.Notes = notes
+		exists = true
+	} else if exists {
+		fmt.Printf("Editing the policy for %v: %v.\n", name, policy.describe())
+	} else {
+		fmt.Printf("Creating a policy for %v.\n", name)
+		policy = sitePolicy{Charset: generator.DefaultCharset.Name}
+	}
+
+	p := newPrompter(os.Stdin)
+	for {
+		err = askSitePolicy(p, &policy, !exists)
+		if err != nil {
+			return err
+		}
+
+		err = policy.validate()
+		if err == nil {
+			break
+		}
+
+		fmt.Printf("The policy is not valid: %s.\n", err)
+
+		// Go through the options again, this time with the new values as
+		// the defaults.
+		exists = true
+	}
+
+	store.Sites[name] = policy
+
+	if *dryRun {
+		data, err := json.MarshalIndent(store, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "marshal site store")
+		}
+
+		_, err = fmt.Printf("%s\n", data)
+		return errors.Wrap(err, "write to stdout")
+	}
+
+	current, err := siteStoreModTime()
+	if err != nil {
+		return err
+	}
+
+	if !current.Equal(modTime) {
+		return fmt.Errorf("the site store was changed while editing, run the edit again to apply the changes on top")
+	}
+
+	err = writeSiteStore(store)
+	if err != nil {
+		return errors.Wrap(err, "write site store")
+	}
+
+	fmt.Printf("Stored the policy for %v: %v.\n", name, policy.describe())
+
+	return nil
+}
+
+// askSitePolicy asks for every option of policy with the current values as
+// the defaults. A new policy has no default length. The entropy is shown after
+// every answer once the length is known.
+func askSitePolicy(p *prompter, policy *sitePolicy, isNew bool) error {
+	def := func(v uint32) *uint32 {
+		return &v
+	}
+
+	lengthDef := def(policy.Length)
+	if isNew {
+		lengthDef = nil
+	}
+
+	charset, err := p.askString("Charset preset", policy.Charset)
+	if err != nil {
+		return errors.Wrap(err, "ask for charset")
+	}
+	policy.Charset = charset
+
+	for _, field := range []struct {
+		name   string
+		prompt string
+		v      *uint32
+		def    *uint32
+	}{
+		{"length", "Password length", &policy.Length, lengthDef},
+		{"uppercase count", "Number of uppercase characters", &policy.UppercaseCount, def(policy.UppercaseCount)},
+		{"digit count", "Number of digit characters", &policy.DigitCount, def(policy.DigitCount)},
+		{"special count", "Number of special characters", &policy.SpecialCount, def(policy.SpecialCount)},
+		{"max repeats", "Maximum repeats of any single character (0 for no limit)", &policy.MaxRepeats, def(policy.MaxRepeats)},
+		{"min classes", "Minimum number of character classes (0 for no requirement)", &policy.MinClasses, def(policy.MinClasses)},
+	} {
+		*field.v, err = p.askUint32(field.prompt, field.def)
+		if err != nil {
+			return errors.Wrapf(err, "ask for %v", field.name)
+		}
+
+		if policy.Length != 0 {
+			fmt.Printf("  %v\n", sitePolicyEntropy(policy))
+		}
+	}
+
+	if n := p.discardPending(); n != 0 {
+		fmt.Printf("WARN: Ignored %v extra value(s) from the last answer.\n", n)
+	}
+
+	policy.Notes, err = p.askString("Notes", policy.Notes)
+	if err != nil {
+		return errors.Wrap(err, "ask for notes")
+	}
+
+	return nil
+}
+
+// sitePolicyEntropy describes the entropy of the policy as it stands, or why
+// it is not valid yet.
+func sitePolicyEntropy(policy *sitePolicy) string {
+	charset, err := generator.CharsetByName(policy.Charset)
+	if err != nil {
+		return fmt.Sprintf("Not valid yet: %s", err)
+	}
+
+	g, err := generator.New(policy.Length, generator.WithUppercase(policy.UppercaseCount), generator.WithDigits(policy.DigitCount), generator.WithSpecial(policy.SpecialCount), generator.WithCharset(charset), generator.WithMaxCharRepeats(policy.MaxRepeats), generator.WithMinClasses(policy.MinClasses))
+	if err != nil {
+		return fmt.Sprintf("Not valid yet: %s", err)
+	}
+
+	entropyMin, err := g.EntropyMin()
+	if err != nil {
+		return fmt.Sprintf("Not valid yet: %s", err)
+	}
+
+	entropyAvg := (float64(g.EntropyMax()) + float64(entropyMin)) / 2
+
+	return fmt.Sprintf("Entropy (min/realistic/max bits): %v/%v/%v (%v)", entropyMin, entropyAvg, g.EntropyMax(), getRatingString(entropyAvg))
+}
@@ -0,0 +1,173 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+var natoAlphabet = [26]string{
+	"Alfa", "Bravo", "Charlie", "Delta", "Echo", "Foxtrot", "Golf", "Hotel", "India", "Juliett", "Kilo", "Lima", "Mike",
+	"November", "Oscar", "Papa", "Quebec", "Romeo", "Sierra", "Tango", "Uniform", "Victor", "Whiskey", "X-ray", "Yankee", "Zulu",
+}
+
+var digitNames = [10]string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"}
+
+var symbolNames = map[rune]string{
+	' ': "space", '~': "tilde", '!': "exclamation mark", '@': "at sign", '#': "hash", '$': "dollar sign", '%': "percent sign",
+	'^': "caret", '&': "ampersand", '*': "asterisk", '_': "underscore", '+': "plus sign", '[': "left square bracket",
+	']': "right square bracket", '/': "slash", '?': "question mark", '<': "less-than sign", '>': "greater-than sign",
+	'.': "period", '`': "backtick", '-': "hyphen", '=': "equals sign", '\\': "backslash", ';': "semicolon",
+	'\'': "apostrophe", ',': "comma",
+}
+
+// spokenPassword spells the password out for a speech engine, one character
+// per line so that the engines pause between them. The caller should wipe the
+// returned buffer once it has been spoken.
+func spokenPassword(pw []byte) []byte {
+	// The longest spoken character is well under 32 bytes. Allocating for
+	// it upfront means appending never leaves unwiped copies behind.
+	ret := make([]byte, 0, len(pw)*32)
+	for i, c := range string(pw) {
+		if i != 0 {
+			ret = append(ret, ",\n"...)
+		}
+
+		ret = appendSpokenChar(ret, c)
+	}
+
+	return append(ret, ".\n"...)
+}
+
+// appendSpokenChar appends the spoken name of c to dst: the NATO phonetic
+// word for letters, with a "capital" marker for uppercase ones. Non-ASCII
+// characters are left to the speech engine.
+func appendSpokenChar(dst []byte, c rune) []byte {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return append(dst, natoAlphabet[c-'a']...)
+	case c >= 'A' && c <= 'Z':
+		dst = append(dst, "capital "...)
+		return append(dst, natoAlphabet[c-'A']...)
+	case c >= '0' && c <= '9':
+		dst = append(dst, "digit "...)
+		return append(dst, digitNames[c-'0']...)
+	}
+
+	if name, ok := symbolNames[c]; ok {
+		return append(dst, name...)
+	}
+
+	if c >= utf8.RuneSelf {
+		return utf8.AppendRune(dst, c)
+	}
+
+	dst = append(dst, "character code "...)
+	return strconv.AppendUint(dst, uint64(c), 10)
+}
+
+type speechEngine struct {
+	name string
+	// args returns the arguments for the engine at the given rate in words
+	// per minute. The text is always written to the engine's stdin, so that
+	// the password never shows up in the process list.
+	args func(wpm uint) []string
+}
+
+func clampRate(v, lo, hi int) string {
+	return strconv.Itoa(max(lo, min(hi, v)))
+}
+
+func speechEngines() []speechEngine {
+	switch runtime.GOOS {
+	case "darwin":
+		return []speechEngine{
+			{name: "say", args: func(wpm uint) []string { return []string{"-r", strconv.FormatUint(uint64(wpm), 10)} }},
+		}
+	case "windows":
+		return []speechEngine{
+			// SAPI rates go from -10 to 10, with 0 being roughly 180 wpm.
+			{name: "powershell", args: func(wpm uint) []string {
+				return []string{"-NoProfile", "-NonInteractive", "-Command", "Add-Type -AssemblyName System.Speech; " +
+					"$s = New-Object System.Speech.Synthesis.SpeechSynthesizer; " +
+					"$s.Rate = " + clampRate((int(wpm)-180)/18, -10, 10) + "; " +
+					"$s.Speak([Console]::In.ReadToEnd())"}
+			}},
+		}
+	default:
+		return []speechEngine{
+			// speech-dispatcher rates go from -100 to 100, with 0 being roughly 160 wpm.
+			{name: "spd-say", args: func(wpm uint) []string {
+				return []string{"-e", "-w", "-r", clampRate((int(wpm)-160)*100/160, -100, 100)}
+			}},
+			{name: "espeak-ng", args: func(wpm uint) []string { return []string{"--stdin", "-s", strconv.FormatUint(uint64(wpm), 10)} }},
+			{name: "espeak", args: func(wpm uint) []string { return []string{"--stdin", "-s", strconv.FormatUint(uint64(wpm), 10)} }},
+		}
+	}
+}
+
+type speaker struct {
+	path   string
+	engine speechEngine
+	wpm    uint
+}
+
+func findSpeaker(wpm uint) (*speaker, error) {
+	engines := speechEngines()
+
+	names := make([]string, len(engines))
+	for i, e := range engines {
+		path, err := exec.LookPath(e.name)
+		if err == nil {
+			return &speaker{path: path, engine: e, wpm: wpm}, nil
+		}
+
+		names[i] = e.name
+	}
+
+	return nil, fmt.Errorf("no text-to-speech engine found (tried %v)", strings.Join(names, ", "))
+}
+
+func (s *speaker) speak(pw []byte) error {
+	text := spokenPassword(pw)
+	defer wipe(text)
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(s.path, s.engine.args(s.wpm)...)
+	cmd.Stdin = bytes.NewReader(text)
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.Wrapf(err, "run %v (%v)", s.engine.name, msg)
+		}
+
+		return errors.Wrapf(err, "run %v", s.engine.name)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// outputTemplate is a parsed -format-template value. The supported verbs are:
+//
+//	%p  the password, as raw bytes without any quoting
+//	%e  the realistic entropy in bits
+//	%r  the rating of the realistic entropy
+//	%l  the password length in characters
+//	%n  the 1-based index of the password in this run
+//	%%  a literal percent sign
+//
+// The \t, \n and \\ escapes are supported as well.
+type outputTemplate []templatePart
+
+type templatePart struct {
+	literal string
+	verb    byte
+}
+
+type templateValues struct {
+	password []byte
+	entropy  float64
+	rating   string
+	index    int
+}
+
+func parseOutputTemplate(s string) (outputTemplate, error) {
+	var ret outputTemplate
+	var literal strings.Builder
+
+	flushFn := func() {
+		if literal.Len() != 0 {
+			ret = append(ret, templatePart{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '%':
+			if i+1 == len(s) {
+				return nil, fmt.Errorf("dangling %% at the end of the template")
+			}
+
+			i++
+			switch s[i] {
+			case '%':
+				literal.WriteByte('%')
+			case 'p', 'e', 'r', 'l', 'n':
+				flushFn()
+				ret = append(ret, templatePart{verb: s[i]})
+			default:
+				return nil, fmt.Errorf("unknown verb %%%c at position %v", s[i], i-1)
+			}
+		case '\\':
+			if i+1 == len(s) {
+				return nil, fmt.Errorf("dangling \\ at the end of the template")
+			}
+
+			i++
+			switch s[i] {
+			case 't':
+				literal.WriteByte('\t')
+			case 'n':
+				literal.WriteByte('\n')
+			case '\\':
+				literal.WriteByte('\\')
+			default:
+				return nil, fmt.Errorf("unknown escape \\%c at position %v", s[i], i-1)
+			}
+		default:
+			literal.WriteByte(s[i])
+		}
+	}
+
+	flushFn()
+
+	return ret, nil
+}
+
+func (t outputTemplate) render(w io.Writer, v templateValues) error {
+	for _, part := range t {
+		var err error
+
+		switch part.verb {
+		case 0:
+			_, err = io.WriteString(w, part.literal)
+		case 'p':
+			// Written straight from the buffer, so that no copy of the
+			// password that can't be wiped is made.
+			_, err = w.Write(v.password)
+		case 'e':
+			_, err = io.WriteString(w, strconv.FormatFloat(v.entropy, 'f', -1, 64))
+		case 'r':
+			_, err = io.WriteString(w, v.rating)
+		case 'l':
+			_, err = io.WriteString(w, strconv.Itoa(utf8.RuneCount(v.password)))
+		case 'n':
+			_, err = io.WriteString(w, strconv.Itoa(v.index))
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "write template part")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTemplateGolden(t *testing.T) {
+	batch := []templateValues{
+		{password: []byte("yQrceeD#se64_qvk"), entropy: 86.5, rating: "Good", index: 1},
+		{password: []byte(`a\b%c"d`), entropy: 31.25, rating: "Poor", index: 2},
+		{password: []byte("müde"), entropy: 40, rating: "Weak", index: 3},
+	}
+
+	var out bytes.Buffer
+	for _, s := range []string{
+		`%p\n`,
+		`%n\t%p\t%l\t%e\t%r\n`,
+		`[%n] %p (%e bits, %r)\n`,
+		`100%% %%p \\n\n`,
+		`no newline %n;`,
+		`%p%p\n`,
+	} {
+		tmpl, err := parseOutputTemplate(s)
+		if err != nil {
+			t.Fatalf("%q: %v", s, err)
+		}
+
+		out.WriteString("== " + s + "\n")
+		for _, v := range batch {
+			err = tmpl.render(&out, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		out.WriteString("\n")
+	}
+
+	checkGolden(t, "template.golden", out.Bytes())
+}
+
+// A batch from a fixed seed renders the same on every run and platform.
+func TestTemplateBatchGolden(t *testing.T) {
+	stdout, stderr, err := runCpass(t, "", "-no-sandbox", "-insecure-seed", "00", "-i-know-this-is-insecure", "-length", "16", "-upper", "2", "-digits", "2", "-special", "2", "-count", "3", "-format-template", `%n\t%p\t%l\t%e\t%r\n`)
+	if err != nil {
+		t.Fatalf("cpass: %v\n%s", err, stderr)
+	}
+
+	checkGolden(t, "template_batch.golden", []byte(stdout))
+}
+
+func TestParseOutputTemplateErrors(t *testing.T) {
+	for _, tc := range []struct {
+		template, want string
+	}{
+		{"%p%", "dangling %"},
+		{`%p\`, `dangling \`},
+		{"%x", "unknown verb %x at position 0"},
+		{`ab\q`, `unknown escape \q at position 2`},
+	} {
+		_, err := parseOutputTemplate(tc.template)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: got %v, want an error containing %q", tc.template, err, tc.want)
+		}
+	}
+}
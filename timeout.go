@@ -0,0 +1,123 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// exitCodeTimedOut is the exit code of a session that timed out waiting for
+// input, the same one timeout(1) uses.
+const exitCodeTimedOut = 124
+
+// timeLeftShown is how close to the session timeout the prompts start
+// showing the time left.
+const timeLeftShown = 30 * time.Second
+
+var errSessionTimeout = errors.New("no input within the session timeout")
+
+type readChunk struct {
+	b   []byte
+	err error
+}
+
+// timeoutReader fails reads with errSessionTimeout once no input has
+// arrived for the timeout. The underlying reader is read in the background,
+// so that a read can be abandoned without closing it and the terminal state
+// can be restored normally.
+type timeoutReader struct {
+	timeout  time.Duration
+	deadline atomic.Int64
+
+	chunks chan readChunk
+	buf    []byte
+	err    error
+}
+
+func newTimeoutReader(r io.Reader, timeout time.Duration) *timeoutReader {
+	t := &timeoutReader{
+		timeout: timeout,
+		chunks:  make(chan readChunk),
+	}
+	t.extend()
+
+	go func() {
+		for {
+			buf := make([]byte, 256)
+			n, err := r.Read(buf)
+			t.chunks <- readChunk{buf[:n], err}
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return t
+}
+
+func (t *timeoutReader) extend() {
+	t.deadline.Store(time.Now().Add(t.timeout).UnixNano())
+}
+
+// timeLeft returns the time until the session times out.
+func (t *timeoutReader) timeLeft() time.Duration {
+	return time.Until(time.Unix(0, t.deadline.Load()))
+}
+
+func (t *timeoutReader) Read(p []byte) (int, error) {
+	if len(t.buf) == 0 && t.err == nil {
+		timer := time.NewTimer(t.timeLeft())
+		defer timer.Stop()
+
+		select {
+		case c := <-t.chunks:
+			t.buf, t.err = c.b, c.err
+			t.extend()
+		case <-timer.C:
+			return 0, errSessionTimeout
+		}
+	}
+
+	if len(t.buf) == 0 {
+		return 0, t.err
+	}
+
+	n := copy(p, t.buf)
+	t.buf = t.buf[n:]
+
+	return n, nil
+}
+
+// exitTimedOut ends a session that timed out waiting for input. pw is wiped,
+// and if a password has been shown, the screen is cleared.
+func exitTimedOut(pw []byte, shown bool) {
+	wipe(pw)
+
+	if shown && stdoutIsTerminal() {
+		stdoutScreen().clearScreen()
+	}
+
+	fmt.Fprint(ui, "\nSession timed out waiting for input.\n")
+	os.Exit(exitCodeTimedOut)
+}
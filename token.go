@@ -0,0 +1,429 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AlexSSD7/cpass/generator"
+)
+
+const tokenReportPrefix = "Generated Token: "
+
+// defaultTokenBytes is the number of random bytes offered at the prompt.
+const defaultTokenBytes = 32
+
+func runToken(args []string) {
+	if len(args) > 0 && args[0] == "verify" {
+		runTokenVerify(args[1:])
+		return
+	}
+
+	fs := flag.NewFlagSet("token", flag.ExitOnError)
+	encoding := fs.String("encoding", "hex", "Encoding of the token: "+strings.Join(generator.TokenEncodingNames(), ", "))
+	nBytes := fs.Int("bytes", 0, fmt.Sprintf("Number of random bytes to encode (1-%v), skips the prompt", generator.MaxTokenBytes))
+	length := fs.Int("length", 0, fmt.Sprintf("Generate tokens of exactly this many characters (1-%v) instead of encoding a number of bytes", generator.MaxTokenLength))
+	bits := fs.Int("bits", 0, "Generate the shortest tokens that carry at least this many bits of entropy instead of encoding a number of bytes")
+	upper := fs.Bool("upper", false, "Use uppercase hex digits")
+	group := fs.Int("group", 0, "Insert a hyphen every this many characters of Crockford tokens, e.g. 4 or 5 (0 for none)")
+	check := fs.Bool("check", false, "Append the Crockford check symbol, or expect it with -verify")
+	verify := fs.Bool("verify", false, "Read a Crockford token and check that it was typed correctly instead of generating one")
+	count := fs.Int("count", 1, fmt.Sprintf("Number of tokens to generate (1-%v)", maxCount))
+	stream := fs.Bool("stream", false, "Stream -chars characters of -charset to stdout or -out instead of generating tokens, e.g. for long key material")
+	chars := fs.Int64("chars", 0, "Number of characters to stream with -stream")
+	streamCharset := fs.String("charset", generator.DefaultCharset.Name, "Charset preset to draw the characters from with -stream")
+	out := fs.String("out", "", "Write the stream to this file, readable only by you, instead of stdout")
+	quiet := fs.Bool("q", false, "Quiet mode: write only the tokens to stdout, one per line, and everything else to stderr")
+	emit := fs.String("emit", "", "Encode the same random bytes in each of these comma-separated encodings, e.g. hex,base64,base58, and write them as a JSON object per token")
+	prefix := fs.String("prefix", "", "Generate base62 tokens that start with this prefix, e.g. ghp_, and end with a CRC32 checksum, checked by cpass token verify")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	if *count < 1 || *count > maxCount {
+		fmt.Fprintf(os.Stderr, "Error: count must be between 1 and %v\n", maxCount)
+		os.Exit(1)
+	}
+
+	if *prefix != "" {
+		runTokenPrefixed(fs, *prefix, *length, *count, *quiet)
+		return
+	}
+
+	if *verify {
+		verifyCrockfordToken(*check)
+		return
+	}
+
+	if *stream {
+		runTokenStream(fs, *streamCharset, *chars, *out)
+		return
+	}
+
+	if *emit != "" {
+		runTokenEmit(fs, *emit, *nBytes, *count)
+		return
+	}
+
+	if *chars != 0 || *out != "" || *streamCharset != generator.DefaultCharset.Name {
+		fmt.Fprint(os.Stderr, "Error: -chars, -charset and -out only apply to -stream\n")
+		os.Exit(1)
+	}
+
+	if (*nBytes != 0 && *length != 0) || (*bits != 0 && (*nBytes != 0 || *length != 0)) {
+		fmt.Fprint(os.Stderr, "Error: only one of -bytes, -length and -bits can be given\n")
+		os.Exit(1)
+	}
+
+	if *bits < 0 {
+		fmt.Fprint(os.Stderr, "Error: bits must not be negative\n")
+		os.Exit(1)
+	}
+
+	if *quiet {
+		ui = os.Stderr
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	p := newPrompter(os.Stdin)
+	if !set["encoding"] {
+		*encoding, err = p.askString("Encoding ("+strings.Join(generator.TokenEncodingNames(), ", ")+")", *encoding)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: ask for encoding: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	enc, err := generator.TokenEncodingByName(*encoding)
+	if err != nil {
+		fmt.Fprintf(ui, "Error: select encoding: %s\n", err)
+		os.Exit(1)
+	}
+
+	if *upper && enc != generator.TokenHex {
+		fmt.Fprintf(ui, "Error: -upper only applies to hex, not %v\n", enc)
+		os.Exit(1)
+	}
+
+	if (*group != 0 || *check) && enc != generator.TokenCrockford {
+		fmt.Fprintf(ui, "Error: -group and -check only apply to crockford, not %v\n", enc)
+		os.Exit(1)
+	}
+
+	if *group < 0 {
+		fmt.Fprint(ui, "Error: group must not be negative\n")
+		os.Exit(1)
+	}
+
+	if *bits != 0 {
+		*length, err = generator.TokenLengthForBits(enc, *bits)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: find token length for %v bits: %s\n", *bits, err)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(ui, "Using token length %v to reach at least %v bits of entropy.\n", *length, *bits)
+	}
+
+	if *nBytes == 0 && *length == 0 {
+		def := uint32(defaultTokenBytes)
+
+		n, err := p.askUint32("Number of random bytes", &def)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: ask for byte length: %s\n", err)
+			os.Exit(1)
+		}
+
+		*nBytes = int(n)
+	}
+
+	if *length == 0 && (*nBytes < 1 || *nBytes > generator.MaxTokenBytes) {
+		fmt.Fprintf(ui, "Error: bytes must be between 1 and %v\n", generator.MaxTokenBytes)
+		os.Exit(1)
+	}
+
+	if *length < 0 || *length > generator.MaxTokenLength {
+		fmt.Fprintf(ui, "Error: length must be between 1 and %v\n", generator.MaxTokenLength)
+		os.Exit(1)
+	}
+
+	generate := func() ([]byte, error) {
+		return generator.GenerateToken(enc, *nBytes)
+	}
+	entropy := generator.TokenEntropy(*nBytes)
+
+	if *length != 0 {
+		generate = func() ([]byte, error) {
+			return generator.GenerateTokenOfLength(enc, *length)
+		}
+		entropy = generator.TokenLengthEntropy(enc, *length)
+	}
+
+	writeGenerated(*count, *quiet, tokenReportPrefix, "token", func() ([]byte, error) {
+		b, err := generate()
+		if err != nil {
+			return nil, err
+		}
+
+		if *upper {
+			upperHex(b)
+		}
+
+		if *group != 0 || *check {
+			return generator.FormatCrockford(b, generator.CrockfordOptions{Group: *group, Check: *check})
+		}
+
+		return b, nil
+	})
+
+	fmt.Fprintln(ui, passphraseEntropyString(entropy))
+	generator.DiscardBufferedRandomness()
+}
+
+// runTokenEmit writes count tokens of nBytes random bytes, each in every
+// encoding of the comma-separated emit, as JSON objects on stdout.
+func runTokenEmit(fs *flag.FlagSet, emit string, nBytes, count int) {
+	var conflicting []string
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "encoding", "length", "bits", "upper", "group", "check", "chars", "charset", "out":
+			conflicting = append(conflicting, "-"+f.Name)
+		}
+	})
+
+	if len(conflicting) != 0 {
+		fmt.Fprintf(os.Stderr, "Error: -emit cannot be combined with %v\n", strings.Join(conflicting, ", "))
+		os.Exit(1)
+	}
+
+	// Only the JSON objects go to stdout.
+	ui = os.Stderr
+
+	names := strings.Split(emit, ",")
+	encs := make([]generator.TokenEncoding, len(names))
+	for i, name := range names {
+		enc, err := generator.TokenEncodingByName(name)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: select encoding: %s\n", err)
+			os.Exit(1)
+		}
+
+		for _, prev := range encs[:i] {
+			if prev == enc {
+				fmt.Fprintf(ui, "Error: encoding %v is given more than once\n", enc)
+				os.Exit(1)
+			}
+		}
+
+		encs[i] = enc
+	}
+
+	if nBytes == 0 {
+		def := uint32(defaultTokenBytes)
+
+		n, err := newPrompter(os.Stdin).askUint32("Number of random bytes", &def)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: ask for byte length: %s\n", err)
+			os.Exit(1)
+		}
+
+		nBytes = int(n)
+	}
+
+	if nBytes < 1 || nBytes > generator.MaxTokenBytes {
+		fmt.Fprintf(ui, "Error: bytes must be between 1 and %v\n", generator.MaxTokenBytes)
+		os.Exit(1)
+	}
+
+	entropy := generator.TokenEntropy(nBytes)
+	for i := 0; i < count; i++ {
+		encoded, err := generator.GenerateTokenEncodings(encs, nBytes)
+		if err != nil {
+			fmt.Fprintf(ui, "Error: generate token: %s\n", err)
+			os.Exit(1)
+		}
+
+		err = writeTokenJSONReport(os.Stdout, JSONTokenReport{
+			SchemaVersion: JSONSchemaVersion,
+			GeneratedAt:   time.Now().UTC().Format(time.RFC3339),
+			Mode:          "token",
+			Bytes:         nBytes,
+			Entropy:       entropy,
+			Rating:        getRatingString(entropy),
+			Encodings:     JSONSecrets{Names: names, Values: encoded},
+		})
+
+		for _, b := range encoded {
+			wipe(b)
+		}
+
+		if err != nil {
+			fmt.Fprintf(ui, "Error: write JSON report: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Fprintln(ui, passphraseEntropyString(entropy)+", the same in every encoding")
+	generator.DiscardBufferedRandomness()
+}
+
+// runTokenPrefixed writes count tokens of prefix, length base62 characters,
+// and their checksum.
+func runTokenPrefixed(fs *flag.FlagSet, prefix string, length, count int, quiet bool) {
+	var conflicting []string
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "encoding", "bytes", "bits", "upper", "group", "check", "verify", "stream", "chars", "charset", "out", "emit":
+			conflicting = append(conflicting, "-"+f.Name)
+		}
+	})
+
+	if len(conflicting) != 0 {
+		fmt.Fprintf(os.Stderr, "Error: -prefix cannot be combined with %v\n", strings.Join(conflicting, ", "))
+		os.Exit(1)
+	}
+
+	if quiet {
+		ui = os.Stderr
+	}
+
+	if length == 0 {
+		length = generator.DefaultPrefixedTokenLength
+	}
+
+	if length < 1 || length > generator.MaxTokenLength {
+		fmt.Fprintf(ui, "Error: length must be between 1 and %v\n", generator.MaxTokenLength)
+		os.Exit(1)
+	}
+
+	writeGenerated(count, quiet, tokenReportPrefix, "token", func() ([]byte, error) {
+		return generator.GeneratePrefixedToken(prefix, length)
+	})
+
+	fmt.Fprintln(ui, passphraseEntropyString(generator.PrefixedTokenEntropy(length))+", the prefix and the checksum add none")
+	generator.DiscardBufferedRandomness()
+}
+
+// runTokenVerify reads a prefixed token, without echo on a terminal, and
+// reports whether its prefix and checksum match. It doesn't print the token.
+func runTokenVerify(args []string) {
+	fs := flag.NewFlagSet("token verify", flag.ExitOnError)
+	prefix := fs.String("prefix", "", "Prefix the token must start with, e.g. ghp_")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	if *prefix == "" {
+		fmt.Fprint(os.Stderr, "Error: -prefix is required\n")
+		os.Exit(1)
+	}
+
+	b, err := readSecret("Token")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: read token: %s\n", err)
+		os.Exit(1)
+	}
+
+	err = generator.VerifyPrefixedToken(b, *prefix)
+	wipe(b)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: verify token: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "The token starts with %v and its checksum matches.\n", *prefix)
+}
+
+func runTokenStream(fs *flag.FlagSet, charset string, chars int64, out string) {
+	var conflicting []string
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "encoding", "bytes", "length", "bits", "upper", "group", "check", "count":
+			conflicting = append(conflicting, "-"+f.Name)
+		}
+	})
+
+	if len(conflicting) != 0 {
+		fmt.Fprintf(os.Stderr, "Error: -stream cannot be combined with %v\n", strings.Join(conflicting, ", "))
+		os.Exit(1)
+	}
+
+	if chars < 1 {
+		fmt.Fprint(os.Stderr, "Error: -stream needs -chars of at least 1\n")
+		os.Exit(1)
+	}
+
+	// The stream itself may go to stdout.
+	ui = os.Stderr
+
+	err := streamToken(charset, chars, out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: stream token: %s\n", err)
+		os.Exit(1)
+	}
+
+	generator.DiscardBufferedRandomness()
+}
+
+// upperHex uppercases the hex digits of b in place, so that no copy of the
+// token is left behind.
+func upperHex(b []byte) {
+	for i, c := range b {
+		if c >= 'a' && c <= 'f' {
+			b[i] = c - 'a' + 'A'
+		}
+	}
+}
+
+// verifyCrockfordToken reads a Crockford token, without echo on a terminal,
+// and reports whether it is well-formed. It doesn't print the token.
+func verifyCrockfordToken(check bool) {
+	b, err := readSecret("Token")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: read token: %s\n", err)
+		os.Exit(1)
+	}
+
+	err = generator.VerifyCrockford(b, check)
+	wipe(b)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: verify token: %s\n", err)
+		os.Exit(1)
+	}
+
+	if check {
+		fmt.Fprint(os.Stderr, "The token is valid Crockford base32 and its check symbol matches.\n")
+	} else {
+		fmt.Fprint(os.Stderr, "The token is valid Crockford base32.\n")
+	}
+}
@@ -0,0 +1,125 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/AlexSSD7/cpass/generator"
+	"github.com/pkg/errors"
+)
+
+// streamToken writes chars characters drawn uniformly from the charset to
+// path, or stdout if it is empty, through Generator.Reader, so that the
+// output is never held in memory as a whole.
+func streamToken(charsetName string, chars int64, path string) error {
+	charset, err := generator.CharsetByName(charsetName)
+	if err != nil {
+		return errors.Wrap(err, "select charset")
+	}
+
+	// The counts only decide which classes the stream draws from.
+	g, err := generator.New(4, generator.WithCharset(charset), generator.WithUppercase(boolCount(charset.Uppercase)), generator.WithDigits(boolCount(charset.Digits != "")), generator.WithSpecial(boolCount(charset.Special != "")))
+	if err != nil {
+		return errors.Wrap(err, "create generator")
+	}
+
+	var w io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return errors.Wrap(err, "open output file")
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	r := g.Reader()
+	defer r.Close()
+
+	err = copyChars(w, r, chars)
+	if err != nil {
+		return err
+	}
+
+	if f, ok := w.(*os.File); ok && path != "" {
+		err = f.Close()
+		if err != nil {
+			return errors.Wrap(err, "close output file")
+		}
+	} else if stdoutIsTerminal() {
+		// Only so that the report starts on a line of its own. The stream
+		// itself has no newline.
+		fmt.Fprintln(ui)
+	}
+
+	fmt.Fprintf(ui, "Wrote %v characters from the %v charset.\n", chars, charset.Name)
+	fmt.Fprintln(ui, passphraseEntropyString(float64(chars)*math.Log2(float64(charset.Size()))))
+
+	return nil
+}
+
+// copyChars copies the first n UTF-8 encoded characters of the endless
+// stream r to w. A character ends where the next one starts, so the copy
+// stops right before the start byte of character n+1.
+func copyChars(w io.Writer, r io.Reader, n int64) error {
+	buf := make([]byte, 4096)
+	defer wipe(buf)
+
+	var count int64
+	for {
+		_, err := io.ReadFull(r, buf)
+		if err != nil {
+			return errors.Wrap(err, "read stream")
+		}
+
+		end := len(buf)
+		for i, b := range buf {
+			if b&0xc0 == 0x80 {
+				continue
+			}
+
+			if count == n {
+				end = i
+				break
+			}
+
+			count++
+		}
+
+		_, err = w.Write(buf[:end])
+		if err != nil {
+			return errors.Wrap(err, "write stream")
+		}
+
+		if end < len(buf) {
+			return nil
+		}
+	}
+}
+
+func boolCount(b bool) uint32 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,96 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/AlexSSD7/cpass/generator"
+)
+
+// repeatReader endlessly repeats s.
+type repeatReader struct {
+	s   string
+	pos int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.s[r.pos]
+		r.pos = (r.pos + 1) % len(r.s)
+	}
+
+	return len(p), nil
+}
+
+func TestCopyCharsCountsCharacters(t *testing.T) {
+	for _, tc := range []struct {
+		s string
+		n int64
+	}{
+		{"ab", 1},
+		{"ab", 4096},
+		{"ab", 4097},
+		{"aé😀", 3},
+		{"aé😀", 10000},
+		{"😀", 1024},
+		{"😀", 1025},
+	} {
+		var out bytes.Buffer
+		err := copyChars(&out, &repeatReader{s: tc.s}, tc.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !utf8.Valid(out.Bytes()) {
+			t.Errorf("%q, %v: output is not valid UTF-8", tc.s, tc.n)
+		}
+
+		if got := utf8.RuneCount(out.Bytes()); int64(got) != tc.n {
+			t.Errorf("%q, %v: got %v characters", tc.s, tc.n, got)
+		}
+	}
+}
+
+func TestCopyCharsFromGenerator(t *testing.T) {
+	g, err := generator.New(1, generator.WithCharset(generator.EmojiCharset))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := g.Reader()
+	defer r.Close()
+
+	var out bytes.Buffer
+	err = copyChars(&out, r, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range out.String() {
+		if !strings.ContainsRune(generator.EmojiCharset.Letters, c) {
+			t.Fatalf("%q is not in the emoji charset", c)
+		}
+	}
+
+	if got := utf8.RuneCount(out.Bytes()); got != 5000 {
+		t.Errorf("got %v characters, want 5000", got)
+	}
+}
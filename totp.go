@@ -0,0 +1,76 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/AlexSSD7/cpass/generator"
+)
+
+func runTOTP(args []string) {
+	fs := flag.NewFlagSet("totp", flag.ExitOnError)
+	nBytes := fs.Int("bytes", generator.DefaultTOTPSecretBytes, fmt.Sprintf("Number of random bytes of the secret (%v-%v)", generator.MinTOTPSecretBytes, generator.MaxTokenBytes))
+	issuer := fs.String("issuer", "", "Service name for the otpauth:// URI, e.g. the company or host")
+	account := fs.String("account", "", "Write the otpauth:// URI for this account name instead of the bare secret, e.g. for a QR encoder")
+	quiet := fs.Bool("q", false, "Quiet mode: write only the secret or URI to stdout, and everything else to stderr")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	if *nBytes < generator.MinTOTPSecretBytes || *nBytes > generator.MaxTokenBytes {
+		fmt.Fprintf(os.Stderr, "Error: bytes must be between %v and %v\n", generator.MinTOTPSecretBytes, generator.MaxTokenBytes)
+		os.Exit(1)
+	}
+
+	if *issuer != "" && *account == "" {
+		fmt.Fprint(os.Stderr, "Error: -issuer needs -account\n")
+		os.Exit(1)
+	}
+
+	if *quiet {
+		ui = os.Stderr
+	}
+
+	generate := func() ([]byte, error) {
+		return generator.GenerateTOTPSecret(*nBytes)
+	}
+	prefix := "Generated Secret: "
+
+	if *account != "" {
+		generate = func() ([]byte, error) {
+			secret, err := generator.GenerateTOTPSecret(*nBytes)
+			if err != nil {
+				return nil, err
+			}
+			defer wipe(secret)
+
+			return generator.TOTPURI(secret, *issuer, *account)
+		}
+		prefix = "Generated URI: "
+	}
+
+	writeGenerated(1, *quiet, prefix, "TOTP secret", generate)
+
+	fmt.Fprintln(ui, passphraseEntropyString(generator.TokenEntropy(*nBytes)))
+	generator.DiscardBufferedRandomness()
+}
@@ -0,0 +1,58 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/AlexSSD7/cpass/generator"
+)
+
+func runUUID(args []string) {
+	fs := flag.NewFlagSet("uuid", flag.ExitOnError)
+	count := fs.Int("count", 1, fmt.Sprintf("Number of distinct UUIDs to generate (1-%v)", maxCount))
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	if *count < 1 || *count > maxCount {
+		fmt.Fprintf(os.Stderr, "Error: count must be between 1 and %v\n", maxCount)
+		os.Exit(1)
+	}
+
+	uuids, err := generator.GenerateUUIDsV4(*count)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: generate UUIDs: %s\n", err)
+		os.Exit(1)
+	}
+
+	err = writeBatch(uuids, func(_ int, b []byte) error {
+		return writeLine(os.Stdout, b)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: write UUIDs: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "Entropy per UUID: %v random bits (%v)\n", generator.UUIDEntropy, getRatingString(generator.UUIDEntropy))
+	generator.DiscardBufferedRandomness()
+}
@@ -0,0 +1,128 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/AlexSSD7/cpass/generator"
+	"github.com/pkg/errors"
+)
+
+// parseInterspersed parses flags that may appear before, between, or after
+// positional arguments, and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		err := parseFlags(fs, args)
+		if err != nil {
+			return nil, err
+		}
+
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func runWordlist(args []string) {
+	if len(args) == 0 || args[0] != "build" {
+		fmt.Fprint(os.Stderr, "Usage: cpass wordlist build <corpus.txt> [-min-len n] [-max-len n] [-chars set] [-drop-common n] [-out path]\n")
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("wordlist build", flag.ExitOnError)
+	minLen := fs.Int("min-len", 4, "Minimum word length in characters")
+	maxLen := fs.Int("max-len", 8, "Maximum word length in characters (0 for no limit)")
+	chars := fs.String("chars", "", "Characters words may consist of (default a-z)")
+	dropCommon := fs.Int("drop-common", 0, fmt.Sprintf("Drop this many of the most common English words (at most %v)", generator.MaxCommonEnglishWords))
+	out := fs.String("out", "", "Write the wordlist to this file instead of stdout")
+
+	positional, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	if len(positional) != 1 {
+		fmt.Fprint(os.Stderr, "Error: expected exactly one corpus file\n")
+		os.Exit(2)
+	}
+
+	err = buildWordlist(positional[0], *out, generator.WordlistBuildOptions{
+		MinLength:    *minLen,
+		MaxLength:    *maxLen,
+		AllowedChars: *chars,
+		DropCommon:   *dropCommon,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: build wordlist: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func buildWordlist(corpusPath, outPath string, opts generator.WordlistBuildOptions) error {
+	corpus, err := os.Open(corpusPath)
+	if err != nil {
+		return errors.Wrap(err, "open corpus")
+	}
+	defer corpus.Close()
+
+	words, err := generator.BuildWordlist(corpus, opts)
+	if err != nil {
+		return errors.Wrap(err, "tokenize corpus")
+	}
+
+	if len(words) == 0 {
+		return fmt.Errorf("no words left after filtering")
+	}
+
+	out := os.Stdout
+	if outPath != "" {
+		out, err = os.Create(outPath)
+		if err != nil {
+			return errors.Wrap(err, "create output file")
+		}
+		defer out.Close()
+	}
+
+	err = generator.WriteWordlist(out, words)
+	if err != nil {
+		return errors.Wrap(err, "write wordlist")
+	}
+
+	if outPath != "" {
+		err = out.Close()
+		if err != nil {
+			return errors.Wrap(err, "close output file")
+		}
+	}
+
+	if len(words) < generator.RecommendedMinWordlistSize {
+		fmt.Fprintf(os.Stderr, "WARN: The wordlist only has %v words, which is below the recommended minimum of %v. Each word carries %.2f bits instead of at least %.2f, so passphrases need considerably more words to be secure.\n",
+			len(words), generator.RecommendedMinWordlistSize, generator.BitsPerWord(len(words)), generator.BitsPerWord(generator.RecommendedMinWordlistSize))
+	}
+
+	fmt.Fprintf(os.Stderr, "Wordlist has %v words (%.2f bits per word).\n", len(words), generator.BitsPerWord(len(words)))
+
+	return nil
+}
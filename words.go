@@ -0,0 +1,87 @@
+// cpass - A minimalist CLI random password generator focusing on convenience and security.
+// Copyright (c) 2023 The cpass Authors.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlexSSD7/cpass/generator"
+)
+
+func runWords(args []string) {
+	fs := flag.NewFlagSet("words", flag.ExitOnError)
+	count := fs.Int("count", 1, fmt.Sprintf("Number of words to print (1-%v)", maxCount))
+	unique := fs.Bool("unique", false, "Never print the same word twice in a run")
+	wordlistFile := fs.String("wordlist", "", "Choose the words from this file of one word per line instead of an embedded wordlist")
+	list := fs.String("list", generator.DefaultWordlistName, "Embedded wordlist to choose the words from: "+strings.Join(generator.WordlistNames(), ", "))
+	lang := fs.String("wordlist-lang", "", "Choose the words from the embedded wordlist for this language: "+strings.Join(generator.WordlistLanguages(), ", "))
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: parse flags: %s\n", err)
+		os.Exit(2)
+	}
+
+	if *count < 1 || *count > maxCount {
+		fmt.Fprintf(os.Stderr, "Error: count must be between 1 and %v\n", maxCount)
+		os.Exit(1)
+	}
+
+	// Only the words go to stdout.
+	ui = os.Stderr
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	wordlist := selectWordlist(set, *wordlistFile, *list, *lang)
+
+	// No separator, so that no word is left out.
+	g, err := generator.NewPassphraseGenerator(1, wordlist, "")
+	if err != nil {
+		fmt.Fprintf(ui, "Error: create passphrase generator instance: %s\n", err)
+		os.Exit(1)
+	}
+
+	words, err := g.GenerateWords(uint32(*count), *unique)
+	if err != nil {
+		fmt.Fprintf(ui, "Error: generate words: %s\n", err)
+		os.Exit(1)
+	}
+
+	for _, w := range words {
+		err = writeLine(os.Stdout, []byte(w))
+		if err != nil {
+			fmt.Fprintf(ui, "Error: write words: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	clear(words)
+
+	fmt.Fprintf(ui, "Each word carries %.2f bits (1 of %v words).\n", generator.BitsPerWord(g.WordlistSize()), g.WordlistSize())
+	if *count > 1 {
+		bits := g.WordsEntropy(uint32(*count), *unique)
+		fmt.Fprintf(ui, "Entropy of all %v words together: %.2f bits (%v)\n", *count, bits, getRatingString(bits))
+	}
+
+	generator.DiscardBufferedRandomness()
+}